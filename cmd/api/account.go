@@ -0,0 +1,224 @@
+package main
+
+import (
+	"billify-api/internal/auth"
+	"billify-api/internal/mailer"
+	"billify-api/internal/store"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"time"
+)
+
+const (
+	verifyEmailTTL   = 48 * time.Hour
+	resetPasswordTTL = time.Hour
+)
+
+var (
+	errEmailNotVerified  = errors.New("verify your email address first")
+	errEmailVerified     = errors.New("email address is already verified")
+	errAccountLinkExpiry = errors.New("this link is invalid or has expired")
+)
+
+// requireVerified turns away users who have not verified their email.
+// Until then they can sign in and manage their account, but not create a
+// business or see and answer invitations sent to that email.
+func (app *application) requireVerified(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(userCtx).(*store.User)
+
+		if !user.EmailVerified {
+			app.forbiddenErrorResponse(w, r, errEmailNotVerified)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// issueUserToken stores a token for purpose and returns it for emailing.
+func (app *application) issueUserToken(r *http.Request, user *store.User, purpose string, ttl time.Duration) (string, error) {
+	token, err := auth.RandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = app.store.Users.CreateToken(r.Context(), &store.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Hash:      auth.HashToken(token),
+		ExpiresAt: time.Now().Add(ttl).Truncate(time.Second),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (app *application) sendAccountEmail(r *http.Request, user *store.User, subject, body string) error {
+	msg := mailer.Message{
+		From:    (&mail.Address{Name: "Billify", Address: app.config.mail.fromAddress}).String(),
+		To:      []string{user.Email},
+		Subject: subject,
+		Body:    body,
+	}
+
+	return app.mailer.Send(r.Context(), msg)
+}
+
+func (app *application) sendVerificationEmail(r *http.Request, user *store.User) error {
+	token, err := app.issueUserToken(r, user, store.TokenVerifyEmail, verifyEmailTTL)
+	if err != nil {
+		return err
+	}
+
+	link := app.config.frontendURL + "/verify-email?token=" + token
+
+	return app.sendAccountEmail(r, user, "Verify your email address", fmt.Sprintf(
+		"Hi %s,\n\nPlease confirm this is your email address:\n\n%s\n\n"+
+			"The link expires in %d hours. If you did not create a Billify account, you can ignore this email.\n",
+		user.FirstName,
+		link,
+		int(verifyEmailTTL.Hours()),
+	))
+}
+
+func (app *application) sendPasswordResetEmail(r *http.Request, user *store.User) error {
+	token, err := app.issueUserToken(r, user, store.TokenResetPassword, resetPasswordTTL)
+	if err != nil {
+		return err
+	}
+
+	link := app.config.frontendURL + "/reset-password?token=" + token
+
+	return app.sendAccountEmail(r, user, "Reset your password", fmt.Sprintf(
+		"Hi %s,\n\nUse this link to choose a new password:\n\n%s\n\n"+
+			"The link works once and expires in %d minutes. Resetting your password signs you out on all devices.\n"+
+			"If you did not ask to reset your password, you can ignore this email.\n",
+		user.FirstName,
+		link,
+		int(resetPasswordTTL.Minutes()),
+	))
+}
+
+type AccountTokenPayload struct {
+	Token string `json:"token" validate:"required"`
+}
+
+func (app *application) verifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	var payload AccountTokenPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Users.VerifyEmail(r.Context(), auth.HashToken(payload.Token)); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.badRequestResponse(w, r, errAccountLinkExpiry)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resendVerificationHandler emails the signed-in user a new verification
+// link. Links sent earlier stop working.
+func (app *application) resendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userCtx).(*store.User)
+
+	if user.EmailVerified {
+		app.conflictResponse(w, r, errEmailVerified)
+		return
+	}
+
+	if err := app.sendVerificationEmail(r, user); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+type ForgotPasswordPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// forgotPasswordHandler emails a password reset link. It answers the same
+// whether or not the email has an account.
+func (app *application) forgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ForgotPasswordPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.store.Users.GetByEmail(r.Context(), payload.Email)
+	switch err {
+	case nil:
+		if err := app.sendPasswordResetEmail(r, user); err != nil {
+			app.logger.Errorw("password reset email not sent", "user_id", user.ID, "error", err.Error())
+		}
+	case store.ErrNotFound:
+	default:
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+type ResetPasswordPayload struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=3,max=72"`
+}
+
+// resetPasswordHandler sets a new password with a token from a reset email
+// and signs the user out on every device.
+func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ResetPasswordPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := &store.User{}
+	if err := user.Password.Set(payload.Password); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.Users.ResetPassword(r.Context(), auth.HashToken(payload.Token), user); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.badRequestResponse(w, r, errAccountLinkExpiry)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	clearRefreshTokenCookie(w)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"billify-api/internal/auth"
+	"billify-api/internal/mailer"
+	"billify-api/internal/mailer/mailertest"
+	"billify-api/internal/store"
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// fakeUserTokens knows one user and keeps the tokens issued. Other methods
+// are never called.
+type fakeUserTokens struct {
+	*store.UserStore
+	user   *store.User
+	tokens []*store.UserToken
+}
+
+func (f *fakeUserTokens) GetByID(_ context.Context, userID uuid.UUID) (*store.User, error) {
+	if userID != f.user.ID {
+		return nil, store.ErrNotFound
+	}
+	return f.user, nil
+}
+
+func (f *fakeUserTokens) GetByEmail(_ context.Context, email string) (*store.User, error) {
+	if !strings.EqualFold(email, f.user.Email) {
+		return nil, store.ErrNotFound
+	}
+	return f.user, nil
+}
+
+func (f *fakeUserTokens) CreateToken(_ context.Context, token *store.UserToken) error {
+	f.tokens = append(f.tokens, token)
+	return nil
+}
+
+type accountFixture struct {
+	app     *application
+	handler http.Handler
+	server  *mailertest.Server
+	users   *fakeUserTokens
+	bearer  string
+}
+
+func newAccountFixture(t *testing.T) *accountFixture {
+	t.Helper()
+
+	server := mailertest.NewServer()
+	t.Cleanup(server.Close)
+
+	users := &fakeUserTokens{user: &store.User{ID: uuid.New(), FirstName: "Ravi", Email: "ravi@example.com"}}
+	sessionID := uuid.New()
+
+	app := &application{
+		config: config{
+			frontendURL: "https://app.billify.test",
+			mail:        mailConfig{fromAddress: "no-reply@billify.test"},
+		},
+		store: store.Storage{
+			Users:    users,
+			Sessions: &fakeSessions{active: map[uuid.UUID]uuid.UUID{sessionID: users.user.ID}},
+		},
+		mailer: mailer.NewSMTPMailer(server.Addr, "", ""),
+		token:  auth.NewJWTAuthenticator("acc", "ref", "billify", "billify", time.Hour, time.Hour),
+		logger: zap.NewNop().Sugar(),
+	}
+
+	bearer, err := app.token.GenerateAccessToken(users.user.ID, sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &accountFixture{app: app, handler: app.mount(), server: server, users: users, bearer: bearer}
+}
+
+// post sends body to path through the application's router, signed in as
+// the fixture's user when signedIn is set.
+func (f *accountFixture) post(path, body string, signedIn bool) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if signedIn {
+		r.Header.Set("Authorization", "Bearer "+f.bearer)
+	}
+	w := httptest.NewRecorder()
+	f.handler.ServeHTTP(w, r)
+	return w
+}
+
+// readMail returns the subject and decoded text of a message the server
+// accepted.
+func readMail(t *testing.T, data []byte) (string, string) {
+	t.Helper()
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoder mime.WordDecoder
+	subject, err := decoder.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	part, err := multipart.NewReader(msg.Body, params["boundary"]).NextRawPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(part))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return subject, string(body)
+}
+
+var linkToken = regexp.MustCompile(`\?token=([A-Za-z0-9_-]+)`)
+
+// checkAccountEmail checks the one email sent carries a link to path whose
+// token is the one stored for purpose, and returns the token.
+func (f *accountFixture) checkAccountEmail(t *testing.T, subject, path, purpose string, ttl time.Duration) string {
+	t.Helper()
+
+	messages := f.server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(messages))
+	}
+	msg := messages[0]
+
+	if msg.From != "no-reply@billify.test" {
+		t.Errorf("MAIL FROM = %q", msg.From)
+	}
+	if len(msg.To) != 1 || msg.To[0] != f.users.user.Email {
+		t.Errorf("RCPT TO = %q, want %s", msg.To, f.users.user.Email)
+	}
+
+	gotSubject, body := readMail(t, msg.Data)
+	if gotSubject != subject {
+		t.Errorf("Subject = %q, want %q", gotSubject, subject)
+	}
+	if !strings.Contains(body, "Hi Ravi,") {
+		t.Errorf("body does not greet the user:\n%s", body)
+	}
+
+	link := f.app.config.frontendURL + path + "?token="
+	if !strings.Contains(body, link) {
+		t.Fatalf("body has no %s link:\n%s", link, body)
+	}
+	match := linkToken.FindStringSubmatch(body)
+	if match == nil {
+		t.Fatalf("no token in body:\n%s", body)
+	}
+	token := match[1]
+
+	if len(f.users.tokens) != 1 {
+		t.Fatalf("stored %d tokens, want 1", len(f.users.tokens))
+	}
+	stored := f.users.tokens[0]
+	if stored.UserID != f.users.user.ID || stored.Purpose != purpose {
+		t.Errorf("stored token for %v/%s, want %v/%s", stored.UserID, stored.Purpose, f.users.user.ID, purpose)
+	}
+	if !bytes.Equal(stored.Hash, auth.HashToken(token)) {
+		t.Error("stored hash is not the hash of the emailed token")
+	}
+	if until := time.Until(stored.ExpiresAt); until <= ttl-time.Minute || until > ttl {
+		t.Errorf("token expires in %v, want %v", until, ttl)
+	}
+
+	return token
+}
+
+func TestResendVerificationHandler(t *testing.T) {
+	f := newAccountFixture(t)
+
+	if w := f.post("/v1/user/verify-email", "", false); w.Code != http.StatusUnauthorized {
+		t.Fatalf("signed out: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	if w := f.post("/v1/user/verify-email", "", true); w.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusAccepted, w.Body.String())
+	}
+
+	f.checkAccountEmail(t, "Verify your email address", "/verify-email", store.TokenVerifyEmail, verifyEmailTTL)
+}
+
+func TestSendPasswordResetEmail(t *testing.T) {
+	f := newAccountFixture(t)
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/auth/forgot-password", nil)
+	if err := f.app.sendPasswordResetEmail(r, f.users.user); err != nil {
+		t.Fatalf("sendPasswordResetEmail: %v", err)
+	}
+
+	f.checkAccountEmail(t, "Reset your password", "/reset-password", store.TokenResetPassword, resetPasswordTTL)
+}
+
+func TestForgotPasswordHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		wantMail bool
+	}{
+		{"known email", "Ravi@Example.com", true},
+		{"unknown email", "nobody@example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAccountFixture(t)
+
+			w := f.post("/v1/auth/forgot-password", `{"email": "`+tt.email+`"}`, false)
+
+			// The answer is the same either way, so it does not reveal
+			// which emails have accounts.
+			if w.Code != http.StatusAccepted {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusAccepted)
+			}
+
+			if tt.wantMail {
+				f.checkAccountEmail(t, "Reset your password", "/reset-password", store.TokenResetPassword, resetPasswordTTL)
+			} else if n := len(f.server.Messages()); n != 0 || len(f.users.tokens) != 0 {
+				t.Errorf("sent %d emails and stored %d tokens, want none", n, len(f.users.tokens))
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"billify-api/internal/store"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type AnalyticsReportParams struct {
+	Format  string `validate:"omitempty,oneof=json csv"`
+	Compare string `validate:"omitempty,oneof=previous_period previous_year"`
+}
+
+type ReportPeriod[T any] struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	Data T         `json:"data"`
+}
+
+type ComparedReport[T any] struct {
+	Current    ReportPeriod[T]  `json:"current"`
+	Comparison *ReportPeriod[T] `json:"comparison,omitempty"`
+}
+
+// comparisonPeriod returns the range a report is compared against: the
+// equally long range immediately before it, or the same dates a year earlier.
+func comparisonPeriod(compare string, from, to time.Time) (time.Time, time.Time) {
+	if compare == "previous_year" {
+		return from.AddDate(-1, 0, 0), to.AddDate(-1, 0, 0)
+	}
+
+	days := int(to.Sub(from).Hours() / 24)
+	prevTo := from.AddDate(0, 0, -1)
+	return prevTo.AddDate(0, 0, -days), prevTo
+}
+
+// serveAnalyticsReport runs fetch for the requested date range and, when a
+// comparison is asked for, for the comparison range too. CSV output prefixes
+// every row with the period it belongs to.
+func serveAnalyticsReport[T any](
+	app *application,
+	w http.ResponseWriter,
+	r *http.Request,
+	name string,
+	header []string,
+	fetch func(context.Context, uuid.UUID, time.Time, time.Time) (T, error),
+	records func(T) [][]string,
+) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	params := AnalyticsReportParams{
+		Format:  r.URL.Query().Get("format"),
+		Compare: r.URL.Query().Get("compare"),
+	}
+	if err := Validate.Struct(params); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	data, err := fetch(r.Context(), busID, from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	report := ComparedReport[T]{Current: ReportPeriod[T]{From: from, To: to, Data: data}}
+	if params.Compare != "" {
+		prevFrom, prevTo := comparisonPeriod(params.Compare, from, to)
+		prevData, err := fetch(r.Context(), busID, prevFrom, prevTo)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		report.Comparison = &ReportPeriod[T]{From: prevFrom, To: prevTo, Data: prevData}
+	}
+
+	if params.Format == "csv" {
+		rows := [][]string{append([]string{"Period"}, header...)}
+		periods := []ReportPeriod[T]{report.Current}
+		if report.Comparison != nil {
+			periods = append(periods, *report.Comparison)
+		}
+		for _, period := range periods {
+			label := fmt.Sprintf("%s to %s", period.From.Format(dateLayout), period.To.Format(dateLayout))
+			for _, record := range records(period.Data) {
+				rows = append(rows, append([]string{label}, record...))
+			}
+		}
+
+		filename := fmt.Sprintf("%s-%s-%s.csv", name, from.Format(dateLayout), to.Format(dateLayout))
+		if err := writeCSV(w, filename, rows); err != nil {
+			app.logger.Errorw("failed to write csv", "error", err.Error())
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, report)
+}
+
+type SalesReportParams struct {
+	Interval string `validate:"omitempty,oneof=day week month"`
+}
+
+func (app *application) getSalesReportHandler(w http.ResponseWriter, r *http.Request) {
+	params := SalesReportParams{Interval: r.URL.Query().Get("interval")}
+	if err := Validate.Struct(params); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if params.Interval == "" {
+		params.Interval = "month"
+	}
+
+	fetch := func(ctx context.Context, busID uuid.UUID, from, to time.Time) (*store.SalesSeries, error) {
+		return app.store.Reports.GetSalesSeries(ctx, busID, from, to, params.Interval)
+	}
+
+	serveAnalyticsReport(app, w, r, "sales", []string{"Period Start", "Invoices", "Total"}, fetch,
+		func(series *store.SalesSeries) [][]string {
+			records := [][]string{}
+			for _, point := range series.Points {
+				records = append(records, []string{
+					point.Period.Format(dateLayout),
+					strconv.Itoa(point.Invoices),
+					formatAmount(point.Total),
+				})
+			}
+			return append(records, []string{"Total", strconv.Itoa(series.Invoices), formatAmount(series.Total)})
+		})
+}
+
+func (app *application) getSalesByCustomerReportHandler(w http.ResponseWriter, r *http.Request) {
+	header := []string{"Customer", "Invoices", "Taxable Value", "Tax", "Total"}
+	serveAnalyticsReport(app, w, r, "sales-by-customer", header, app.store.Reports.GetSalesByCustomer,
+		func(sales []store.CustomerSales) [][]string {
+			records := [][]string{}
+			for _, row := range sales {
+				records = append(records, []string{
+					row.Name,
+					strconv.Itoa(row.Invoices),
+					formatAmount(row.Taxable),
+					formatAmount(row.Tax),
+					formatAmount(row.Total),
+				})
+			}
+			return records
+		})
+}
+
+func (app *application) getSalesByProductReportHandler(w http.ResponseWriter, r *http.Request) {
+	header := []string{"Product", "HSN/SAC", "Unit", "Quantity", "Taxable Value", "Tax", "Total"}
+	serveAnalyticsReport(app, w, r, "sales-by-product", header, app.store.Reports.GetSalesByProduct,
+		func(sales []store.ProductSales) [][]string {
+			records := [][]string{}
+			for _, row := range sales {
+				records = append(records, []string{
+					row.Name,
+					row.HSNCode,
+					row.Unit,
+					strconv.FormatFloat(row.Quantity, 'f', -1, 64),
+					formatAmount(row.Taxable),
+					formatAmount(row.Tax),
+					formatAmount(row.Total),
+				})
+			}
+			return records
+		})
+}
+
+func (app *application) getTaxByRateReportHandler(w http.ResponseWriter, r *http.Request) {
+	header := []string{"Tax Rate", "Taxable Value", "Tax", "Total"}
+	serveAnalyticsReport(app, w, r, "tax-by-rate", header, app.store.Reports.GetTaxByRate,
+		func(rates []store.TaxRateSales) [][]string {
+			records := [][]string{}
+			for _, row := range rates {
+				records = append(records, []string{
+					strconv.FormatFloat(row.TaxRate, 'f', -1, 64),
+					formatAmount(row.Taxable),
+					formatAmount(row.Tax),
+					formatAmount(row.Total),
+				})
+			}
+			return records
+		})
+}
+
+func (app *application) getProfitAndLossReportHandler(w http.ResponseWriter, r *http.Request) {
+	serveAnalyticsReport(app, w, r, "profit-and-loss", []string{"Line", "Amount"}, app.store.Reports.GetProfitAndLoss,
+		func(pl *store.ProfitAndLoss) [][]string {
+			records := [][]string{
+				{"Sales", formatAmount(pl.Sales)},
+				{"Purchases", formatAmount(pl.Purchases)},
+				{"Gross Profit", formatAmount(pl.GrossProfit)},
+			}
+			for _, category := range pl.ExpensesByCategory {
+				records = append(records, []string{"Expense: " + category.Category, formatAmount(category.Amount)})
+			}
+			return append(records,
+				[]string{"Total Expenses", formatAmount(pl.Expenses)},
+				[]string{"Net Profit", formatAmount(pl.NetProfit)},
+				[]string{"Output Tax", formatAmount(pl.OutputTax)},
+				[]string{"Input Tax", formatAmount(pl.InputTax)},
+			)
+		})
+}
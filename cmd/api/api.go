@@ -2,14 +2,19 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"billify-api/internal/auth"
 	"billify-api/internal/env"
+	"billify-api/internal/files"
+	"billify-api/internal/gateway"
+	"billify-api/internal/mailer"
 	"billify-api/internal/pdf"
 	"billify-api/internal/store"
 
@@ -21,12 +26,17 @@ import (
 )
 
 type application struct {
-	config config
-	store  store.Storage
-	logger *zap.SugaredLogger
-	token  auth.Authenticator
-	oauth  auth.OAuthAuthenticator
-	pdf    pdf.PDFGenerator
+	config      config
+	store       store.Storage
+	logger      *zap.SugaredLogger
+	token       auth.Authenticator
+	oauth       auth.OAuthAuthenticator
+	portalToken auth.Authenticator
+	pdf         pdf.PDFGenerator
+	files       files.Store
+	gateway     gateway.Provider
+	mailer      mailer.Mailer
+	links       *auth.SignedTokens
 }
 
 type config struct {
@@ -35,7 +45,11 @@ type config struct {
 	env         string
 	apiURL      string
 	frontendURL string
+	uploadsDir  string
 	auth        authConfig
+	gateway     gatewayConfig
+	mail        mailConfig
+	reminders   reminderConfig
 }
 
 type authConfig struct {
@@ -43,12 +57,35 @@ type authConfig struct {
 	oauth map[string]oauthConfig
 }
 
+// tokenConfig holds the JWT settings. linkSecret signs the tokens in links
+// handed out to people without an account, such as invoice share links.
 type tokenConfig struct {
-	accSecret string
-	refSecret string
-	iss       string
-	accExp    time.Duration
-	refExp    time.Duration
+	accSecret  string
+	refSecret  string
+	linkSecret string
+	iss        string
+	accExp     time.Duration
+	refExp     time.Duration
+}
+
+// validate refuses a configuration that would sign tokens with an empty key,
+// which anyone could then forge.
+func (c tokenConfig) validate() error {
+	var missing []string
+	for _, secret := range []struct{ name, value string }{
+		{"ACCESS_SECRET", c.accSecret},
+		{"REFRESH_SECRET", c.refSecret},
+		{"LINK_SECRET", c.linkSecret},
+	} {
+		if secret.value == "" {
+			missing = append(missing, secret.name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s must be set", strings.Join(missing, ", "))
+	}
+
+	return nil
 }
 
 type oauthConfig struct {
@@ -60,6 +97,33 @@ type oauthConfig struct {
 	scopes       []string
 }
 
+// gatewayConfig selects the online payment provider. Payment links are
+// disabled while provider is empty.
+type gatewayConfig struct {
+	provider      string
+	apiURL        string
+	keyID         string
+	keySecret     string
+	webhookSecret string
+}
+
+// mailConfig points at the SMTP relay. Invoices are sent from fromAddress
+// under the business's name, with replies going to the business.
+type mailConfig struct {
+	smtpAddr    string
+	username    string
+	password    string
+	fromAddress string
+}
+
+// reminderConfig schedules the payment reminder job; an interval of zero
+// turns it off. graceDays is how long a missed one-off reminder is still
+// sent late.
+type reminderConfig struct {
+	interval  time.Duration
+	graceDays int
+}
+
 type dbConfig struct {
 	addr         string
 	maxOpenConns int
@@ -100,7 +164,32 @@ func (app *application) mount() http.Handler {
 			r.Get("/refresh", app.refreshTokenHandler)
 			r.Post("/register", app.registerUserHandler)
 			r.Post("/login", app.loginHandler)
+			r.Post("/login/mfa", app.verifyMFALoginHandler)
 			r.Post("/logout", app.logoutHandler)
+			r.Post("/verify-email", app.verifyEmailHandler)
+			r.Post("/forgot-password", app.forgotPasswordHandler)
+			r.Post("/reset-password", app.resetPasswordHandler)
+		})
+		r.Route("/webhooks", func(r chi.Router) {
+			r.Post("/{provider}", app.paymentWebhookHandler)
+		})
+		r.Route("/public", func(r chi.Router) {
+			r.Get("/invoices/{token}", app.getPublicInvoiceHandler)
+			r.Get("/invoices/{token}/pdf", app.getPublicInvoicePDFHandler)
+		})
+		r.Route("/portal", func(r chi.Router) {
+			r.Post("/auth/magic-link", app.requestPortalLoginHandler)
+			r.Post("/auth/verify", app.verifyPortalLoginHandler)
+			r.Group(func(r chi.Router) {
+				r.Use(app.PortalMiddleware)
+				r.Get("/me", app.getPortalProfileHandler)
+				r.Get("/invoices", app.getPortalInvoicesHandler)
+				r.Get("/invoices/{id}", app.getPortalInvoiceHandler)
+				r.Get("/invoices/{id}/pdf", app.getPortalInvoicePDFHandler)
+				r.Post("/invoices/{id}/pay", app.payPortalInvoiceHandler)
+				r.Get("/customers/{custID}/statement", app.getPortalStatementHandler)
+				r.Get("/customers/{custID}/statement/pdf", app.getPortalStatementPDFHandler)
+			})
 		})
 		r.Route("/oauth", func(r chi.Router) {
 			r.Get("/{provider}", app.providerOAuthHandler)
@@ -110,42 +199,197 @@ func (app *application) mount() http.Handler {
 			r.Use(app.AuthMiddleware)
 			r.Get("/", app.getUserHandler)
 			r.Put("/", app.updateUserHandler)
+			r.Post("/verify-email", app.resendVerificationHandler)
+			r.With(app.requireVerified).Get("/invitations", app.getUserInvitationsHandler)
+			r.With(app.requireVerified).Post("/invitations/{id}/accept", app.acceptInvitationHandler)
+			r.With(app.requireVerified).Post("/invitations/{id}/decline", app.declineInvitationHandler)
+			r.Get("/sessions", app.getSessionsHandler)
+			r.Delete("/sessions", app.revokeSessionsHandler)
+			r.Delete("/sessions/{id}", app.revokeSessionHandler)
+			r.Get("/mfa", app.getMFAHandler)
+			r.Post("/mfa/enroll", app.enrollMFAHandler)
+			r.Post("/mfa/confirm", app.confirmMFAHandler)
+			r.Post("/mfa/recovery-codes", app.regenerateRecoveryCodesHandler)
+			r.Post("/mfa/disable", app.disableMFAHandler)
 		})
 		r.Route("/business", func(r chi.Router) {
 			r.Use(app.AuthMiddleware)
 			r.Get("/", app.getBusinessesByUserIDHandler)
-			r.Post("/dashboard", app.getBusinessesDashboardHandler)
-			r.Post("/", app.createBusinessHandler)
-			r.Put("/", app.updateBusinessHandler)
-			r.Get("/{busID}", app.getBusinessByIDHandler)
+			r.With(app.authorize(permView, app.byBody(""))).Post("/dashboard", app.getBusinessesDashboardHandler)
+			r.With(app.requireVerified).Post("/", app.createBusinessHandler)
+			r.With(app.authorize(permSettings, app.byBody(store.ResourceBusiness))).Put("/", app.updateBusinessHandler)
+			r.With(app.authorize(permView, byParam("busID"))).Get("/{busID}", app.getBusinessByIDHandler)
+			r.With(app.authorize(permView, byParam("busID"))).Get("/{busID}/reports/aging", app.getAgingReportHandler)
+			r.With(app.authorize(permView, byParam("busID"))).Get("/{busID}/reports/stock-valuation", app.getStockValuationReportHandler)
+			r.With(app.authorize(permView, byParam("busID"))).Get("/{busID}/reports/input-tax-credit", app.getInputTaxCreditReportHandler)
+			r.With(app.authorize(permView, byParam("busID"))).Get("/{busID}/reports/sales", app.getSalesReportHandler)
+			r.With(app.authorize(permView, byParam("busID"))).Get("/{busID}/reports/sales-by-customer", app.getSalesByCustomerReportHandler)
+			r.With(app.authorize(permView, byParam("busID"))).Get("/{busID}/reports/sales-by-product", app.getSalesByProductReportHandler)
+			r.With(app.authorize(permView, byParam("busID"))).Get("/{busID}/reports/tax-by-rate", app.getTaxByRateReportHandler)
+			r.With(app.authorize(permView, byParam("busID"))).Get("/{busID}/reports/profit-and-loss", app.getProfitAndLossReportHandler)
+			r.With(app.authorize(permView, byParam("busID"))).Get("/{busID}/reports/trial-balance", app.getTrialBalanceReportHandler)
+			r.With(app.authorize(permView, byParam("busID"))).Get("/{busID}/reports/general-ledger", app.getGeneralLedgerReportHandler)
+			r.With(app.authorize(permView, byParam("busID"))).Get("/{busID}/export/tally.xml", app.exportTallyHandler)
+			r.With(app.authorize(permView, byParam("busID"))).Get("/{busID}/export/tally-mapping", app.getTallyMappingHandler)
+			r.With(app.authorize(permAccounting, byParam("busID"))).Put("/{busID}/export/tally-mapping", app.updateTallyMappingHandler)
+			r.With(app.authorize(permView, byParam("busID"))).Get("/{busID}/email-templates/{kind}", app.getEmailTemplateHandler)
+			r.With(app.authorize(permSettings, byParam("busID"))).Put("/{busID}/email-templates/{kind}", app.updateEmailTemplateHandler)
+			r.With(app.authorize(permDeleteBusiness, byParam("busID"))).Delete("/{busID}", app.deleteBusinessHandler)
+			r.With(app.authorize(permSecurity, byParam("busID"))).Put("/{busID}/security", app.updateBusinessSecurityHandler)
+			r.With(app.authorize(permView, byParam("busID"))).Get("/{busID}/members", app.getMembersHandler)
+			r.With(app.authorize(permMembers, byParam("busID"))).Put("/{busID}/members/{userID}", app.updateMemberHandler)
+			r.With(app.authorize(permView, byParam("busID"))).Delete("/{busID}/members/{userID}", app.removeMemberHandler)
+			r.With(app.authorize(permMembers, byParam("busID"))).Get("/{busID}/invitations", app.getInvitationsHandler)
+			r.With(app.authorize(permMembers, byParam("busID"))).Post("/{busID}/invitations", app.createInvitationHandler)
+			r.With(app.authorize(permMembers, app.byRecord(store.ResourceInvitation, "invitationID"))).Delete("/invitations/{invitationID}", app.revokeInvitationHandler)
 		})
 		r.Route("/invoices", func(r chi.Router) {
             r.Use(app.AuthMiddleware)
-            r.Post("/", app.createInvoiceHandler)
-            r.Get("/{id}", app.getInvoiceByIDHandler)
-            r.Put("/", app.updateInvoiceHandler)
-			r.Put("/{id}/status", app.updateInvoiceStatusHandler)
-            r.Delete("/{id}", app.deleteInvoiceHandler)
-			r.Get("/{invID}/pdf", app.getInvoiceAsPDFHandler)
-            r.Get("/business/{busID}", app.getInvoicesByBusinessIDHandler)
-			r.Get("/next-invoice-no/{busID}", app.getNextInvoiceNumberHandler)
+            r.With(app.authorize(permInvoices, app.byBody(""))).Post("/", app.createInvoiceHandler)
+            r.With(app.authorize(permView, app.byRecord(store.ResourceInvoice, "id"))).Get("/{id}", app.getInvoiceByIDHandler)
+            r.With(app.authorize(permInvoices, app.byBody(store.ResourceInvoice))).Put("/", app.updateInvoiceHandler)
+			r.With(app.authorize(permInvoices, app.byRecord(store.ResourceInvoice, "id"))).Put("/{id}/status", app.updateInvoiceStatusHandler)
+            r.With(app.authorize(permInvoices, app.byRecord(store.ResourceInvoice, "id"))).Delete("/{id}", app.deleteInvoiceHandler)
+			r.With(app.authorize(permView, app.byRecord(store.ResourceInvoice, "invID"))).Get("/{invID}/pdf", app.getInvoiceAsPDFHandler)
+            r.With(app.authorize(permView, byParam("busID"))).Get("/business/{busID}", app.getInvoicesByBusinessIDHandler)
+			r.With(app.authorize(permView, byParam("busID"))).Get("/next-invoice-no/{busID}", app.getNextInvoiceNumberHandler)
+			r.With(app.authorize(permView, app.byRecord(store.ResourceInvoice, "id"))).Get("/{id}/payments", app.getInvoicePaymentsHandler)
+			r.With(app.authorize(permPayments, app.byRecord(store.ResourceInvoice, "id"))).Post("/{id}/payments", app.createInvoicePaymentHandler)
+			r.With(app.authorize(permPayments, app.byRecord(store.ResourcePayment, "paymentID"))).Delete("/payments/{paymentID}", app.deleteInvoicePaymentHandler)
+			r.With(app.authorize(permView, app.byRecord(store.ResourceInvoice, "id"))).Get("/{id}/payment-links", app.getPaymentLinksHandler)
+			r.With(app.authorize(permInvoices, app.byRecord(store.ResourceInvoice, "id"))).Post("/{id}/payment-links", app.createPaymentLinkHandler)
+			r.With(app.authorize(permInvoices, app.byRecord(store.ResourceInvoice, "id"))).Post("/{id}/send", app.sendInvoiceHandler)
+			r.With(app.authorize(permView, app.byRecord(store.ResourceInvoice, "id"))).Get("/{id}/deliveries", app.getInvoiceDeliveriesHandler)
+			r.With(app.authorize(permView, app.byRecord(store.ResourceInvoice, "id"))).Get("/{id}/reminders", app.getInvoiceRemindersHandler)
+			r.With(app.authorize(permView, app.byRecord(store.ResourceInvoice, "id"))).Get("/{id}/shares", app.getInvoiceSharesHandler)
+			r.With(app.authorize(permInvoices, app.byRecord(store.ResourceInvoice, "id"))).Post("/{id}/shares", app.createInvoiceShareHandler)
+			r.With(app.authorize(permInvoices, app.byRecord(store.ResourceInvoiceShare, "shareID"))).Delete("/shares/{shareID}", app.revokeInvoiceShareHandler)
+			r.With(app.authorize(permView, app.byRecord(store.ResourceInvoiceShare, "shareID"))).Get("/shares/{shareID}/views", app.getInvoiceShareViewsHandler)
+			r.With(app.authorize(permView, app.byRecord(store.ResourceInvoice, "id"))).Get("/{id}/credit-notes", app.getCreditNotesHandler)
+			r.With(app.authorize(permInvoices, app.byRecord(store.ResourceInvoice, "id"))).Post("/{id}/credit-notes", app.createCreditNoteHandler)
+			r.With(app.authorize(permInvoices, app.byRecord(store.ResourceInvoice, "id"))).Post("/{id}/void", app.voidInvoiceHandler)
+			r.With(app.authorize(permView, app.byRecord(store.ResourceCreditNote, "creditNoteID"))).Get("/credit-notes/{creditNoteID}", app.getCreditNoteHandler)
+			r.With(app.authorize(permInvoices, app.byRecord(store.ResourceCreditNote, "creditNoteID"))).Delete("/credit-notes/{creditNoteID}", app.deleteCreditNoteHandler)
         })
 		r.Route("/customers", func(r chi.Router) {
 		    r.Use(app.AuthMiddleware)
-		    r.Get("/business/{busID}", app.getCustomersByBusinessIDHandler)
-		    r.Post("/", app.createCustomerHandler)
-		    r.Put("/", app.updateCustomerHandler)
-		    r.Delete("/{id}", app.deleteCustomerHandler)
+		    r.With(app.authorize(permView, byParam("busID"))).Get("/business/{busID}", app.getCustomersByBusinessIDHandler)
+		    r.With(app.authorize(permInvoices, byParam("busID"))).Post("/business/{busID}/import", app.importCustomersHandler)
+		    r.With(app.authorize(permInvoices, app.byBody(""))).Post("/", app.createCustomerHandler)
+		    r.With(app.authorize(permInvoices, app.byBody(store.ResourceCustomer))).Put("/", app.updateCustomerHandler)
+		    r.With(app.authorize(permInvoices, app.byRecord(store.ResourceCustomer, "id"))).Delete("/{id}", app.deleteCustomerHandler)
+		    r.With(app.authorize(permView, app.byRecord(store.ResourceCustomer, "id"))).Get("/{id}/statement", app.getCustomerStatementHandler)
+		    r.With(app.authorize(permView, app.byRecord(store.ResourceCustomer, "id"))).Get("/{id}/statement/pdf", app.getCustomerStatementPDFHandler)
+		    r.With(app.authorize(permInvoices, app.byRecord(store.ResourceCustomer, "id"))).Put("/{id}/price-list", app.setCustomerPriceListHandler)
+		    r.With(app.authorize(permInvoices, app.byRecord(store.ResourceCustomer, "id"))).Put("/{id}/reminders", app.setCustomerRemindersHandler)
 		    // r.Get("/{id}", app.getCustomerByIDHandler)
 		})
 		r.Route("/products", func(r chi.Router) {
 		    r.Use(app.AuthMiddleware)
-		    r.Post("/", app.createProductHandler)
-		    r.Put("/", app.updateProductHandler)
-		    r.Delete("/{id}", app.deleteProductHandler)
-		    r.Get("/business/{busID}", app.getProductsByBusinessIDHandler)
+		    r.With(app.authorize(permCatalog, app.byBody(""))).Post("/", app.createProductHandler)
+		    r.With(app.authorize(permCatalog, app.byBody(store.ResourceProduct))).Put("/", app.updateProductHandler)
+		    r.With(app.authorize(permCatalog, app.byRecord(store.ResourceProduct, "id"))).Delete("/{id}", app.deleteProductHandler)
+		    r.With(app.authorize(permView, byParam("busID"))).Get("/business/{busID}", app.getProductsByBusinessIDHandler)
+		    r.With(app.authorize(permCatalog, byParam("busID"))).Post("/business/{busID}/import", app.importProductsHandler)
+		    r.With(app.authorize(permView, byParam("busID"))).Get("/business/{busID}/export", app.exportProductsHandler)
+		    r.With(app.authorize(permView, byParam("busID"))).Get("/business/{busID}/low-stock", app.getLowStockHandler)
+		    r.With(app.authorize(permView, byParam("busID"))).Get("/business/{busID}/lookup", app.lookupProductHandler)
+		    r.Get("/units", app.getUnitsHandler)
+		    r.With(app.authorize(permCatalog, app.byBody(store.ResourceProductVariant))).Put("/variants", app.updateVariantHandler)
+		    r.With(app.authorize(permCatalog, app.byRecord(store.ResourceProductVariant, "variantID"))).Delete("/variants/{variantID}", app.deleteVariantHandler)
+		    r.With(app.authorize(permView, app.byRecord(store.ResourceProduct, "id"))).Get("/{id}/variants", app.getVariantsByProductIDHandler)
+		    r.With(app.authorize(permCatalog, app.byRecord(store.ResourceProduct, "id"))).Post("/{id}/variants", app.createVariantHandler)
+		    r.With(app.authorize(permView, app.byRecord(store.ResourceProduct, "id"))).Get("/{id}/stock", app.getProductStockHandler)
+		    r.With(app.authorize(permView, app.byRecord(store.ResourceProduct, "id"))).Get("/{id}/stock/movements", app.getProductStockMovementsHandler)
+		    r.With(app.authorize(permCatalog, app.byRecord(store.ResourceProduct, "id"))).Post("/{id}/stock/movements", app.createStockMovementHandler)
+		    r.With(app.authorize(permView, app.byRecord(store.ResourceProduct, "id"))).Get("/{id}/price", app.resolveProductPriceHandler)
 		    // r.Get("/{id}", app.getProductByIDHandler)
 		})
+		r.Route("/categories", func(r chi.Router) {
+		    r.Use(app.AuthMiddleware)
+		    r.With(app.authorize(permCatalog, app.byBody(""))).Post("/", app.createCategoryHandler)
+		    r.With(app.authorize(permCatalog, app.byBody(store.ResourceCategory))).Put("/", app.updateCategoryHandler)
+		    r.With(app.authorize(permCatalog, app.byRecord(store.ResourceCategory, "id"))).Delete("/{id}", app.deleteCategoryHandler)
+		    r.With(app.authorize(permView, byParam("busID"))).Get("/business/{busID}", app.getCategoriesByBusinessIDHandler)
+		})
+		r.Route("/vendors", func(r chi.Router) {
+		    r.Use(app.AuthMiddleware)
+		    r.With(app.authorize(permPurchases, app.byBody(""))).Post("/", app.createVendorHandler)
+		    r.With(app.authorize(permPurchases, app.byBody(store.ResourceVendor))).Put("/", app.updateVendorHandler)
+		    r.With(app.authorize(permView, app.byRecord(store.ResourceVendor, "id"))).Get("/{id}", app.getVendorByIDHandler)
+		    r.With(app.authorize(permPurchases, app.byRecord(store.ResourceVendor, "id"))).Delete("/{id}", app.deleteVendorHandler)
+		    r.With(app.authorize(permView, byParam("busID"))).Get("/business/{busID}", app.getVendorsByBusinessIDHandler)
+		})
+		r.Route("/purchases", func(r chi.Router) {
+		    r.Use(app.AuthMiddleware)
+		    r.With(app.authorize(permPurchases, app.byBody(""))).Post("/", app.createPurchaseBillHandler)
+		    r.With(app.authorize(permPurchases, app.byBody(store.ResourcePurchaseBill))).Put("/", app.updatePurchaseBillHandler)
+		    r.With(app.authorize(permView, app.byRecord(store.ResourcePurchaseBill, "id"))).Get("/{id}", app.getPurchaseBillByIDHandler)
+		    r.With(app.authorize(permPurchases, app.byRecord(store.ResourcePurchaseBill, "id"))).Delete("/{id}", app.deletePurchaseBillHandler)
+		    r.With(app.authorize(permPurchases, app.byRecord(store.ResourcePurchaseBill, "id"))).Post("/{id}/payments", app.createBillPaymentHandler)
+		    r.With(app.authorize(permPurchases, app.byRecord(store.ResourceBillPayment, "paymentID"))).Delete("/payments/{paymentID}", app.deleteBillPaymentHandler)
+		    r.With(app.authorize(permView, byParam("busID"))).Get("/business/{busID}", app.getPurchaseBillsByBusinessIDHandler)
+		})
+		r.Route("/expenses", func(r chi.Router) {
+		    r.Use(app.AuthMiddleware)
+		    r.With(app.authorize(permPurchases, app.byBody(""))).Post("/", app.createExpenseHandler)
+		    r.With(app.authorize(permPurchases, app.byBody(store.ResourceExpense))).Put("/", app.updateExpenseHandler)
+		    r.With(app.authorize(permView, app.byRecord(store.ResourceExpense, "id"))).Get("/{id}", app.getExpenseByIDHandler)
+		    r.With(app.authorize(permPurchases, app.byRecord(store.ResourceExpense, "id"))).Delete("/{id}", app.deleteExpenseHandler)
+		    r.With(app.authorize(permPurchases, app.byRecord(store.ResourceExpense, "id"))).Put("/{id}/receipt", app.uploadExpenseReceiptHandler)
+		    r.With(app.authorize(permView, app.byRecord(store.ResourceExpense, "id"))).Get("/{id}/receipt", app.getExpenseReceiptHandler)
+		    r.With(app.authorize(permPurchases, app.byRecord(store.ResourceExpense, "id"))).Delete("/{id}/receipt", app.deleteExpenseReceiptHandler)
+		    r.With(app.authorize(permView, byParam("busID"))).Get("/business/{busID}", app.getExpensesByBusinessIDHandler)
+		    r.With(app.authorize(permView, byParam("busID"))).Get("/business/{busID}/monthly", app.getExpenseMonthlyTotalsHandler)
+		    r.With(app.authorize(permView, byParam("busID"))).Get("/business/{busID}/categories", app.getExpenseCategoriesHandler)
+		})
+		r.Route("/accounts", func(r chi.Router) {
+		    r.Use(app.AuthMiddleware)
+		    r.With(app.authorize(permAccounting, app.byBody(""))).Post("/", app.createAccountHandler)
+		    r.With(app.authorize(permAccounting, app.byBody(store.ResourceAccount))).Put("/", app.updateAccountHandler)
+		    r.With(app.authorize(permAccounting, app.byRecord(store.ResourceAccount, "id"))).Delete("/{id}", app.deleteAccountHandler)
+		    r.With(app.authorize(permView, byParam("busID"))).Get("/business/{busID}", app.getAccountsByBusinessIDHandler)
+		})
+		r.Route("/journal", func(r chi.Router) {
+		    r.Use(app.AuthMiddleware)
+		    r.With(app.authorize(permAccounting, app.byBody(""))).Post("/", app.createJournalEntryHandler)
+		    r.With(app.authorize(permView, app.byRecord(store.ResourceJournalEntry, "id"))).Get("/{id}", app.getJournalEntryByIDHandler)
+		    r.With(app.authorize(permAccounting, app.byRecord(store.ResourceJournalEntry, "id"))).Delete("/{id}", app.deleteJournalEntryHandler)
+		    r.With(app.authorize(permView, byParam("busID"))).Get("/business/{busID}", app.getJournalEntriesByBusinessIDHandler)
+		    r.With(app.authorize(permAccounting, byParam("busID"))).Post("/business/{busID}/rebuild", app.rebuildLedgerHandler)
+		})
+		r.Route("/bank", func(r chi.Router) {
+		    r.Use(app.AuthMiddleware)
+		    r.With(app.authorize(permPayments, byParam("busID"))).Post("/business/{busID}/import", app.importBankStatementHandler)
+		    r.With(app.authorize(permView, byParam("busID"))).Get("/business/{busID}/transactions", app.getBankTransactionsHandler)
+		    r.With(app.authorize(permView, app.byRecord(store.ResourceBankTransaction, "id"))).Get("/transactions/{id}/suggestions", app.getBankTransactionSuggestionsHandler)
+		    r.With(app.authorize(permPayments, app.byRecord(store.ResourceBankTransaction, "id"))).Post("/transactions/{id}/match", app.matchBankTransactionHandler)
+		    r.With(app.authorize(permPayments, app.byRecord(store.ResourceBankTransaction, "id"))).Delete("/transactions/{id}/match", app.unmatchBankTransactionHandler)
+		    r.With(app.authorize(permPayments, app.byRecord(store.ResourceBankTransaction, "id"))).Put("/transactions/{id}/ignore", app.ignoreBankTransactionHandler)
+		})
+		r.Route("/late-fees", func(r chi.Router) {
+		    r.Use(app.AuthMiddleware)
+		    r.With(app.authorize(permView, byParam("busID"))).Get("/business/{busID}/policies", app.getLateFeePoliciesHandler)
+		    r.With(app.authorize(permSettings, byParam("busID"))).Put("/business/{busID}/policies", app.saveLateFeePolicyHandler)
+		    r.With(app.authorize(permSettings, app.byRecord(store.ResourceLateFeePolicy, "id"))).Delete("/policies/{id}", app.deleteLateFeePolicyHandler)
+		    r.With(app.authorize(permView, byParam("busID"))).Get("/business/{busID}/accruals", app.getLateFeeAccrualsHandler)
+		    r.With(app.authorize(permAccounting, byParam("busID"))).Post("/business/{busID}/issue", app.issueLateFeesHandler)
+		})
+		r.Route("/reminders", func(r chi.Router) {
+		    r.Use(app.AuthMiddleware)
+		    r.With(app.authorize(permSettings, app.byBody(""))).Post("/", app.createReminderRuleHandler)
+		    r.With(app.authorize(permSettings, app.byBody(store.ResourceReminderRule))).Put("/", app.updateReminderRuleHandler)
+		    r.With(app.authorize(permSettings, app.byRecord(store.ResourceReminderRule, "id"))).Delete("/{id}", app.deleteReminderRuleHandler)
+		    r.With(app.authorize(permView, byParam("busID"))).Get("/business/{busID}", app.getReminderRulesByBusinessIDHandler)
+		})
+		r.Route("/price-lists", func(r chi.Router) {
+		    r.Use(app.AuthMiddleware)
+		    r.With(app.authorize(permCatalog, app.byBody(""))).Post("/", app.createPriceListHandler)
+		    r.With(app.authorize(permCatalog, app.byBody(store.ResourcePriceList))).Put("/", app.updatePriceListHandler)
+		    r.With(app.authorize(permView, app.byRecord(store.ResourcePriceList, "id"))).Get("/{id}", app.getPriceListByIDHandler)
+		    r.With(app.authorize(permCatalog, app.byRecord(store.ResourcePriceList, "id"))).Delete("/{id}", app.deletePriceListHandler)
+		    r.With(app.authorize(permView, byParam("busID"))).Get("/business/{busID}", app.getPriceListsByBusinessIDHandler)
+		})
 	})
 
 	return r
@@ -161,6 +405,13 @@ func (app *application) run(mux http.Handler) error {
 	}
 	shutdown := make(chan error)
 
+	jobs, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	if app.config.reminders.interval > 0 {
+		go app.runReminders(jobs)
+	}
+
 	go func() {
 		quit := make(chan os.Signal, 1)
 
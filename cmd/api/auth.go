@@ -1,6 +1,7 @@
 package main
 
 import (
+	"billify-api/internal/auth"
 	"billify-api/internal/store"
 	"crypto/rand"
 	"encoding/base64"
@@ -142,13 +143,27 @@ func (app *application) callbackOAuthHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	refreshToken, err := app.token.GenerateRefreshToken(userID)
+	mfaEnabled, err := app.mfaEnabled(r, userID)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	app.SetRefreshTokenCookie(w, refreshToken, app.config.frontendURL)
+	if mfaEnabled {
+		mfaToken, err := app.mfaChallenge(r, userID, "")
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, app.config.frontendURL+"/login/mfa?token="+mfaToken, http.StatusTemporaryRedirect)
+		return
+	}
+
+	if _, err := app.startSession(w, r, userID, ""); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
 
 	redirectURL := app.config.frontendURL + "/buss"
 
@@ -197,14 +212,22 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		default:
 			app.internalServerError(w, r, err)
 		}
+		return
+	}
+
+	if err := app.sendVerificationEmail(r, user); err != nil {
+		app.logger.Errorw("verification email not sent", "user_id", user.ID, "error", err.Error())
 	}
 
 	app.jsonResponse(w, http.StatusCreated, user)
 }
 
+// LoginPayload takes an optional device name, such as "Work laptop", to
+// tell sessions apart.
 type LoginPayload struct {
 	Email    string `json:"email" validate:"required,email,max=255"`
 	Password string `json:"password" validate:"required"`
+	Device   string `json:"device" validate:"max=100"`
 }
 
 func (app *application) loginHandler(w http.ResponseWriter, r *http.Request) {
@@ -235,24 +258,38 @@ func (app *application) loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessToken, err := app.token.GenerateAccessToken(user.ID)
+	mfaEnabled, err := app.mfaEnabled(r, user.ID)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	refreshToken, err := app.token.GenerateRefreshToken(user.ID)
+	// With two-factor authentication on, the password only earns a
+	// challenge token; verifyMFALoginHandler hands out the real tokens.
+	if mfaEnabled {
+		mfaToken, err := app.mfaChallenge(r, user.ID, payload.Device)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, MFAChallengeResponse{MFARequired: true, MFAToken: mfaToken})
+		return
+	}
+
+	accessToken, err := app.startSession(w, r, user.ID, payload.Device)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	app.SetRefreshTokenCookie(w, refreshToken, app.config.frontendURL)
-
 	w.Header().Set("Content-Type", "application/json")
 	w.Write([]byte(fmt.Sprintf(`{"accessToken": "%s"}`, accessToken)))
 }
 
+// refreshTokenHandler exchanges the refresh token cookie for an access
+// token and a new refresh token. Each refresh token works once; presenting
+// one again revokes its session.
 func (app *application) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
 	cookie, err := r.Cookie("refreshToken")
 	if err != nil {
@@ -260,29 +297,63 @@ func (app *application) refreshTokenHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	refreshToken := cookie.Value
-	fmt.Println(refreshToken)
-	newAccessToken, err := app.token.RefreshToken(refreshToken)
+	claims, err := app.token.ValidateRefreshToken(cookie.Value)
 	if err != nil {
-		app.badRequestResponse(w, r, err)
+		clearRefreshTokenCookie(w)
+		app.unauthorizedErrorResponse(w, r, err)
+		return
+	}
+
+	refreshToken, next, err := app.newRefreshToken(claims.UserID, claims.SessionID)
+	if err != nil {
+		app.internalServerError(w, r, err)
 		return
 	}
 
+	current := &store.RefreshToken{
+		ID:        claims.TokenID,
+		SessionID: claims.SessionID,
+		Hash:      auth.HashToken(cookie.Value),
+	}
+
+	err = app.store.Sessions.Rotate(r.Context(), current, next, clientIP(r), r.UserAgent())
+	if err != nil {
+		switch err {
+		case store.ErrTokenReuse:
+			app.logger.Warnw("refresh token reused, session revoked", "user_id", claims.UserID, "session_id", claims.SessionID)
+			clearRefreshTokenCookie(w)
+			app.unauthorizedErrorResponse(w, r, err)
+		case store.ErrNotFound:
+			clearRefreshTokenCookie(w)
+			app.unauthorizedErrorResponse(w, r, errors.New("session has ended"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	newAccessToken, err := app.token.GenerateAccessToken(claims.UserID, claims.SessionID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.SetRefreshTokenCookie(w, refreshToken, app.config.frontendURL)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.Write([]byte(fmt.Sprintf(`{"accessToken": "%s"}`, newAccessToken)))
 }
 
+// logoutHandler ends the session of the refresh token cookie and clears it.
 func (app *application) logoutHandler(w http.ResponseWriter, r *http.Request) {
-	cookie := &http.Cookie{
-		Name:     "refreshToken",
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteStrictMode,
-		Expires:  time.Now().Add(-time.Hour),
+	if claims := app.refreshClaims(r); claims != nil {
+		err := app.store.Sessions.Revoke(r.Context(), claims.UserID, claims.SessionID, store.SessionLoggedOut)
+		if err != nil && err != store.ErrNotFound {
+			app.internalServerError(w, r, err)
+			return
+		}
 	}
 
-	http.SetCookie(w, cookie)
+	clearRefreshTokenCookie(w)
 	w.WriteHeader(http.StatusNoContent)
-}
\ No newline at end of file
+}
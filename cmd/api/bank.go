@@ -0,0 +1,370 @@
+package main
+
+import (
+	"billify-api/internal/bankstmt"
+	"billify-api/internal/store"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const maxMatchSuggestions = 3
+
+var bankImportFields = []string{"date", "description", "amount", "credit", "debit", "reference"}
+
+type BankImportReport struct {
+	DryRun    bool                 `json:"dry_run"`
+	Statement *store.BankStatement `json:"statement"`
+	Invalid   int                  `json:"invalid"`
+	Errors    []ImportRowResult    `json:"errors"`
+}
+
+// readBankCSV maps the rows of an uploaded CSV or XLSX statement onto
+// transactions. A row carries either a signed "amount" or separate "credit"
+// and "debit" columns; blank rows, which banks put around the table, are
+// skipped. The "date_format" form value, such as "DD/MM/YYYY", fixes how dates
+// are read when guessing is ambiguous.
+func readBankCSV(w http.ResponseWriter, r *http.Request, report *BankImportReport) ([]bankstmt.Transaction, error) {
+	table, dryRun, err := readImportRequest(w, r, bankImportFields)
+	if err != nil {
+		return nil, err
+	}
+	report.DryRun = dryRun
+
+	if _, ok := table.columns["date"]; !ok {
+		return nil, errors.New("statement has no date column")
+	}
+	_, hasAmount := table.columns["amount"]
+	_, hasCredit := table.columns["credit"]
+	if !hasAmount && !hasCredit {
+		return nil, errors.New("statement needs an amount or credit column")
+	}
+
+	dateFormat := r.FormValue("date_format")
+
+	transactions := []bankstmt.Transaction{}
+	for i, row := range table.rows {
+		// Row numbers match the spreadsheet, where row 1 is the header.
+		rowNo := i + 2
+
+		date := table.value(row, "date")
+		amount := table.value(row, "amount")
+		credit := table.value(row, "credit")
+		debit := table.value(row, "debit")
+		if date == "" && amount == "" && credit == "" && debit == "" {
+			continue
+		}
+
+		var rowErrors []string
+		txn := bankstmt.Transaction{
+			Description: table.value(row, "description"),
+			Reference:   table.value(row, "reference"),
+		}
+
+		txn.Date, err = bankstmt.ParseDate(date, dateFormat)
+		if err != nil {
+			rowErrors = append(rowErrors, fmt.Sprintf("date: %s", err.Error()))
+		}
+
+		if amount != "" {
+			if txn.Amount, err = bankstmt.ParseAmount(amount); err != nil {
+				rowErrors = append(rowErrors, fmt.Sprintf("amount: %s", err.Error()))
+			}
+		} else {
+			for _, column := range []struct {
+				name  string
+				value string
+				sign  float64
+			}{
+				{"credit", credit, 1},
+				{"debit", debit, -1},
+			} {
+				if column.value == "" {
+					continue
+				}
+				value, err := bankstmt.ParseAmount(column.value)
+				if err != nil {
+					rowErrors = append(rowErrors, fmt.Sprintf("%s: %s", column.name, err.Error()))
+					continue
+				}
+				txn.Amount += column.sign * value
+			}
+		}
+
+		if len(rowErrors) > 0 {
+			report.Invalid++
+			report.Errors = append(report.Errors, ImportRowResult{Row: rowNo, Status: ImportStatusInvalid, Errors: rowErrors})
+			continue
+		}
+
+		transactions = append(transactions, txn)
+	}
+
+	return transactions, nil
+}
+
+// importBankStatementHandler stores the transactions of an uploaded
+// statement. OFX and QFX files are recognised by their extension or a
+// "format" form value of "ofx"; anything else is read as CSV or XLSX with the
+// usual column mapping.
+func (app *application) importBankStatementHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize)
+	if err := r.ParseMultipartForm(maxImportFileSize); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	statement := &store.BankStatement{BusID: busID, Filename: filepath.Base(header.Filename), Format: "csv"}
+	ext := strings.ToLower(filepath.Ext(header.Filename))
+	if r.FormValue("format") == "ofx" || ext == ".ofx" || ext == ".qfx" {
+		statement.Format = "ofx"
+	}
+
+	report := &BankImportReport{Statement: statement, Errors: []ImportRowResult{}}
+
+	var transactions []bankstmt.Transaction
+	if statement.Format == "ofx" {
+		if report.DryRun, err = parseDryRun(r); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		transactions, err = bankstmt.ParseOFX(file)
+	} else {
+		transactions, err = readBankCSV(w, r, report)
+	}
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	bankstmt.AssignIDs(transactions)
+
+	rows := make([]*store.BankTransaction, 0, len(transactions))
+	for _, txn := range transactions {
+		rows = append(rows, &store.BankTransaction{
+			TxnDate:     txn.Date,
+			Amount:      txn.Amount,
+			Description: txn.Description,
+			Reference:   txn.Reference,
+			FitID:       txn.FitID,
+		})
+	}
+
+	if err := app.store.Bank.Import(r.Context(), statement, rows, report.DryRun); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, report)
+}
+
+type BankTransactionParams struct {
+	Status string `validate:"omitempty,oneof=unmatched matched ignored"`
+}
+
+func (app *application) getBankTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	params := BankTransactionParams{Status: r.URL.Query().Get("status")}
+	if err := Validate.Struct(params); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	transactions, err := app.store.Bank.GetTransactions(r.Context(), busID, params.Status)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, transactions)
+}
+
+type MatchSuggestion struct {
+	*store.OpenInvoice
+	Score   int      `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+// getBankTransactionSuggestionsHandler ranks the open invoices a credit most
+// likely pays.
+func (app *application) getBankTransactionSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	txnID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	txn, err := app.store.Bank.GetTransaction(r.Context(), txnID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	suggestions := []MatchSuggestion{}
+	if txn.Status != store.BankTransactionUnmatched || txn.Amount <= 0 {
+		app.jsonResponse(w, http.StatusOK, suggestions)
+		return
+	}
+
+	invoices, err := app.store.Bank.GetOpenInvoices(r.Context(), txn.BusID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	candidates := make([]bankstmt.Invoice, 0, len(invoices))
+	byID := make(map[uuid.UUID]*store.OpenInvoice, len(invoices))
+	for _, invoice := range invoices {
+		byID[invoice.InvID] = invoice
+		candidates = append(candidates, bankstmt.Invoice{
+			ID:       invoice.InvID,
+			Number:   invoice.InvNo,
+			Customer: invoice.CustName,
+			Total:    invoice.Total,
+			Balance:  invoice.Balance,
+		})
+	}
+
+	credit := bankstmt.Transaction{
+		Date:        txn.TxnDate,
+		Amount:      txn.Amount,
+		Description: txn.Description,
+		Reference:   txn.Reference,
+	}
+	for _, suggestion := range bankstmt.Suggest(credit, candidates, maxMatchSuggestions) {
+		suggestions = append(suggestions, MatchSuggestion{
+			OpenInvoice: byID[suggestion.InvoiceID],
+			Score:       suggestion.Score,
+			Reasons:     suggestion.Reasons,
+		})
+	}
+
+	app.jsonResponse(w, http.StatusOK, suggestions)
+}
+
+type MatchBankTransactionPayload struct {
+	InvID uuid.UUID `json:"inv_id" validate:"required,uuid"`
+}
+
+func (app *application) matchBankTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	txnID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload MatchBankTransactionPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	payment, err := app.store.Bank.Match(r.Context(), txnID, payload.InvID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrTransactionMatched:
+			app.conflictResponse(w, r, err)
+		case store.ErrNotACredit, store.ErrInvoiceOverpayment:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, payment)
+}
+
+func (app *application) unmatchBankTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	txnID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Bank.Unmatch(r.Context(), txnID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrTransactionUnmatched:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type IgnoreBankTransactionPayload struct {
+	Ignored bool `json:"ignored"`
+}
+
+func (app *application) ignoreBankTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	txnID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload IgnoreBankTransactionPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Bank.SetIgnored(r.Context(), txnID, payload.Ignored); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrTransactionMatched:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
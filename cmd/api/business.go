@@ -2,6 +2,7 @@ package main
 
 import (
 	"billify-api/internal/store"
+	"errors"
 	"net/http"
 	"time"
 
@@ -88,6 +89,7 @@ func (app *application) getBusinessByIDHandler(w http.ResponseWriter, r *http.Re
 			return
 		}
 	}
+	business.Role = r.Context().Value(memberCtx).(*store.Member).Role
 
 	if err := app.jsonResponse(w, http.StatusOK, business); err != nil {
 		app.internalServerError(w, r, err)
@@ -121,6 +123,8 @@ func (app *application) getBusinessesByUserIDHandler(w http.ResponseWriter, r *h
 	}
 }
 
+var errBankDetails = errors.New("only the owner can change the bank details")
+
 type UpdateBusinessPayload struct {
 	ID           uuid.UUID `json:"id" validate:"required,uuid"`
 	Name         string    `json:"name" validate:"required,min=3,max=100"`
@@ -150,13 +154,31 @@ func (app *application) updateBusinessHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// Get the user ID from the context
-	user := r.Context().Value(userCtx).(*store.User)
+	member := r.Context().Value(memberCtx).(*store.Member)
+
+	existing, err := app.store.Business.GetByID(r.Context(), payload.ID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	bankChanged := existing.BankName != payload.BankName ||
+		existing.AccountNo != payload.AccountNo ||
+		existing.IFSC != payload.IFSC ||
+		existing.BankBranch != payload.BankBranch
+	if bankChanged && !roleCan(member.Role, permBankDetails) {
+		app.forbiddenErrorResponse(w, r, errBankDetails)
+		return
+	}
 
 	// Create a new business instance
 	business := &store.Business{
 		ID:           payload.ID,
-		UserID:       user.ID,
 		Name:         payload.Name,
 		GSTNo:        payload.GSTNo,
 		CompanyEmail: payload.CompanyEmail,
@@ -176,11 +198,29 @@ func (app *application) updateBusinessHandler(w http.ResponseWriter, r *http.Req
 		app.internalServerError(w, r, err)
 		return
 	}
+	business.Role = member.Role
 
 	// Return the created business as a JSON response
 	app.jsonResponse(w, http.StatusCreated, business)
 }
 
+// deleteBusinessHandler deletes the business and everything in it. Only
+// the owner may do this.
+func (app *application) deleteBusinessHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Business.Delete(r.Context(), busID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 type BusinessDashboardRequest struct {
 	BusId uuid.UUID `json:"busId" validate:"required,uuid"`
 	From  time.Time `json:"from" validate:"required"`
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"billify-api/internal/store"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type CreateCategoryPayload struct {
+	BusID    uuid.UUID  `json:"bus_id" validate:"required,uuid"`
+	ParentID *uuid.UUID `json:"parent_id" validate:"omitempty,uuid"`
+	Name     string     `json:"name" validate:"required,min=2,max=100"`
+}
+
+func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateCategoryPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	category := &store.Category{
+		BusID:    payload.BusID,
+		ParentID: payload.ParentID,
+		Name:     payload.Name,
+	}
+
+	if err := app.store.Categories.Create(r.Context(), category); err != nil {
+		switch err {
+		case store.ErrDuplicateCategory:
+			app.conflictResponse(w, r, err)
+		case store.ErrInvalidCategory:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, category)
+}
+
+func (app *application) getCategoriesByBusinessIDHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	categories, err := app.store.Categories.GetByBusID(r.Context(), busID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, categories)
+}
+
+type UpdateCategoryPayload struct {
+	ID       uuid.UUID  `json:"id" validate:"required,uuid"`
+	ParentID *uuid.UUID `json:"parent_id" validate:"omitempty,uuid"`
+	Name     string     `json:"name" validate:"required,min=2,max=100"`
+}
+
+func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdateCategoryPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	category := &store.Category{
+		ID:       payload.ID,
+		ParentID: payload.ParentID,
+		Name:     payload.Name,
+	}
+
+	if err := app.store.Categories.Update(r.Context(), category); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrDuplicateCategory:
+			app.conflictResponse(w, r, err)
+		case store.ErrCategoryCycle, store.ErrInvalidCategory:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) deleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Categories.Delete(r.Context(), categoryID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenConfigValidate(t *testing.T) {
+	valid := tokenConfig{accSecret: "acc", refSecret: "ref", linkSecret: "link"}
+	if err := valid.validate(); err != nil {
+		t.Errorf("validate = %v, want nil", err)
+	}
+
+	tests := []struct {
+		name    string
+		config  tokenConfig
+		missing []string
+	}{
+		{"no link secret", tokenConfig{accSecret: "acc", refSecret: "ref"}, []string{"LINK_SECRET"}},
+		{"no access secret", tokenConfig{refSecret: "ref", linkSecret: "link"}, []string{"ACCESS_SECRET"}},
+		{"none", tokenConfig{}, []string{"ACCESS_SECRET", "REFRESH_SECRET", "LINK_SECRET"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.validate()
+			if err == nil {
+				t.Fatal("validate succeeded")
+			}
+			for _, name := range tt.missing {
+				if !strings.Contains(err.Error(), name) {
+					t.Errorf("error %q does not name %s", err, name)
+				}
+			}
+		})
+	}
+}
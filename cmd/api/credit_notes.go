@@ -0,0 +1,144 @@
+package main
+
+import (
+	"billify-api/internal/store"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type CreditNoteItemPayload struct {
+	ProdID    uuid.UUID  `json:"prod_id" validate:"required,uuid"`
+	VariantID *uuid.UUID `json:"variant_id" validate:"omitempty,uuid"`
+	Quantity  float64    `json:"quantity" validate:"required,gt=0"`
+}
+
+type CreditNotePayload struct {
+	CNDate  time.Time               `json:"cn_date" validate:"required"`
+	Reason  string                  `json:"reason" validate:"max=500"`
+	Restock bool                    `json:"restock"`
+	Items   []CreditNoteItemPayload `json:"items" validate:"required,min=1,dive"`
+}
+
+func (app *application) creditNoteError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case store.ErrNotFound:
+		app.notFoundResponse(w, r, err)
+	case store.ErrCreditNoteQuantity, store.ErrCreditNoteExceedsDue, store.ErrCreditNoteEmpty:
+		app.badRequestResponse(w, r, err)
+	case store.ErrInvoiceHasPayments:
+		app.conflictResponse(w, r, err)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
+
+// createCreditNoteHandler issues a credit note against an invoice for some
+// of the goods billed on it. Prices and tax are those of the invoice.
+func (app *application) createCreditNoteHandler(w http.ResponseWriter, r *http.Request) {
+	invoiceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload CreditNotePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	note := &store.CreditNote{
+		InvID:   invoiceID,
+		CNDate:  payload.CNDate,
+		Reason:  payload.Reason,
+		Restock: payload.Restock,
+		Items:   make([]*store.CreditNoteItem, 0, len(payload.Items)),
+	}
+	for _, item := range payload.Items {
+		note.Items = append(note.Items, &store.CreditNoteItem{
+			ProdID:    item.ProdID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+		})
+	}
+
+	if err := app.store.CreditNotes.Create(r.Context(), note); err != nil {
+		app.creditNoteError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, note)
+}
+
+// voidInvoiceHandler cancels an invoice with a credit note for all of it
+// dated today, taking its goods back into stock.
+func (app *application) voidInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	invoiceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	note, err := app.store.CreditNotes.Void(r.Context(), invoiceID, time.Now())
+	if err != nil {
+		app.creditNoteError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, note)
+}
+
+func (app *application) getCreditNotesHandler(w http.ResponseWriter, r *http.Request) {
+	invoiceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	notes, err := app.store.CreditNotes.GetByInvoiceID(r.Context(), invoiceID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, notes)
+}
+
+func (app *application) getCreditNoteHandler(w http.ResponseWriter, r *http.Request) {
+	creditNoteID, err := uuid.Parse(chi.URLParam(r, "creditNoteID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	note, err := app.store.CreditNotes.GetByID(r.Context(), creditNoteID)
+	if err != nil {
+		app.creditNoteError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, note)
+}
+
+func (app *application) deleteCreditNoteHandler(w http.ResponseWriter, r *http.Request) {
+	creditNoteID, err := uuid.Parse(chi.URLParam(r, "creditNoteID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.CreditNotes.Delete(r.Context(), creditNoteID); err != nil {
+		app.creditNoteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,8 +2,13 @@ package main
 
 import (
 	"billify-api/internal/store"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -57,6 +62,15 @@ func (app *application) createCustomerHandler(w http.ResponseWriter, r *http.Req
 }
 
 
+type CustomerQueryParams struct {
+	Query      string `validate:"max=100"`
+	HasPending *bool
+	Sort       string `validate:"omitempty,oneof=name pending_amount created_at"`
+	Order      string `validate:"omitempty,oneof=asc desc"`
+	Cursor     string
+	Limit      int    `validate:"min=1,max=200"`
+}
+
 func (app *application) getCustomersByBusinessIDHandler(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "busID")
 	
@@ -66,13 +80,57 @@ func (app *application) getCustomersByBusinessIDHandler(w http.ResponseWriter, r
 		return
 	}
 
-	customers, err := app.store.Customers.GetByBusID(r.Context(), BusID)
+	query := r.URL.Query()
+	params := CustomerQueryParams{
+		Query:  strings.TrimSpace(query.Get("q")),
+		Sort:   query.Get("sort"),
+		Order:  query.Get("order"),
+		Cursor: query.Get("cursor"),
+		Limit:  50,
+	}
+
+	if v := query.Get("has_pending"); v != "" {
+		hasPending, err := strconv.ParseBool(v)
+		if err != nil {
+			app.badRequestResponse(w, r, errors.New("has_pending must be a boolean"))
+			return
+		}
+		params.HasPending = &hasPending
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			app.badRequestResponse(w, r, errors.New("limit must be a number"))
+			return
+		}
+		params.Limit = limit
+	}
+
+	if err := Validate.Struct(params); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	page, err := app.store.Customers.GetByBusID(r.Context(), BusID, store.CustomerFilter{
+		Query:      params.Query,
+		HasPending: params.HasPending,
+		Sort:       params.Sort,
+		Order:      params.Order,
+		Cursor:     params.Cursor,
+		Limit:      params.Limit,
+	})
 	if err != nil {
-		app.internalServerError(w, r, err)
+		switch err {
+		case store.ErrInvalidCursor:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 	
-	app.jsonResponse(w, http.StatusOK, customers)
+	app.jsonResponse(w, http.StatusOK, page)
 }
 
 type UpdateCustomerPayload struct {
@@ -127,4 +185,169 @@ func (app *application) deleteCustomerHandler(w http.ResponseWriter, r *http.Req
     }
 
     w.WriteHeader(http.StatusNoContent)
-}
\ No newline at end of file
+}
+func (app *application) getCustomerStatementHandler(w http.ResponseWriter, r *http.Request) {
+	customerID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if _, err := app.store.Customers.GetByID(r.Context(), customerID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	statement, err := app.store.Customers.GetStatement(r.Context(), customerID, from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, statement)
+}
+
+func (app *application) getCustomerStatementPDFHandler(w http.ResponseWriter, r *http.Request) {
+	customerID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	customer, err := app.store.Customers.GetByID(r.Context(), customerID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	business, err := app.store.Business.GetByID(r.Context(), customer.BusID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	statement, err := app.store.Customers.GetStatement(r.Context(), customerID, from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	pdfData, err := app.pdf.GenerateStatementPDF(business, customer, statement)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.WriteHeader(http.StatusOK)
+	w.Write(pdfData)
+}
+
+var customerImportFields = []string{"name", "gstno", "email", "phone", "b_address", "s_address"}
+
+func (app *application) importCustomersHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	table, dryRun, err := readImportRequest(w, r, customerImportFields)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	report := &ImportReport{DryRun: dryRun, Rows: []ImportRowResult{}}
+
+	var customers []*store.Customer
+	var customerRows []int
+	seen := make(map[string]int)
+	for i, row := range table.rows {
+		// Row numbers match the spreadsheet, where row 1 is the header.
+		rowNo := i + 2
+
+		payload := CreateCustomerPayload{
+			BusinessID: busID,
+			Name:       table.value(row, "name"),
+			GSTNo:      table.value(row, "gstno"),
+			Email:      table.value(row, "email"),
+			Phone:      table.value(row, "phone"),
+			BAddress:   table.value(row, "b_address"),
+			SAddress:   table.value(row, "s_address"),
+		}
+
+		if err := Validate.Struct(payload); err != nil {
+			report.add(ImportRowResult{Row: rowNo, Status: ImportStatusInvalid, Errors: validationMessages(payload, err)})
+			continue
+		}
+
+		key := strings.ToLower(payload.Email) + "|" + payload.Phone
+		if first, ok := seen[key]; ok {
+			report.add(ImportRowResult{
+				Row:    rowNo,
+				Status: ImportStatusInvalid,
+				Errors: []string{fmt.Sprintf("duplicate of row %d with the same email and phone", first)},
+			})
+			continue
+		}
+		seen[key] = rowNo
+
+		customers = append(customers, &store.Customer{
+			BusID:    payload.BusinessID,
+			GSTNo:    payload.GSTNo,
+			Name:     payload.Name,
+			Email:    payload.Email,
+			Phone:    payload.Phone,
+			BAddress: payload.BAddress,
+			SAddress: payload.SAddress,
+		})
+		customerRows = append(customerRows, rowNo)
+	}
+
+	if len(customers) > 0 {
+		created, err := app.store.Customers.Import(r.Context(), customers, dryRun)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		for i, customer := range customers {
+			result := ImportRowResult{Row: customerRows[i], Status: ImportStatusUpdated, ID: &customer.ID}
+			if created[i] {
+				result.Status = ImportStatusCreated
+			}
+			if dryRun && created[i] {
+				// The rolled back insert never got a real ID.
+				result.ID = nil
+			}
+			report.add(result)
+		}
+	}
+
+	sort.Slice(report.Rows, func(i, j int) bool { return report.Rows[i].Row < report.Rows[j].Row })
+
+	app.jsonResponse(w, http.StatusOK, report)
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"billify-api/internal/mailer"
+	"billify-api/internal/store"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const emailDateLayout = "02 Jan 2006"
+
+var (
+	errUnknownTemplate = errors.New("unknown email template")
+	errNoCustomerEmail = errors.New("customer has no email address")
+)
+
+// InvoiceEmailData is what invoice email templates can refer to.
+type InvoiceEmailData struct {
+	BusinessName string
+	CustomerName string
+	InvoiceNo    int64
+	InvoiceDate  string
+	DueDate      string
+	Amount       string
+	AmountDue    string
+	DaysOverdue  int
+	PaymentLink  string
+}
+
+// sampleEmailData fills in every field so that saving a template catches
+// references to fields that do not exist.
+var sampleEmailData = map[string]any{
+	mailer.TemplateInvoice: InvoiceEmailData{
+		BusinessName: "Acme Traders",
+		CustomerName: "Sample Customer",
+		InvoiceNo:    1,
+		InvoiceDate:  "01 Apr 2024",
+		DueDate:      "30 Apr 2024",
+		Amount:       "₹1180.00",
+		AmountDue:    "₹1180.00",
+		PaymentLink:  "https://rzp.io/i/sample",
+	},
+	mailer.TemplateReminder: InvoiceEmailData{
+		BusinessName: "Acme Traders",
+		CustomerName: "Sample Customer",
+		InvoiceNo:    1,
+		InvoiceDate:  "01 Apr 2024",
+		DueDate:      "30 Apr 2024",
+		Amount:       "₹1180.00",
+		AmountDue:    "₹590.00",
+		DaysOverdue:  7,
+		PaymentLink:  "https://rzp.io/i/sample",
+	},
+}
+
+func formatRupees(amount float64) string {
+	return "₹" + formatAmount(amount)
+}
+
+// emailTemplate returns the business's template of kind, falling back to the
+// default when it has not written one.
+func (app *application) emailTemplate(ctx context.Context, busID uuid.UUID, kind string) (mailer.Template, error) {
+	tmpl, err := app.store.Emails.GetTemplate(ctx, busID, kind)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return mailer.DefaultTemplates[kind], nil
+		}
+		return mailer.Template{}, err
+	}
+
+	return mailer.Template{Subject: tmpl.Subject, Body: tmpl.Body}, nil
+}
+
+func (app *application) getEmailTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	kind := chi.URLParam(r, "kind")
+	if _, ok := mailer.DefaultTemplates[kind]; !ok {
+		app.notFoundResponse(w, r, errUnknownTemplate)
+		return
+	}
+
+	tmpl, err := app.emailTemplate(r.Context(), busID, kind)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, tmpl)
+}
+
+type EmailTemplatePayload struct {
+	Subject string `json:"subject" validate:"required,max=200"`
+	Body    string `json:"body" validate:"required,max=10000"`
+}
+
+func (app *application) updateEmailTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	kind := chi.URLParam(r, "kind")
+	if _, ok := mailer.DefaultTemplates[kind]; !ok {
+		app.notFoundResponse(w, r, errUnknownTemplate)
+		return
+	}
+
+	var payload EmailTemplatePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	candidate := mailer.Template{Subject: payload.Subject, Body: payload.Body}
+	if _, _, err := candidate.Render(sampleEmailData[kind]); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	tmpl := &store.EmailTemplate{
+		BusID:   busID,
+		Kind:    kind,
+		Subject: payload.Subject,
+		Body:    payload.Body,
+	}
+
+	if err := app.store.Emails.SaveTemplate(r.Context(), tmpl); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// generateInvoicePDF renders an invoice with its items and the business's
+// products.
+func (app *application) generateInvoicePDF(ctx context.Context, invoice *store.Invoice, business *store.Business, customer *store.Customer) ([]byte, error) {
+	items, err := app.store.InvoiceItems.GetByInvoiceID(ctx, invoice.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := app.store.Products.GetByBusID(ctx, invoice.BusID)
+	if err != nil {
+		return nil, err
+	}
+
+	return app.pdf.GenerateInvoicePDF(business, invoice, customer, items, products)
+}
+
+// invoiceEmailData gathers the template fields of an invoice. While there
+// is a balance and a payment provider is configured, the email carries a
+// payment link for it; when the provider cannot create one the email goes
+// out without.
+func (app *application) invoiceEmailData(ctx context.Context, invoice *store.Invoice, business *store.Business, customer *store.Customer) (InvoiceEmailData, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	due := time.Date(invoice.DueDate.Year(), invoice.DueDate.Month(), invoice.DueDate.Day(), 0, 0, 0, 0, time.UTC)
+
+	data := InvoiceEmailData{
+		BusinessName: business.Name,
+		CustomerName: customer.Name,
+		InvoiceNo:    invoice.InvNo,
+		InvoiceDate:  invoice.InvDate.Format(emailDateLayout),
+		DueDate:      invoice.DueDate.Format(emailDateLayout),
+		Amount:       formatRupees(invoice.TotalAmount),
+		DaysOverdue:  int(today.Sub(due).Hours() / 24),
+	}
+
+	balance, err := app.invoiceBalance(ctx, invoice)
+	if err != nil {
+		return data, err
+	}
+	data.AmountDue = formatRupees(balance)
+
+	if app.gateway == nil || balance <= 0 {
+		return data, nil
+	}
+
+	link, err := app.openPaymentLink(ctx, invoice.ID, balance)
+	if err != nil {
+		return data, err
+	}
+	if link == nil {
+		link, err = app.createPaymentLink(ctx, invoice, customer, balance)
+		if err != nil {
+			if !errors.Is(err, errGatewayRequest) {
+				return data, err
+			}
+			app.logger.Warnw("sending invoice without a payment link", "inv_id", invoice.ID, "error", err.Error())
+			return data, nil
+		}
+	}
+	data.PaymentLink = link.URL
+
+	return data, nil
+}
+
+// emailInvoice emails the invoice PDF to its customer using the business's
+// template of kind and logs the attempt. A send the mail server rejects is
+// not an error: it comes back as a delivery with status failed.
+func (app *application) emailInvoice(ctx context.Context, invoice *store.Invoice, kind string, cc []string) (*store.EmailDelivery, error) {
+	customer, err := app.store.Customers.GetByID(ctx, invoice.CustID)
+	if err != nil {
+		return nil, err
+	}
+	if customer.Email == "" {
+		return nil, errNoCustomerEmail
+	}
+
+	business, err := app.store.Business.GetByID(ctx, invoice.BusID)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := app.emailTemplate(ctx, invoice.BusID, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := app.invoiceEmailData(ctx, invoice, business, customer)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, body, err := tmpl.Render(data)
+	if err != nil {
+		return nil, err
+	}
+
+	pdfData, err := app.generateInvoicePDF(ctx, invoice, business, customer)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := mailer.Message{
+		From:    (&mail.Address{Name: business.Name, Address: app.config.mail.fromAddress}).String(),
+		To:      []string{(&mail.Address{Name: customer.Name, Address: customer.Email}).String()},
+		Cc:      cc,
+		Subject: subject,
+		Body:    body,
+		Attachments: []mailer.Attachment{{
+			Filename:    fmt.Sprintf("invoice-%d.pdf", invoice.InvNo),
+			ContentType: "application/pdf",
+			Data:        pdfData,
+		}},
+	}
+	if business.CompanyEmail != "" {
+		msg.ReplyTo = (&mail.Address{Name: business.Name, Address: business.CompanyEmail}).String()
+	}
+
+	delivery := &store.EmailDelivery{
+		BusID:     invoice.BusID,
+		InvID:     &invoice.ID,
+		Kind:      kind,
+		Recipient: customer.Email,
+		CC:        cc,
+		Subject:   subject,
+		Status:    store.EmailDeliverySent,
+	}
+	if err := app.mailer.Send(ctx, msg); err != nil {
+		delivery.Status = store.EmailDeliveryFailed
+		delivery.Error = err.Error()
+	}
+
+	if err := app.store.Emails.CreateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+type SendInvoicePayload struct {
+	CC []string `json:"cc" validate:"max=10,dive,email"`
+}
+
+// sendInvoiceHandler emails the invoice PDF to the customer using the
+// business's invoice template. Every attempt is logged; a successful one
+// moves the invoice to sent.
+func (app *application) sendInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	invoiceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload SendInvoicePayload
+	if err := readJSON(w, r, &payload); err != nil && !errors.Is(err, io.EOF) {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	invoice, err := app.store.Invoices.GetByID(r.Context(), invoiceID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	delivery, err := app.emailInvoice(r.Context(), invoice, mailer.TemplateInvoice, payload.CC)
+	if err != nil {
+		switch {
+		case err == errNoCustomerEmail, errors.Is(err, mailer.ErrInvalidTemplate):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if delivery.Status == store.EmailDeliveryFailed {
+		app.badGatewayResponse(w, r, errors.New(delivery.Error))
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, delivery)
+}
+
+func (app *application) getInvoiceDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	invoiceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	deliveries, err := app.store.Emails.GetDeliveriesByInvoiceID(r.Context(), invoiceID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, deliveries)
+}
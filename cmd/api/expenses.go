@@ -0,0 +1,439 @@
+package main
+
+import (
+	"billify-api/internal/files"
+	"billify-api/internal/store"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const maxReceiptSize = 5 << 20 // 5mb
+
+// receiptTypes maps the content types accepted for receipts to the file
+// extension they are stored under.
+var receiptTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+type ExpensePayload struct {
+	ID          uuid.UUID  `json:"id"`
+	BusID       uuid.UUID  `json:"bus_id" validate:"required,uuid"`
+	VendorID    *uuid.UUID `json:"vendor_id" validate:"omitempty,uuid"`
+	Category    string     `json:"category" validate:"required,max=50"`
+	Description string     `json:"description" validate:"max=500"`
+	ExpenseDate time.Time  `json:"expense_date" validate:"required"`
+	Amount      float64    `json:"amount" validate:"gt=0"`
+	TaxAmount   float64    `json:"tax_amount" validate:"gte=0"`
+	PaymentMode string     `json:"payment_mode" validate:"required,oneof=cash bank upi cheque card"`
+}
+
+// expenseFromPayload checks that the vendor, when given, belongs to the same
+// business as the expense.
+func (app *application) expenseFromPayload(r *http.Request, payload ExpensePayload) (*store.Expense, error) {
+	if payload.VendorID != nil {
+		vendor, err := app.store.Vendors.GetByID(r.Context(), *payload.VendorID)
+		if err != nil {
+			if err == store.ErrNotFound {
+				return nil, errVendorNotFound
+			}
+			return nil, err
+		}
+		if vendor.BusID != payload.BusID {
+			return nil, errVendorNotFound
+		}
+	}
+
+	return &store.Expense{
+		ID:          payload.ID,
+		BusID:       payload.BusID,
+		VendorID:    payload.VendorID,
+		Category:    strings.TrimSpace(payload.Category),
+		Description: payload.Description,
+		ExpenseDate: payload.ExpenseDate,
+		Amount:      payload.Amount,
+		TaxAmount:   payload.TaxAmount,
+		PaymentMode: payload.PaymentMode,
+	}, nil
+}
+
+func (app *application) createExpenseHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ExpensePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	expense, err := app.expenseFromPayload(r, payload)
+	if err != nil {
+		if err == errVendorNotFound {
+			app.badRequestResponse(w, r, err)
+		} else {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.store.Expenses.Create(r.Context(), expense); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.badRequestResponse(w, r, errors.New("business not found"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, expense)
+}
+
+func (app *application) updateExpenseHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ExpensePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	expense, err := app.expenseFromPayload(r, payload)
+	if err != nil {
+		if err == errVendorNotFound {
+			app.badRequestResponse(w, r, err)
+		} else {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.store.Expenses.Update(r.Context(), expense); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getExpenseByIDHandler(w http.ResponseWriter, r *http.Request) {
+	expenseID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	expense, err := app.store.Expenses.GetByID(r.Context(), expenseID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, expense)
+}
+
+type ExpenseQueryParams struct {
+	Category    string `validate:"max=50"`
+	PaymentMode string `validate:"omitempty,oneof=cash bank upi cheque card"`
+}
+
+func (app *application) getExpensesByBusinessIDHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	query := r.URL.Query()
+	params := ExpenseQueryParams{
+		Category:    strings.TrimSpace(query.Get("category")),
+		PaymentMode: query.Get("payment_mode"),
+	}
+	if err := Validate.Struct(params); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	filter := store.ExpenseFilter{
+		From:        from,
+		To:          to,
+		Category:    params.Category,
+		PaymentMode: params.PaymentMode,
+	}
+	if v := query.Get("vendor_id"); v != "" {
+		vendorID, err := uuid.Parse(v)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		filter.VendorID = &vendorID
+	}
+
+	expenses, err := app.store.Expenses.GetByBusID(r.Context(), busID, filter)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, expenses)
+}
+
+func (app *application) getExpenseMonthlyTotalsHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	months, err := app.store.Expenses.GetMonthlyTotals(r.Context(), busID, from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, months)
+}
+
+func (app *application) getExpenseCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	categories, err := app.store.Expenses.GetCategories(r.Context(), busID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, categories)
+}
+
+func (app *application) deleteExpenseHandler(w http.ResponseWriter, r *http.Request) {
+	expenseID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	expense, err := app.store.Expenses.GetByID(r.Context(), expenseID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.store.Expenses.Delete(r.Context(), expenseID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if expense.ReceiptKey != nil {
+		if err := app.files.Delete(r.Context(), *expense.ReceiptKey); err != nil {
+			app.logger.Errorw("failed to delete receipt", "key", *expense.ReceiptKey, "error", err.Error())
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// uploadExpenseReceiptHandler stores the multipart "file" upload as the
+// expense's receipt, replacing any earlier one. Only images and PDFs are
+// accepted, judged by their content rather than the file name.
+func (app *application) uploadExpenseReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	expenseID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	expense, err := app.store.Expenses.GetByID(r.Context(), expenseID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxReceiptSize+1<<20)
+	if err := r.ParseMultipartForm(maxReceiptSize); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxReceiptSize {
+		app.badRequestResponse(w, r, fmt.Errorf("receipt must be at most %d MB", maxReceiptSize>>20))
+		return
+	}
+
+	reader := bufio.NewReader(file)
+	sniff, err := reader.Peek(512)
+	if err != nil && err != io.EOF {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	contentType := http.DetectContentType(sniff)
+	ext, ok := receiptTypes[contentType]
+	if !ok {
+		app.badRequestResponse(w, r, errors.New("receipt must be a JPEG, PNG or WebP image or a PDF"))
+		return
+	}
+
+	key := fmt.Sprintf("receipts/%s/%s%s", expense.BusID, expense.ID, ext)
+	if err := app.files.Save(r.Context(), key, reader); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	name := filepath.Base(header.Filename)
+	if err := app.store.Expenses.SetReceipt(r.Context(), expense.ID, &key, &name, &contentType); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if expense.ReceiptKey != nil && *expense.ReceiptKey != key {
+		if err := app.files.Delete(r.Context(), *expense.ReceiptKey); err != nil {
+			app.logger.Errorw("failed to delete receipt", "key", *expense.ReceiptKey, "error", err.Error())
+		}
+	}
+
+	expense.ReceiptName = &name
+	expense.ReceiptType = &contentType
+	app.jsonResponse(w, http.StatusOK, expense)
+}
+
+func (app *application) getExpenseReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	expenseID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	expense, err := app.store.Expenses.GetByID(r.Context(), expenseID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if expense.ReceiptKey == nil {
+		app.notFoundResponse(w, r, errors.New("expense has no receipt"))
+		return
+	}
+
+	file, err := app.files.Open(r.Context(), *expense.ReceiptKey)
+	if err != nil {
+		switch err {
+		case files.ErrNotFound:
+			app.notFoundResponse(w, r, errors.New("receipt file is missing"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", *expense.ReceiptType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", *expense.ReceiptName))
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, file)
+}
+
+func (app *application) deleteExpenseReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	expenseID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	expense, err := app.store.Expenses.GetByID(r.Context(), expenseID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if expense.ReceiptKey == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if err := app.store.Expenses.SetReceipt(r.Context(), expense.ID, nil, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.files.Delete(r.Context(), *expense.ReceiptKey); err != nil {
+		app.logger.Errorw("failed to delete receipt", "key", *expense.ReceiptKey, "error", err.Error())
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+const (
+	maxImportFileSize = 10 << 20 // 10mb
+	maxImportRows     = 10_000
+
+	ImportStatusCreated = "created"
+	ImportStatusUpdated = "updated"
+	ImportStatusInvalid = "invalid"
+)
+
+type ImportRowResult struct {
+	Row    int        `json:"row"`
+	Status string     `json:"status"`
+	ID     *uuid.UUID `json:"id,omitempty"`
+	Errors []string   `json:"errors,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun  bool              `json:"dry_run"`
+	Total   int               `json:"total"`
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Invalid int               `json:"invalid"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+func (report *ImportReport) add(result ImportRowResult) {
+	report.Total++
+	switch result.Status {
+	case ImportStatusCreated:
+		report.Created++
+	case ImportStatusUpdated:
+		report.Updated++
+	case ImportStatusInvalid:
+		report.Invalid++
+	}
+	report.Rows = append(report.Rows, result)
+}
+
+// importTable is an uploaded spreadsheet with its column mapping applied.
+type importTable struct {
+	columns map[string]int
+	rows    [][]string
+}
+
+// value returns the cell mapped to field, or "" when the column is unmapped.
+func (t *importTable) value(row []string, field string) string {
+	index, ok := t.columns[field]
+	if !ok || index >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[index])
+}
+
+// readImportRequest reads the multipart "file" upload (CSV, or XLSX when the
+// file name ends in .xlsx) and maps its header row onto fields. The optional
+// "mapping" form value is a JSON object of field name to column header;
+// unmapped fields default to a column of the same name.
+func readImportRequest(w http.ResponseWriter, r *http.Request, fields []string) (*importTable, bool, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize)
+	if err := r.ParseMultipartForm(maxImportFileSize); err != nil {
+		return nil, false, err
+	}
+
+	dryRun, err := parseDryRun(r)
+	if err != nil {
+		return nil, false, err
+	}
+
+	mapping := map[string]string{}
+	if v := r.FormValue("mapping"); v != "" {
+		if err := json.Unmarshal([]byte(v), &mapping); err != nil {
+			return nil, false, fmt.Errorf("invalid mapping: %w", err)
+		}
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	var records [][]string
+	if strings.EqualFold(filepath.Ext(header.Filename), ".xlsx") {
+		records, err = readXLSXRecords(file)
+	} else {
+		records, err = readCSVRecords(file)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	table, err := newImportTable(records, fields, mapping)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return table, dryRun, nil
+}
+
+// parseDryRun reads the optional "dry_run" form value of an upload.
+func parseDryRun(r *http.Request) (bool, error) {
+	v := r.FormValue("dry_run")
+	if v == "" {
+		return false, nil
+	}
+
+	dryRun, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errors.New("dry_run must be a boolean")
+	}
+
+	return dryRun, nil
+}
+
+func readCSVRecords(file io.Reader) ([][]string, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+
+	return records, nil
+}
+
+func newImportTable(records [][]string, fields []string, mapping map[string]string) (*importTable, error) {
+	if len(records) == 0 {
+		return nil, errors.New("file is empty")
+	}
+	if len(records)-1 > maxImportRows {
+		return nil, fmt.Errorf("file has more than %d rows", maxImportRows)
+	}
+
+	header := make(map[string]int)
+	for i, name := range records[0] {
+		header[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+
+	known := make(map[string]bool)
+	columns := make(map[string]int)
+	for _, field := range fields {
+		known[field] = true
+
+		column, ok := mapping[field]
+		if !ok {
+			column = field
+		}
+		if index, ok := header[strings.ToLower(strings.TrimSpace(column))]; ok {
+			columns[field] = index
+		} else if _, mapped := mapping[field]; mapped {
+			return nil, fmt.Errorf("column %q mapped to %s not found", column, field)
+		}
+	}
+
+	for field := range mapping {
+		if !known[field] {
+			return nil, fmt.Errorf("unknown field %q in mapping", field)
+		}
+	}
+
+	return &importTable{columns: columns, rows: records[1:]}, nil
+}
+
+// validationMessages turns validator errors into messages keyed by the json
+// names of payload's fields.
+func validationMessages(payload any, err error) []string {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return []string{err.Error()}
+	}
+
+	payloadType := reflect.Indirect(reflect.ValueOf(payload)).Type()
+
+	messages := make([]string, 0, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		name := fieldErr.Field()
+		if field, ok := payloadType.FieldByName(fieldErr.StructField()); ok {
+			if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag != "" {
+				name = tag
+			}
+		}
+		messages = append(messages, fmt.Sprintf("%s failed on the '%s' rule", name, fieldErr.Tag()))
+	}
+
+	return messages
+}
+
+func parseImportNumber(value string) (float64, error) {
+	return strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+}
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"billify-api/internal/gst"
 	"billify-api/internal/store"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"time"
 
@@ -11,11 +16,12 @@ import (
 )
 
 type InvoiceItemPayload struct {
-	ID        uuid.UUID `json:"id"`
-	ProdID    uuid.UUID `json:"prod_id" validate:"required,uuid"`
-	TaxRate   float64   `json:"tax_rate"`
-	Quantity  int       `json:"quantity" validate:"required"`
-	UnitPrice float64   `json:"unit_price" validate:"required"`
+	ID        uuid.UUID  `json:"id"`
+	ProdID    uuid.UUID  `json:"prod_id" validate:"required,uuid"`
+	VariantID *uuid.UUID `json:"variant_id" validate:"omitempty,uuid"`
+	TaxRate   float64    `json:"tax_rate"`
+	Quantity  float64    `json:"quantity" validate:"required,gt=0"`
+	UnitPrice float64    `json:"unit_price" validate:"gte=0"`
 }
 
 type InvoicePayload struct {
@@ -23,7 +29,7 @@ type InvoicePayload struct {
 	InvNo       int64                      `json:"inv_no" validate:"required"`
 	BusID       uuid.UUID                  `json:"bus_id" validate:"required,uuid"`
 	CustID      uuid.UUID                  `json:"cust_id" validate:"required,uuid"`
-	TotalAmount float64                    `json:"total_amount" validate:"required"`
+	TotalAmount float64                    `json:"total_amount" validate:"gte=0"`
 	InvDate     time.Time                  `json:"inv_date" validate:"required"`
 	DueDate     time.Time                  `json:"due_date" validate:"required"`
 	IsPaid      bool                       `json:"is_paid"`
@@ -31,6 +37,94 @@ type InvoicePayload struct {
 	Items       []InvoiceItemPayload `json:"items" validate:"required,dive"`
 }
 
+var errQuantityPrecision = errors.New("quantity has too many decimal places for its unit")
+
+// checkQuantity rejects quantities with more decimals than the product's
+// unit allows.
+func checkQuantity(product *store.Product, qty float64) error {
+	if unit, ok := gst.LookupUnit(product.UQC); ok && !unit.AllowsQuantity(qty) {
+		return fmt.Errorf("%w: %s is billed in %s, which allows %d", errQuantityPrecision, product.Name, unit.Code, unit.Decimals)
+	}
+	return nil
+}
+
+// priceInvoice checks that the customer belongs to the invoice's business and
+// each quantity against its product's unit, fills in any unit price left at
+// zero with the customer's resolved price, stamps each item with its
+// product's current tax rate and recomputes the invoice total from the items,
+// so the stored total always matches what the PDF shows.
+func (app *application) priceInvoice(ctx context.Context, payload *InvoicePayload) error {
+	customer, err := app.store.Customers.GetByID(ctx, payload.CustID)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return store.ErrInvalidCustomer
+		}
+		return err
+	}
+	if customer.BusID != payload.BusID {
+		return store.ErrInvalidCustomer
+	}
+
+	products, err := app.store.Products.GetByBusID(ctx, payload.BusID)
+	if err != nil {
+		return err
+	}
+
+	productsByID := make(map[uuid.UUID]*store.Product, len(products))
+	for _, product := range products {
+		productsByID[product.ID] = product
+	}
+
+	var total float64
+	for i := range payload.Items {
+		item := &payload.Items[i]
+
+		product, ok := productsByID[item.ProdID]
+		if !ok {
+			return store.ErrInvalidItemReference
+		}
+
+		if err := checkQuantity(product, item.Quantity); err != nil {
+			return err
+		}
+
+		if item.UnitPrice == 0 {
+			price, err := app.store.PriceLists.ResolvePrice(ctx, store.PriceQuery{
+				CustID:    payload.CustID,
+				ProdID:    item.ProdID,
+				VariantID: item.VariantID,
+				Quantity:  item.Quantity,
+				Date:      payload.InvDate,
+			})
+			if err != nil {
+				return err
+			}
+			item.UnitPrice = price.UnitPrice
+		}
+
+		item.TaxRate = product.TaxRate
+		taxable := item.UnitPrice * item.Quantity
+		total += taxable + taxable*item.TaxRate/100
+	}
+
+	payload.TotalAmount = math.Round(total*100) / 100
+	return nil
+}
+
+func invoiceItems(payloads []InvoiceItemPayload) []*store.InvoiceItem {
+	items := make([]*store.InvoiceItem, 0, len(payloads))
+	for _, itemPayload := range payloads {
+		items = append(items, &store.InvoiceItem{
+			ProdID:    itemPayload.ProdID,
+			VariantID: itemPayload.VariantID,
+			Quantity:  itemPayload.Quantity,
+			UnitPrice: itemPayload.UnitPrice,
+			TaxRate:   itemPayload.TaxRate,
+		})
+	}
+	return items
+}
+
 func (app *application) createInvoiceHandler(w http.ResponseWriter, r *http.Request) {
 	var payload InvoicePayload
 	if err := readJSON(w, r, &payload); err != nil {
@@ -43,6 +137,20 @@ func (app *application) createInvoiceHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if err := app.priceInvoice(r.Context(), &payload); err != nil {
+		switch {
+		case errors.Is(err, errQuantityPrecision):
+			app.badRequestResponse(w, r, err)
+		case err == store.ErrInvalidCustomer:
+			app.notFoundResponse(w, r, err)
+		case err == store.ErrNotFound, err == store.ErrInvalidItemReference:
+			app.badRequestResponse(w, r, store.ErrInvalidItemReference)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
 	invoice := &store.Invoice{
 		InvNo:       payload.InvNo,
 		BusID:       payload.BusID,
@@ -54,32 +162,23 @@ func (app *application) createInvoiceHandler(w http.ResponseWriter, r *http.Requ
 		PaidDate:    payload.PaidDate,
 	}
 
-	err := app.store.Invoices.Create(r.Context(), invoice)
+	items := invoiceItems(payload.Items)
+
+	err := app.store.Invoices.Create(r.Context(), invoice, items)
 	if err != nil {
-		if err == store.ErrDuplicateInvoice {
+		switch err {
+		case store.ErrDuplicateInvoice:
 			app.conflictResponse(w, r, err)
-		} else {
+		case store.ErrInvalidCustomer:
+			app.notFoundResponse(w, r, err)
+		case store.ErrInvalidItemReference:
+			app.badRequestResponse(w, r, err)
+		default:
 			app.internalServerError(w, r, err)
 		}
 		return
 	}
 
-	// var items []*store.InvoiceItem
-	for _, itemPayload := range payload.Items {
-		item := store.InvoiceItem{
-			InvID:     invoice.ID,
-			ProdID:    itemPayload.ProdID,
-			Quantity:  itemPayload.Quantity,
-			UnitPrice: itemPayload.UnitPrice,
-		}
-		err = app.store.InvoiceItems.Create(r.Context(), &item)
-		if err != nil {
-			app.internalServerError(w, r, err)
-			return
-		}
-		// items = append(items, &item)
-	}
-
 	w.WriteHeader(http.StatusCreated)
 }
 
@@ -115,6 +214,20 @@ func (app *application) updateInvoiceHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if err := app.priceInvoice(r.Context(), &payload); err != nil {
+		switch {
+		case errors.Is(err, errQuantityPrecision):
+			app.badRequestResponse(w, r, err)
+		case err == store.ErrInvalidCustomer:
+			app.notFoundResponse(w, r, err)
+		case err == store.ErrNotFound, err == store.ErrInvalidItemReference:
+			app.badRequestResponse(w, r, store.ErrInvalidItemReference)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
 	invoice := &store.Invoice{
 		ID:          payload.ID,
 		InvNo:       payload.InvNo,
@@ -127,34 +240,23 @@ func (app *application) updateInvoiceHandler(w http.ResponseWriter, r *http.Requ
 		PaidDate:    payload.PaidDate,
 	}
 
-	err := app.store.Invoices.Update(r.Context(), invoice)
+	items := invoiceItems(payload.Items)
+
+	err := app.store.Invoices.Update(r.Context(), invoice, items)
 	if err != nil {
-		if err == store.ErrNotFound {
+		switch err {
+		case store.ErrNotFound, store.ErrInvalidCustomer:
 			app.notFoundResponse(w, r, err)
-		} else {
+		case store.ErrInvalidItemReference:
+			app.badRequestResponse(w, r, err)
+		case store.ErrInvoiceCredited:
+			app.conflictResponse(w, r, err)
+		default:
 			app.internalServerError(w, r, err)
 		}
 		return
 	}
 
-	var items []*store.InvoiceItem
-	for _, itemPayload := range payload.Items {
-		item := &store.InvoiceItem{
-			ID:        itemPayload.ID,
-			InvID:     invoice.ID,
-			ProdID:    itemPayload.ProdID,
-			Quantity:  itemPayload.Quantity,
-			UnitPrice: itemPayload.UnitPrice,
-		}
-		items = append(items, item)
-	}
-
-	err = app.store.InvoiceItems.UpdateAll(r.Context(), invoice.ID, items)
-	if err != nil {
-		app.internalServerError(w, r, err)
-		return
-	}
-
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -169,9 +271,12 @@ func (app *application) deleteInvoiceHandler(w http.ResponseWriter, r *http.Requ
 
 	err = app.store.Invoices.Delete(r.Context(), invoiceID)
 	if err != nil {
-		if err == store.ErrNotFound {
+		switch err {
+		case store.ErrNotFound:
 			app.notFoundResponse(w, r, err)
-		} else {
+		case store.ErrInvoiceCredited:
+			app.conflictResponse(w, r, err)
+		default:
 			app.internalServerError(w, r, err)
 		}
 		return
@@ -215,6 +320,10 @@ func (app *application) getInvoiceByIDHandler(w http.ResponseWriter, r *http.Req
 		DueDate:     invoice.DueDate,
 		IsPaid:      invoice.IsPaid,
 		PaidDate:    invoice.PaidDate,
+		Status:      invoice.Status,
+		SentAt:      invoice.SentAt,
+		Kind:        invoice.Kind,
+		RefInvID:    invoice.RefInvID,
 		CreatedAt:   invoice.CreatedAt,
 		Items:       items,
 	}
@@ -257,6 +366,10 @@ func (app *application) getInvoicesByBusinessIDHandler(w http.ResponseWriter, r
 			DueDate:     invoice.DueDate,
 			IsPaid:      invoice.IsPaid,
 			PaidDate:    invoice.PaidDate,
+			Status:      invoice.Status,
+			SentAt:      invoice.SentAt,
+			Kind:        invoice.Kind,
+			RefInvID:    invoice.RefInvID,
 			CreatedAt:   invoice.CreatedAt,
 			Items:       items,
 		})
@@ -305,12 +418,6 @@ func (app *application) getInvoiceAsPDFHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	items, err := app.store.InvoiceItems.GetByInvoiceID(r.Context(), invoiceID)
-	if err != nil {
-		app.internalServerError(w, r, err)
-		return
-	}
-
 	customer, err := app.store.Customers.GetByID(r.Context(), invoice.CustID)
 	if err != nil {
 		app.internalServerError(w, r, err)
@@ -323,13 +430,7 @@ func (app *application) getInvoiceAsPDFHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	product, err := app.store.Products.GetByBusID(r.Context(), invoice.BusID)
-	if err != nil {
-		app.internalServerError(w, r, err)
-		return
-	}
-
-	pdfData, err := app.pdf.GenerateInvoicePDF(business, invoice, customer, items, product)
+	pdfData, err := app.generateInvoicePDF(r.Context(), invoice, business, customer)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"billify-api/internal/store"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// fakeCustomers looks customers up by id. Other methods are never called.
+type fakeCustomers struct {
+	*store.CustomerStore
+	byID map[uuid.UUID]*store.Customer
+}
+
+func (f *fakeCustomers) GetByID(_ context.Context, id uuid.UUID) (*store.Customer, error) {
+	customer, ok := f.byID[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return customer, nil
+}
+
+// fakeProducts lists a fixed set of products for every business.
+type fakeProducts struct {
+	*store.ProductStore
+	products []*store.Product
+}
+
+func (f *fakeProducts) GetByBusID(context.Context, uuid.UUID) ([]*store.Product, error) {
+	return f.products, nil
+}
+
+// fakeInvoices keeps the invoices it is asked to write.
+type fakeInvoices struct {
+	*store.InvoiceStore
+	written []*store.Invoice
+}
+
+func (f *fakeInvoices) Create(_ context.Context, invoice *store.Invoice, _ []*store.InvoiceItem) error {
+	f.written = append(f.written, invoice)
+	return nil
+}
+
+func (f *fakeInvoices) Update(_ context.Context, invoice *store.Invoice, _ []*store.InvoiceItem) error {
+	f.written = append(f.written, invoice)
+	return nil
+}
+
+func TestInvoiceHandlersCheckCustomerBusiness(t *testing.T) {
+	busID, otherBusID := uuid.New(), uuid.New()
+	ownCustomer := &store.Customer{ID: uuid.New(), BusID: busID}
+	otherCustomer := &store.Customer{ID: uuid.New(), BusID: otherBusID}
+	product := &store.Product{ID: uuid.New(), BusID: busID, Name: "Widget", TaxRate: 18}
+
+	tests := []struct {
+		name   string
+		custID uuid.UUID
+		want   int
+	}{
+		{"own customer", ownCustomer.ID, 0},
+		{"other business's customer", otherCustomer.ID, http.StatusNotFound},
+		{"unknown customer", uuid.New(), http.StatusNotFound},
+	}
+
+	handlers := []struct {
+		name    string
+		method  string
+		handler func(*application) http.HandlerFunc
+		ok      int
+	}{
+		{"create", http.MethodPost, func(app *application) http.HandlerFunc { return app.createInvoiceHandler }, http.StatusCreated},
+		{"update", http.MethodPut, func(app *application) http.HandlerFunc { return app.updateInvoiceHandler }, http.StatusNoContent},
+	}
+
+	for _, h := range handlers {
+		for _, tt := range tests {
+			t.Run(h.name+"/"+tt.name, func(t *testing.T) {
+				invoices := &fakeInvoices{}
+				app := &application{
+					store: store.Storage{
+						Customers: &fakeCustomers{byID: map[uuid.UUID]*store.Customer{
+							ownCustomer.ID:   ownCustomer,
+							otherCustomer.ID: otherCustomer,
+						}},
+						Products: &fakeProducts{products: []*store.Product{product}},
+						Invoices: invoices,
+					},
+					logger: zap.NewNop().Sugar(),
+				}
+
+				now := time.Now()
+				body, _ := json.Marshal(InvoicePayload{
+					ID:      uuid.New(),
+					InvNo:   1,
+					BusID:   busID,
+					CustID:  tt.custID,
+					InvDate: now,
+					DueDate: now.AddDate(0, 0, 30),
+					Items: []InvoiceItemPayload{
+						{ProdID: product.ID, Quantity: 2, UnitPrice: 100},
+					},
+				})
+
+				r := httptest.NewRequest(h.method, "/v1/invoices/", bytes.NewReader(body))
+				w := httptest.NewRecorder()
+				h.handler(app)(w, r)
+
+				want := tt.want
+				if want == 0 {
+					want = h.ok
+				}
+				if w.Code != want {
+					t.Fatalf("status = %d, want %d: %s", w.Code, want, w.Body.String())
+				}
+				if want == h.ok {
+					if len(invoices.written) != 1 || invoices.written[0].TotalAmount != 236 {
+						t.Errorf("written = %+v, want one invoice totalling 236", invoices.written)
+					}
+				} else if len(invoices.written) != 0 {
+					t.Errorf("wrote %d invoices for a customer outside the business", len(invoices.written))
+				}
+			})
+		}
+	}
+}
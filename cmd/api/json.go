@@ -1,6 +1,7 @@
 package main
 
 import (
+	"billify-api/internal/gst"
 	"encoding/json"
 	"net/http"
 
@@ -11,6 +12,16 @@ var Validate *validator.Validate
 
 func init() {
 	Validate = validator.New(validator.WithRequiredStructEnabled())
+
+	Validate.RegisterValidation("gst_rate", func(fl validator.FieldLevel) bool {
+		return gst.IsValidTaxRate(fl.Field().Float())
+	})
+	Validate.RegisterValidation("hsn", func(fl validator.FieldLevel) bool {
+		return gst.IsValidHSN(fl.Field().String())
+	})
+	Validate.RegisterValidation("uqc", func(fl validator.FieldLevel) bool {
+		return gst.IsValidUnit(fl.Field().String())
+	})
 }
 
 func writeJSON(w http.ResponseWriter, status int, data any) error {
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"billify-api/internal/store"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type LateFeePolicyPayload struct {
+	CustID     *uuid.UUID `json:"cust_id" validate:"omitempty,uuid"`
+	Kind       string     `json:"kind" validate:"required,oneof=flat interest"`
+	FlatAmount float64    `json:"flat_amount" validate:"required_if=Kind flat,gte=0"`
+	AnnualRate float64    `json:"annual_rate" validate:"required_if=Kind interest,gte=0,lte=100"`
+	GraceDays  int        `json:"grace_days" validate:"gte=0,lte=365"`
+	Cap        *float64   `json:"cap" validate:"omitempty,gt=0"`
+}
+
+func (app *application) getLateFeePoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	policies, err := app.store.LateFees.GetPolicies(r.Context(), busID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, policies)
+}
+
+// saveLateFeePolicyHandler sets the business's late fee policy, or with
+// cust_id the policy for one customer, which then applies instead.
+func (app *application) saveLateFeePolicyHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload LateFeePolicyPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	policy := &store.LateFeePolicy{
+		BusID:      busID,
+		CustID:     payload.CustID,
+		Kind:       payload.Kind,
+		FlatAmount: payload.FlatAmount,
+		AnnualRate: payload.AnnualRate,
+		GraceDays:  payload.GraceDays,
+		Cap:        payload.Cap,
+	}
+
+	if err := app.store.LateFees.SavePolicy(r.Context(), policy); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, policy)
+}
+
+func (app *application) deleteLateFeePolicyHandler(w http.ResponseWriter, r *http.Request) {
+	policyID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.LateFees.DeletePolicy(r.Context(), policyID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getLateFeeAccrualsHandler lists the late fees overdue invoices have
+// accrued as of the as_of date (today by default), optionally for one
+// customer given by cust_id.
+func (app *application) getLateFeeAccrualsHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	asOf, err := parseDateParam(r, "as_of", startOfToday())
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var custID *uuid.UUID
+	if value := r.URL.Query().Get("cust_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		custID = &id
+	}
+
+	accruals, err := app.store.LateFees.GetAccruals(r.Context(), busID, custID, asOf)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, accruals)
+}
+
+type IssueLateFeesPayload struct {
+	As     string      `json:"as" validate:"required,oneof=invoice debit_note"`
+	AsOf   *time.Time  `json:"as_of"`
+	InvIDs []uuid.UUID `json:"inv_ids" validate:"dive,uuid"`
+}
+
+// issueLateFeesHandler bills the late fees still to be charged, as invoices
+// or as debit notes against the overdue invoices. With inv_ids only those
+// invoices are charged.
+func (app *application) issueLateFeesHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload IssueLateFeesPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	asOf := startOfToday()
+	if payload.AsOf != nil {
+		asOf = time.Date(payload.AsOf.Year(), payload.AsOf.Month(), payload.AsOf.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	charges, err := app.store.LateFees.Issue(r.Context(), busID, payload.As, asOf, payload.InvIDs)
+	if err != nil {
+		switch err {
+		case store.ErrDuplicateInvoice:
+			app.conflictResponse(w, r, err)
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, charges)
+}
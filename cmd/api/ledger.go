@@ -0,0 +1,380 @@
+package main
+
+import (
+	"billify-api/internal/store"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type CreateAccountPayload struct {
+	BusID uuid.UUID `json:"bus_id" validate:"required,uuid"`
+	Code  string    `json:"code" validate:"required,max=20"`
+	Name  string    `json:"name" validate:"required,max=100"`
+	Type  string    `json:"type" validate:"required,oneof=asset liability equity income expense"`
+}
+
+func (app *application) createAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateAccountPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	account := &store.Account{
+		BusID: payload.BusID,
+		Code:  payload.Code,
+		Name:  payload.Name,
+		Type:  payload.Type,
+	}
+
+	if err := app.store.Ledger.CreateAccount(r.Context(), account); err != nil {
+		switch err {
+		case store.ErrDuplicateAccount:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, account)
+}
+
+func (app *application) getAccountsByBusinessIDHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	accounts, err := app.store.Ledger.GetAccounts(r.Context(), busID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, accounts)
+}
+
+type UpdateAccountPayload struct {
+	ID   uuid.UUID `json:"id" validate:"required,uuid"`
+	Code string    `json:"code" validate:"required,max=20"`
+	Name string    `json:"name" validate:"required,max=100"`
+}
+
+func (app *application) updateAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdateAccountPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	account := &store.Account{
+		ID:   payload.ID,
+		Code: payload.Code,
+		Name: payload.Name,
+	}
+
+	if err := app.store.Ledger.UpdateAccount(r.Context(), account); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrDuplicateAccount:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) deleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	accountID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Ledger.DeleteAccount(r.Context(), accountID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrSystemAccount, store.ErrAccountInUse:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type JournalLinePayload struct {
+	AccountID uuid.UUID `json:"account_id" validate:"required,uuid"`
+	Debit     float64   `json:"debit" validate:"gte=0"`
+	Credit    float64   `json:"credit" validate:"gte=0"`
+}
+
+type JournalEntryPayload struct {
+	BusID     uuid.UUID            `json:"bus_id" validate:"required,uuid"`
+	EntryDate time.Time            `json:"entry_date" validate:"required"`
+	Narration string               `json:"narration" validate:"required,max=500"`
+	Lines     []JournalLinePayload `json:"lines" validate:"required,min=2,dive"`
+}
+
+func (app *application) createJournalEntryHandler(w http.ResponseWriter, r *http.Request) {
+	var payload JournalEntryPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	entry := &store.JournalEntry{
+		BusID:     payload.BusID,
+		EntryDate: payload.EntryDate,
+		Narration: payload.Narration,
+	}
+	for _, line := range payload.Lines {
+		entry.Lines = append(entry.Lines, store.JournalLine{
+			AccountID: line.AccountID,
+			Debit:     line.Debit,
+			Credit:    line.Credit,
+		})
+	}
+
+	if err := app.store.Ledger.CreateEntry(r.Context(), entry); err != nil {
+		switch err {
+		case store.ErrInvalidAccount, store.ErrInvalidJournalLine, store.ErrUnbalancedEntry:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	created, err := app.store.Ledger.GetEntryByID(r.Context(), entry.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, created)
+}
+
+func (app *application) getJournalEntryByIDHandler(w http.ResponseWriter, r *http.Request) {
+	entryID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	entry, err := app.store.Ledger.GetEntryByID(r.Context(), entryID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, entry)
+}
+
+func (app *application) getJournalEntriesByBusinessIDHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	entries, err := app.store.Ledger.GetEntries(r.Context(), busID, from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, entries)
+}
+
+func (app *application) deleteJournalEntryHandler(w http.ResponseWriter, r *http.Request) {
+	entryID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Ledger.DeleteEntry(r.Context(), entryID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrPostedEntry:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) rebuildLedgerHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Ledger.Rebuild(r.Context(), busID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getTrialBalanceReportHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	params := ReportFormatParams{Format: r.URL.Query().Get("format")}
+	if err := Validate.Struct(params); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	asOf, err := parseDateParam(r, "as_of", startOfToday())
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	report, err := app.store.Ledger.GetTrialBalance(r.Context(), busID, asOf)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if params.Format == "csv" {
+		records := [][]string{{"Code", "Account", "Type", "Debit", "Credit"}}
+		for _, row := range report.Accounts {
+			records = append(records, []string{
+				row.Code,
+				row.Name,
+				row.Type,
+				formatAmount(row.Debit),
+				formatAmount(row.Credit),
+			})
+		}
+		records = append(records, []string{"", "Total", "", formatAmount(report.TotalDebit), formatAmount(report.TotalCredit)})
+
+		filename := fmt.Sprintf("trial-balance-%s.csv", asOf.Format(dateLayout))
+		if err := writeCSV(w, filename, records); err != nil {
+			app.logger.Errorw("failed to write csv", "error", err.Error())
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, report)
+}
+
+func (app *application) getGeneralLedgerReportHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	params := ReportFormatParams{Format: r.URL.Query().Get("format")}
+	if err := Validate.Struct(params); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var accountID *uuid.UUID
+	if value := r.URL.Query().Get("account_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		accountID = &id
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	report, err := app.store.Ledger.GetGeneralLedger(r.Context(), busID, accountID, from, to)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if params.Format == "csv" {
+		records := [][]string{{"Account", "Date", "Narration", "Debit", "Credit", "Balance"}}
+		for _, account := range report.Accounts {
+			name := account.Code + " " + account.Name
+			records = append(records, []string{name, from.Format(dateLayout), "Opening balance", "", "", formatAmount(account.Opening)})
+			for _, line := range account.Lines {
+				records = append(records, []string{
+					name,
+					line.EntryDate.Format(dateLayout),
+					line.Narration,
+					formatAmount(line.Debit),
+					formatAmount(line.Credit),
+					formatAmount(line.Balance),
+				})
+			}
+			records = append(records, []string{name, to.Format(dateLayout), "Closing balance", formatAmount(account.Debit), formatAmount(account.Credit), formatAmount(account.Closing)})
+		}
+
+		filename := fmt.Sprintf("general-ledger-%s-%s.csv", from.Format(dateLayout), to.Format(dateLayout))
+		if err := writeCSV(w, filename, records); err != nil {
+			app.logger.Errorw("failed to write csv", "error", err.Error())
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, report)
+}
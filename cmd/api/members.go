@@ -0,0 +1,321 @@
+package main
+
+import (
+	"billify-api/internal/mailer"
+	"billify-api/internal/store"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const invitationTTL = 7 * 24 * time.Hour
+
+var (
+	errOwnerOnly  = errors.New("only the owner can grant, change or remove the admin role")
+	errOwnerFixed = errors.New("the owner's membership cannot be changed; the owner can delete the business instead")
+)
+
+func (app *application) getMembersHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	members, err := app.store.Memberships.GetMembers(r.Context(), busID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, members)
+}
+
+// memberParams reads the business and user in the URL and loads the
+// membership being acted on. The error response has been written when ok
+// is false.
+func (app *application) memberParams(w http.ResponseWriter, r *http.Request) (*store.Member, bool) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	member, err := app.store.Memberships.GetMember(r.Context(), busID, userID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return nil, false
+	}
+
+	return member, true
+}
+
+type UpdateMemberPayload struct {
+	Role string `json:"role" validate:"required,oneof=admin accountant sales viewer"`
+}
+
+func (app *application) updateMemberHandler(w http.ResponseWriter, r *http.Request) {
+	actor := r.Context().Value(memberCtx).(*store.Member)
+
+	member, ok := app.memberParams(w, r)
+	if !ok {
+		return
+	}
+
+	var payload UpdateMemberPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if member.Role == store.RoleOwner {
+		app.forbiddenErrorResponse(w, r, errOwnerFixed)
+		return
+	}
+
+	if actor.Role != store.RoleOwner && (member.Role == store.RoleAdmin || payload.Role == store.RoleAdmin) {
+		app.forbiddenErrorResponse(w, r, errOwnerOnly)
+		return
+	}
+
+	if err := app.store.Memberships.UpdateRole(r.Context(), member.BusID, member.UserID, payload.Role); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	member.Role = payload.Role
+
+	app.jsonResponse(w, http.StatusOK, member)
+}
+
+// removeMemberHandler takes a member out of the business. Anyone but the
+// owner may leave; removing someone else needs the members permission.
+func (app *application) removeMemberHandler(w http.ResponseWriter, r *http.Request) {
+	actor := r.Context().Value(memberCtx).(*store.Member)
+
+	member, ok := app.memberParams(w, r)
+	if !ok {
+		return
+	}
+
+	if member.Role == store.RoleOwner {
+		app.forbiddenErrorResponse(w, r, errOwnerFixed)
+		return
+	}
+
+	if member.UserID != actor.UserID {
+		if !roleCan(actor.Role, permMembers) {
+			app.forbiddenErrorResponse(w, r, errNoPermission)
+			return
+		}
+		if member.Role == store.RoleAdmin && actor.Role != store.RoleOwner {
+			app.forbiddenErrorResponse(w, r, errOwnerOnly)
+			return
+		}
+	}
+
+	if err := app.store.Memberships.Remove(r.Context(), member.BusID, member.UserID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	invitations, err := app.store.Memberships.GetInvitationsByBusID(r.Context(), busID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, invitations)
+}
+
+type CreateInvitationPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+	Role  string `json:"role" validate:"required,oneof=admin accountant sales viewer"`
+}
+
+// createInvitationHandler invites someone by email to join the business.
+// The invitation is kept even if the email cannot be sent; it is also shown
+// to the invitee in the app once they sign up or sign in.
+func (app *application) createInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	actor := r.Context().Value(memberCtx).(*store.Member)
+
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload CreateInvitationPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Role == store.RoleAdmin && actor.Role != store.RoleOwner {
+		app.forbiddenErrorResponse(w, r, errOwnerOnly)
+		return
+	}
+
+	invitation := &store.Invitation{
+		BusID:     busID,
+		Email:     strings.TrimSpace(payload.Email),
+		Role:      payload.Role,
+		InvitedBy: &actor.UserID,
+		ExpiresAt: time.Now().Add(invitationTTL).Truncate(time.Second),
+	}
+
+	if err := app.store.Memberships.CreateInvitation(r.Context(), invitation); err != nil {
+		switch err {
+		case store.ErrAlreadyMember, store.ErrDuplicateInvitation:
+			app.conflictResponse(w, r, err)
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.sendInvitation(r, actor, invitation); err != nil {
+		app.logger.Errorw("invitation email not sent", "invitation", invitation.ID, "error", err.Error())
+	}
+
+	app.jsonResponse(w, http.StatusCreated, invitation)
+}
+
+func (app *application) sendInvitation(r *http.Request, inviter *store.Member, invitation *store.Invitation) error {
+	name := strings.TrimSpace(inviter.FirstName + " " + inviter.LastName)
+	if name == "" {
+		name = inviter.Email
+	}
+
+	msg := mailer.Message{
+		From:    (&mail.Address{Name: "Billify", Address: app.config.mail.fromAddress}).String(),
+		To:      []string{invitation.Email},
+		ReplyTo: inviter.Email,
+		Subject: fmt.Sprintf("%s invited you to %s on Billify", name, invitation.BusinessName),
+		Body: fmt.Sprintf(
+			"%s has invited you to join %s on Billify as %s.\n\n"+
+				"Sign in or create an account with this email address to accept or decline:\n\n%s\n\n"+
+				"The invitation expires on %s.\n",
+			name,
+			invitation.BusinessName,
+			invitation.Role,
+			app.config.frontendURL+"/invitations",
+			invitation.ExpiresAt.Format("2 Jan 2006"),
+		),
+	}
+
+	return app.mailer.Send(r.Context(), msg)
+}
+
+func (app *application) revokeInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	invitationID, err := uuid.Parse(chi.URLParam(r, "invitationID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Memberships.RevokeInvitation(r.Context(), invitationID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getUserInvitationsHandler lists the pending invitations sent to the
+// signed-in user's email.
+func (app *application) getUserInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userCtx).(*store.User)
+
+	invitations, err := app.store.Memberships.GetPendingInvitations(r.Context(), user.Email)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, invitations)
+}
+
+func (app *application) acceptInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	app.respondToInvitation(w, r, true)
+}
+
+func (app *application) declineInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	app.respondToInvitation(w, r, false)
+}
+
+func (app *application) respondToInvitation(w http.ResponseWriter, r *http.Request, accept bool) {
+	user := r.Context().Value(userCtx).(*store.User)
+
+	invitationID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	invitation, err := app.store.Memberships.RespondToInvitation(r.Context(), invitationID, user, accept)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, invitation)
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"billify-api/internal/auth"
+	"billify-api/internal/store"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	mfaIssuer         = "Billify"
+	mfaChallengeTTL   = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+var (
+	errMFAInvalidCode = errors.New("invalid two-factor authentication code")
+	errMFAChallenge   = errors.New("this sign-in has expired; sign in again")
+	errMFANotStarted  = errors.New("start two-factor enrolment first")
+	errMFARequired    = errors.New("this business requires two-factor authentication; enable it in your account settings")
+	errMFAOwnerFirst  = errors.New("enable two-factor authentication on your own account first")
+)
+
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfaRequired"`
+	MFAToken    string `json:"mfaToken"`
+}
+
+// mfaChallenge starts the second step of signing in for a user with
+// two-factor authentication, returning the token to present with a code.
+func (app *application) mfaChallenge(r *http.Request, userID uuid.UUID, device string) (string, error) {
+	challenge := &store.MFAChallenge{
+		UserID:    userID,
+		Device:    device,
+		ExpiresAt: time.Now().Add(mfaChallengeTTL).Truncate(time.Second),
+	}
+	if err := app.store.MFA.CreateChallenge(r.Context(), challenge); err != nil {
+		return "", err
+	}
+
+	return app.links.Issue(auth.PurposeMFAChallenge, challenge.ID, challenge.ExpiresAt), nil
+}
+
+// mfaEnabled reports whether the user has two-factor authentication on.
+func (app *application) mfaEnabled(r *http.Request, userID uuid.UUID) (bool, error) {
+	mfa, err := app.store.MFA.GetByUserID(r.Context(), userID)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return mfa.Enabled, nil
+}
+
+// checkMFACode accepts a code from the user's authenticator app or one of
+// their recovery codes, using it up either way.
+func (app *application) checkMFACode(r *http.Request, mfa *store.UserMFA, code string) error {
+	if step, ok := auth.ValidateTOTP(mfa.Secret, code, time.Now()); ok {
+		return app.store.MFA.UseStep(r.Context(), mfa.UserID, step)
+	}
+
+	err := app.store.MFA.UseRecoveryCode(r.Context(), mfa.UserID, auth.HashToken(auth.NormalizeRecoveryCode(code)))
+	if err == store.ErrNotFound {
+		return errMFAInvalidCode
+	}
+	return err
+}
+
+// enabledMFA loads the signed-in user's enrolment and checks it is on. The
+// error response has been written when ok is false.
+func (app *application) enabledMFA(w http.ResponseWriter, r *http.Request) (*store.UserMFA, bool) {
+	user := r.Context().Value(userCtx).(*store.User)
+
+	mfa, err := app.store.MFA.GetByUserID(r.Context(), user.ID)
+	if err != nil && err != store.ErrNotFound {
+		app.internalServerError(w, r, err)
+		return nil, false
+	}
+
+	if mfa == nil || !mfa.Enabled {
+		app.conflictResponse(w, r, store.ErrMFANotActive)
+		return nil, false
+	}
+
+	return mfa, true
+}
+
+func newRecoveryCodes() ([]string, [][]byte, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([][]byte, recoveryCodeCount)
+	for i := range codes {
+		code, err := auth.NewRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		codes[i] = code
+		hashes[i] = auth.HashToken(auth.NormalizeRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+type MFALoginPayload struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required,max=32"`
+}
+
+// verifyMFALoginHandler completes signing in with a code from the user's
+// authenticator app or a recovery code. A challenge allows a few attempts
+// before the user has to enter their password again.
+func (app *application) verifyMFALoginHandler(w http.ResponseWriter, r *http.Request) {
+	var payload MFALoginPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	challengeID, err := app.links.Verify(auth.PurposeMFAChallenge, payload.MFAToken, time.Now())
+	if err != nil {
+		app.unauthorizedErrorResponse(w, r, errMFAChallenge)
+		return
+	}
+
+	challenge, err := app.store.MFA.AttemptChallenge(r.Context(), challengeID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.unauthorizedErrorResponse(w, r, errMFAChallenge)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	mfa, err := app.store.MFA.GetByUserID(r.Context(), challenge.UserID)
+	if err != nil && err != store.ErrNotFound {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if mfa == nil || !mfa.Enabled {
+		app.unauthorizedErrorResponse(w, r, errMFAChallenge)
+		return
+	}
+
+	if err := app.checkMFACode(r, mfa, payload.Code); err != nil {
+		switch err {
+		case errMFAInvalidCode, store.ErrMFACodeUsed:
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.store.MFA.CompleteChallenge(r.Context(), challenge.ID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.unauthorizedErrorResponse(w, r, errMFAChallenge)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	accessToken, err := app.startSession(w, r, challenge.UserID, challenge.Device)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"accessToken": accessToken})
+}
+
+func (app *application) getMFAHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userCtx).(*store.User)
+
+	mfa, err := app.store.MFA.GetByUserID(r.Context(), user.ID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			mfa = &store.UserMFA{UserID: user.ID}
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	app.jsonResponse(w, http.StatusOK, mfa)
+}
+
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// enrollMFAHandler starts enrolment with a new secret. The URI is meant to
+// be shown as a QR code for the authenticator app to scan; the secret is
+// for typing in by hand. Enrolment is pending until confirmed with a code.
+func (app *application) enrollMFAHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userCtx).(*store.User)
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.MFA.Enroll(r.Context(), user.ID, secret); err != nil {
+		switch err {
+		case store.ErrMFAEnabled:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, MFAEnrollment{
+		Secret: auth.TOTPSecretString(secret),
+		URI:    auth.TOTPURI(mfaIssuer, user.Email, secret),
+	})
+}
+
+type MFACodePayload struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// confirmMFAHandler turns on two-factor authentication once the user
+// enters a code from their authenticator app, and returns their recovery
+// codes. The codes are only ever shown this once.
+func (app *application) confirmMFAHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userCtx).(*store.User)
+
+	var payload MFACodePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	mfa, err := app.store.MFA.GetByUserID(r.Context(), user.ID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.conflictResponse(w, r, errMFANotStarted)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	if mfa.Enabled {
+		app.conflictResponse(w, r, store.ErrMFAEnabled)
+		return
+	}
+
+	step, ok := auth.ValidateTOTP(mfa.Secret, payload.Code, time.Now())
+	if !ok {
+		app.badRequestResponse(w, r, errMFAInvalidCode)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.MFA.Enable(r.Context(), user.ID, step, hashes); err != nil {
+		switch err {
+		case store.ErrMFAEnabled:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// regenerateRecoveryCodesHandler replaces the user's recovery codes, for
+// when they have used or lost them.
+func (app *application) regenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	var payload MFACodePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	mfa, ok := app.enabledMFA(w, r)
+	if !ok {
+		return
+	}
+
+	if err := app.checkMFACode(r, mfa, payload.Code); err != nil {
+		switch err {
+		case errMFAInvalidCode, store.ErrMFACodeUsed:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.MFA.ReplaceRecoveryCodes(r.Context(), mfa.UserID, hashes); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (app *application) disableMFAHandler(w http.ResponseWriter, r *http.Request) {
+	var payload MFACodePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	mfa, ok := app.enabledMFA(w, r)
+	if !ok {
+		return
+	}
+
+	if err := app.checkMFACode(r, mfa, payload.Code); err != nil {
+		switch err {
+		case errMFAInvalidCode, store.ErrMFACodeUsed:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.store.MFA.Disable(r.Context(), mfa.UserID); err != nil {
+		switch err {
+		case store.ErrMFANotActive:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type BusinessSecurityPayload struct {
+	RequireMFA bool `json:"require_mfa"`
+}
+
+// updateBusinessSecurityHandler lets the owner require two-factor
+// authentication of every member. Members without it are turned away from
+// the business until they enable it.
+func (app *application) updateBusinessSecurityHandler(w http.ResponseWriter, r *http.Request) {
+	member := r.Context().Value(memberCtx).(*store.Member)
+
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload BusinessSecurityPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.RequireMFA && !member.MFAEnabled {
+		app.conflictResponse(w, r, errMFAOwnerFirst)
+		return
+	}
+
+	if err := app.store.Business.SetRequireMFA(r.Context(), busID, payload.RequireMFA); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, payload)
+}
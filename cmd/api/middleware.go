@@ -1,6 +1,7 @@
 package main
 
 import (
+	"billify-api/internal/auth"
 	"context"
 	"errors"
 	"fmt"
@@ -11,40 +12,96 @@ import (
 	"github.com/google/uuid"
 )
 
+var errSessionEnded = errors.New("session has ended")
+
+// bearerSubject validates the request's bearer token with authenticator
+// and returns the id the token was issued to, with the session it was
+// issued for if any.
+func bearerSubject(r *http.Request, authenticator auth.Authenticator) (uuid.UUID, uuid.UUID, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("authorization header is missing")
+	}
+
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("authorization header is malformed")
+	}
+
+	accessToken := parts[1]
+	jwtToken, err := authenticator.ValidateAccessToken(accessToken)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	claims, ok := jwtToken.Claims.(jwt.MapClaims)
+	if !ok {
+		return uuid.Nil, uuid.Nil, errors.New("invalid token claims")
+	}
+
+	subject, ok := claims["sub"].(string)
+	if !ok {
+		return uuid.Nil, uuid.Nil, errors.New("invalid user ID")
+	}
+	subjectID, err := uuid.Parse(subject)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	sessionID := uuid.Nil
+	if sid, ok := claims["sid"].(string); ok {
+		if sessionID, err = uuid.Parse(sid); err != nil {
+			return uuid.Nil, uuid.Nil, errors.New("invalid session ID")
+		}
+	}
+
+	return subjectID, sessionID, nil
+}
+
+// AuthMiddleware authenticates staff requests. The access token must name
+// a session that is still active, so signing out or revoking a session
+// takes effect at once rather than when the token expires.
 func (app *application) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			app.unauthorizedErrorResponse(w, r, fmt.Errorf("authorization header is missing"))
+		userID, sessionID, err := bearerSubject(r, app.token)
+		if err != nil {
+			app.unauthorizedErrorResponse(w, r, err)
 			return
 		}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			app.unauthorizedErrorResponse(w, r, fmt.Errorf("authorization header is malformed"))
+		ctx := r.Context()
+
+		if sessionID == uuid.Nil {
+			app.unauthorizedErrorResponse(w, r, errSessionEnded)
 			return
 		}
-
-		accessToken := parts[1]
-		jwtToken, err := app.token.ValidateAccessToken(accessToken)
+		active, err := app.store.Sessions.IsActive(ctx, userID, sessionID)
 		if err != nil {
-			app.unauthorizedErrorResponse(w, r, err)
+			app.internalServerError(w, r, err)
 			return
 		}
-
-		claims, ok := jwtToken.Claims.(jwt.MapClaims)
-		if !ok {
-			app.unauthorizedErrorResponse(w, r, errors.New("invalid token claims"))
+		if !active {
+			app.unauthorizedErrorResponse(w, r, errSessionEnded)
 			return
 		}
 
-		userIDStr, ok := claims["sub"].(string)
-		if !ok {
-			app.unauthorizedErrorResponse(w, r, errors.New("invalid user ID"))
+		user, err := app.store.Users.GetByID(ctx, userID)
+		if err != nil {
+			app.unauthorizedErrorResponse(w, r, err)
 			return
 		}
 
-		userID, err := uuid.Parse(userIDStr)
+		ctx = context.WithValue(ctx, userCtx, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// PortalMiddleware authenticates customer portal requests. Portal tokens
+// have their own audience, so staff tokens are not accepted here and
+// portal tokens are not accepted anywhere else.
+func (app *application) PortalMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accountID, _, err := bearerSubject(r, app.portalToken)
 		if err != nil {
 			app.unauthorizedErrorResponse(w, r, err)
 			return
@@ -52,13 +109,13 @@ func (app *application) AuthMiddleware(next http.Handler) http.Handler {
 
 		ctx := r.Context()
 
-		user, err := app.store.Users.GetByID(ctx, userID)
+		account, err := app.store.Portal.GetAccountByID(ctx, accountID)
 		if err != nil {
 			app.unauthorizedErrorResponse(w, r, err)
 			return
 		}
 
-		ctx = context.WithValue(ctx, userCtx, user)
+		ctx = context.WithValue(ctx, portalAccountCtx, account)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
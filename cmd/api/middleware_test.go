@@ -0,0 +1,107 @@
+package main
+
+import (
+	"billify-api/internal/auth"
+	"billify-api/internal/store"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// fakeSessions knows which sessions are active. Other methods are never
+// called.
+type fakeSessions struct {
+	*store.SessionStore
+	active map[uuid.UUID]uuid.UUID
+}
+
+func (f *fakeSessions) IsActive(_ context.Context, userID, sessionID uuid.UUID) (bool, error) {
+	owner, ok := f.active[sessionID]
+	return ok && owner == userID, nil
+}
+
+type fakeUsers struct {
+	*store.UserStore
+}
+
+func (f *fakeUsers) GetByID(_ context.Context, userID uuid.UUID) (*store.User, error) {
+	return &store.User{ID: userID}, nil
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	token := auth.NewJWTAuthenticator("acc", "ref", "billify", "billify", time.Hour, time.Hour)
+	portalToken := auth.NewJWTAuthenticator("acc", "ref", "billify", portalAudience, time.Hour, time.Hour)
+
+	userID, otherUserID := uuid.New(), uuid.New()
+	active, revoked := uuid.New(), uuid.New()
+	sessions := &fakeSessions{active: map[uuid.UUID]uuid.UUID{active: userID}}
+
+	app := &application{
+		store:  store.Storage{Sessions: sessions, Users: &fakeUsers{}},
+		token:  token,
+		logger: zap.NewNop().Sugar(),
+	}
+
+	issue := func(authenticator *auth.JWTAuthenticator, userID, sessionID uuid.UUID) string {
+		t.Helper()
+		accessToken, err := authenticator.GenerateAccessToken(userID, sessionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return accessToken
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"active session", issue(token, userID, active), http.StatusNoContent},
+		{"revoked session", issue(token, userID, revoked), http.StatusUnauthorized},
+		{"someone else's session", issue(token, otherUserID, active), http.StatusUnauthorized},
+		{"no session", issue(token, userID, uuid.Nil), http.StatusUnauthorized},
+		{"portal token", issue(portalToken, userID, active), http.StatusUnauthorized},
+		{"no token", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen *store.User
+			handler := app.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = r.Context().Value(userCtx).(*store.User)
+				w.WriteHeader(http.StatusNoContent)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/v1/users/me", nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusNoContent && (seen == nil || seen.ID != userID) {
+				t.Errorf("user in context = %+v, want %v", seen, userID)
+			}
+		})
+	}
+
+	// Revoking the session turns away the token it was issued for at once.
+	accessToken := issue(token, userID, active)
+	delete(sessions.active, active)
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/users/me", nil)
+	r.Header.Set("Authorization", "Bearer "+accessToken)
+	w := httptest.NewRecorder()
+	app.AuthMiddleware(http.NotFoundHandler()).ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("after revocation: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
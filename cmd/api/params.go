@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// startOfToday is midnight UTC today, the default for date parameters.
+func startOfToday() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// parseDateParam reads a YYYY-MM-DD query parameter, returning fallback when
+// it is absent.
+func parseDateParam(r *http.Request, key string, fallback time.Time) (time.Time, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be a date in YYYY-MM-DD format", key)
+	}
+
+	return date, nil
+}
+
+// parseDateRange reads the from and to query parameters. Without them the
+// range covers the current financial year (April to March) up to today.
+func parseDateRange(r *http.Request) (time.Time, time.Time, error) {
+	to, err := parseDateParam(r, "to", startOfToday())
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	from, err := parseDateParam(r, "from", financialYearStart(to))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must not be after to")
+	}
+
+	return from, to, nil
+}
+
+func financialYearStart(date time.Time) time.Time {
+	year := date.Year()
+	if date.Month() < time.April {
+		year--
+	}
+	return time.Date(year, time.April, 1, 0, 0, 0, 0, time.UTC)
+}
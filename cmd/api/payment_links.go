@@ -0,0 +1,253 @@
+package main
+
+import (
+	"billify-api/internal/gateway"
+	"billify-api/internal/store"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const maxWebhookSize = 1 << 20 // 1mb
+
+var (
+	errGatewayNotConfigured = errors.New("online payments are not configured")
+	errNothingDue           = errors.New("invoice has no balance due")
+)
+
+var errGatewayRequest = errors.New("payment provider request failed")
+
+// invoiceBalance is what remains to be paid on an invoice, rounded to paise.
+func (app *application) invoiceBalance(ctx context.Context, invoice *store.Invoice) (float64, error) {
+	payments, err := app.store.Payments.GetByInvoiceID(ctx, invoice.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	balance := invoice.TotalAmount
+	for _, payment := range payments {
+		balance -= payment.Amount
+	}
+
+	return math.Round(balance*100) / 100, nil
+}
+
+// openPaymentLink returns the unpaid link of the configured provider for
+// exactly the balance, or nil when there is none.
+func (app *application) openPaymentLink(ctx context.Context, invoiceID uuid.UUID, balance float64) (*store.PaymentLink, error) {
+	if app.gateway == nil {
+		return nil, nil
+	}
+
+	links, err := app.store.PaymentLinks.GetByInvoiceID(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, link := range links {
+		if link.Provider == app.gateway.Name() && link.Status == store.PaymentLinkCreated && link.Amount == balance {
+			return link, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// createPaymentLink asks the provider for a link for balance and stores it.
+// Provider failures are wrapped in errGatewayRequest.
+func (app *application) createPaymentLink(ctx context.Context, invoice *store.Invoice, customer *store.Customer, balance float64) (*store.PaymentLink, error) {
+	if app.gateway == nil {
+		return nil, errGatewayNotConfigured
+	}
+
+	created, err := app.gateway.CreateLink(ctx, gateway.LinkRequest{
+		Amount:        balance,
+		ReferenceID:   invoice.ID.String(),
+		Description:   fmt.Sprintf("Invoice #%d", invoice.InvNo),
+		CustomerName:  customer.Name,
+		CustomerEmail: customer.Email,
+		CustomerPhone: customer.Phone,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errGatewayRequest, err)
+	}
+
+	link := &store.PaymentLink{
+		InvID:          invoice.ID,
+		Provider:       app.gateway.Name(),
+		ProviderLinkID: created.ID,
+		URL:            created.URL,
+		Amount:         balance,
+	}
+
+	if err := app.store.PaymentLinks.Create(ctx, link); err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// createPaymentLinkHandler returns a "Pay now" link for the invoice's
+// balance. A link already created for the same balance is reused, so asking
+// twice does not leave the customer with two links to pay.
+func (app *application) createPaymentLinkHandler(w http.ResponseWriter, r *http.Request) {
+	if app.gateway == nil {
+		app.serviceUnavailableResponse(w, r, errGatewayNotConfigured)
+		return
+	}
+
+	invoiceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	invoice, err := app.store.Invoices.GetByID(r.Context(), invoiceID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	balance, err := app.invoiceBalance(r.Context(), invoice)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if balance <= 0 {
+		app.badRequestResponse(w, r, errNothingDue)
+		return
+	}
+
+	link, created, err := app.payableLink(r.Context(), invoice, balance)
+	if err != nil {
+		switch {
+		case errors.Is(err, errGatewayRequest):
+			app.badGatewayResponse(w, r, err)
+		case err == store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if !created {
+		app.jsonResponse(w, http.StatusOK, link)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, link)
+}
+
+// payableLink returns the open link for the balance, creating one when
+// there is none; created reports which.
+func (app *application) payableLink(ctx context.Context, invoice *store.Invoice, balance float64) (*store.PaymentLink, bool, error) {
+	link, err := app.openPaymentLink(ctx, invoice.ID, balance)
+	if err != nil {
+		return nil, false, err
+	}
+	if link != nil {
+		return link, false, nil
+	}
+
+	customer, err := app.store.Customers.GetByID(ctx, invoice.CustID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	link, err = app.createPaymentLink(ctx, invoice, customer, balance)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return link, true, nil
+}
+
+func (app *application) getPaymentLinksHandler(w http.ResponseWriter, r *http.Request) {
+	invoiceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	links, err := app.store.PaymentLinks.GetByInvoiceID(r.Context(), invoiceID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, links)
+}
+
+type WebhookResult struct {
+	Status string `json:"status"`
+}
+
+// paymentWebhookHandler records a payment the provider reports against one
+// of our links. Deliveries that are valid but cannot be recorded are still
+// acknowledged so the provider does not retry them indefinitely; they are
+// logged for someone to reconcile by hand.
+func (app *application) paymentWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if app.gateway == nil || chi.URLParam(r, "provider") != app.gateway.Name() {
+		app.notFoundResponse(w, r, errGatewayNotConfigured)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookSize))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.gateway.VerifyWebhook(r.Header, body); err != nil {
+		app.unauthorizedErrorResponse(w, r, err)
+		return
+	}
+
+	event, err := app.gateway.ParseWebhook(body)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if event == nil {
+		app.jsonResponse(w, http.StatusOK, WebhookResult{Status: "ignored"})
+		return
+	}
+
+	payment := &store.Payment{
+		Amount:    event.Amount,
+		PaidOn:    event.PaidAt,
+		Method:    event.Method,
+		Reference: event.PaymentID,
+	}
+
+	recorded, err := app.store.PaymentLinks.RecordPayment(r.Context(), app.gateway.Name(), event.LinkID, event.PaymentID, payment)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound, store.ErrInvoiceOverpayment:
+			app.logger.Errorw("unrecorded gateway payment", "link_id", event.LinkID, "payment_id", event.PaymentID, "amount", event.Amount, "error", err.Error())
+			app.jsonResponse(w, http.StatusOK, WebhookResult{Status: "ignored"})
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	status := "duplicate"
+	if recorded {
+		status = "recorded"
+	}
+
+	app.jsonResponse(w, http.StatusOK, WebhookResult{Status: status})
+}
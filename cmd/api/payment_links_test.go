@@ -0,0 +1,94 @@
+package main
+
+import (
+	"billify-api/internal/gateway"
+	"billify-api/internal/store"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// fakePaymentLinks records each gateway payment once, as the unique
+// gateway_payment_id does in the database. Other methods are never called.
+type fakePaymentLinks struct {
+	*store.PaymentLinkStore
+	payments map[string]*store.Payment
+}
+
+func (f *fakePaymentLinks) RecordPayment(_ context.Context, _, _, gatewayPaymentID string, payment *store.Payment) (bool, error) {
+	if _, ok := f.payments[gatewayPaymentID]; ok {
+		return false, nil
+	}
+	f.payments[gatewayPaymentID] = payment
+	return true, nil
+}
+
+func TestPaymentWebhookHandler(t *testing.T) {
+	const secret = "whsec_test"
+
+	fake := gateway.NewFakeServer(secret)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	provider := gateway.NewRazorpay(server.URL, "rzp_test_key", "rzp_test_secret", secret)
+	link, err := provider.CreateLink(context.Background(), gateway.LinkRequest{Amount: 1180})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	body, signature, err := fake.Pay(link.ID, "upi")
+	if err != nil {
+		t.Fatalf("Pay: %v", err)
+	}
+
+	links := &fakePaymentLinks{payments: map[string]*store.Payment{}}
+	app := &application{
+		store:   store.Storage{PaymentLinks: links},
+		gateway: provider,
+		logger:  zap.NewNop().Sugar(),
+	}
+
+	router := chi.NewRouter()
+	router.Post("/webhooks/{provider}", app.paymentWebhookHandler)
+
+	deliver := func(signature string) (int, string) {
+		r := httptest.NewRequest(http.MethodPost, "/webhooks/razorpay", bytes.NewReader(body))
+		r.Header.Set("X-Razorpay-Signature", signature)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		var resp struct {
+			Data WebhookResult `json:"data"`
+		}
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp.Data.Status
+	}
+
+	if code, _ := deliver(gateway.Sign(body, "whsec_other")); code != http.StatusUnauthorized {
+		t.Errorf("bad signature: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if len(links.payments) != 0 {
+		t.Fatalf("bad signature recorded %d payments", len(links.payments))
+	}
+
+	for i, want := range []string{"recorded", "duplicate"} {
+		code, status := deliver(signature)
+		if code != http.StatusOK || status != want {
+			t.Errorf("delivery %d: %d %q, want %d %q", i+1, code, status, http.StatusOK, want)
+		}
+	}
+
+	if len(links.payments) != 1 {
+		t.Fatalf("got %d payments, want 1", len(links.payments))
+	}
+	for _, payment := range links.payments {
+		if payment.Amount != 1180 || payment.Method != "upi" {
+			t.Errorf("payment = %+v, want 1180 by upi", payment)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"billify-api/internal/store"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type PaymentPayload struct {
+	Amount    float64   `json:"amount" validate:"required,gt=0"`
+	PaidOn    time.Time `json:"paid_on" validate:"required"`
+	Method    string    `json:"method" validate:"omitempty,oneof=cash bank upi cheque card"`
+	Reference string    `json:"reference" validate:"max=100"`
+}
+
+func (app *application) createInvoicePaymentHandler(w http.ResponseWriter, r *http.Request) {
+	invoiceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload PaymentPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	payment := &store.Payment{
+		InvID:     invoiceID,
+		Amount:    payload.Amount,
+		PaidOn:    payload.PaidOn,
+		Method:    payload.Method,
+		Reference: payload.Reference,
+	}
+
+	if err := app.store.Payments.Create(r.Context(), payment); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrInvoiceOverpayment:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, payment)
+}
+
+func (app *application) getInvoicePaymentsHandler(w http.ResponseWriter, r *http.Request) {
+	invoiceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	payments, err := app.store.Payments.GetByInvoiceID(r.Context(), invoiceID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, payments)
+}
+
+func (app *application) deleteInvoicePaymentHandler(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := uuid.Parse(chi.URLParam(r, "paymentID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Payments.Delete(r.Context(), paymentID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrCreditNotePayment:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"billify-api/internal/store"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// permission is something a member of a business may be allowed to do.
+type permission int
+
+const (
+	// permView covers reading anything in the business.
+	permView permission = iota
+	// permInvoices covers invoices, customers and getting paid online:
+	// sending invoices, payment links and share links.
+	permInvoices
+	// permPayments covers recording payments against invoices and matching
+	// bank transactions.
+	permPayments
+	// permCatalog covers products, variants, categories, price lists and
+	// stock.
+	permCatalog
+	// permPurchases covers vendors, purchase bills and expenses.
+	permPurchases
+	// permAccounting covers the ledger, the Tally mapping and billing late
+	// fees.
+	permAccounting
+	// permSettings covers the business profile, email templates, reminder
+	// rules and late fee policies.
+	permSettings
+	permMembers
+	permBankDetails
+	permDeleteBusiness
+	// permSecurity covers requiring two-factor authentication of members.
+	permSecurity
+)
+
+var rolePermissions = map[string][]permission{
+	store.RoleOwner: {
+		permView, permInvoices, permPayments, permCatalog, permPurchases,
+		permAccounting, permSettings, permMembers, permBankDetails, permDeleteBusiness,
+		permSecurity,
+	},
+	store.RoleAdmin: {
+		permView, permInvoices, permPayments, permCatalog, permPurchases,
+		permAccounting, permSettings, permMembers,
+	},
+	store.RoleAccountant: {permView, permPayments, permPurchases, permAccounting},
+	store.RoleSales:      {permView, permInvoices, permCatalog},
+	store.RoleViewer:     {permView},
+}
+
+func roleCan(role string, perm permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+type memberKey string
+
+const memberCtx memberKey = "member"
+
+var (
+	errNoBusiness   = errors.New("request does not name a business")
+	errNotMember    = errors.New("you are not a member of this business")
+	errNoPermission = errors.New("your role in this business does not allow this")
+)
+
+// businessResolver finds the businesses a request acts on. Most requests
+// name one; an update naming both a record and a business acts on both.
+type businessResolver func(*http.Request) ([]uuid.UUID, error)
+
+// authorize lets the request through when the user is a member of the
+// business it acts on with a role that has perm. The membership is put in
+// the request context for handlers that need finer checks.
+func (app *application) authorize(perm permission, resolve businessResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := r.Context().Value(userCtx).(*store.User)
+
+			busIDs, err := resolve(r)
+			if err != nil {
+				switch {
+				case err == store.ErrNotFound:
+					app.notFoundResponse(w, r, err)
+				case errors.Is(err, errNoBusiness), isRequestError(err):
+					app.badRequestResponse(w, r, err)
+				default:
+					app.internalServerError(w, r, err)
+				}
+				return
+			}
+
+			var member *store.Member
+			for _, busID := range busIDs {
+				m, err := app.store.Memberships.GetMember(r.Context(), busID, user.ID)
+				if err != nil {
+					switch err {
+					case store.ErrNotFound:
+						app.forbiddenErrorResponse(w, r, errNotMember)
+					default:
+						app.internalServerError(w, r, err)
+					}
+					return
+				}
+
+				// The owner can still reach the security settings, so a
+				// requirement they can no longer meet can be lifted.
+				if m.MFARequired && !m.MFAEnabled && perm != permSecurity {
+					app.forbiddenErrorResponse(w, r, errMFARequired)
+					return
+				}
+
+				if !roleCan(m.Role, perm) {
+					app.forbiddenErrorResponse(w, r, errNoPermission)
+					return
+				}
+
+				if member == nil {
+					member = m
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), memberCtx, member)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// requestError marks a resolver error caused by a malformed request.
+type requestError struct{ err error }
+
+func (e requestError) Error() string { return e.err.Error() }
+func (e requestError) Unwrap() error { return e.err }
+
+func isRequestError(err error) bool {
+	var re requestError
+	return errors.As(err, &re)
+}
+
+// byParam resolves the business from a URL parameter holding its id.
+func byParam(name string) businessResolver {
+	return func(r *http.Request) ([]uuid.UUID, error) {
+		busID, err := uuid.Parse(chi.URLParam(r, name))
+		if err != nil {
+			return nil, requestError{err}
+		}
+		return []uuid.UUID{busID}, nil
+	}
+}
+
+// byRecord resolves the business from a URL parameter holding the id of a
+// record of resource.
+func (app *application) byRecord(resource, name string) businessResolver {
+	return func(r *http.Request) ([]uuid.UUID, error) {
+		id, err := uuid.Parse(chi.URLParam(r, name))
+		if err != nil {
+			return nil, requestError{err}
+		}
+
+		busID, err := app.store.Memberships.ResourceBusiness(r.Context(), resource, id)
+		if err != nil {
+			return nil, err
+		}
+		return []uuid.UUID{busID}, nil
+	}
+}
+
+// byBody resolves the business from the JSON body: its "bus_id" (or
+// "busId"), and with a resource, the business of the record its "id" names.
+// The body is put back for the handler to read.
+//
+// Handlers decode the body with encoding/json, which matches keys without
+// regard to case and keeps the last of repeated keys. A body naming one of
+// these keys more than once, or in another case, is rejected so that the
+// ids checked here are the ones the handler uses.
+func (app *application) byBody(resource string) businessResolver {
+	return func(r *http.Request) ([]uuid.UUID, error) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1_048_578))
+		if err != nil {
+			return nil, requestError{err}
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		fields, err := bodyIDFields(body)
+		if err != nil {
+			return nil, requestError{err}
+		}
+
+		var busIDs []uuid.UUID
+		keys := []string{"bus_id", "busId"}
+		if resource != "" {
+			keys = append([]string{"id"}, keys...)
+		}
+		for _, key := range keys {
+			raw, ok := fields[key]
+			if !ok || string(raw) == "null" {
+				continue
+			}
+
+			var id uuid.UUID
+			if err := json.Unmarshal(raw, &id); err != nil {
+				return nil, requestError{err}
+			}
+
+			if key == "id" {
+				id, err = app.store.Memberships.ResourceBusiness(r.Context(), resource, id)
+				if err != nil {
+					return nil, err
+				}
+			}
+			busIDs = append(busIDs, id)
+		}
+
+		if len(busIDs) == 0 {
+			return nil, errNoBusiness
+		}
+		return busIDs, nil
+	}
+}
+
+// bodyIDKeys are the top-level keys byBody authorizes.
+var bodyIDKeys = []string{"id", "bus_id", "busId"}
+
+// bodyIDFields returns the values of bodyIDKeys in a JSON object, failing
+// on any of them repeated or spelled in another case.
+func bodyIDFields(body []byte) (map[string]json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok != json.Delim('{') {
+		return nil, errors.New("body must be a JSON object")
+	}
+
+	fields := map[string]json.RawMessage{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := tok.(string)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		for _, k := range bodyIDKeys {
+			if !strings.EqualFold(key, k) {
+				continue
+			}
+			if _, ok := fields[k]; ok || key != k {
+				return nil, fmt.Errorf("body has more than one %q field", k)
+			}
+			fields[k] = value
+		}
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"billify-api/internal/store"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// fakeMemberships knows which business each record belongs to and the
+// caller's role in each business. Other methods are never called.
+type fakeMemberships struct {
+	*store.MembershipStore
+	records map[uuid.UUID]uuid.UUID
+	roles   map[uuid.UUID]string
+}
+
+func (f *fakeMemberships) ResourceBusiness(_ context.Context, _ string, id uuid.UUID) (uuid.UUID, error) {
+	busID, ok := f.records[id]
+	if !ok {
+		return uuid.Nil, store.ErrNotFound
+	}
+	return busID, nil
+}
+
+func (f *fakeMemberships) GetMember(_ context.Context, busID, userID uuid.UUID) (*store.Member, error) {
+	role, ok := f.roles[busID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &store.Member{BusID: busID, UserID: userID, Role: role}, nil
+}
+
+type authorizeFixture struct {
+	app                    *application
+	ownBus, otherBus       uuid.UUID
+	ownRecord, otherRecord uuid.UUID
+}
+
+func newAuthorizeFixture() *authorizeFixture {
+	f := &authorizeFixture{
+		ownBus:      uuid.New(),
+		otherBus:    uuid.New(),
+		ownRecord:   uuid.New(),
+		otherRecord: uuid.New(),
+	}
+
+	memberships := &fakeMemberships{
+		records: map[uuid.UUID]uuid.UUID{
+			f.ownRecord:   f.ownBus,
+			f.otherRecord: f.otherBus,
+		},
+		roles: map[uuid.UUID]string{f.ownBus: store.RoleOwner},
+	}
+
+	f.app = &application{
+		store:  store.Storage{Memberships: memberships},
+		logger: zap.NewNop().Sugar(),
+	}
+	return f
+}
+
+// serve runs a request through authorize and reports the status and the
+// body the handler read, if it was reached.
+func (f *authorizeFixture) serve(perm permission, resolve businessResolver, pattern string, r *http.Request) (int, string) {
+	var handlerBody string
+	handler := f.app.authorize(perm, resolve)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		handlerBody = string(body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	router := chi.NewRouter()
+	router.Handle(pattern, handler)
+
+	ctx := context.WithValue(r.Context(), userCtx, &store.User{ID: uuid.New()})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r.WithContext(ctx))
+
+	return w.Code, handlerBody
+}
+
+func TestByBody(t *testing.T) {
+	f := newAuthorizeFixture()
+
+	tests := []struct {
+		name     string
+		resource string
+		body     string
+		want     int
+	}{
+		{"own business", "", fmt.Sprintf(`{"bus_id":%q}`, f.ownBus), http.StatusNoContent},
+		{"own business camel case", "", fmt.Sprintf(`{"busId":%q}`, f.ownBus), http.StatusNoContent},
+		{"other business", "", fmt.Sprintf(`{"bus_id":%q}`, f.otherBus), http.StatusForbidden},
+		{"own record", store.ResourceCustomer, fmt.Sprintf(`{"id":%q,"bus_id":%q}`, f.ownRecord, f.ownBus), http.StatusNoContent},
+		{"other record", store.ResourceCustomer, fmt.Sprintf(`{"id":%q,"bus_id":%q}`, f.otherRecord, f.ownBus), http.StatusForbidden},
+		{"unknown record", store.ResourceCustomer, fmt.Sprintf(`{"id":%q}`, uuid.New()), http.StatusNotFound},
+		{"no business", "", `{"name":"x"}`, http.StatusBadRequest},
+		{"not an object", "", `[]`, http.StatusBadRequest},
+		{"malformed id", "", `{"bus_id":"nope"}`, http.StatusBadRequest},
+
+		// encoding/json would decode the last, case-insensitive match of
+		// these into the handler's payload.
+		{"case variant id", store.ResourceCustomer, fmt.Sprintf(`{"id":%q,"ID":%q}`, f.ownRecord, f.otherRecord), http.StatusBadRequest},
+		{"case variant bus_id", "", fmt.Sprintf(`{"bus_id":%q,"BUS_ID":%q}`, f.ownBus, f.otherBus), http.StatusBadRequest},
+		{"case variant busId", "", fmt.Sprintf(`{"busId":%q,"BUSID":%q}`, f.ownBus, f.otherBus), http.StatusBadRequest},
+		{"only a case variant", "", fmt.Sprintf(`{"Bus_Id":%q}`, f.ownBus), http.StatusBadRequest},
+		{"repeated bus_id", "", fmt.Sprintf(`{"bus_id":%q,"bus_id":%q}`, f.ownBus, f.otherBus), http.StatusBadRequest},
+		{"repeated id", store.ResourceCustomer, fmt.Sprintf(`{"id":%q,"id":%q}`, f.ownRecord, f.otherRecord), http.StatusBadRequest},
+		{"bus_id and busId naming different businesses", "", fmt.Sprintf(`{"bus_id":%q,"busId":%q}`, f.ownBus, f.otherBus), http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tt.body))
+
+			got, body := f.serve(permSettings, f.app.byBody(tt.resource), "/", r)
+			if got != tt.want {
+				t.Fatalf("status = %d, want %d", got, tt.want)
+			}
+			if got == http.StatusNoContent && body != tt.body {
+				t.Errorf("handler read %q, want the original body %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestByParam(t *testing.T) {
+	f := newAuthorizeFixture()
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"own business", "/business/" + f.ownBus.String(), http.StatusNoContent},
+		{"other business", "/business/" + f.otherBus.String(), http.StatusForbidden},
+		{"malformed id", "/business/nope", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+
+			got, _ := f.serve(permView, byParam("busID"), "/business/{busID}", r)
+			if got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestByRecord(t *testing.T) {
+	f := newAuthorizeFixture()
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"own record", "/invoices/" + f.ownRecord.String(), http.StatusNoContent},
+		{"other record", "/invoices/" + f.otherRecord.String(), http.StatusForbidden},
+		{"unknown record", "/invoices/" + uuid.NewString(), http.StatusNotFound},
+		{"malformed id", "/invoices/nope", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+
+			got, _ := f.serve(permView, f.app.byRecord(store.ResourceInvoice, "id"), "/invoices/{id}", r)
+			if got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoleCan(t *testing.T) {
+	tests := []struct {
+		role string
+		perm permission
+		want bool
+	}{
+		{store.RoleOwner, permDeleteBusiness, true},
+		{store.RoleOwner, permSecurity, true},
+		{store.RoleAdmin, permMembers, true},
+		{store.RoleAdmin, permBankDetails, false},
+		{store.RoleAdmin, permDeleteBusiness, false},
+		{store.RoleAccountant, permPayments, true},
+		{store.RoleAccountant, permInvoices, false},
+		{store.RoleSales, permInvoices, true},
+		{store.RoleSales, permAccounting, false},
+		{store.RoleViewer, permView, true},
+		{store.RoleViewer, permCatalog, false},
+		{"unknown", permView, false},
+	}
+
+	for _, tt := range tests {
+		if got := roleCan(tt.role, tt.perm); got != tt.want {
+			t.Errorf("roleCan(%q, %d) = %v, want %v", tt.role, tt.perm, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"billify-api/internal/auth"
+	"billify-api/internal/mailer"
+	"billify-api/internal/store"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	portalAudience = "billify-portal"
+	portalLoginTTL = 15 * time.Minute
+)
+
+type portalAccountKey string
+
+const portalAccountCtx portalAccountKey = "portal_account"
+
+var errPortalLoginInvalid = errors.New("this sign-in link is invalid or has expired")
+
+type PortalMagicLinkPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// requestPortalLoginHandler emails a one-time sign-in link to a customer.
+// It answers the same whether or not any business bills the address, so it
+// cannot be used to find out who is a customer.
+func (app *application) requestPortalLoginHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PortalMagicLinkPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	email := strings.TrimSpace(payload.Email)
+
+	customers, err := app.store.Portal.GetCustomers(r.Context(), email)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if len(customers) > 0 {
+		if err := app.sendPortalLogin(r, email); err != nil {
+			app.logger.Errorw("portal sign-in link not sent", "error", err.Error())
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (app *application) sendPortalLogin(r *http.Request, email string) error {
+	login := &store.PortalLogin{
+		Email:     email,
+		ExpiresAt: time.Now().Add(portalLoginTTL).Truncate(time.Second),
+	}
+	if err := app.store.Portal.CreateLogin(r.Context(), login); err != nil {
+		return err
+	}
+
+	token := app.links.Issue(auth.PurposePortalLogin, login.ID, login.ExpiresAt)
+	link := app.config.frontendURL + "/portal/login?token=" + token
+
+	msg := mailer.Message{
+		From:    (&mail.Address{Name: "Billify", Address: app.config.mail.fromAddress}).String(),
+		To:      []string{email},
+		Subject: "Your sign-in link",
+		Body: fmt.Sprintf(
+			"Use this link to sign in and see your invoices and statements:\n\n%s\n\n"+
+				"The link works once and expires in %d minutes. If you did not ask for it, you can ignore this email.\n",
+			link,
+			int(portalLoginTTL.Minutes()),
+		),
+	}
+
+	return app.mailer.Send(r.Context(), msg)
+}
+
+type PortalVerifyPayload struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type PortalTokenResponse struct {
+	Token   string               `json:"token"`
+	Account *store.PortalAccount `json:"account"`
+}
+
+// verifyPortalLoginHandler exchanges a sign-in link's token for a portal
+// access token.
+func (app *application) verifyPortalLoginHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PortalVerifyPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	loginID, err := app.links.Verify(auth.PurposePortalLogin, payload.Token, time.Now())
+	if err != nil {
+		app.unauthorizedErrorResponse(w, r, errPortalLoginInvalid)
+		return
+	}
+
+	account, err := app.store.Portal.ConsumeLogin(r.Context(), loginID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.unauthorizedErrorResponse(w, r, errPortalLoginInvalid)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	token, err := app.portalToken.GenerateAccessToken(account.ID, uuid.Nil)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, PortalTokenResponse{Token: token, Account: account})
+}
+
+type PortalProfile struct {
+	Email     string                  `json:"email"`
+	Customers []*store.PortalCustomer `json:"customers"`
+}
+
+func (app *application) getPortalProfileHandler(w http.ResponseWriter, r *http.Request) {
+	account := r.Context().Value(portalAccountCtx).(*store.PortalAccount)
+
+	customers, err := app.store.Portal.GetCustomers(r.Context(), account.Email)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, PortalProfile{Email: account.Email, Customers: customers})
+}
+
+// getPortalInvoicesHandler lists the invoices of every business billing
+// the signed-in customer, or with bus_id those of one business.
+func (app *application) getPortalInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	account := r.Context().Value(portalAccountCtx).(*store.PortalAccount)
+
+	var busID *uuid.UUID
+	if value := r.URL.Query().Get("bus_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		busID = &id
+	}
+
+	invoices, err := app.store.Portal.GetInvoices(r.Context(), account.Email, busID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, invoices)
+}
+
+// portalCustomer checks that the customer record belongs to the signed-in
+// account. Records of other customers are reported as not found, as if
+// they did not exist.
+func (app *application) portalCustomer(w http.ResponseWriter, r *http.Request, customerID uuid.UUID) bool {
+	account := r.Context().Value(portalAccountCtx).(*store.PortalAccount)
+
+	customers, err := app.store.Portal.GetCustomers(r.Context(), account.Email)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return false
+	}
+
+	for _, customer := range customers {
+		if customer.CustID == customerID {
+			return true
+		}
+	}
+
+	app.notFoundResponse(w, r, store.ErrNotFound)
+	return false
+}
+
+// portalInvoice loads the invoice in the URL if it was billed to the
+// signed-in account. The error response has been written when ok is false.
+func (app *application) portalInvoice(w http.ResponseWriter, r *http.Request) (*store.Invoice, bool) {
+	invoiceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	invoice, err := app.store.Invoices.GetByID(r.Context(), invoiceID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return nil, false
+	}
+
+	if !app.portalCustomer(w, r, invoice.CustID) {
+		return nil, false
+	}
+
+	return invoice, true
+}
+
+func (app *application) getPortalInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	invoice, ok := app.portalInvoice(w, r)
+	if !ok {
+		return
+	}
+
+	view, err := app.publicInvoice(r.Context(), invoice)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	view.PDFURL = fmt.Sprintf("%s/v1/portal/invoices/%s/pdf", app.config.apiURL, invoice.ID)
+
+	app.jsonResponse(w, http.StatusOK, view)
+}
+
+func (app *application) getPortalInvoicePDFHandler(w http.ResponseWriter, r *http.Request) {
+	invoice, ok := app.portalInvoice(w, r)
+	if !ok {
+		return
+	}
+
+	customer, err := app.store.Customers.GetByID(r.Context(), invoice.CustID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	business, err := app.store.Business.GetByID(r.Context(), invoice.BusID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	pdfData, err := app.generateInvoicePDF(r.Context(), invoice, business, customer)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", fmt.Sprintf("invoice-%d.pdf", invoice.InvNo)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(pdfData)
+}
+
+// payPortalInvoiceHandler returns a link to pay the invoice's balance
+// online, reusing one already created for the same amount.
+func (app *application) payPortalInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	if app.gateway == nil {
+		app.serviceUnavailableResponse(w, r, errGatewayNotConfigured)
+		return
+	}
+
+	invoice, ok := app.portalInvoice(w, r)
+	if !ok {
+		return
+	}
+
+	balance, err := app.invoiceBalance(r.Context(), invoice)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if balance <= 0 {
+		app.badRequestResponse(w, r, errNothingDue)
+		return
+	}
+
+	link, _, err := app.payableLink(r.Context(), invoice, balance)
+	if err != nil {
+		switch {
+		case errors.Is(err, errGatewayRequest):
+			app.badGatewayResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, link)
+}
+
+func (app *application) getPortalStatementHandler(w http.ResponseWriter, r *http.Request) {
+	customerID, err := uuid.Parse(chi.URLParam(r, "custID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if !app.portalCustomer(w, r, customerID) {
+		return
+	}
+
+	statement, err := app.store.Customers.GetStatement(r.Context(), customerID, from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, statement)
+}
+
+func (app *application) getPortalStatementPDFHandler(w http.ResponseWriter, r *http.Request) {
+	customerID, err := uuid.Parse(chi.URLParam(r, "custID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if !app.portalCustomer(w, r, customerID) {
+		return
+	}
+
+	customer, err := app.store.Customers.GetByID(r.Context(), customerID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	business, err := app.store.Business.GetByID(r.Context(), customer.BusID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	statement, err := app.store.Customers.GetStatement(r.Context(), customerID, from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	pdfData, err := app.pdf.GenerateStatementPDF(business, customer, statement)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.WriteHeader(http.StatusOK)
+	w.Write(pdfData)
+}
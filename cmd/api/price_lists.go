@@ -0,0 +1,292 @@
+package main
+
+import (
+	"billify-api/internal/store"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type PriceListItemPayload struct {
+	ProdID    uuid.UUID  `json:"prod_id" validate:"required,uuid"`
+	VariantID *uuid.UUID `json:"variant_id" validate:"omitempty,uuid"`
+	MinQty    float64    `json:"min_qty" validate:"gt=0"`
+	UnitPrice float64    `json:"unit_price" validate:"gte=0"`
+}
+
+type CreatePriceListPayload struct {
+	BusID     uuid.UUID              `json:"bus_id" validate:"required,uuid"`
+	Name      string                 `json:"name" validate:"required,min=2,max=100"`
+	ValidFrom *time.Time             `json:"valid_from"`
+	ValidTo   *time.Time             `json:"valid_to"`
+	Items     []PriceListItemPayload `json:"items" validate:"dive"`
+}
+
+type UpdatePriceListPayload struct {
+	ID        uuid.UUID              `json:"id" validate:"required,uuid"`
+	Name      string                 `json:"name" validate:"required,min=2,max=100"`
+	ValidFrom *time.Time             `json:"valid_from"`
+	ValidTo   *time.Time             `json:"valid_to"`
+	Items     []PriceListItemPayload `json:"items" validate:"dive"`
+}
+
+func priceListItems(payload []PriceListItemPayload) []*store.PriceListItem {
+	items := make([]*store.PriceListItem, 0, len(payload))
+	for _, item := range payload {
+		items = append(items, &store.PriceListItem{
+			ProdID:    item.ProdID,
+			VariantID: item.VariantID,
+			MinQty:    item.MinQty,
+			UnitPrice: item.UnitPrice,
+		})
+	}
+	return items
+}
+
+func validatePriceListWindow(from, to *time.Time) error {
+	if from != nil && to != nil && from.After(*to) {
+		return errors.New("valid_from must not be after valid_to")
+	}
+	return nil
+}
+
+func (app *application) createPriceListHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreatePriceListPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := validatePriceListWindow(payload.ValidFrom, payload.ValidTo); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	list := &store.PriceList{
+		BusID:     payload.BusID,
+		Name:      payload.Name,
+		ValidFrom: payload.ValidFrom,
+		ValidTo:   payload.ValidTo,
+		Items:     priceListItems(payload.Items),
+	}
+
+	if err := app.store.PriceLists.Create(r.Context(), list); err != nil {
+		switch err {
+		case store.ErrDuplicatePriceList, store.ErrDuplicatePriceTier:
+			app.conflictResponse(w, r, err)
+		case store.ErrInvalidItemReference:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, list)
+}
+
+func (app *application) getPriceListByIDHandler(w http.ResponseWriter, r *http.Request) {
+	listID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	list, err := app.store.PriceLists.GetByID(r.Context(), listID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, list)
+}
+
+func (app *application) getPriceListsByBusinessIDHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	lists, err := app.store.PriceLists.GetByBusID(r.Context(), busID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, lists)
+}
+
+func (app *application) updatePriceListHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdatePriceListPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := validatePriceListWindow(payload.ValidFrom, payload.ValidTo); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	list := &store.PriceList{
+		ID:        payload.ID,
+		Name:      payload.Name,
+		ValidFrom: payload.ValidFrom,
+		ValidTo:   payload.ValidTo,
+		Items:     priceListItems(payload.Items),
+	}
+
+	if err := app.store.PriceLists.Update(r.Context(), list); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrDuplicatePriceList, store.ErrDuplicatePriceTier:
+			app.conflictResponse(w, r, err)
+		case store.ErrInvalidItemReference:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) deletePriceListHandler(w http.ResponseWriter, r *http.Request) {
+	listID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.PriceLists.Delete(r.Context(), listID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type CustomerPriceListPayload struct {
+	PriceListID *uuid.UUID `json:"price_list_id" validate:"omitempty,uuid"`
+}
+
+// setCustomerPriceListHandler assigns a price list to a customer; a null
+// price_list_id puts the customer back on list prices.
+func (app *application) setCustomerPriceListHandler(w http.ResponseWriter, r *http.Request) {
+	customerID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload CustomerPriceListPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Customers.SetPriceList(r.Context(), customerID, payload.PriceListID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resolveProductPriceHandler returns the unit price a customer pays for a
+// quantity of a product, using the same rule invoices are priced with.
+func (app *application) resolveProductPriceHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	customerID, err := uuid.Parse(query.Get("cust_id"))
+	if err != nil {
+		app.badRequestResponse(w, r, errors.New("cust_id is required"))
+		return
+	}
+
+	priceQuery := store.PriceQuery{
+		CustID:   customerID,
+		ProdID:   productID,
+		Quantity: 1,
+	}
+
+	if value := query.Get("variant_id"); value != "" {
+		variantID, err := uuid.Parse(value)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		priceQuery.VariantID = &variantID
+	}
+
+	if value := query.Get("qty"); value != "" {
+		qty, err := strconv.ParseFloat(value, 64)
+		if err != nil || qty <= 0 {
+			app.badRequestResponse(w, r, errors.New("qty must be a positive number"))
+			return
+		}
+		priceQuery.Quantity = qty
+	}
+
+	priceQuery.Date, err = parseDateParam(r, "date", startOfToday())
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	price, err := app.store.PriceLists.ResolvePrice(r.Context(), priceQuery)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, price)
+}
@@ -1,20 +1,35 @@
 package main
 
 import (
+	"billify-api/internal/gst"
 	"billify-api/internal/store"
+	"errors"
+	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
 type CreateProductPayload struct {
-	BusID   uuid.UUID `json:"bus_id" validate:"required,uuid"`
-	Name    string    `json:"name" validate:"required,min=3,max=100"`
-	Price   float64   `json:"price" validate:"required"`
-	TaxRate float64   `json:"tax_rate" validate:"required"`
-	Unit    string    `json:"unit" validate:"required"`
-	HSNCode string    `json:"hsn_code" validate:"required"`
+	BusID             uuid.UUID  `json:"bus_id" validate:"required,uuid"`
+	Name              string     `json:"name" validate:"required,min=3,max=100"`
+	Price             float64    `json:"price" validate:"required,gt=0"`
+	TaxRate           float64    `json:"tax_rate" validate:"gst_rate"`
+	Unit              string     `json:"unit" validate:"required,max=50"`
+	HSNCode           string     `json:"hsn_code" validate:"required"`
+	ItemType          string     `json:"item_type" validate:"omitempty,oneof=goods service"`
+	UQC               string     `json:"uqc" validate:"omitempty,uqc"`
+	CostPrice         *float64   `json:"cost_price" validate:"omitempty,gt=0"`
+	TrackStock        bool       `json:"track_stock"`
+	OpeningStock      float64    `json:"opening_stock" validate:"gte=0"`
+	LowStockThreshold *float64   `json:"low_stock_threshold" validate:"omitempty,gte=0"`
+	CategoryID        *uuid.UUID `json:"category_id" validate:"omitempty,uuid"`
+	SKU               string     `json:"sku" validate:"omitempty,max=64,printascii"`
+	Barcode           string     `json:"barcode" validate:"omitempty,max=64,printascii"`
 }
 
 func (app *application) createProductHandler(w http.ResponseWriter, r *http.Request) {
@@ -29,30 +44,91 @@ func (app *application) createProductHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if err := normalizeProductType(&payload.ItemType, &payload.UQC, payload.HSNCode, payload.TrackStock); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
 	product := &store.Product{
-		BusID:   payload.BusID,
-		Name:    payload.Name,
-		Price:   payload.Price,
-		TaxRate: payload.TaxRate,
-		Unit:    payload.Unit,
-		HSNCode: payload.HSNCode,
+		BusID:             payload.BusID,
+		Name:              payload.Name,
+		Price:             payload.Price,
+		TaxRate:           payload.TaxRate,
+		Unit:              payload.Unit,
+		HSNCode:           payload.HSNCode,
+		ItemType:          payload.ItemType,
+		UQC:               payload.UQC,
+		CostPrice:         payload.CostPrice,
+		TrackStock:        payload.TrackStock,
+		OpeningStock:      payload.OpeningStock,
+		LowStockThreshold: payload.LowStockThreshold,
+		CategoryID:        payload.CategoryID,
+		SKU:               payload.SKU,
+		Barcode:           payload.Barcode,
 	}
 
 	if err := app.store.Products.Create(r.Context(), product); err != nil {
-		app.internalServerError(w, r, err)
+		switch err {
+		case store.ErrDuplicateCode:
+			app.conflictResponse(w, r, err)
+		case store.ErrInvalidCategory:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 
 	w.WriteHeader(http.StatusCreated)
 }
 
+// normalizeProductType defaults the item type and UQC and checks that the
+// code in hsn_code is an HSN for goods or a SAC for services.
+func normalizeProductType(itemType, uqc *string, code string, trackStock bool) error {
+	if *itemType == "" {
+		*itemType = store.ProductTypeGoods
+	}
+
+	switch *itemType {
+	case store.ProductTypeService:
+		if !gst.IsValidSAC(code) {
+			return errors.New("hsn_code must be a 6 digit SAC starting with 99 for services")
+		}
+		if trackStock {
+			return errors.New("stock cannot be tracked for services")
+		}
+		if *uqc == "" {
+			*uqc = gst.DefaultServiceUnit
+		}
+	default:
+		if !gst.IsValidHSN(code) {
+			return errors.New("hsn_code must be a 4, 6 or 8 digit HSN code for goods")
+		}
+		if *uqc == "" {
+			*uqc = gst.DefaultGoodsUnit
+		}
+	}
+
+	*uqc = strings.ToUpper(*uqc)
+	return nil
+}
+
 type UpdateProductPayload struct {
-	ID      uuid.UUID `json:"id" validate:"required,uuid"`
-	Name    string    `json:"name" validate:"required,min=3,max=100"`
-	Price   float64   `json:"price" validate:"required,numeric"`
-	TaxRate float64   `json:"tax_rate" validate:"required,numeric"`
-	Unit    string    `json:"unit" validate:"required"`
-	HSNCode string    `json:"hsn_code" validate:"required"`
+	ID                uuid.UUID  `json:"id" validate:"required,uuid"`
+	Name              string     `json:"name" validate:"required,min=3,max=100"`
+	Price             float64    `json:"price" validate:"required,gt=0"`
+	TaxRate           float64    `json:"tax_rate" validate:"gst_rate"`
+	Unit              string     `json:"unit" validate:"required,max=50"`
+	HSNCode           string     `json:"hsn_code" validate:"required"`
+	ItemType          string     `json:"item_type" validate:"omitempty,oneof=goods service"`
+	UQC               string     `json:"uqc" validate:"omitempty,uqc"`
+	CostPrice         *float64   `json:"cost_price" validate:"omitempty,gt=0"`
+	TrackStock        bool       `json:"track_stock"`
+	OpeningStock      float64    `json:"opening_stock" validate:"gte=0"`
+	LowStockThreshold *float64   `json:"low_stock_threshold" validate:"omitempty,gte=0"`
+	CategoryID        *uuid.UUID `json:"category_id" validate:"omitempty,uuid"`
+	SKU               string     `json:"sku" validate:"omitempty,max=64,printascii"`
+	Barcode           string     `json:"barcode" validate:"omitempty,max=64,printascii"`
 }
 
 func (app *application) updateProductHandler(w http.ResponseWriter, r *http.Request) {
@@ -67,19 +143,38 @@ func (app *application) updateProductHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if err := normalizeProductType(&payload.ItemType, &payload.UQC, payload.HSNCode, payload.TrackStock); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
 	product := &store.Product{
-		ID:      payload.ID,
-		Name:    payload.Name,
-		Price:   payload.Price,
-		TaxRate: payload.TaxRate,
-		Unit:    payload.Unit,
-		HSNCode: payload.HSNCode,
+		ID:                payload.ID,
+		Name:              payload.Name,
+		Price:             payload.Price,
+		TaxRate:           payload.TaxRate,
+		Unit:              payload.Unit,
+		HSNCode:           payload.HSNCode,
+		ItemType:          payload.ItemType,
+		UQC:               payload.UQC,
+		CostPrice:         payload.CostPrice,
+		TrackStock:        payload.TrackStock,
+		OpeningStock:      payload.OpeningStock,
+		LowStockThreshold: payload.LowStockThreshold,
+		CategoryID:        payload.CategoryID,
+		SKU:               payload.SKU,
+		Barcode:           payload.Barcode,
 	}
 
 	if err := app.store.Products.Update(r.Context(), product); err != nil {
-		if err == store.ErrNotFound {
+		switch err {
+		case store.ErrNotFound:
 			app.notFoundResponse(w, r, err)
-		} else {
+		case store.ErrDuplicateCode:
+			app.conflictResponse(w, r, err)
+		case store.ErrInvalidCategory:
+			app.badRequestResponse(w, r, err)
+		default:
 			app.internalServerError(w, r, err)
 		}
 		return
@@ -127,3 +222,189 @@ func (app *application) getProductsByBusinessIDHandler(w http.ResponseWriter, r
 	app.jsonResponse(w, http.StatusOK, products)
 }
 
+var productImportFields = []string{"name", "price", "tax_rate", "unit", "hsn_code", "item_type", "uqc"}
+
+func (app *application) importProductsHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	table, dryRun, err := readImportRequest(w, r, productImportFields)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	report := &ImportReport{DryRun: dryRun, Rows: []ImportRowResult{}}
+
+	var products []*store.Product
+	var productRows []int
+	seen := make(map[string]int)
+	for i, row := range table.rows {
+		// Row numbers match the spreadsheet, where row 1 is the header.
+		rowNo := i + 2
+
+		payload := CreateProductPayload{
+			BusID:    busID,
+			Name:     table.value(row, "name"),
+			Unit:     table.value(row, "unit"),
+			HSNCode:  table.value(row, "hsn_code"),
+			ItemType: strings.ToLower(table.value(row, "item_type")),
+			UQC:      table.value(row, "uqc"),
+		}
+
+		var errs []string
+		if payload.Price, err = parseImportNumber(table.value(row, "price")); err != nil {
+			errs = append(errs, "price must be a number")
+		}
+		if payload.TaxRate, err = parseImportNumber(strings.TrimSuffix(table.value(row, "tax_rate"), "%")); err != nil {
+			errs = append(errs, "tax_rate must be a number")
+		}
+		if err := Validate.Struct(payload); err != nil {
+			errs = append(errs, validationMessages(payload, err)...)
+		} else if err := normalizeProductType(&payload.ItemType, &payload.UQC, payload.HSNCode, false); err != nil {
+			errs = append(errs, err.Error())
+		}
+		if len(errs) > 0 {
+			report.add(ImportRowResult{Row: rowNo, Status: ImportStatusInvalid, Errors: errs})
+			continue
+		}
+
+		key := strings.ToLower(payload.Name)
+		if first, ok := seen[key]; ok {
+			report.add(ImportRowResult{
+				Row:    rowNo,
+				Status: ImportStatusInvalid,
+				Errors: []string{fmt.Sprintf("duplicate of row %d with the same name", first)},
+			})
+			continue
+		}
+		seen[key] = rowNo
+
+		products = append(products, &store.Product{
+			BusID:    payload.BusID,
+			Name:     payload.Name,
+			Price:    payload.Price,
+			TaxRate:  payload.TaxRate,
+			Unit:     payload.Unit,
+			HSNCode:  payload.HSNCode,
+			ItemType: payload.ItemType,
+			UQC:      payload.UQC,
+		})
+		productRows = append(productRows, rowNo)
+	}
+
+	if len(products) > 0 {
+		created, err := app.store.Products.Import(r.Context(), products, dryRun)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		for i, product := range products {
+			result := ImportRowResult{Row: productRows[i], Status: ImportStatusUpdated, ID: &product.ID}
+			if created[i] {
+				result.Status = ImportStatusCreated
+			}
+			if dryRun && created[i] {
+				// The rolled back insert never got a real ID.
+				result.ID = nil
+			}
+			report.add(result)
+		}
+	}
+
+	sort.Slice(report.Rows, func(i, j int) bool { return report.Rows[i].Row < report.Rows[j].Row })
+
+	app.jsonResponse(w, http.StatusOK, report)
+}
+
+type ProductExportParams struct {
+	Format string `validate:"omitempty,oneof=csv xlsx"`
+}
+
+func (app *application) exportProductsHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	params := ProductExportParams{Format: r.URL.Query().Get("format")}
+	if err := Validate.Struct(params); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	products, err := app.store.Products.GetByBusID(r.Context(), busID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	sort.Slice(products, func(i, j int) bool { return products[i].Name < products[j].Name })
+
+	if params.Format == "xlsx" {
+		records := [][]any{{"name", "price", "tax_rate", "unit", "hsn_code", "item_type", "uqc"}}
+		for _, product := range products {
+			records = append(records, []any{product.Name, product.Price, product.TaxRate, product.Unit, product.HSNCode, product.ItemType, product.UQC})
+		}
+
+		if err := writeXLSX(w, "products.xlsx", records); err != nil {
+			app.logger.Errorw("failed to write xlsx", "error", err.Error())
+		}
+		return
+	}
+
+	records := [][]string{productImportFields}
+	for _, product := range products {
+		records = append(records, []string{
+			product.Name,
+			formatAmount(product.Price),
+			strconv.FormatFloat(product.TaxRate, 'f', -1, 64),
+			product.Unit,
+			product.HSNCode,
+			product.ItemType,
+			product.UQC,
+		})
+	}
+
+	if err := writeCSV(w, "products.csv", records); err != nil {
+		app.logger.Errorw("failed to write csv", "error", err.Error())
+	}
+}
+
+func (app *application) lookupProductHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	code := strings.TrimSpace(r.URL.Query().Get("code"))
+	if code == "" {
+		app.badRequestResponse(w, r, errors.New("code is required"))
+		return
+	}
+
+	lookup, err := app.store.Products.GetByCode(r.Context(), busID, code)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, errors.New("no product found for this code"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, lookup)
+}
+
+// getUnitsHandler lists the GST unit quantity codes a product can be billed
+// in, with the decimal places each allows.
+func (app *application) getUnitsHandler(w http.ResponseWriter, r *http.Request) {
+	app.jsonResponse(w, http.StatusOK, gst.Units)
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"billify-api/internal/gst"
+	"billify-api/internal/store"
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+var errVendorNotFound = errors.New("vendor not found for this business")
+
+type PurchaseBillItemPayload struct {
+	ProdID    uuid.UUID  `json:"prod_id" validate:"required,uuid"`
+	VariantID *uuid.UUID `json:"variant_id" validate:"omitempty,uuid"`
+	Quantity  float64    `json:"quantity" validate:"required,gt=0"`
+	UnitPrice float64    `json:"unit_price" validate:"gte=0"`
+	TaxRate   *float64   `json:"tax_rate" validate:"omitempty,gst_rate"`
+}
+
+type PurchaseBillPayload struct {
+	ID       uuid.UUID                 `json:"id"`
+	BusID    uuid.UUID                 `json:"bus_id" validate:"required,uuid"`
+	VendorID uuid.UUID                 `json:"vendor_id" validate:"required,uuid"`
+	BillNo   string                    `json:"bill_no" validate:"required,max=50"`
+	BillDate time.Time                 `json:"bill_date" validate:"required"`
+	DueDate  time.Time                 `json:"due_date" validate:"required,gtefield=BillDate"`
+	Items    []PurchaseBillItemPayload `json:"items" validate:"required,min=1,dive"`
+}
+
+// buildPurchaseBill turns a payload into a bill and its items. Items without
+// a tax rate take the product's, and the tax is split into CGST and SGST or
+// charged as IGST depending on whether the vendor is in the business's state.
+func (app *application) buildPurchaseBill(ctx context.Context, payload *PurchaseBillPayload) (*store.PurchaseBill, []*store.PurchaseBillItem, error) {
+	business, err := app.store.Business.GetByID(ctx, payload.BusID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vendor, err := app.store.Vendors.GetByID(ctx, payload.VendorID)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, nil, errVendorNotFound
+		}
+		return nil, nil, err
+	}
+	if vendor.BusID != payload.BusID {
+		return nil, nil, errVendorNotFound
+	}
+
+	products, err := app.store.Products.GetByBusID(ctx, payload.BusID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	productsByID := make(map[uuid.UUID]*store.Product, len(products))
+	for _, product := range products {
+		productsByID[product.ID] = product
+	}
+
+	var taxable, tax float64
+	items := make([]*store.PurchaseBillItem, 0, len(payload.Items))
+	for _, itemPayload := range payload.Items {
+		product, ok := productsByID[itemPayload.ProdID]
+		if !ok {
+			return nil, nil, store.ErrInvalidItemReference
+		}
+
+		if err := checkQuantity(product, itemPayload.Quantity); err != nil {
+			return nil, nil, err
+		}
+
+		taxRate := product.TaxRate
+		if itemPayload.TaxRate != nil {
+			taxRate = *itemPayload.TaxRate
+		}
+
+		value := itemPayload.UnitPrice * itemPayload.Quantity
+		taxable += value
+		tax += value * taxRate / 100
+
+		items = append(items, &store.PurchaseBillItem{
+			ProdID:    itemPayload.ProdID,
+			VariantID: itemPayload.VariantID,
+			Quantity:  itemPayload.Quantity,
+			UnitPrice: itemPayload.UnitPrice,
+			TaxRate:   taxRate,
+		})
+	}
+
+	taxable = math.Round(taxable*100) / 100
+	tax = math.Round(tax*100) / 100
+	cgst, sgst, igst := gst.SplitTax(tax, gst.IsIntraState(vendor.GSTNo, business.GSTNo))
+
+	bill := &store.PurchaseBill{
+		ID:            payload.ID,
+		BusID:         payload.BusID,
+		VendorID:      payload.VendorID,
+		BillNo:        payload.BillNo,
+		BillDate:      payload.BillDate,
+		DueDate:       payload.DueDate,
+		TaxableAmount: taxable,
+		CGSTAmount:    cgst,
+		SGSTAmount:    sgst,
+		IGSTAmount:    igst,
+		TotalAmount:   math.Round((taxable+tax)*100) / 100,
+	}
+
+	return bill, items, nil
+}
+
+func (app *application) purchaseBillError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, errQuantityPrecision), err == errVendorNotFound, err == store.ErrInvalidItemReference:
+		app.badRequestResponse(w, r, err)
+	case err == store.ErrNotFound:
+		app.notFoundResponse(w, r, err)
+	case err == store.ErrDuplicateBill:
+		app.conflictResponse(w, r, err)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) createPurchaseBillHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PurchaseBillPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	bill, items, err := app.buildPurchaseBill(r.Context(), &payload)
+	if err != nil {
+		app.purchaseBillError(w, r, err)
+		return
+	}
+
+	if err := app.store.PurchaseBills.Create(r.Context(), bill); err != nil {
+		if err == store.ErrNotFound {
+			err = errVendorNotFound
+		}
+		app.purchaseBillError(w, r, err)
+		return
+	}
+
+	for _, item := range items {
+		item.BillID = bill.ID
+		if err := app.store.PurchaseBillItems.Create(r.Context(), item); err != nil {
+			app.purchaseBillError(w, r, err)
+			return
+		}
+	}
+
+	app.jsonResponse(w, http.StatusCreated, bill)
+}
+
+func (app *application) updatePurchaseBillHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PurchaseBillPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	bill, items, err := app.buildPurchaseBill(r.Context(), &payload)
+	if err != nil {
+		app.purchaseBillError(w, r, err)
+		return
+	}
+
+	if err := app.store.PurchaseBills.Update(r.Context(), bill); err != nil {
+		app.purchaseBillError(w, r, err)
+		return
+	}
+
+	if err := app.store.PurchaseBillItems.UpdateAll(r.Context(), bill.ID, items); err != nil {
+		app.purchaseBillError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getPurchaseBillByIDHandler(w http.ResponseWriter, r *http.Request) {
+	billID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	bill, err := app.store.PurchaseBills.GetByID(r.Context(), billID)
+	if err != nil {
+		app.purchaseBillError(w, r, err)
+		return
+	}
+
+	items, err := app.store.PurchaseBillItems.GetByBillID(r.Context(), billID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	payments, err := app.store.BillPayments.GetByBillID(r.Context(), billID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, store.PurchaseBillResponse{
+		PurchaseBill: bill,
+		Items:        items,
+		Payments:     payments,
+	})
+}
+
+func (app *application) getPurchaseBillsByBusinessIDHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	bills, err := app.store.PurchaseBills.GetByBusID(r.Context(), busID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, bills)
+}
+
+func (app *application) deletePurchaseBillHandler(w http.ResponseWriter, r *http.Request) {
+	billID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.PurchaseBills.Delete(r.Context(), billID); err != nil {
+		app.purchaseBillError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type BillPaymentPayload struct {
+	Amount    float64   `json:"amount" validate:"required,gt=0"`
+	PaidOn    time.Time `json:"paid_on" validate:"required"`
+	Method    string    `json:"method" validate:"omitempty,oneof=cash bank upi cheque card"`
+	Reference string    `json:"reference" validate:"max=100"`
+}
+
+func (app *application) createBillPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	billID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload BillPaymentPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	payment := &store.BillPayment{
+		BillID:    billID,
+		Amount:    payload.Amount,
+		PaidOn:    payload.PaidOn,
+		Method:    payload.Method,
+		Reference: payload.Reference,
+	}
+
+	if err := app.store.BillPayments.Create(r.Context(), payment); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrOverpayment:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, payment)
+}
+
+func (app *application) deleteBillPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := uuid.Parse(chi.URLParam(r, "paymentID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.BillPayments.Delete(r.Context(), paymentID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
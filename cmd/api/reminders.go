@@ -0,0 +1,266 @@
+package main
+
+import (
+	"billify-api/internal/mailer"
+	"billify-api/internal/store"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type CreateReminderRulePayload struct {
+	BusID      uuid.UUID `json:"bus_id" validate:"required,uuid"`
+	OffsetDays int       `json:"offset_days" validate:"min=-365,max=365"`
+	RepeatDays *int      `json:"repeat_days" validate:"omitempty,min=1,max=365"`
+	IsActive   *bool     `json:"is_active"`
+}
+
+type UpdateReminderRulePayload struct {
+	ID         uuid.UUID `json:"id" validate:"required,uuid"`
+	OffsetDays int       `json:"offset_days" validate:"min=-365,max=365"`
+	RepeatDays *int      `json:"repeat_days" validate:"omitempty,min=1,max=365"`
+	IsActive   bool      `json:"is_active"`
+}
+
+// createReminderRuleHandler adds a reminder rule. offset_days is relative to
+// the due date, so -3 reminds three days before it and 0 on the day; with
+// repeat_days the reminder goes out again every so many days until the
+// invoice is paid.
+func (app *application) createReminderRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateReminderRulePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	rule := &store.ReminderRule{
+		BusID:      payload.BusID,
+		OffsetDays: payload.OffsetDays,
+		RepeatDays: payload.RepeatDays,
+		IsActive:   payload.IsActive == nil || *payload.IsActive,
+	}
+
+	if err := app.store.Reminders.CreateRule(r.Context(), rule); err != nil {
+		switch err {
+		case store.ErrDuplicateReminderRule:
+			app.conflictResponse(w, r, err)
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, rule)
+}
+
+func (app *application) getReminderRulesByBusinessIDHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	rules, err := app.store.Reminders.GetRulesByBusID(r.Context(), busID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, rules)
+}
+
+func (app *application) updateReminderRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdateReminderRulePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	rule := &store.ReminderRule{
+		ID:         payload.ID,
+		OffsetDays: payload.OffsetDays,
+		RepeatDays: payload.RepeatDays,
+		IsActive:   payload.IsActive,
+	}
+
+	if err := app.store.Reminders.UpdateRule(r.Context(), rule); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrDuplicateReminderRule:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, rule)
+}
+
+func (app *application) deleteReminderRuleHandler(w http.ResponseWriter, r *http.Request) {
+	ruleID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Reminders.DeleteRule(r.Context(), ruleID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getInvoiceRemindersHandler(w http.ResponseWriter, r *http.Request) {
+	invoiceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	entries, err := app.store.Reminders.GetLogByInvoiceID(r.Context(), invoiceID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, entries)
+}
+
+type CustomerRemindersPayload struct {
+	OptOut bool `json:"opt_out"`
+}
+
+func (app *application) setCustomerRemindersHandler(w http.ResponseWriter, r *http.Request) {
+	customerID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload CustomerRemindersPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Customers.SetRemindersOptOut(r.Context(), customerID, payload.OptOut); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// runReminders sends due payment reminders every interval until ctx is
+// done. It runs once on start so a restart does not hold up the day's
+// reminders.
+func (app *application) runReminders(ctx context.Context) {
+	ticker := time.NewTicker(app.config.reminders.interval)
+	defer ticker.Stop()
+
+	for {
+		sent, failed, err := app.sendDueReminders(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			app.logger.Errorw("payment reminders", "error", err.Error())
+		}
+		if sent > 0 || failed > 0 {
+			app.logger.Infow("payment reminders", "sent", sent, "failed", failed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDueReminders emails every reminder due on asOf. Each is claimed in the
+// reminder log before it is sent, so running the job twice, or on two
+// instances at once, sends it once. A reminder that cannot be sent is
+// released and tried again on the next run.
+func (app *application) sendDueReminders(ctx context.Context, asOf time.Time) (int, int, error) {
+	due, err := app.store.Reminders.GetDue(ctx, asOf, app.config.reminders.graceDays)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	sent, failed := 0, 0
+	for _, reminder := range due {
+		if err := ctx.Err(); err != nil {
+			return sent, failed, err
+		}
+
+		entry, claimed, err := app.store.Reminders.Claim(ctx, reminder)
+		if err != nil {
+			return sent, failed, err
+		}
+		if !claimed {
+			continue
+		}
+
+		delivery, err := app.sendReminder(ctx, reminder)
+		if err != nil {
+			app.logger.Warnw("payment reminder not sent", "inv_id", reminder.InvID, "rule_id", reminder.RuleID, "error", err.Error())
+			failed++
+
+			if err := app.store.Reminders.Release(ctx, entry); err != nil {
+				return sent, failed, err
+			}
+			continue
+		}
+
+		if err := app.store.Reminders.Complete(ctx, entry, delivery.ID); err != nil {
+			return sent, failed, err
+		}
+		sent++
+	}
+
+	return sent, failed, nil
+}
+
+func (app *application) sendReminder(ctx context.Context, reminder *store.DueReminder) (*store.EmailDelivery, error) {
+	invoice, err := app.store.Invoices.GetByID(ctx, reminder.InvID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := app.emailInvoice(ctx, invoice, mailer.TemplateReminder, nil)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Status == store.EmailDeliveryFailed {
+		return nil, errors.New(delivery.Error)
+	}
+
+	return delivery, nil
+}
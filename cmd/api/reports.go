@@ -0,0 +1,165 @@
+package main
+
+import (
+	"billify-api/internal/store"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type ReportFormatParams struct {
+	Format string `validate:"omitempty,oneof=json csv"`
+}
+
+func (app *application) getAgingReportHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	params := ReportFormatParams{Format: r.URL.Query().Get("format")}
+	if err := Validate.Struct(params); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	asOf, err := parseDateParam(r, "as_of", startOfToday())
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	report, err := app.store.Reports.GetAging(r.Context(), busID, asOf)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if params.Format == "csv" {
+		records := [][]string{{"Customer", "Current", "1-30 Days", "31-60 Days", "61-90 Days", "90+ Days", "Total", "Accrued Late Fees"}}
+		for _, row := range report.Customers {
+			records = append(records, agingRecord(row.Name, row.AgingBuckets))
+		}
+		records = append(records, agingRecord("Total", report.Totals))
+
+		filename := fmt.Sprintf("aging-%s.csv", asOf.Format(dateLayout))
+		if err := writeCSV(w, filename, records); err != nil {
+			app.logger.Errorw("failed to write csv", "error", err.Error())
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, report)
+}
+
+func agingRecord(name string, buckets store.AgingBuckets) []string {
+	return []string{
+		name,
+		formatAmount(buckets.Current),
+		formatAmount(buckets.Days1To30),
+		formatAmount(buckets.Days31To60),
+		formatAmount(buckets.Days61To90),
+		formatAmount(buckets.Over90),
+		formatAmount(buckets.Total),
+		formatAmount(buckets.AccruedLateFees),
+	}
+}
+
+func (app *application) getStockValuationReportHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	params := ReportFormatParams{Format: r.URL.Query().Get("format")}
+	if err := Validate.Struct(params); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	valuation, err := app.store.Reports.GetStockValuation(r.Context(), busID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if params.Format == "csv" {
+		records := [][]string{{"Product", "Unit", "On Hand", "Unit Cost", "Cost Value", "Sale Value"}}
+		for _, item := range valuation.Items {
+			records = append(records, []string{
+				item.Name,
+				item.Unit,
+				strconv.FormatFloat(item.OnHand, 'f', -1, 64),
+				formatAmount(item.UnitCost),
+				formatAmount(item.CostValue),
+				formatAmount(item.SaleValue),
+			})
+		}
+		records = append(records, []string{"Total", "", "", "", formatAmount(valuation.CostValue), formatAmount(valuation.SaleValue)})
+
+		if err := writeCSV(w, "stock-valuation.csv", records); err != nil {
+			app.logger.Errorw("failed to write csv", "error", err.Error())
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, valuation)
+}
+
+func (app *application) getInputTaxCreditReportHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	params := ReportFormatParams{Format: r.URL.Query().Get("format")}
+	if err := Validate.Struct(params); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	report, err := app.store.Reports.GetInputTaxCredit(r.Context(), busID, from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if params.Format == "csv" {
+		records := [][]string{{"Month", "Taxable Value", "CGST", "SGST", "IGST", "Total ITC"}}
+		for _, period := range report.Months {
+			records = append(records, inputTaxCreditRecord(period))
+		}
+		records = append(records, inputTaxCreditRecord(report.Totals))
+
+		filename := fmt.Sprintf("input-tax-credit-%s-%s.csv", from.Format(dateLayout), to.Format(dateLayout))
+		if err := writeCSV(w, filename, records); err != nil {
+			app.logger.Errorw("failed to write csv", "error", err.Error())
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, report)
+}
+
+func inputTaxCreditRecord(period store.InputTaxCreditPeriod) []string {
+	return []string{
+		period.Month,
+		formatAmount(period.TaxableAmount),
+		formatAmount(period.CGSTAmount),
+		formatAmount(period.SGSTAmount),
+		formatAmount(period.IGSTAmount),
+		formatAmount(period.Total),
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"billify-api/internal/auth"
+	"billify-api/internal/store"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// clientIP returns the address of the client, without the port.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// newRefreshToken issues a refresh token for the session. Only the
+// returned record's hash of it is stored.
+func (app *application) newRefreshToken(userID, sessionID uuid.UUID) (string, *store.RefreshToken, error) {
+	record := &store.RefreshToken{
+		ID:        uuid.New(),
+		SessionID: sessionID,
+		ExpiresAt: time.Now().Add(app.config.auth.token.refExp).Truncate(time.Second),
+	}
+
+	token, err := app.token.GenerateRefreshToken(userID, sessionID, record.ID)
+	if err != nil {
+		return "", nil, err
+	}
+	record.Hash = auth.HashToken(token)
+
+	return token, record, nil
+}
+
+// startSession signs the user in on a new device: it records the session,
+// sets the cookie holding its first refresh token and returns an access
+// token for the session.
+func (app *application) startSession(w http.ResponseWriter, r *http.Request, userID uuid.UUID, device string) (string, error) {
+	session := &store.Session{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Device:    device,
+		UserAgent: r.UserAgent(),
+		IP:        clientIP(r),
+	}
+
+	token, record, err := app.newRefreshToken(userID, session.ID)
+	if err != nil {
+		return "", err
+	}
+	session.ExpiresAt = record.ExpiresAt
+
+	accessToken, err := app.token.GenerateAccessToken(userID, session.ID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := app.store.Sessions.Create(r.Context(), session, record); err != nil {
+		return "", err
+	}
+
+	app.SetRefreshTokenCookie(w, token, app.config.frontendURL)
+	return accessToken, nil
+}
+
+func clearRefreshTokenCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "refreshToken",
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+		Expires:  time.Now().Add(-time.Hour),
+	})
+}
+
+// refreshClaims reads the refresh token cookie. It returns nil when there
+// is no valid refresh token.
+func (app *application) refreshClaims(r *http.Request) *auth.RefreshClaims {
+	cookie, err := r.Cookie("refreshToken")
+	if err != nil {
+		return nil
+	}
+
+	claims, err := app.token.ValidateRefreshToken(cookie.Value)
+	if err != nil {
+		return nil
+	}
+
+	return claims
+}
+
+// getSessionsHandler lists the devices the user is signed in on, marking
+// the one making the request.
+func (app *application) getSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userCtx).(*store.User)
+
+	sessions, err := app.store.Sessions.GetActiveByUserID(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if claims := app.refreshClaims(r); claims != nil {
+		for _, session := range sessions {
+			session.Current = session.ID == claims.SessionID
+		}
+	}
+
+	app.jsonResponse(w, http.StatusOK, sessions)
+}
+
+func (app *application) revokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userCtx).(*store.User)
+
+	sessionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Sessions.Revoke(r.Context(), user.ID, sessionID, store.SessionRevoked); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if claims := app.refreshClaims(r); claims != nil && claims.SessionID == sessionID {
+		clearRefreshTokenCookie(w)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeSessionsHandler signs the user out everywhere, including here.
+func (app *application) revokeSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userCtx).(*store.User)
+
+	if err := app.store.Sessions.RevokeAll(r.Context(), user.ID, store.SessionRevoked); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	clearRefreshTokenCookie(w)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,443 @@
+package main
+
+import (
+	"billify-api/internal/auth"
+	"billify-api/internal/store"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const defaultShareDays = 30
+
+var (
+	errShareNotFound = errors.New("share link not found")
+	errShareExpired  = errors.New("this share link has expired")
+	errShareRevoked  = errors.New("this share link has been revoked")
+)
+
+// InvoiceShareResponse is a share with the link to hand to the customer.
+type InvoiceShareResponse struct {
+	*store.InvoiceShare
+	Token  string `json:"token"`
+	URL    string `json:"url"`
+	PDFURL string `json:"pdf_url"`
+}
+
+func (app *application) shareResponse(share *store.InvoiceShare) *InvoiceShareResponse {
+	token := app.links.Issue(auth.PurposeInvoiceShare, share.ID, share.ExpiresAt)
+	url := app.config.apiURL + "/v1/public/invoices/" + token
+
+	return &InvoiceShareResponse{
+		InvoiceShare: share,
+		Token:        token,
+		URL:          url,
+		PDFURL:       url + "/pdf",
+	}
+}
+
+type CreateInvoiceSharePayload struct {
+	ExpiresInDays int `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
+}
+
+// createInvoiceShareHandler creates a read-only link to the invoice for
+// people without an account. It expires after expires_in_days, 30 by
+// default.
+func (app *application) createInvoiceShareHandler(w http.ResponseWriter, r *http.Request) {
+	invoiceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload CreateInvoiceSharePayload
+	if err := readJSON(w, r, &payload); err != nil && !errors.Is(err, io.EOF) {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	days := payload.ExpiresInDays
+	if days == 0 {
+		days = defaultShareDays
+	}
+
+	share := &store.InvoiceShare{
+		InvID: invoiceID,
+		// Tokens carry the expiry in whole seconds.
+		ExpiresAt: time.Now().AddDate(0, 0, days).Truncate(time.Second),
+	}
+
+	if err := app.store.Shares.Create(r.Context(), share); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, app.shareResponse(share))
+}
+
+func (app *application) getInvoiceSharesHandler(w http.ResponseWriter, r *http.Request) {
+	invoiceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	shares, err := app.store.Shares.GetByInvoiceID(r.Context(), invoiceID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]*InvoiceShareResponse, 0, len(shares))
+	for _, share := range shares {
+		response = append(response, app.shareResponse(share))
+	}
+
+	app.jsonResponse(w, http.StatusOK, response)
+}
+
+func (app *application) revokeInvoiceShareHandler(w http.ResponseWriter, r *http.Request) {
+	shareID, err := uuid.Parse(chi.URLParam(r, "shareID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Shares.Revoke(r.Context(), shareID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getInvoiceShareViewsHandler(w http.ResponseWriter, r *http.Request) {
+	shareID, err := uuid.Parse(chi.URLParam(r, "shareID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	views, err := app.store.Shares.GetViews(r.Context(), shareID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, views)
+}
+
+type PublicParty struct {
+	Name    string `json:"name"`
+	GSTNo   string `json:"gstno,omitempty"`
+	Email   string `json:"email,omitempty"`
+	Phone   string `json:"phone,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+type PublicBankDetails struct {
+	BankName  string `json:"bank_name"`
+	AccountNo string `json:"account_no"`
+	IFSC      string `json:"ifsc"`
+	Branch    string `json:"branch,omitempty"`
+}
+
+type PublicInvoiceItem struct {
+	Name      string  `json:"name"`
+	HSNCode   string  `json:"hsn_code,omitempty"`
+	Quantity  float64 `json:"quantity"`
+	Unit      string  `json:"unit,omitempty"`
+	UnitPrice float64 `json:"unit_price"`
+	TaxRate   float64 `json:"tax_rate"`
+	TaxAmount float64 `json:"tax_amount"`
+	Amount    float64 `json:"amount"`
+}
+
+// PublicInvoice is what a share link shows: the invoice as printed, without
+// internal ids or anything else about the business's books.
+type PublicInvoice struct {
+	Kind          string              `json:"kind"`
+	InvNo         int64               `json:"inv_no"`
+	InvDate       time.Time           `json:"inv_date"`
+	DueDate       time.Time           `json:"due_date"`
+	IsPaid        bool                `json:"is_paid"`
+	Business      PublicParty         `json:"business"`
+	Customer      PublicParty         `json:"customer"`
+	ShipTo        string              `json:"ship_to,omitempty"`
+	Items         []PublicInvoiceItem `json:"items"`
+	Subtotal      float64             `json:"subtotal"`
+	TaxTotal      float64             `json:"tax_total"`
+	Total         float64             `json:"total"`
+	AmountPaid    float64             `json:"amount_paid"`
+	BalanceDue    float64             `json:"balance_due"`
+	BankDetails   *PublicBankDetails  `json:"bank_details,omitempty"`
+	PaymentLink   string              `json:"payment_link,omitempty"`
+	PDFURL        string              `json:"pdf_url"`
+	LinkExpiresAt *time.Time          `json:"link_expires_at,omitempty"`
+}
+
+// sharedInvoice resolves a share token to its share and invoice. Tokens
+// that do not verify are reported as not found; expired and revoked ones
+// as gone. The error response has been written when ok is false.
+func (app *application) sharedInvoice(w http.ResponseWriter, r *http.Request) (*store.InvoiceShare, *store.Invoice, bool) {
+	shareID, err := app.links.Verify(auth.PurposeInvoiceShare, chi.URLParam(r, "token"), time.Now())
+	if err != nil {
+		switch err {
+		case auth.ErrExpiredToken:
+			app.goneResponse(w, r, errShareExpired)
+		default:
+			app.notFoundResponse(w, r, errShareNotFound)
+		}
+		return nil, nil, false
+	}
+
+	share, err := app.store.Shares.GetByID(r.Context(), shareID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, errShareNotFound)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return nil, nil, false
+	}
+
+	switch {
+	case share.RevokedAt != nil:
+		app.goneResponse(w, r, errShareRevoked)
+		return nil, nil, false
+	case !time.Now().Before(share.ExpiresAt):
+		app.goneResponse(w, r, errShareExpired)
+		return nil, nil, false
+	}
+
+	invoice, err := app.store.Invoices.GetByID(r.Context(), share.InvID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, errShareNotFound)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return nil, nil, false
+	}
+
+	return share, invoice, true
+}
+
+// recordShareView logs that a share was opened. Failing to log it does not
+// keep the customer from seeing the invoice.
+func (app *application) recordShareView(r *http.Request, share *store.InvoiceShare, format string) {
+	view := &store.InvoiceShareView{
+		ShareID:   share.ID,
+		Format:    format,
+		IP:        clientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+
+	if err := app.store.Shares.RecordView(r.Context(), view); err != nil {
+		app.logger.Warnw("share view not recorded", "share_id", share.ID, "error", err.Error())
+	}
+}
+
+// setPublicHeaders keeps shared invoices out of caches and search engines,
+// and the token out of the Referer header of links followed from the page.
+func setPublicHeaders(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex")
+}
+
+func (app *application) getPublicInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	share, invoice, ok := app.sharedInvoice(w, r)
+	if !ok {
+		return
+	}
+
+	view, err := app.publicInvoice(r.Context(), invoice)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	view.PDFURL = app.config.apiURL + "/v1/public/invoices/" + chi.URLParam(r, "token") + "/pdf"
+	view.LinkExpiresAt = &share.ExpiresAt
+
+	app.recordShareView(r, share, store.ShareViewPage)
+
+	setPublicHeaders(w)
+	app.jsonResponse(w, http.StatusOK, view)
+}
+
+func (app *application) getPublicInvoicePDFHandler(w http.ResponseWriter, r *http.Request) {
+	share, invoice, ok := app.sharedInvoice(w, r)
+	if !ok {
+		return
+	}
+
+	customer, err := app.store.Customers.GetByID(r.Context(), invoice.CustID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	business, err := app.store.Business.GetByID(r.Context(), invoice.BusID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	pdfData, err := app.generateInvoicePDF(r.Context(), invoice, business, customer)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.recordShareView(r, share, store.ShareViewPDF)
+
+	setPublicHeaders(w)
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", fmt.Sprintf("invoice-%d.pdf", invoice.InvNo)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(pdfData)
+}
+
+// publicInvoice builds the sanitised view of an invoice. Only a payment
+// link that already exists is offered; opening a share link never creates
+// one at the payment provider.
+func (app *application) publicInvoice(ctx context.Context, invoice *store.Invoice) (*PublicInvoice, error) {
+	business, err := app.store.Business.GetByID(ctx, invoice.BusID)
+	if err != nil {
+		return nil, err
+	}
+
+	customer, err := app.store.Customers.GetByID(ctx, invoice.CustID)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := app.store.InvoiceItems.GetByInvoiceID(ctx, invoice.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := app.store.Products.GetByBusID(ctx, invoice.BusID)
+	if err != nil {
+		return nil, err
+	}
+	productByID := make(map[uuid.UUID]*store.Product, len(products))
+	for _, product := range products {
+		productByID[product.ID] = product
+	}
+
+	balance, err := app.invoiceBalance(ctx, invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &PublicInvoice{
+		Kind:    invoice.Kind,
+		InvNo:   invoice.InvNo,
+		InvDate: invoice.InvDate,
+		DueDate: invoice.DueDate,
+		IsPaid:  invoice.IsPaid,
+		Business: PublicParty{
+			Name:    business.Name,
+			GSTNo:   business.GSTNo,
+			Email:   business.CompanyEmail,
+			Phone:   business.CompanyPhone,
+			Address: joinAddress(business.Address, business.City, business.State, business.ZipCode, business.Country),
+		},
+		Customer: PublicParty{
+			Name:    customer.Name,
+			GSTNo:   customer.GSTNo,
+			Address: customer.BAddress,
+		},
+		ShipTo:     customer.SAddress,
+		Items:      make([]PublicInvoiceItem, 0, len(items)),
+		Total:      invoice.TotalAmount,
+		AmountPaid: roundAmount(invoice.TotalAmount - balance),
+		BalanceDue: balance,
+	}
+
+	for _, item := range items {
+		line := PublicInvoiceItem{
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+			TaxRate:   item.TaxRate,
+		}
+		if product, ok := productByID[item.ProdID]; ok {
+			line.Name = product.Name
+			line.HSNCode = product.HSNCode
+			line.Unit = product.Unit
+		}
+		if item.VariantName != "" {
+			line.Name = fmt.Sprintf("%s - %s", line.Name, item.VariantName)
+		}
+		line.Amount = roundAmount(item.UnitPrice * item.Quantity)
+		line.TaxAmount = roundAmount(line.Amount * line.TaxRate / 100)
+
+		view.Subtotal += line.Amount
+		view.TaxTotal += line.TaxAmount
+		view.Items = append(view.Items, line)
+	}
+	view.Subtotal = roundAmount(view.Subtotal)
+	view.TaxTotal = roundAmount(view.TaxTotal)
+
+	if business.AccountNo != "" {
+		view.BankDetails = &PublicBankDetails{
+			BankName:  business.BankName,
+			AccountNo: business.AccountNo,
+			IFSC:      business.IFSC,
+			Branch:    business.BankBranch,
+		}
+	}
+
+	if balance > 0 {
+		link, err := app.openPaymentLink(ctx, invoice.ID, balance)
+		if err != nil {
+			return nil, err
+		}
+		if link != nil {
+			view.PaymentLink = link.URL
+		}
+	}
+
+	return view, nil
+}
+
+func joinAddress(parts ...string) string {
+	filled := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			filled = append(filled, part)
+		}
+	}
+	return strings.Join(filled, ", ")
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
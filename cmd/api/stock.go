@@ -0,0 +1,113 @@
+package main
+
+import (
+	"billify-api/internal/store"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func (app *application) getProductStockHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	level, err := app.store.Stock.GetLevel(r.Context(), productID)
+	if err != nil {
+		switch err {
+		case store.ErrStockNotTracked:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, level)
+}
+
+func (app *application) getProductStockMovementsHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	movements, err := app.store.Stock.GetMovements(r.Context(), productID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, movements)
+}
+
+type CreateStockMovementPayload struct {
+	Kind     string  `json:"kind" validate:"required,oneof=purchase adjustment return"`
+	Quantity float64 `json:"quantity" validate:"required"`
+	Note     string  `json:"note" validate:"max=255"`
+}
+
+func (app *application) createStockMovementHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload CreateStockMovementPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// Only adjustments can take stock away; purchases and returns add to it.
+	if payload.Kind != store.StockMovementAdjustment && payload.Quantity < 0 {
+		app.badRequestResponse(w, r, errors.New("quantity must be positive for purchases and returns"))
+		return
+	}
+
+	movement := &store.StockMovement{
+		ProdID:   productID,
+		Kind:     payload.Kind,
+		Quantity: payload.Quantity,
+		Note:     payload.Note,
+	}
+
+	if err := app.store.Stock.CreateMovement(r.Context(), movement); err != nil {
+		switch err {
+		case store.ErrStockNotTracked:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, movement)
+}
+
+func (app *application) getLowStockHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	levels, err := app.store.Stock.GetLowStock(r.Context(), busID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, levels)
+}
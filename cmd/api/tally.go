@@ -0,0 +1,249 @@
+package main
+
+import (
+	"billify-api/internal/store"
+	"billify-api/internal/tally"
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// tallyMapping returns the business's Tally ledger names, falling back to
+// the defaults when none are configured.
+func (app *application) tallyMapping(r *http.Request, busID uuid.UUID) (tally.Mapping, error) {
+	mapping, err := app.store.Tally.GetMapping(r.Context(), busID)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return tally.DefaultMapping, nil
+		}
+		return tally.Mapping{}, err
+	}
+
+	return tally.Mapping{
+		Sales:      mapping.Sales,
+		CGST:       mapping.CGST,
+		SGST:       mapping.SGST,
+		IGST:       mapping.IGST,
+		Receipt:    mapping.Receipt,
+		PartyGroup: mapping.PartyGroup,
+	}, nil
+}
+
+func (app *application) getTallyMappingHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	mapping, err := app.tallyMapping(r, busID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, mapping)
+}
+
+type TallyMappingPayload struct {
+	Sales      string `json:"sales" validate:"required,max=100"`
+	CGST       string `json:"cgst" validate:"required,max=100"`
+	SGST       string `json:"sgst" validate:"required,max=100"`
+	IGST       string `json:"igst" validate:"required,max=100"`
+	Receipt    string `json:"receipt" validate:"required,max=100"`
+	PartyGroup string `json:"party_group" validate:"required,max=100"`
+}
+
+func (app *application) updateTallyMappingHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload TallyMappingPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	mapping := &store.TallyMapping{
+		BusID:      busID,
+		Sales:      payload.Sales,
+		CGST:       payload.CGST,
+		SGST:       payload.SGST,
+		IGST:       payload.IGST,
+		Receipt:    payload.Receipt,
+		PartyGroup: payload.PartyGroup,
+	}
+
+	if err := app.store.Tally.SaveMapping(r.Context(), mapping); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// partyNames gives every customer a distinct Tally ledger name. Customers
+// sharing a name are told apart by GSTIN, or by ID when they have none.
+func partyNames(vouchers []*store.InvoiceVoucher) map[uuid.UUID]string {
+	counts := map[string]int{}
+	seen := map[uuid.UUID]bool{}
+	for _, voucher := range vouchers {
+		if !seen[voucher.CustID] {
+			seen[voucher.CustID] = true
+			counts[voucher.CustName]++
+		}
+	}
+
+	names := map[uuid.UUID]string{}
+	for _, voucher := range vouchers {
+		name := voucher.CustName
+		if counts[name] > 1 {
+			suffix := voucher.CustGSTNo
+			if suffix == "" {
+				suffix = voucher.CustID.String()[:8]
+			}
+			name = fmt.Sprintf("%s (%s)", name, suffix)
+		}
+		names[voucher.CustID] = name
+	}
+
+	return names
+}
+
+// exportTallyHandler writes the invoices dated in the range as sales
+// vouchers, the credit notes issued in the range as credit notes and the
+// payments received in the range as receipts, preceded by a party ledger for
+// every customer involved.
+func (app *application) exportTallyHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	business, err := app.store.Business.GetByID(r.Context(), busID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	mapping, err := app.tallyMapping(r, busID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	vouchers, err := app.store.Tally.GetInvoiceVouchers(r.Context(), busID, from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	end := to.AddDate(0, 0, 1)
+	inRange := func(date time.Time) bool {
+		return !date.Before(from) && date.Before(end)
+	}
+
+	export := tally.Export{Company: business.Name, Mapping: mapping}
+	names := partyNames(vouchers)
+	added := map[uuid.UUID]bool{}
+	for _, voucher := range vouchers {
+		party := names[voucher.CustID]
+		if !added[voucher.CustID] {
+			added[voucher.CustID] = true
+			export.Parties = append(export.Parties, tally.Party{
+				Name:    party,
+				GSTIN:   voucher.CustGSTNo,
+				Address: voucher.CustAddress,
+			})
+		}
+
+		if inRange(voucher.InvDate) {
+			export.Sales = append(export.Sales, tally.SalesVoucher{
+				Number:    fmt.Sprintf("%d", voucher.InvNo),
+				Date:      voucher.InvDate,
+				Party:     party,
+				Narration: fmt.Sprintf("Invoice #%d", voucher.InvNo),
+				Taxable:   voucher.TaxableAmount,
+				CGST:      voucher.CGSTAmount,
+				SGST:      voucher.SGSTAmount,
+				IGST:      voucher.IGSTAmount,
+			})
+		}
+
+		for _, note := range voucher.CreditNotes {
+			if !inRange(note.CNDate) {
+				continue
+			}
+
+			export.CreditNotes = append(export.CreditNotes, tally.CreditNoteVoucher{
+				Number:    fmt.Sprintf("CN-%d", note.CNNo),
+				Date:      note.CNDate,
+				Party:     party,
+				Narration: fmt.Sprintf("Credit note #%d against invoice #%d", note.CNNo, voucher.InvNo),
+				Taxable:   note.TaxableAmount,
+				CGST:      note.CGSTAmount,
+				SGST:      note.SGSTAmount,
+				IGST:      note.IGSTAmount,
+			})
+		}
+
+		for i, payment := range voucher.Payments {
+			if !inRange(payment.PaidOn) {
+				continue
+			}
+
+			number := fmt.Sprintf("R-%d", voucher.InvNo)
+			if i > 0 {
+				number = fmt.Sprintf("R-%d-%d", voucher.InvNo, i+1)
+			}
+			export.Receipts = append(export.Receipts, tally.ReceiptVoucher{
+				Number:    number,
+				Date:      payment.PaidOn,
+				Party:     party,
+				Narration: fmt.Sprintf("Payment received for invoice #%d", voucher.InvNo),
+				Amount:    payment.Amount,
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tally.Write(&buf, export); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	filename := fmt.Sprintf("tally-%s-%s.xml", from.Format(dateLayout), to.Format(dateLayout))
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
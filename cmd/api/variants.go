@@ -0,0 +1,136 @@
+package main
+
+import (
+	"billify-api/internal/store"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type CreateVariantPayload struct {
+	Name    string  `json:"name" validate:"required,min=1,max=100"`
+	Price   float64 `json:"price" validate:"required,gt=0"`
+	SKU     string  `json:"sku" validate:"omitempty,max=64,printascii"`
+	Barcode string  `json:"barcode" validate:"omitempty,max=64,printascii"`
+}
+
+func (app *application) createVariantHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload CreateVariantPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	variant := &store.ProductVariant{
+		ProdID:  productID,
+		Name:    payload.Name,
+		Price:   payload.Price,
+		SKU:     payload.SKU,
+		Barcode: payload.Barcode,
+	}
+
+	if err := app.store.ProductVariants.Create(r.Context(), variant); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrDuplicateVariant, store.ErrDuplicateCode:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, variant)
+}
+
+func (app *application) getVariantsByProductIDHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	variants, err := app.store.ProductVariants.GetByProductID(r.Context(), productID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, variants)
+}
+
+type UpdateVariantPayload struct {
+	ID      uuid.UUID `json:"id" validate:"required,uuid"`
+	Name    string    `json:"name" validate:"required,min=1,max=100"`
+	Price   float64   `json:"price" validate:"required,gt=0"`
+	SKU     string    `json:"sku" validate:"omitempty,max=64,printascii"`
+	Barcode string    `json:"barcode" validate:"omitempty,max=64,printascii"`
+}
+
+func (app *application) updateVariantHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdateVariantPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	variant := &store.ProductVariant{
+		ID:      payload.ID,
+		Name:    payload.Name,
+		Price:   payload.Price,
+		SKU:     payload.SKU,
+		Barcode: payload.Barcode,
+	}
+
+	if err := app.store.ProductVariants.Update(r.Context(), variant); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrDuplicateVariant, store.ErrDuplicateCode:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) deleteVariantHandler(w http.ResponseWriter, r *http.Request) {
+	variantID, err := uuid.Parse(chi.URLParam(r, "variantID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.ProductVariants.Delete(r.Context(), variantID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"billify-api/internal/store"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type CreateVendorPayload struct {
+	BusID   uuid.UUID `json:"bus_id" validate:"required,uuid"`
+	Name    string    `json:"name" validate:"required,min=2,max=100"`
+	GSTNo   string    `json:"gstno" validate:"omitempty,len=15,alphanum"`
+	Email   string    `json:"email" validate:"omitempty,email"`
+	Phone   string    `json:"phone" validate:"omitempty,e164"`
+	Address string    `json:"address" validate:"max=200"`
+}
+
+func (app *application) createVendorHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateVendorPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	vendor := &store.Vendor{
+		BusID:   payload.BusID,
+		Name:    payload.Name,
+		GSTNo:   payload.GSTNo,
+		Email:   payload.Email,
+		Phone:   payload.Phone,
+		Address: payload.Address,
+	}
+
+	if err := app.store.Vendors.Create(r.Context(), vendor); err != nil {
+		switch err {
+		case store.ErrDuplicateVendor:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, vendor)
+}
+
+func (app *application) getVendorByIDHandler(w http.ResponseWriter, r *http.Request) {
+	vendorID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	vendor, err := app.store.Vendors.GetByID(r.Context(), vendorID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, vendor)
+}
+
+func (app *application) getVendorsByBusinessIDHandler(w http.ResponseWriter, r *http.Request) {
+	busID, err := uuid.Parse(chi.URLParam(r, "busID"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	vendors, err := app.store.Vendors.GetByBusID(r.Context(), busID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, vendors)
+}
+
+type UpdateVendorPayload struct {
+	ID      uuid.UUID `json:"id" validate:"required,uuid"`
+	Name    string    `json:"name" validate:"required,min=2,max=100"`
+	GSTNo   string    `json:"gstno" validate:"omitempty,len=15,alphanum"`
+	Email   string    `json:"email" validate:"omitempty,email"`
+	Phone   string    `json:"phone" validate:"omitempty,e164"`
+	Address string    `json:"address" validate:"max=200"`
+}
+
+func (app *application) updateVendorHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdateVendorPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	vendor := &store.Vendor{
+		ID:      payload.ID,
+		Name:    payload.Name,
+		GSTNo:   payload.GSTNo,
+		Email:   payload.Email,
+		Phone:   payload.Phone,
+		Address: payload.Address,
+	}
+
+	if err := app.store.Vendors.Update(r.Context(), vendor); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrDuplicateVendor:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) deleteVendorHandler(w http.ResponseWriter, r *http.Request) {
+	vendorID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Vendors.Delete(r.Context(), vendorID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrVendorInUse:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
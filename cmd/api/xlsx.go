@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// readXLSXRecords returns the rows of the first sheet in the workbook.
+func readXLSXRecords(file io.Reader) ([][]string, error) {
+	workbook, err := excelize.OpenReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xlsx: %w", err)
+	}
+	defer workbook.Close()
+
+	sheets := workbook.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("invalid xlsx: workbook has no sheets")
+	}
+
+	return workbook.GetRows(sheets[0])
+}
+
+func writeXLSX(w http.ResponseWriter, filename string, records [][]any) error {
+	workbook := excelize.NewFile()
+	defer workbook.Close()
+
+	sheet := workbook.GetSheetName(0)
+	for i, record := range records {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			return err
+		}
+		if err := workbook.SetSheetRow(sheet, cell, &record); err != nil {
+			return err
+		}
+	}
+
+	w.Header().Set("Content-Type", xlsxContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+
+	return workbook.Write(w)
+}
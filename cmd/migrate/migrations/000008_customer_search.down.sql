@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS invoice_cust_id_is_paid_idx;
+DROP INDEX IF EXISTS customer_gstno_trgm_idx;
+DROP INDEX IF EXISTS customer_phone_trgm_idx;
+DROP INDEX IF EXISTS customer_email_trgm_idx;
+DROP INDEX IF EXISTS customer_name_trgm_idx;
+DROP INDEX IF EXISTS customer_buss_id_created_at_idx;
+DROP INDEX IF EXISTS customer_buss_id_name_idx;
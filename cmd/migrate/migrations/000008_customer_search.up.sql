@@ -0,0 +1,11 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS customer_buss_id_name_idx ON customer (buss_id, name, id);
+CREATE INDEX IF NOT EXISTS customer_buss_id_created_at_idx ON customer (buss_id, created_at, id);
+
+CREATE INDEX IF NOT EXISTS customer_name_trgm_idx ON customer USING gin (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS customer_email_trgm_idx ON customer USING gin ((email::text) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS customer_phone_trgm_idx ON customer USING gin (phone gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS customer_gstno_trgm_idx ON customer USING gin (gstno gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS invoice_cust_id_is_paid_idx ON invoice (cust_id, is_paid);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS invoice_buss_id_inv_date_idx;
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS invoice_buss_id_inv_date_idx ON invoice (buss_id, inv_date)
+    INCLUDE (cust_id, total_amount, due_date, is_paid, paid_date);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "stock_movement" CASCADE;
+
+ALTER TABLE product
+    DROP COLUMN IF EXISTS cost_price,
+    DROP COLUMN IF EXISTS low_stock_threshold,
+    DROP COLUMN IF EXISTS opening_stock,
+    DROP COLUMN IF EXISTS track_stock;
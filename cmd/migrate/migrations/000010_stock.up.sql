@@ -0,0 +1,19 @@
+ALTER TABLE product
+    ADD COLUMN IF NOT EXISTS track_stock BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS opening_stock NUMERIC(12, 3) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS low_stock_threshold NUMERIC(12, 3),
+    ADD COLUMN IF NOT EXISTS cost_price NUMERIC(10, 2);
+
+CREATE TABLE IF NOT EXISTS "stock_movement" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    prod_id UUID NOT NULL REFERENCES product(id) ON DELETE CASCADE,
+    inv_id UUID REFERENCES invoice(id) ON DELETE SET NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('sale', 'purchase', 'adjustment', 'return')),
+    quantity NUMERIC(12, 3) NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS stock_movement_prod_id_idx ON stock_movement (prod_id, created_at);
+CREATE INDEX IF NOT EXISTS stock_movement_inv_id_idx ON stock_movement (inv_id);
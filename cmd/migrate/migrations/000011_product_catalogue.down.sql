@@ -0,0 +1,13 @@
+ALTER TABLE invoice_item
+    DROP CONSTRAINT IF EXISTS invoice_item_variant_fkey,
+    DROP COLUMN IF EXISTS variant_id;
+
+DROP TABLE IF EXISTS "product_code" CASCADE;
+DROP TABLE IF EXISTS "product_variant" CASCADE;
+
+ALTER TABLE product
+    DROP COLUMN IF EXISTS barcode,
+    DROP COLUMN IF EXISTS sku,
+    DROP COLUMN IF EXISTS category_id;
+
+DROP TABLE IF EXISTS "category" CASCADE;
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS "category" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    parent_id UUID REFERENCES category(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE NULLS NOT DISTINCT (buss_id, parent_id, name)
+);
+
+ALTER TABLE product
+    ADD COLUMN IF NOT EXISTS category_id UUID REFERENCES category(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS sku VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS barcode VARCHAR(64);
+
+CREATE TABLE IF NOT EXISTS "product_variant" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    prod_id UUID NOT NULL REFERENCES product(id) ON DELETE CASCADE,
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    price NUMERIC(10, 2) NOT NULL,
+    sku VARCHAR(64),
+    barcode VARCHAR(64),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (prod_id, name),
+    UNIQUE (id, prod_id)
+);
+
+-- Every SKU and barcode of a business, whether on a product or a variant,
+-- is registered here so that codes are unique across both and lookups are
+-- a single primary key probe.
+CREATE TABLE IF NOT EXISTS "product_code" (
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    code VARCHAR(64) NOT NULL,
+    prod_id UUID NOT NULL REFERENCES product(id) ON DELETE CASCADE,
+    variant_id UUID REFERENCES product_variant(id) ON DELETE CASCADE,
+    PRIMARY KEY (buss_id, code)
+);
+
+CREATE INDEX IF NOT EXISTS product_code_prod_id_idx ON product_code (prod_id);
+
+ALTER TABLE invoice_item
+    ADD COLUMN IF NOT EXISTS variant_id UUID,
+    ADD CONSTRAINT invoice_item_variant_fkey FOREIGN KEY (variant_id, prod_id)
+        REFERENCES product_variant(id, prod_id) ON DELETE SET NULL (variant_id);
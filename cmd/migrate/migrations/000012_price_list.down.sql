@@ -0,0 +1,5 @@
+ALTER TABLE customer
+    DROP COLUMN IF EXISTS price_list_id;
+
+DROP TABLE IF EXISTS "price_list_item" CASCADE;
+DROP TABLE IF EXISTS "price_list" CASCADE;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS "price_list" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    valid_from DATE,
+    valid_to DATE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (buss_id, name),
+    CHECK (valid_from IS NULL OR valid_to IS NULL OR valid_from <= valid_to)
+);
+
+CREATE TABLE IF NOT EXISTS "price_list_item" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    list_id UUID NOT NULL REFERENCES price_list(id) ON DELETE CASCADE,
+    prod_id UUID NOT NULL REFERENCES product(id) ON DELETE CASCADE,
+    variant_id UUID,
+    min_qty NUMERIC(12, 3) NOT NULL DEFAULT 1 CHECK (min_qty > 0),
+    unit_price NUMERIC(10, 2) NOT NULL CHECK (unit_price >= 0),
+    FOREIGN KEY (variant_id, prod_id) REFERENCES product_variant(id, prod_id) ON DELETE CASCADE,
+    UNIQUE NULLS NOT DISTINCT (list_id, prod_id, variant_id, min_qty)
+);
+
+CREATE INDEX IF NOT EXISTS price_list_item_list_id_prod_id_idx ON price_list_item (list_id, prod_id);
+
+ALTER TABLE customer
+    ADD COLUMN IF NOT EXISTS price_list_id UUID REFERENCES price_list(id) ON DELETE SET NULL;
@@ -0,0 +1,6 @@
+ALTER TABLE invoice_item
+    ALTER COLUMN quantity TYPE INT USING round(quantity)::INT;
+
+ALTER TABLE product
+    DROP COLUMN IF EXISTS uqc,
+    DROP COLUMN IF EXISTS item_type;
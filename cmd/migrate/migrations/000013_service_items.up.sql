@@ -0,0 +1,24 @@
+ALTER TABLE product
+    ADD COLUMN IF NOT EXISTS item_type VARCHAR(10) NOT NULL DEFAULT 'goods' CHECK (item_type IN ('goods', 'service')),
+    ADD COLUMN IF NOT EXISTS uqc CHAR(3) NOT NULL DEFAULT 'NOS';
+
+-- Map the common free text units onto UQC codes; anything else is OTH.
+UPDATE product
+SET uqc = CASE lower(trim(unit))
+    WHEN 'kg' THEN 'KGS' WHEN 'kgs' THEN 'KGS' WHEN 'kilogram' THEN 'KGS' WHEN 'kilograms' THEN 'KGS'
+    WHEN 'g' THEN 'GMS' WHEN 'gm' THEN 'GMS' WHEN 'gms' THEN 'GMS' WHEN 'gram' THEN 'GMS' WHEN 'grams' THEN 'GMS'
+    WHEN 'l' THEN 'LTR' WHEN 'ltr' THEN 'LTR' WHEN 'litre' THEN 'LTR' WHEN 'liter' THEN 'LTR' WHEN 'litres' THEN 'LTR'
+    WHEN 'ml' THEN 'MLT'
+    WHEN 'm' THEN 'MTR' WHEN 'mtr' THEN 'MTR' WHEN 'meter' THEN 'MTR' WHEN 'metre' THEN 'MTR' WHEN 'meters' THEN 'MTR'
+    WHEN 'pc' THEN 'PCS' WHEN 'pcs' THEN 'PCS' WHEN 'piece' THEN 'PCS' WHEN 'pieces' THEN 'PCS'
+    WHEN 'no' THEN 'NOS' WHEN 'nos' THEN 'NOS' WHEN 'number' THEN 'NOS' WHEN 'numbers' THEN 'NOS'
+    WHEN 'box' THEN 'BOX' WHEN 'boxes' THEN 'BOX'
+    WHEN 'doz' THEN 'DOZ' WHEN 'dozen' THEN 'DOZ'
+    WHEN 'set' THEN 'SET' WHEN 'sets' THEN 'SET'
+    WHEN 'pair' THEN 'PRS' WHEN 'pairs' THEN 'PRS'
+    WHEN 'unit' THEN 'UNT' WHEN 'units' THEN 'UNT'
+    ELSE 'OTH'
+END;
+
+ALTER TABLE invoice_item
+    ALTER COLUMN quantity TYPE NUMERIC(12, 3);
@@ -0,0 +1,7 @@
+ALTER TABLE stock_movement
+    DROP COLUMN IF EXISTS bill_id;
+
+DROP TABLE IF EXISTS "bill_payment" CASCADE;
+DROP TABLE IF EXISTS "purchase_bill_item" CASCADE;
+DROP TABLE IF EXISTS "purchase_bill" CASCADE;
+DROP TABLE IF EXISTS "vendor" CASCADE;
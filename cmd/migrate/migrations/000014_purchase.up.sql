@@ -0,0 +1,59 @@
+CREATE TABLE IF NOT EXISTS "vendor" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    gstno VARCHAR(15) NOT NULL DEFAULT '',
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    phone VARCHAR(20) NOT NULL DEFAULT '',
+    address TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (buss_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS "purchase_bill" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    vendor_id UUID NOT NULL REFERENCES vendor(id) ON DELETE RESTRICT,
+    bill_no VARCHAR(50) NOT NULL,
+    bill_date DATE NOT NULL,
+    due_date DATE NOT NULL,
+    taxable_amount NUMERIC(12, 2) NOT NULL,
+    cgst_amount NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    sgst_amount NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    igst_amount NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    total_amount NUMERIC(12, 2) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (vendor_id, bill_no)
+);
+
+CREATE INDEX IF NOT EXISTS purchase_bill_buss_id_bill_date_idx ON purchase_bill (buss_id, bill_date);
+
+CREATE TABLE IF NOT EXISTS "purchase_bill_item" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    bill_id UUID NOT NULL REFERENCES purchase_bill(id) ON DELETE CASCADE,
+    prod_id UUID NOT NULL REFERENCES product(id) ON DELETE RESTRICT,
+    variant_id UUID,
+    quantity NUMERIC(12, 3) NOT NULL CHECK (quantity > 0),
+    unit_price NUMERIC(10, 2) NOT NULL CHECK (unit_price >= 0),
+    tax_rate NUMERIC(5, 2) NOT NULL,
+    FOREIGN KEY (variant_id, prod_id) REFERENCES product_variant(id, prod_id)
+);
+
+CREATE INDEX IF NOT EXISTS purchase_bill_item_bill_id_idx ON purchase_bill_item (bill_id);
+
+CREATE TABLE IF NOT EXISTS "bill_payment" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    bill_id UUID NOT NULL REFERENCES purchase_bill(id) ON DELETE CASCADE,
+    amount NUMERIC(12, 2) NOT NULL CHECK (amount > 0),
+    paid_on DATE NOT NULL,
+    method VARCHAR(20) NOT NULL DEFAULT '',
+    reference VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS bill_payment_bill_id_idx ON bill_payment (bill_id);
+
+ALTER TABLE stock_movement
+    ADD COLUMN IF NOT EXISTS bill_id UUID REFERENCES purchase_bill(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS stock_movement_bill_id_idx ON stock_movement (bill_id);
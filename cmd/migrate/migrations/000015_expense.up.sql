@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS "expense" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    vendor_id UUID REFERENCES vendor(id) ON DELETE SET NULL,
+    category VARCHAR(50) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    expense_date DATE NOT NULL,
+    amount NUMERIC(12, 2) NOT NULL CHECK (amount >= 0),
+    tax_amount NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (tax_amount >= 0),
+    payment_mode VARCHAR(20) NOT NULL CHECK (payment_mode IN ('cash', 'bank', 'upi', 'cheque', 'card')),
+    receipt_key TEXT,
+    receipt_name VARCHAR(255),
+    receipt_type VARCHAR(100),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS expense_buss_id_expense_date_idx ON expense (buss_id, expense_date);
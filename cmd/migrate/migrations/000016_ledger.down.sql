@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "journal_line" CASCADE;
+DROP FUNCTION IF EXISTS check_journal_balance();
+DROP TABLE IF EXISTS "journal_entry" CASCADE;
+DROP TABLE IF EXISTS "account" CASCADE;
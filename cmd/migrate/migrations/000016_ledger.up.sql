@@ -0,0 +1,73 @@
+CREATE TABLE IF NOT EXISTS "account" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    code VARCHAR(20) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('asset', 'liability', 'equity', 'income', 'expense')),
+    system_key VARCHAR(60),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (buss_id, code),
+    UNIQUE (buss_id, system_key)
+);
+
+CREATE TABLE IF NOT EXISTS "journal_entry" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    entry_date DATE NOT NULL,
+    narration TEXT NOT NULL DEFAULT '',
+    source_type VARCHAR(20) CHECK (source_type IN ('invoice', 'receipt', 'expense', 'purchase_bill', 'bill_payment')),
+    inv_id UUID REFERENCES invoice(id) ON DELETE CASCADE,
+    expense_id UUID REFERENCES expense(id) ON DELETE CASCADE,
+    bill_id UUID REFERENCES purchase_bill(id) ON DELETE CASCADE,
+    bill_payment_id UUID REFERENCES bill_payment(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS journal_entry_buss_id_entry_date_idx ON journal_entry (buss_id, entry_date);
+CREATE INDEX IF NOT EXISTS journal_entry_inv_id_idx ON journal_entry (inv_id);
+CREATE INDEX IF NOT EXISTS journal_entry_expense_id_idx ON journal_entry (expense_id);
+CREATE INDEX IF NOT EXISTS journal_entry_bill_id_idx ON journal_entry (bill_id);
+CREATE INDEX IF NOT EXISTS journal_entry_bill_payment_id_idx ON journal_entry (bill_payment_id);
+
+CREATE TABLE IF NOT EXISTS "journal_line" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    entry_id UUID NOT NULL REFERENCES journal_entry(id) ON DELETE CASCADE,
+    account_id UUID NOT NULL REFERENCES account(id) ON DELETE RESTRICT,
+    debit NUMERIC(14, 2) NOT NULL DEFAULT 0 CHECK (debit >= 0),
+    credit NUMERIC(14, 2) NOT NULL DEFAULT 0 CHECK (credit >= 0),
+    CHECK ((debit = 0) <> (credit = 0))
+);
+
+CREATE INDEX IF NOT EXISTS journal_line_entry_id_idx ON journal_line (entry_id);
+CREATE INDEX IF NOT EXISTS journal_line_account_id_idx ON journal_line (account_id);
+
+-- Every journal entry must balance once the transaction that wrote it
+-- commits, whichever code path wrote the lines.
+CREATE OR REPLACE FUNCTION check_journal_balance() RETURNS trigger AS $$
+DECLARE
+    entry UUID;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        entry := OLD.entry_id;
+    ELSE
+        entry := NEW.entry_id;
+    END IF;
+
+    IF EXISTS (
+        SELECT 1
+        FROM journal_line
+        WHERE entry_id = entry
+        HAVING SUM(debit) <> SUM(credit)
+    ) THEN
+        RAISE EXCEPTION 'journal entry % does not balance', entry USING ERRCODE = 'check_violation';
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS journal_line_balance ON journal_line;
+CREATE CONSTRAINT TRIGGER journal_line_balance
+    AFTER INSERT OR UPDATE OR DELETE ON journal_line
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION check_journal_balance();
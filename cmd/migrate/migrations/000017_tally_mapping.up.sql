@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS "tally_mapping" (
+    buss_id UUID PRIMARY KEY REFERENCES business(buss_id) ON DELETE CASCADE,
+    sales_ledger VARCHAR(100) NOT NULL,
+    cgst_ledger VARCHAR(100) NOT NULL,
+    sgst_ledger VARCHAR(100) NOT NULL,
+    igst_ledger VARCHAR(100) NOT NULL,
+    receipt_ledger VARCHAR(100) NOT NULL,
+    party_group VARCHAR(100) NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
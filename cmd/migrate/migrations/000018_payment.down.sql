@@ -0,0 +1,8 @@
+UPDATE journal_entry e
+SET inv_id = p.inv_id
+FROM payment p
+WHERE e.source_type = 'receipt' AND e.payment_id = p.id;
+
+ALTER TABLE journal_entry DROP COLUMN IF EXISTS payment_id;
+
+DROP TABLE IF EXISTS "payment" CASCADE;
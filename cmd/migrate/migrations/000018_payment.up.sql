@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS "payment" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    inv_id UUID NOT NULL REFERENCES invoice(id) ON DELETE CASCADE,
+    amount NUMERIC(12, 2) NOT NULL CHECK (amount > 0),
+    paid_on DATE NOT NULL,
+    method VARCHAR(20) NOT NULL DEFAULT '',
+    reference VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS payment_inv_id_idx ON payment (inv_id);
+CREATE INDEX IF NOT EXISTS payment_buss_id_paid_on_idx ON payment (buss_id, paid_on);
+
+-- Invoices already marked paid were paid in full on their paid date.
+INSERT INTO payment (buss_id, inv_id, amount, paid_on)
+SELECT buss_id, id, total_amount, COALESCE(paid_date, inv_date)::date
+FROM invoice
+WHERE is_paid AND total_amount > 0;
+
+ALTER TABLE journal_entry
+    ADD COLUMN IF NOT EXISTS payment_id UUID REFERENCES payment(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS journal_entry_payment_id_idx ON journal_entry (payment_id);
+
+-- Receipts were posted per invoice; move them onto the backfilled payments.
+UPDATE journal_entry e
+SET payment_id = p.id,
+    inv_id = NULL
+FROM payment p
+WHERE e.source_type = 'receipt' AND p.inv_id = e.inv_id;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "bank_transaction" CASCADE;
+DROP TABLE IF EXISTS "bank_statement" CASCADE;
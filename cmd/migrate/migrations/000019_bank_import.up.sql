@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS "bank_statement" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    format VARCHAR(10) NOT NULL CHECK (format IN ('csv', 'ofx')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS "bank_transaction" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    statement_id UUID NOT NULL REFERENCES bank_statement(id) ON DELETE CASCADE,
+    txn_date DATE NOT NULL,
+    amount NUMERIC(12, 2) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    reference VARCHAR(100) NOT NULL DEFAULT '',
+    fit_id VARCHAR(100) NOT NULL,
+    payment_id UUID REFERENCES payment(id) ON DELETE SET NULL,
+    ignored BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (buss_id, fit_id)
+);
+
+CREATE INDEX IF NOT EXISTS bank_transaction_buss_id_txn_date_idx ON bank_transaction (buss_id, txn_date);
+CREATE INDEX IF NOT EXISTS bank_transaction_payment_id_idx ON bank_transaction (payment_id);
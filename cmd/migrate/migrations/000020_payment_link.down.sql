@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS payment_gateway_payment_id_idx;
+
+ALTER TABLE payment DROP COLUMN IF EXISTS gateway_payment_id;
+
+DROP TABLE IF EXISTS "payment_link" CASCADE;
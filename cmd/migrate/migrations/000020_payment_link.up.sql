@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS "payment_link" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    inv_id UUID NOT NULL REFERENCES invoice(id) ON DELETE CASCADE,
+    provider VARCHAR(20) NOT NULL,
+    provider_link_id VARCHAR(100) NOT NULL,
+    url TEXT NOT NULL,
+    amount NUMERIC(12, 2) NOT NULL CHECK (amount > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'created' CHECK (status IN ('created', 'paid')),
+    paid_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (provider, provider_link_id)
+);
+
+CREATE INDEX IF NOT EXISTS payment_link_inv_id_idx ON payment_link (inv_id);
+
+-- The provider's payment ID makes recording a webhook idempotent.
+ALTER TABLE payment
+    ADD COLUMN IF NOT EXISTS gateway_payment_id VARCHAR(100);
+
+CREATE UNIQUE INDEX IF NOT EXISTS payment_gateway_payment_id_idx ON payment (gateway_payment_id);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS "email_delivery" CASCADE;
+DROP TABLE IF EXISTS "email_template" CASCADE;
+
+ALTER TABLE invoice
+    DROP COLUMN IF EXISTS sent_at,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,27 @@
+ALTER TABLE invoice
+    ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'sent')),
+    ADD COLUMN IF NOT EXISTS sent_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS "email_template" (
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    kind VARCHAR(30) NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (buss_id, kind)
+);
+
+CREATE TABLE IF NOT EXISTS "email_delivery" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    inv_id UUID REFERENCES invoice(id) ON DELETE CASCADE,
+    kind VARCHAR(30) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    cc TEXT[] NOT NULL DEFAULT '{}',
+    subject TEXT NOT NULL,
+    status VARCHAR(10) NOT NULL CHECK (status IN ('sent', 'failed')),
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS email_delivery_inv_id_idx ON email_delivery (inv_id);
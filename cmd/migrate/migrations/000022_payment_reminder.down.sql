@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "reminder_log";
+DROP TABLE IF EXISTS "reminder_rule";
+
+ALTER TABLE customer
+    DROP COLUMN IF EXISTS reminders_opt_out;
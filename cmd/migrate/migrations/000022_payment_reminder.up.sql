@@ -0,0 +1,28 @@
+ALTER TABLE customer
+    ADD COLUMN IF NOT EXISTS reminders_opt_out BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- A rule fires offset_days after the due date (negative for before it). With
+-- repeat_days set it fires again every repeat_days until the invoice is paid.
+CREATE TABLE IF NOT EXISTS "reminder_rule" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    offset_days INT NOT NULL CHECK (offset_days BETWEEN -365 AND 365),
+    repeat_days INT CHECK (repeat_days BETWEEN 1 AND 365),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE NULLS NOT DISTINCT (buss_id, offset_days, repeat_days)
+);
+
+-- One row per rule, invoice and occurrence, claimed before the email is
+-- sent so that no reminder goes out twice.
+CREATE TABLE IF NOT EXISTS "reminder_log" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    rule_id UUID NOT NULL REFERENCES reminder_rule(id) ON DELETE CASCADE,
+    inv_id UUID NOT NULL REFERENCES invoice(id) ON DELETE CASCADE,
+    occurrence INT NOT NULL,
+    delivery_id UUID REFERENCES email_delivery(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (rule_id, inv_id, occurrence)
+);
+
+CREATE INDEX IF NOT EXISTS reminder_log_inv_id_idx ON reminder_log (inv_id);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "late_fee_charge";
+
+ALTER TABLE invoice
+    DROP COLUMN IF EXISTS ref_inv_id,
+    DROP COLUMN IF EXISTS kind;
+
+DROP TABLE IF EXISTS "late_fee_policy";
@@ -0,0 +1,33 @@
+-- A business wide policy has no cust_id; a customer's own policy overrides
+-- it.
+CREATE TABLE IF NOT EXISTS "late_fee_policy" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    cust_id UUID REFERENCES customer(id) ON DELETE CASCADE,
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('flat', 'interest')),
+    flat_amount NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (flat_amount >= 0),
+    annual_rate NUMERIC(5, 2) NOT NULL DEFAULT 0 CHECK (annual_rate >= 0),
+    grace_days INT NOT NULL DEFAULT 0 CHECK (grace_days >= 0),
+    cap NUMERIC(10, 2) CHECK (cap >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE NULLS NOT DISTINCT (buss_id, cust_id)
+);
+
+-- Late fees are issued either as an ordinary invoice or as a debit note
+-- against the overdue invoice; both are invoice rows so that they can be
+-- paid, aged and posted like any other.
+ALTER TABLE invoice
+    ADD COLUMN IF NOT EXISTS kind VARCHAR(20) NOT NULL DEFAULT 'invoice' CHECK (kind IN ('invoice', 'debit_note')),
+    ADD COLUMN IF NOT EXISTS ref_inv_id UUID REFERENCES invoice(id) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS "late_fee_charge" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    inv_id UUID NOT NULL REFERENCES invoice(id) ON DELETE CASCADE,
+    charge_inv_id UUID NOT NULL UNIQUE REFERENCES invoice(id) ON DELETE CASCADE,
+    amount NUMERIC(10, 2) NOT NULL CHECK (amount > 0),
+    accrued_to DATE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS late_fee_charge_inv_id_idx ON late_fee_charge (inv_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "invoice_share_view" CASCADE;
+DROP TABLE IF EXISTS "invoice_share" CASCADE;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS "invoice_share" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    inv_id UUID NOT NULL REFERENCES invoice(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS invoice_share_inv_id_idx ON invoice_share (inv_id);
+
+CREATE TABLE IF NOT EXISTS "invoice_share_view" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    share_id UUID NOT NULL REFERENCES invoice_share(id) ON DELETE CASCADE,
+    format VARCHAR(10) NOT NULL CHECK (format IN ('view', 'pdf')),
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    viewed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS invoice_share_view_share_id_idx ON invoice_share_view (share_id, viewed_at);
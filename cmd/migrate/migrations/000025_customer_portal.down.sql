@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "portal_login" CASCADE;
+DROP TABLE IF EXISTS "portal_account" CASCADE;
+
+DROP INDEX IF EXISTS customer_email_idx;
@@ -0,0 +1,16 @@
+CREATE INDEX IF NOT EXISTS customer_email_idx ON customer (email);
+
+CREATE TABLE IF NOT EXISTS "portal_account" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email citext UNIQUE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_login_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS "portal_login" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email citext NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "business_invitation" CASCADE;
+DROP TABLE IF EXISTS "business_member" CASCADE;
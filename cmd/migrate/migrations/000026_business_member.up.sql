@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS "business_member" (
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'admin', 'accountant', 'sales', 'viewer')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (buss_id, user_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS business_member_owner_idx ON business_member (buss_id) WHERE role = 'owner';
+CREATE INDEX IF NOT EXISTS business_member_user_id_idx ON business_member (user_id);
+
+INSERT INTO business_member (buss_id, user_id, role)
+SELECT buss_id, user_id, 'owner'
+FROM business
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS "business_invitation" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    email citext NOT NULL,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('admin', 'accountant', 'sales', 'viewer')),
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined', 'revoked')),
+    expires_at TIMESTAMPTZ NOT NULL,
+    responded_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS business_invitation_pending_idx ON business_invitation (buss_id, email) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS business_invitation_email_idx ON business_invitation (email);
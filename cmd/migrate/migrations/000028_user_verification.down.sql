@@ -0,0 +1,8 @@
+UPDATE user_session SET revoked_reason = 'revoked' WHERE revoked_reason = 'password_reset';
+ALTER TABLE user_session DROP CONSTRAINT IF EXISTS user_session_revoked_reason_check;
+ALTER TABLE user_session ADD CONSTRAINT user_session_revoked_reason_check
+    CHECK (revoked_reason IN ('logout', 'revoked', 'reuse'));
+
+DROP TABLE IF EXISTS "user_token" CASCADE;
+
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
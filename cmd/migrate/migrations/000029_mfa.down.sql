@@ -0,0 +1,5 @@
+ALTER TABLE business DROP COLUMN IF EXISTS require_mfa;
+
+DROP TABLE IF EXISTS "mfa_challenge" CASCADE;
+DROP TABLE IF EXISTS "mfa_recovery_code" CASCADE;
+DROP TABLE IF EXISTS "user_mfa" CASCADE;
@@ -0,0 +1,11 @@
+ALTER TABLE product
+    DROP CONSTRAINT IF EXISTS product_category_id_fkey,
+    ADD CONSTRAINT product_category_id_fkey FOREIGN KEY (category_id)
+        REFERENCES category(id) ON DELETE SET NULL;
+
+ALTER TABLE category
+    DROP CONSTRAINT IF EXISTS category_parent_id_fkey,
+    ADD CONSTRAINT category_parent_id_fkey FOREIGN KEY (parent_id)
+        REFERENCES category(id) ON DELETE CASCADE;
+
+ALTER TABLE category DROP CONSTRAINT IF EXISTS category_id_buss_id_key;
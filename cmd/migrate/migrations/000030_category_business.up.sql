@@ -0,0 +1,26 @@
+-- Categories and products may only point at categories of their own
+-- business. Links that already cross businesses are cut first: such a
+-- category becomes a top-level one, its name tagged so it cannot clash with
+-- one already there.
+UPDATE category c
+SET parent_id = NULL,
+    name = left(c.name, 244) || ' (' || left(c.id::text, 8) || ')'
+FROM category p
+WHERE c.parent_id = p.id AND c.buss_id <> p.buss_id;
+
+UPDATE product pr
+SET category_id = NULL
+FROM category c
+WHERE pr.category_id = c.id AND pr.buss_id <> c.buss_id;
+
+ALTER TABLE category ADD CONSTRAINT category_id_buss_id_key UNIQUE (id, buss_id);
+
+ALTER TABLE category
+    DROP CONSTRAINT IF EXISTS category_parent_id_fkey,
+    ADD CONSTRAINT category_parent_id_fkey FOREIGN KEY (parent_id, buss_id)
+        REFERENCES category(id, buss_id) ON DELETE CASCADE;
+
+ALTER TABLE product
+    DROP CONSTRAINT IF EXISTS product_category_id_fkey,
+    ADD CONSTRAINT product_category_id_fkey FOREIGN KEY (category_id, buss_id)
+        REFERENCES category(id, buss_id) ON DELETE SET NULL (category_id);
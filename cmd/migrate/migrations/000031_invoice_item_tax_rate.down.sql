@@ -0,0 +1 @@
+ALTER TABLE invoice_item DROP COLUMN IF EXISTS tax_rate;
@@ -0,0 +1,10 @@
+-- Invoice lines keep the rate they were billed at, so that later changes
+-- to a product's rate do not rewrite tax already charged.
+ALTER TABLE invoice_item ADD COLUMN IF NOT EXISTS tax_rate NUMERIC(5, 2);
+
+UPDATE invoice_item ii
+SET tax_rate = p.tax_rate
+FROM product p
+WHERE p.id = ii.prod_id AND ii.tax_rate IS NULL;
+
+ALTER TABLE invoice_item ALTER COLUMN tax_rate SET NOT NULL;
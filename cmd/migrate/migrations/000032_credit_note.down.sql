@@ -0,0 +1,15 @@
+DELETE FROM journal_entry WHERE source_type = 'credit_note';
+
+ALTER TABLE journal_entry
+    DROP COLUMN IF EXISTS credit_note_id,
+    DROP CONSTRAINT IF EXISTS journal_entry_source_type_check,
+    ADD CONSTRAINT journal_entry_source_type_check
+        CHECK (source_type IN ('invoice', 'receipt', 'expense', 'purchase_bill', 'bill_payment'));
+
+ALTER TABLE stock_movement DROP COLUMN IF EXISTS credit_note_id;
+
+DELETE FROM payment WHERE credit_note_id IS NOT NULL;
+ALTER TABLE payment DROP COLUMN IF EXISTS credit_note_id;
+
+DROP TABLE IF EXISTS "credit_note_item";
+DROP TABLE IF EXISTS "credit_note";
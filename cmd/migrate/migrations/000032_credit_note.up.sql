@@ -0,0 +1,47 @@
+-- A credit note reduces what a customer owes on one of their invoices, for
+-- goods returned or billed in error. It is applied to the invoice as a
+-- payment linked to the note, so everything that works out what is due on
+-- an invoice takes it into account.
+CREATE TABLE IF NOT EXISTS "credit_note" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    buss_id UUID NOT NULL REFERENCES business(buss_id) ON DELETE CASCADE,
+    inv_id UUID NOT NULL REFERENCES invoice(id) ON DELETE CASCADE,
+    cn_no INT NOT NULL,
+    cn_date DATE NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    restock BOOLEAN NOT NULL DEFAULT FALSE,
+    total_amount NUMERIC(10, 2) NOT NULL CHECK (total_amount > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (buss_id, cn_no)
+);
+
+CREATE INDEX IF NOT EXISTS credit_note_inv_id_idx ON credit_note (inv_id);
+
+CREATE TABLE IF NOT EXISTS "credit_note_item" (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    cn_id UUID NOT NULL REFERENCES credit_note(id) ON DELETE CASCADE,
+    prod_id UUID NOT NULL REFERENCES product(id) ON DELETE CASCADE,
+    variant_id UUID,
+    quantity NUMERIC(12, 3) NOT NULL CHECK (quantity > 0),
+    unit_price NUMERIC(10, 2) NOT NULL CHECK (unit_price >= 0),
+    tax_rate NUMERIC(5, 2) NOT NULL,
+    FOREIGN KEY (variant_id, prod_id) REFERENCES product_variant(id, prod_id) ON DELETE SET NULL (variant_id)
+);
+
+CREATE INDEX IF NOT EXISTS credit_note_item_cn_id_idx ON credit_note_item (cn_id);
+
+ALTER TABLE payment
+    ADD COLUMN IF NOT EXISTS credit_note_id UUID UNIQUE REFERENCES credit_note(id) ON DELETE CASCADE;
+
+ALTER TABLE stock_movement
+    ADD COLUMN IF NOT EXISTS credit_note_id UUID REFERENCES credit_note(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS stock_movement_credit_note_id_idx ON stock_movement (credit_note_id);
+
+ALTER TABLE journal_entry
+    ADD COLUMN IF NOT EXISTS credit_note_id UUID REFERENCES credit_note(id) ON DELETE CASCADE,
+    DROP CONSTRAINT IF EXISTS journal_entry_source_type_check,
+    ADD CONSTRAINT journal_entry_source_type_check
+        CHECK (source_type IN ('invoice', 'receipt', 'expense', 'purchase_bill', 'bill_payment', 'credit_note'));
+
+CREATE INDEX IF NOT EXISTS journal_entry_credit_note_id_idx ON journal_entry (credit_note_id);
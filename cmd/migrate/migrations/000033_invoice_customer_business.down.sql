@@ -0,0 +1,6 @@
+ALTER TABLE invoice
+    DROP CONSTRAINT IF EXISTS invoice_cust_id_fkey,
+    ADD CONSTRAINT invoice_cust_id_fkey FOREIGN KEY (cust_id)
+        REFERENCES customer(id) ON DELETE CASCADE;
+
+ALTER TABLE customer DROP CONSTRAINT IF EXISTS customer_id_buss_id_key;
@@ -0,0 +1,11 @@
+-- An invoice may only bill a customer of its own business. Unlike category
+-- links, a cross-business invoice cannot be cut loose without losing its
+-- payments and postings, so rows already there are left for review: the
+-- constraint is added NOT VALID, which holds every new or re-pointed invoice
+-- to it without checking existing ones.
+ALTER TABLE customer ADD CONSTRAINT customer_id_buss_id_key UNIQUE (id, buss_id);
+
+ALTER TABLE invoice
+    DROP CONSTRAINT IF EXISTS invoice_cust_id_fkey,
+    ADD CONSTRAINT invoice_cust_id_fkey FOREIGN KEY (cust_id, buss_id)
+        REFERENCES customer(id, buss_id) ON DELETE CASCADE NOT VALID;
@@ -1,16 +1,43 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
 type Authenticator interface {
-	GenerateAccessToken(userID uuid.UUID) (string, error)
+	GenerateAccessToken(userID, sessionID uuid.UUID) (string, error)
 	ValidateAccessToken(token string) (*jwt.Token, error)
-	GenerateRefreshToken(userID uuid.UUID) (string, error)
-	ValidateRefreshToken(token string) (*jwt.Token, error)
-	RefreshToken(token string) (string, error)
+	GenerateRefreshToken(userID, sessionID, tokenID uuid.UUID) (string, error)
+	ValidateRefreshToken(token string) (*RefreshClaims, error)
+}
+
+// RefreshClaims identify a refresh token: the user it was issued to, the
+// session it belongs to and the token itself (its JTI).
+type RefreshClaims struct {
+	UserID    uuid.UUID
+	SessionID uuid.UUID
+	TokenID   uuid.UUID
+}
+
+// RandomToken returns an unguessable, URL-safe token of 256 bits.
+func RandomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the SHA-256 hash of a token for storing in place of the
+// token itself.
+func HashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
 }
 
 type OAuthConfigReader interface {
@@ -28,7 +28,10 @@ func NewJWTAuthenticator(accSecret, refSecret, iss, aud string, accExp, refExp t
 	}
 }
 
-func (a *JWTAuthenticator) GenerateAccessToken(userID uuid.UUID) (string, error) {
+// GenerateAccessToken issues an access token for userID. A token issued
+// for a session names it in the sid claim, so it stops working when the
+// session is revoked; pass uuid.Nil for tokens without one.
+func (a *JWTAuthenticator) GenerateAccessToken(userID, sessionID uuid.UUID) (string, error) {
 	claims := jwt.MapClaims{
 		"sub": userID,
 		"exp": time.Now().Add(a.accExp).Unix(),
@@ -37,6 +40,9 @@ func (a *JWTAuthenticator) GenerateAccessToken(userID uuid.UUID) (string, error)
 		"iss": a.iss,
 		"aud": a.aud,
 	}
+	if sessionID != uuid.Nil {
+		claims["sid"] = sessionID
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString([]byte(a.accSecret))
@@ -60,9 +66,11 @@ func (a *JWTAuthenticator) ValidateAccessToken(token string) (*jwt.Token, error)
 	)
 }
 
-func (a *JWTAuthenticator) GenerateRefreshToken(userID uuid.UUID) (string, error) {
+func (a *JWTAuthenticator) GenerateRefreshToken(userID, sessionID, tokenID uuid.UUID) (string, error) {
 	claims := jwt.MapClaims{
 		"sub": userID,
+		"sid": sessionID,
+		"jti": tokenID,
 		"exp": time.Now().Add(a.refExp).Unix(),
 		"iat": time.Now().Unix(),
 		"nbf": time.Now().Unix(),
@@ -78,13 +86,14 @@ func (a *JWTAuthenticator) GenerateRefreshToken(userID uuid.UUID) (string, error
 	return tokenString, nil
 }
 
-func (a *JWTAuthenticator) ValidateRefreshToken(token string) (*jwt.Token, error) {
+func (a *JWTAuthenticator) ValidateRefreshToken(token string) (*RefreshClaims, error) {
 	parsedToken, err := jwt.Parse(token, func(t *jwt.Token) (any, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
 		}
 		return []byte(a.refSecret), nil
 	},
+		jwt.WithExpirationRequired(),
 		jwt.WithAudience(a.aud),
 		jwt.WithIssuer(a.iss),
 		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}),
@@ -94,26 +103,22 @@ func (a *JWTAuthenticator) ValidateRefreshToken(token string) (*jwt.Token, error
 		return nil, fmt.Errorf("invalid refresh token")
 	}
 
-	return parsedToken, nil
-}
-
-func (a *JWTAuthenticator) RefreshToken(refreshTokenString string) (string, error) {
-	token, err := a.ValidateRefreshToken(refreshTokenString)
-	if err != nil {
-		return "", err
-	}
-
-	claims, ok := token.Claims.(jwt.MapClaims)
+	claims, ok := parsedToken.Claims.(jwt.MapClaims)
 	if !ok {
-		return "", fmt.Errorf("unexpected claims type")
+		return nil, fmt.Errorf("unexpected claims type")
 	}
 
-	claims["exp"] = time.Now().Add(a.accExp).Unix()
-
-	newToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := newToken.SignedString([]byte(a.accSecret))
-	if err != nil {
-		return "", err
+	var refresh RefreshClaims
+	for name, id := range map[string]*uuid.UUID{
+		"sub": &refresh.UserID,
+		"sid": &refresh.SessionID,
+		"jti": &refresh.TokenID,
+	} {
+		value, _ := claims[name].(string)
+		if *id, err = uuid.Parse(value); err != nil {
+			return nil, fmt.Errorf("invalid refresh token")
+		}
 	}
-	return tokenString, nil
-}
\ No newline at end of file
+
+	return &refresh, nil
+}
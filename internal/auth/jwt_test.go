@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestAccessTokenSession(t *testing.T) {
+	authenticator := NewJWTAuthenticator("acc", "ref", "billify", "billify", time.Hour, time.Hour)
+	userID, sessionID := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name      string
+		sessionID uuid.UUID
+		wantSID   any
+	}{
+		{"with session", sessionID, sessionID.String()},
+		{"without session", uuid.Nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := authenticator.GenerateAccessToken(userID, tt.sessionID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := authenticator.ValidateAccessToken(token)
+			if err != nil {
+				t.Fatalf("ValidateAccessToken: %v", err)
+			}
+			claims := parsed.Claims.(jwt.MapClaims)
+			if claims["sub"] != userID.String() {
+				t.Errorf("sub = %v, want %v", claims["sub"], userID)
+			}
+			if claims["sid"] != tt.wantSID {
+				t.Errorf("sid = %v, want %v", claims["sid"], tt.wantSID)
+			}
+		})
+	}
+}
+
+func TestAccessTokenRejected(t *testing.T) {
+	authenticator := NewJWTAuthenticator("acc", "ref", "billify", "billify", time.Hour, time.Hour)
+	userID := uuid.New()
+
+	token := func(a *JWTAuthenticator) string {
+		t.Helper()
+		s, err := a.GenerateAccessToken(userID, uuid.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"other secret", token(NewJWTAuthenticator("other", "ref", "billify", "billify", time.Hour, time.Hour))},
+		{"other audience", token(NewJWTAuthenticator("acc", "ref", "billify", "portal", time.Hour, time.Hour))},
+		{"other issuer", token(NewJWTAuthenticator("acc", "ref", "someone", "billify", time.Hour, time.Hour))},
+		{"expired", token(NewJWTAuthenticator("acc", "ref", "billify", "billify", -time.Minute, time.Hour))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := authenticator.ValidateAccessToken(tt.token); err == nil {
+				t.Error("ValidateAccessToken succeeded")
+			}
+		})
+	}
+}
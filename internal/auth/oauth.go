@@ -18,13 +18,14 @@ type OAuthAuthenticator struct {
 }
 
 type googleUser struct {
-	ID        string `json:"id"`
-	Email     string `json:"email"`
-	Name      string `json:"name"`
-	FirstName string `json:"given_name"`
-	LastName  string `json:"family_name"`
-	Link      string `json:"link"`
-	Picture   string `json:"picture"`
+	ID            string `json:"id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	FirstName     string `json:"given_name"`
+	LastName      string `json:"family_name"`
+	Link          string `json:"link"`
+	Picture       string `json:"picture"`
 }
 
 func NewOAuthAuthenticator(configs map[string]OAuthConfigReader) OAuthAuthenticator {
@@ -84,9 +85,10 @@ func (o *OAuthAuthenticator) GetUserInfo(provider, token string) (*store.User, e
 	}
 
 	userInfo := store.User{
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Email:     user.Email,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
 	}
 
 	return &userInfo, nil
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Purposes a signed token can be issued for. A token only verifies for the
+// purpose it was issued for.
+const (
+	PurposeInvoiceShare = "invoice-share"
+	PurposePortalLogin  = "portal-login"
+	PurposeMFAChallenge = "mfa-challenge"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token has expired")
+)
+
+// SignedTokens issues compact, URL-safe tokens that name a record and an
+// expiry and carry an HMAC-SHA256 signature, so a forged or tampered token
+// is turned away without a database lookup. Revocation is up to the record
+// the token names.
+type SignedTokens struct {
+	secret []byte
+}
+
+func NewSignedTokens(secret string) *SignedTokens {
+	return &SignedTokens{secret: []byte(secret)}
+}
+
+// Issue returns a token for id of purpose that is valid until expires.
+func (s *SignedTokens) Issue(purpose string, id uuid.UUID, expires time.Time) string {
+	payload := make([]byte, 24)
+	copy(payload, id[:])
+	binary.BigEndian.PutUint64(payload[16:], uint64(expires.Unix()))
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.sign(purpose, encoded))
+}
+
+// Verify checks a token of purpose and returns the id it was issued for.
+// The signature is checked before the expiry, so ErrExpiredToken is only
+// returned for tokens that were genuinely issued. Without a secret every
+// token is refused.
+func (s *SignedTokens) Verify(purpose, token string, now time.Time) (uuid.UUID, error) {
+	if len(s.secret) == 0 {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.sign(purpose, encoded)) {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(payload) != 24 {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	expires := time.Unix(int64(binary.BigEndian.Uint64(payload[16:])), 0)
+	if !now.Before(expires) {
+		return uuid.Nil, ErrExpiredToken
+	}
+
+	id, err := uuid.FromBytes(payload[:16])
+	if err != nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	return id, nil
+}
+
+func (s *SignedTokens) sign(purpose, encoded string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{0})
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSignedTokensVerify(t *testing.T) {
+	tokens := NewSignedTokens("link-secret")
+	id := uuid.New()
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	expires := now.Add(time.Hour)
+	token := tokens.Issue(PurposeInvoiceShare, id, expires)
+
+	encoded, signature, _ := strings.Cut(token, ".")
+	payload, _ := base64.RawURLEncoding.DecodeString(encoded)
+	payload[0] ^= 1
+	otherID := base64.RawURLEncoding.EncodeToString(payload) + "." + signature
+
+	tests := []struct {
+		name    string
+		tokens  *SignedTokens
+		purpose string
+		token   string
+		now     time.Time
+		wantErr error
+	}{
+		{"valid", tokens, PurposeInvoiceShare, token, now, nil},
+		{"valid until the last second", tokens, PurposeInvoiceShare, token, expires.Add(-time.Second), nil},
+		{"expired at expiry", tokens, PurposeInvoiceShare, token, expires, ErrExpiredToken},
+		{"expired after expiry", tokens, PurposeInvoiceShare, token, expires.Add(time.Hour), ErrExpiredToken},
+		{"other purpose", tokens, PurposePortalLogin, token, now, ErrInvalidToken},
+		{"other secret", NewSignedTokens("other-secret"), PurposeInvoiceShare, token, now, ErrInvalidToken},
+		{"empty secret", NewSignedTokens(""), PurposeInvoiceShare, token, now, ErrInvalidToken},
+		{"payload changed", tokens, PurposeInvoiceShare, otherID, now, ErrInvalidToken},
+		{"signature changed", tokens, PurposeInvoiceShare, encoded + "." + strings.Repeat("A", len(signature)), now, ErrInvalidToken},
+		{"signature missing", tokens, PurposeInvoiceShare, encoded, now, ErrInvalidToken},
+		{"signature not base64", tokens, PurposeInvoiceShare, encoded + ".!!", now, ErrInvalidToken},
+		{"empty", tokens, PurposeInvoiceShare, "", now, ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.tokens.Verify(tt.purpose, tt.token, tt.now)
+			if err != tt.wantErr {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got != id {
+				t.Errorf("id = %v, want %v", got, id)
+			}
+		})
+	}
+}
+
+func TestSignedTokensEmptySecretCannotForge(t *testing.T) {
+	// A token signed with an empty key must not pass a server that was
+	// started without one either.
+	tokens := NewSignedTokens("")
+	token := tokens.Issue(PurposeMFAChallenge, uuid.New(), time.Now().Add(time.Hour))
+
+	if _, err := tokens.Verify(PurposeMFAChallenge, token, time.Now()); err != ErrInvalidToken {
+		t.Errorf("err = %v, want %v", err, ErrInvalidToken)
+	}
+}
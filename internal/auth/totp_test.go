@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 4226 and RFC 6238 test vectors.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestTOTPCodeRFC4226(t *testing.T) {
+	// RFC 4226 appendix D, HOTP values for counters 0 to 9.
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+
+	for counter, code := range want {
+		if got := TOTPCode(rfcSecret, int64(counter)); got != code {
+			t.Errorf("TOTPCode(counter %d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// RFC 6238 appendix B, SHA-1 rows, keeping the last six of the eight
+	// digits given there.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		if got := TOTPCode(rfcSecret, TOTPStep(time.Unix(tt.unix, 0))); got != tt.want {
+			t.Errorf("code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := TOTPStep(now)
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", TOTPCode(rfcSecret, step), step, true},
+		{"previous step", TOTPCode(rfcSecret, step-1), step - 1, true},
+		{"next step", TOTPCode(rfcSecret, step+1), step + 1, true},
+		{"two steps back", TOTPCode(rfcSecret, step-2), 0, false},
+		{"two steps ahead", TOTPCode(rfcSecret, step+2), 0, false},
+		{"surrounding spaces", " " + TOTPCode(rfcSecret, step) + "\n", step, true},
+		{"too short", TOTPCode(rfcSecret, step)[:5], 0, false},
+		{"too long", TOTPCode(rfcSecret, step) + "0", 0, false},
+		{"empty", "", 0, false},
+		{"wrong code", "000000", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := ValidateTOTP(rfcSecret, tt.code, now)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("ValidateTOTP = %d, %v, want %d, %v", gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+
+	if _, ok := ValidateTOTP([]byte("another secret of 20b"), TOTPCode(rfcSecret, step), now); ok {
+		t.Error("code accepted for another secret")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("Billify", "ravi@example.com", rfcSecret)
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" {
+		t.Errorf("URI = %s, want otpauth://totp/", uri)
+	}
+	if label, _ := url.PathUnescape(parsed.EscapedPath()); label != "/Billify:ravi@example.com" {
+		t.Errorf("label = %q", label)
+	}
+
+	query := parsed.Query()
+	want := map[string]string{
+		"secret":    "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		"issuer":    "Billify",
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	}
+	for key, value := range want {
+		if query.Get(key) != value {
+			t.Errorf("%s = %q, want %q", key, query.Get(key), value)
+		}
+	}
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	a, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(a) != 20 {
+		t.Errorf("secret is %d bytes, want 20", len(a))
+	}
+	if string(a) == string(b) {
+		t.Error("two secrets are the same")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	format := regexp.MustCompile(`^[A-Z2-7]{4}-[A-Z2-7]{4}-[A-Z2-7]{4}-[A-Z2-7]{4}$`)
+
+	seen := map[string]bool{}
+	for range 50 {
+		code, err := NewRecoveryCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !format.MatchString(code) {
+			t.Fatalf("recovery code %q is not formatted XXXX-XXXX-XXXX-XXXX", code)
+		}
+		if seen[code] {
+			t.Fatalf("recovery code %q generated twice", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []string{
+		"K7QX-M2PA-9DWT-L4RE",
+		"k7qx-m2pa-9dwt-l4re",
+		"K7QX M2PA 9DWT L4RE",
+		"k7qxm2pa9dwtl4re",
+		" K7QX-M2PA-9DWT-L4RE ",
+	}
+
+	for _, code := range tests {
+		if got := NormalizeRecoveryCode(code); got != "K7QXM2PA9DWTL4RE" {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want K7QXM2PA9DWTL4RE", code, got)
+		}
+	}
+}
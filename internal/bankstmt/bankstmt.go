@@ -0,0 +1,187 @@
+// Package bankstmt reads bank statements and suggests which invoices their
+// credits pay, so that reconciling receipts does not mean reading every line
+// of a statement by hand.
+package bankstmt
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Transaction is one line of a statement. Credits are positive and debits
+// negative. FitID identifies the line across uploads so a statement can be
+// imported twice without duplicating it.
+type Transaction struct {
+	Date        time.Time
+	Amount      float64
+	Description string
+	Reference   string
+	FitID       string
+}
+
+var (
+	ErrNoTransactions = errors.New("no transactions found in statement")
+
+	ofxTransaction = regexp.MustCompile(`(?is)<STMTTRN>(.*?)</STMTTRN>`)
+	ofxField       = regexp.MustCompile(`(?i)<([A-Z0-9.]+)>([^<\r\n]*)`)
+)
+
+// ParseOFX reads the transactions of an OFX or QFX file. Both the SGML form
+// of OFX 1.x, where leaf elements are not closed, and the XML form of OFX 2.x
+// are accepted.
+func ParseOFX(r io.Reader) ([]Transaction, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := ofxTransaction.FindAllSubmatch(data, -1)
+	if len(blocks) == 0 {
+		return nil, ErrNoTransactions
+	}
+
+	transactions := make([]Transaction, 0, len(blocks))
+	for i, block := range blocks {
+		fields := map[string]string{}
+		for _, match := range ofxField.FindAllSubmatch(block[1], -1) {
+			fields[strings.ToUpper(string(match[1]))] = html.UnescapeString(strings.TrimSpace(string(match[2])))
+		}
+
+		posted := fields["DTPOSTED"]
+		if len(posted) < 8 {
+			return nil, fmt.Errorf("transaction %d: missing DTPOSTED", i+1)
+		}
+		date, err := time.Parse("20060102", posted[:8])
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: invalid DTPOSTED %q", i+1, posted)
+		}
+
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(fields["TRNAMT"], ",", ""), 64)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: invalid TRNAMT %q", i+1, fields["TRNAMT"])
+		}
+
+		description := fields["NAME"]
+		if memo := fields["MEMO"]; memo != "" && memo != description {
+			description = strings.TrimSpace(description + " " + memo)
+		}
+
+		reference := fields["CHECKNUM"]
+		if reference == "" {
+			reference = fields["REFNUM"]
+		}
+
+		fitID := ""
+		if id := fields["FITID"]; id != "" {
+			fitID = "ofx:" + id
+		}
+
+		transactions = append(transactions, Transaction{
+			Date:        date,
+			Amount:      amount,
+			Description: description,
+			Reference:   reference,
+			FitID:       fitID,
+		})
+	}
+
+	return transactions, nil
+}
+
+// dateLayouts are tried in order when a CSV statement does not say how its
+// dates are written. Day-first layouts come first, as Indian banks use them.
+var dateLayouts = []string{
+	"02/01/2006",
+	"02-01-2006",
+	"02.01.2006",
+	"02/01/06",
+	"02-01-06",
+	"02-Jan-2006",
+	"02 Jan 2006",
+	"02-Jan-06",
+	"02 Jan 06",
+	"2006-01-02",
+	"2006/01/02",
+}
+
+var formatTokens = strings.NewReplacer(
+	"YYYY", "2006",
+	"YY", "06",
+	"MMM", "Jan",
+	"MM", "01",
+	"DD", "02",
+)
+
+// ParseDate reads a statement date. format uses DD, MM, MMM, YY and YYYY,
+// for example "DD/MM/YYYY"; when it is empty the common layouts are tried.
+func ParseDate(value, format string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if format != "" {
+		return time.Parse(formatTokens.Replace(format), value)
+	}
+
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognised date %q", value)
+}
+
+// ParseAmount reads a statement amount, allowing thousands separators, a
+// rupee sign, parentheses for negatives and a trailing Cr or Dr.
+func ParseAmount(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	sign := 1.0
+
+	upper := strings.ToUpper(value)
+	switch {
+	case strings.HasSuffix(upper, "DR"):
+		sign = -1
+		value = value[:len(value)-2]
+	case strings.HasSuffix(upper, "CR"):
+		value = value[:len(value)-2]
+	}
+
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		sign = -sign
+		value = value[1 : len(value)-1]
+	}
+
+	value = strings.NewReplacer(",", "", "₹", "", "INR", "", " ", "").Replace(value)
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+
+	return sign * amount, nil
+}
+
+// AssignIDs gives transactions without a bank-issued FitID one derived from
+// their contents. Identical lines in the same statement are told apart by
+// how many came before them, so uploading the statement again still matches.
+func AssignIDs(transactions []Transaction) {
+	seen := map[string]int{}
+	for i := range transactions {
+		txn := &transactions[i]
+		if txn.FitID != "" {
+			continue
+		}
+
+		key := fmt.Sprintf("%s|%.2f|%s|%s", txn.Date.Format("2006-01-02"), txn.Amount, txn.Description, txn.Reference)
+		seen[key]++
+
+		sum := sha1.Sum([]byte(fmt.Sprintf("%s|%d", key, seen[key])))
+		txn.FitID = "csv:" + hex.EncodeToString(sum[:])
+	}
+}
@@ -0,0 +1,128 @@
+package bankstmt
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Invoice is an unpaid invoice a credit could be matched to.
+type Invoice struct {
+	ID       uuid.UUID
+	Number   int64
+	Customer string
+	Total    float64
+	Balance  float64
+}
+
+type Suggestion struct {
+	InvoiceID uuid.UUID
+	Score     int
+	Reasons   []string
+}
+
+const (
+	scoreBalance      = 50
+	scoreTotal        = 30
+	scoreInvoiceNo    = 40
+	scoreCustomer     = 30
+	scoreCustomerWord = 15
+)
+
+var (
+	digitRun = regexp.MustCompile(`[0-9]+`)
+	wordRun  = regexp.MustCompile(`[a-z0-9]+`)
+
+	// nameNoise is left out when looking for a customer's name, since
+	// company suffixes appear in most narrations.
+	nameNoise = map[string]bool{
+		"pvt": true, "private": true, "ltd": true, "limited": true, "llp": true,
+		"and": true, "the": true, "co": true, "company": true, "india": true,
+		"enterprises": true, "traders": true,
+	}
+)
+
+func words(value string) []string {
+	return wordRun.FindAllString(strings.ToLower(value), -1)
+}
+
+func paise(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// Suggest scores how likely the credit txn pays each invoice, by its amount
+// and by the invoice number and customer name appearing in the narration.
+// Invoices the credit would overpay are skipped. The best limit suggestions
+// are returned, highest score first.
+func Suggest(txn Transaction, invoices []Invoice, limit int) []Suggestion {
+	narration := strings.Join(words(txn.Description+" "+txn.Reference), " ")
+	numbers := map[string]bool{}
+	for _, run := range digitRun.FindAllString(txn.Description+" "+txn.Reference, -1) {
+		numbers[strings.TrimLeft(run, "0")] = true
+	}
+	narrationWords := map[string]bool{}
+	for _, word := range strings.Fields(narration) {
+		narrationWords[word] = true
+	}
+
+	amount := paise(txn.Amount)
+	suggestions := []Suggestion{}
+	for _, invoice := range invoices {
+		if amount <= 0 || amount > paise(invoice.Balance) {
+			continue
+		}
+
+		suggestion := Suggestion{InvoiceID: invoice.ID, Reasons: []string{}}
+		switch {
+		case amount == paise(invoice.Balance):
+			suggestion.Score += scoreBalance
+			suggestion.Reasons = append(suggestion.Reasons, "amount matches the balance due")
+		case amount == paise(invoice.Total):
+			suggestion.Score += scoreTotal
+			suggestion.Reasons = append(suggestion.Reasons, "amount matches the invoice total")
+		}
+
+		if numbers[strconv.FormatInt(invoice.Number, 10)] {
+			suggestion.Score += scoreInvoiceNo
+			suggestion.Reasons = append(suggestion.Reasons, "invoice number found in narration")
+		}
+
+		name := []string{}
+		for _, word := range words(invoice.Customer) {
+			if !nameNoise[word] {
+				name = append(name, word)
+			}
+		}
+		if len(name) > 0 {
+			if strings.Contains(" "+narration+" ", " "+strings.Join(name, " ")+" ") {
+				suggestion.Score += scoreCustomer
+				suggestion.Reasons = append(suggestion.Reasons, "customer name found in narration")
+			} else {
+				for _, word := range name {
+					if len(word) >= 4 && narrationWords[word] {
+						suggestion.Score += scoreCustomerWord
+						suggestion.Reasons = append(suggestion.Reasons, "part of customer name found in narration")
+						break
+					}
+				}
+			}
+		}
+
+		if suggestion.Score > 0 {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Score > suggestions[j].Score
+	})
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	return suggestions
+}
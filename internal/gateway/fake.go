@@ -0,0 +1,128 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeServer speaks enough of the Razorpay payment links API for a Razorpay
+// provider to be pointed at it, so payment links can be exercised without
+// network access or an account. Pay stands in for the customer paying and
+// returns the webhook Razorpay would send.
+type FakeServer struct {
+	webhookSecret string
+
+	mu    sync.Mutex
+	seq   int
+	links map[string]*fakeLink
+}
+
+type fakeLink struct {
+	razorpayLink
+	Amount      int64  `json:"amount"`
+	Currency    string `json:"currency"`
+	ReferenceID string `json:"reference_id"`
+}
+
+func NewFakeServer(webhookSecret string) *FakeServer {
+	return &FakeServer{
+		webhookSecret: webhookSecret,
+		links:         map[string]*fakeLink{},
+	}
+}
+
+func (s *FakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if key, _, ok := r.BasicAuth(); !ok || key == "" {
+		writeFakeError(w, http.StatusUnauthorized, "BAD_REQUEST_ERROR", "authentication failed")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/payment_links":
+		s.createLink(w, r)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/payment_links/"):
+		s.mu.Lock()
+		link, ok := s.links[strings.TrimPrefix(r.URL.Path, "/v1/payment_links/")]
+		s.mu.Unlock()
+		if !ok {
+			writeFakeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "The id provided does not exist")
+			return
+		}
+		writeFakeJSON(w, http.StatusOK, link)
+	default:
+		writeFakeError(w, http.StatusNotFound, "BAD_REQUEST_ERROR", "The requested URL was not found on the server.")
+	}
+}
+
+func (s *FakeServer) createLink(w http.ResponseWriter, r *http.Request) {
+	var req razorpayLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeFakeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "invalid request body")
+		return
+	}
+	if req.Amount < 100 {
+		writeFakeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "amount must be at least INR 1.00")
+		return
+	}
+
+	s.mu.Lock()
+	s.seq++
+	id := fmt.Sprintf("plink_fake%06d", s.seq)
+	link := &fakeLink{
+		razorpayLink: razorpayLink{ID: id, ShortURL: "https://rzp.io/i/" + id, Status: "created"},
+		Amount:       req.Amount,
+		Currency:     req.Currency,
+		ReferenceID:  req.ReferenceID,
+	}
+	s.links[id] = link
+	s.mu.Unlock()
+
+	writeFakeJSON(w, http.StatusOK, link)
+}
+
+// Pay marks a link paid in full by method and returns the signed
+// payment_link.paid webhook for it, ready to be posted to the webhook
+// endpoint with the signature in the X-Razorpay-Signature header.
+func (s *FakeServer) Pay(linkID, method string) ([]byte, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[linkID]
+	if !ok {
+		return nil, "", errors.New("fake gateway: unknown payment link")
+	}
+	link.Status = "paid"
+
+	var webhook razorpayWebhook
+	webhook.Event = razorpayLinkPaidEvent
+	webhook.Payload.PaymentLink.Entity = link.razorpayLink
+	webhook.Payload.Payment.Entity.ID = "pay_" + strings.TrimPrefix(linkID, "plink_")
+	webhook.Payload.Payment.Entity.Amount = link.Amount
+	webhook.Payload.Payment.Entity.Method = method
+	webhook.Payload.Payment.Entity.CreatedAt = time.Now().Unix()
+
+	body, err := json.Marshal(webhook)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return body, Sign(body, s.webhookSecret), nil
+}
+
+func writeFakeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeFakeError(w http.ResponseWriter, status int, code, description string) {
+	var body razorpayError
+	body.Error.Code = code
+	body.Error.Description = description
+	writeFakeJSON(w, status, body)
+}
@@ -0,0 +1,133 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testWebhookSecret = "whsec_test"
+
+func newFakeRazorpay(t *testing.T) (*FakeServer, *Razorpay) {
+	t.Helper()
+
+	fake := NewFakeServer(testWebhookSecret)
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return fake, NewRazorpay(server.URL, "rzp_test_key", "rzp_test_secret", testWebhookSecret)
+}
+
+func TestFakeServerCreateLink(t *testing.T) {
+	fake, provider := newFakeRazorpay(t)
+
+	link, err := provider.CreateLink(context.Background(), LinkRequest{
+		Amount:      1180.5,
+		ReferenceID: "inv-101",
+		Description: "Invoice #101",
+	})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	if link.ID == "" || link.URL != "https://rzp.io/i/"+link.ID || link.Status != "created" {
+		t.Errorf("link = %+v", link)
+	}
+
+	stored := fake.links[link.ID]
+	if stored == nil {
+		t.Fatalf("link %s not kept by the fake", link.ID)
+	}
+	if stored.Amount != 118050 || stored.Currency != "INR" || stored.ReferenceID != "inv-101" {
+		t.Errorf("stored link = %+v, want 118050 paise in INR for inv-101", stored)
+	}
+
+	if _, err := provider.CreateLink(context.Background(), LinkRequest{Amount: 0.5}); err == nil {
+		t.Error("CreateLink below INR 1.00 succeeded")
+	}
+}
+
+func TestFakeServerRequiresAuth(t *testing.T) {
+	fake := NewFakeServer(testWebhookSecret)
+
+	w := httptest.NewRecorder()
+	fake.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/payment_links/plink_fake000001", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestFakeServerPayWebhook(t *testing.T) {
+	fake, provider := newFakeRazorpay(t)
+
+	link, err := provider.CreateLink(context.Background(), LinkRequest{Amount: 250})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+
+	body, signature, err := fake.Pay(link.ID, "netbanking")
+	if err != nil {
+		t.Fatalf("Pay: %v", err)
+	}
+
+	header := http.Header{}
+	header.Set(razorpaySignatureHeader, signature)
+	if err := provider.VerifyWebhook(header, body); err != nil {
+		t.Fatalf("VerifyWebhook: %v", err)
+	}
+
+	event, err := provider.ParseWebhook(body)
+	if err != nil {
+		t.Fatalf("ParseWebhook: %v", err)
+	}
+	if event == nil {
+		t.Fatal("ParseWebhook returned no event for a paid link")
+	}
+	if event.LinkID != link.ID || event.PaymentID == "" || event.Amount != 250 || event.Method != "bank" {
+		t.Errorf("event = %+v", event)
+	}
+
+	if _, _, err := fake.Pay("plink_unknown", "upi"); err == nil {
+		t.Error("Pay on an unknown link succeeded")
+	}
+}
+
+func TestVerifyWebhookRejectsBadSignature(t *testing.T) {
+	fake, provider := newFakeRazorpay(t)
+
+	link, err := provider.CreateLink(context.Background(), LinkRequest{Amount: 100})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	body, signature, err := fake.Pay(link.ID, "upi")
+	if err != nil {
+		t.Fatalf("Pay: %v", err)
+	}
+
+	tampered := append([]byte{}, body...)
+	tampered[len(tampered)-2] ^= 1
+
+	tests := []struct {
+		name      string
+		provider  *Razorpay
+		signature string
+		body      []byte
+	}{
+		{"missing signature", provider, "", body},
+		{"signed with another secret", provider, Sign(body, "whsec_other"), body},
+		{"body changed after signing", provider, signature, tampered},
+		{"no webhook secret configured", NewRazorpay(RazorpayAPIURL, "key", "secret", ""), Sign(body, ""), body},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.signature != "" {
+				header.Set(razorpaySignatureHeader, tt.signature)
+			}
+			if err := tt.provider.VerifyWebhook(header, tt.body); err != ErrInvalidSignature {
+				t.Errorf("VerifyWebhook = %v, want %v", err, ErrInvalidSignature)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// Package gateway creates "Pay now" links with an online payment provider
+// and reads the webhooks it sends once a customer has paid.
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrInvalidSignature = errors.New("webhook signature does not match")
+)
+
+// LinkRequest describes the payment a link should collect. ReferenceID ties
+// the link back to the invoice it was created for.
+type LinkRequest struct {
+	Amount        float64
+	Currency      string
+	ReferenceID   string
+	Description   string
+	CustomerName  string
+	CustomerEmail string
+	CustomerPhone string
+	CallbackURL   string
+}
+
+type Link struct {
+	ID     string
+	URL    string
+	Status string
+}
+
+// Event is a payment a webhook reports against a link.
+type Event struct {
+	LinkID    string
+	PaymentID string
+	Amount    float64
+	Method    string
+	PaidAt    time.Time
+}
+
+// Provider is an online payment provider.
+//
+// ParseWebhook returns a nil event for webhooks that do not report a paid
+// link, which should be acknowledged and otherwise ignored.
+type Provider interface {
+	Name() string
+	CreateLink(ctx context.Context, req LinkRequest) (*Link, error)
+	VerifyWebhook(header http.Header, body []byte) error
+	ParseWebhook(body []byte) (*Event, error)
+}
@@ -0,0 +1,214 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	RazorpayAPIURL          = "https://api.razorpay.com"
+	razorpaySignatureHeader = "X-Razorpay-Signature"
+	razorpayLinkPaidEvent   = "payment_link.paid"
+)
+
+// Razorpay creates Razorpay payment links. Amounts are sent in paise and the
+// webhook body is signed with HMAC-SHA256 using the webhook secret.
+type Razorpay struct {
+	apiURL        string
+	keyID         string
+	keySecret     string
+	webhookSecret string
+	client        *http.Client
+}
+
+func NewRazorpay(apiURL, keyID, keySecret, webhookSecret string) *Razorpay {
+	return &Razorpay{
+		apiURL:        strings.TrimRight(apiURL, "/"),
+		keyID:         keyID,
+		keySecret:     keySecret,
+		webhookSecret: webhookSecret,
+		client:        &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (p *Razorpay) Name() string {
+	return "razorpay"
+}
+
+type razorpayCustomer struct {
+	Name    string `json:"name,omitempty"`
+	Email   string `json:"email,omitempty"`
+	Contact string `json:"contact,omitempty"`
+}
+
+type razorpayLinkRequest struct {
+	Amount         int64            `json:"amount"`
+	Currency       string           `json:"currency"`
+	ReferenceID    string           `json:"reference_id"`
+	Description    string           `json:"description"`
+	Customer       razorpayCustomer `json:"customer"`
+	CallbackURL    string           `json:"callback_url,omitempty"`
+	CallbackMethod string           `json:"callback_method,omitempty"`
+}
+
+type razorpayLink struct {
+	ID       string `json:"id"`
+	ShortURL string `json:"short_url"`
+	Status   string `json:"status"`
+}
+
+type razorpayError struct {
+	Error struct {
+		Code        string `json:"code"`
+		Description string `json:"description"`
+	} `json:"error"`
+}
+
+func (p *Razorpay) CreateLink(ctx context.Context, req LinkRequest) (*Link, error) {
+	currency := req.Currency
+	if currency == "" {
+		currency = "INR"
+	}
+
+	payload := razorpayLinkRequest{
+		Amount:      int64(math.Round(req.Amount * 100)),
+		Currency:    currency,
+		ReferenceID: req.ReferenceID,
+		Description: req.Description,
+		Customer: razorpayCustomer{
+			Name:    req.CustomerName,
+			Email:   req.CustomerEmail,
+			Contact: req.CustomerPhone,
+		},
+	}
+	if req.CallbackURL != "" {
+		payload.CallbackURL = req.CallbackURL
+		payload.CallbackMethod = "get"
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.apiURL+"/v1/payment_links", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.SetBasicAuth(p.keyID, p.keySecret)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr razorpayError
+		if json.Unmarshal(respBody, &apiErr) == nil && apiErr.Error.Description != "" {
+			return nil, fmt.Errorf("razorpay: %s", apiErr.Error.Description)
+		}
+		return nil, fmt.Errorf("razorpay: unexpected status %d", resp.StatusCode)
+	}
+
+	var link razorpayLink
+	if err := json.Unmarshal(respBody, &link); err != nil {
+		return nil, fmt.Errorf("razorpay: invalid response: %w", err)
+	}
+
+	return &Link{ID: link.ID, URL: link.ShortURL, Status: link.Status}, nil
+}
+
+// Sign returns the hex HMAC-SHA256 of body, as Razorpay signs webhooks.
+func Sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (p *Razorpay) VerifyWebhook(header http.Header, body []byte) error {
+	if p.webhookSecret == "" {
+		return ErrInvalidSignature
+	}
+
+	expected := Sign(body, p.webhookSecret)
+	if !hmac.Equal([]byte(expected), []byte(header.Get(razorpaySignatureHeader))) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+type razorpayWebhook struct {
+	Event   string `json:"event"`
+	Payload struct {
+		PaymentLink struct {
+			Entity razorpayLink `json:"entity"`
+		} `json:"payment_link"`
+		Payment struct {
+			Entity struct {
+				ID        string `json:"id"`
+				Amount    int64  `json:"amount"`
+				Method    string `json:"method"`
+				CreatedAt int64  `json:"created_at"`
+			} `json:"entity"`
+		} `json:"payment"`
+	} `json:"payload"`
+}
+
+// razorpayMethods maps Razorpay payment methods onto the ones invoices record.
+var razorpayMethods = map[string]string{
+	"upi":        "upi",
+	"card":       "card",
+	"netbanking": "bank",
+}
+
+func (p *Razorpay) ParseWebhook(body []byte) (*Event, error) {
+	var webhook razorpayWebhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return nil, fmt.Errorf("razorpay: invalid webhook: %w", err)
+	}
+
+	if webhook.Event != razorpayLinkPaidEvent {
+		return nil, nil
+	}
+
+	payment := webhook.Payload.Payment.Entity
+	if webhook.Payload.PaymentLink.Entity.ID == "" || payment.ID == "" {
+		return nil, fmt.Errorf("razorpay: webhook is missing the payment link or payment")
+	}
+
+	method, ok := razorpayMethods[payment.Method]
+	if !ok {
+		method = "bank"
+	}
+
+	paidAt := time.Now()
+	if payment.CreatedAt > 0 {
+		paidAt = time.Unix(payment.CreatedAt, 0)
+	}
+
+	return &Event{
+		LinkID:    webhook.Payload.PaymentLink.Entity.ID,
+		PaymentID: payment.ID,
+		Amount:    float64(payment.Amount) / 100,
+		Method:    method,
+		PaidAt:    paidAt,
+	}, nil
+}
@@ -0,0 +1,69 @@
+package gst
+
+import (
+	"math"
+	"strings"
+)
+
+// TaxRates are the GST slabs (in percent) a product can be billed at.
+var TaxRates = []float64{0, 0.1, 0.25, 1.5, 3, 5, 12, 18, 28}
+
+func IsValidTaxRate(rate float64) bool {
+	for _, slab := range TaxRates {
+		if math.Abs(rate-slab) < 0.001 {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidHSN reports whether code is a 4, 6 or 8 digit HSN code.
+func IsValidHSN(code string) bool {
+	switch len(code) {
+	case 4, 6, 8:
+		return isDigits(code)
+	default:
+		return false
+	}
+}
+
+// IsValidSAC reports whether code is a 6 digit Services Accounting Code.
+// Every SAC falls under chapter 99.
+func IsValidSAC(code string) bool {
+	return len(code) == 6 && isDigits(code) && strings.HasPrefix(code, "99")
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// StateCode returns the two digit state code a GSTIN starts with, or "" when
+// gstin is too short to carry one.
+func StateCode(gstin string) string {
+	if len(gstin) < 2 || !isDigits(gstin[:2]) {
+		return ""
+	}
+	return gstin[:2]
+}
+
+// IsIntraState reports whether a supply between the two GSTINs stays within
+// one state. A missing GSTIN is treated as a local supply.
+func IsIntraState(supplier, recipient string) bool {
+	a, b := StateCode(supplier), StateCode(recipient)
+	return a == "" || b == "" || a == b
+}
+
+// SplitTax divides a tax amount into CGST and SGST halves for an intra-state
+// supply, or charges all of it as IGST otherwise.
+func SplitTax(tax float64, intraState bool) (cgst, sgst, igst float64) {
+	if !intraState {
+		return 0, 0, tax
+	}
+	cgst = math.Round(tax*50) / 100
+	return cgst, math.Round((tax-cgst)*100) / 100, 0
+}
@@ -0,0 +1,95 @@
+package gst
+
+import (
+	"math"
+	"strings"
+)
+
+// Unit is a Unit Quantity Code (UQC) accepted in GST returns. Decimals is
+// the number of decimal places a quantity in that unit may carry.
+type Unit struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Decimals int    `json:"decimals"`
+}
+
+// Units is the UQC list published for GSTR-1 HSN summaries. Countable units
+// take whole quantities; measures take up to three decimals. OTH covers
+// services billed by the hour, day or session.
+var Units = []Unit{
+	{"BAG", "Bags", 0},
+	{"BAL", "Bale", 0},
+	{"BDL", "Bundles", 0},
+	{"BKL", "Buckles", 0},
+	{"BOU", "Billion of units", 3},
+	{"BOX", "Box", 0},
+	{"BTL", "Bottles", 0},
+	{"BUN", "Bunches", 0},
+	{"CAN", "Cans", 0},
+	{"CBM", "Cubic meters", 3},
+	{"CCM", "Cubic centimeters", 3},
+	{"CMS", "Centimeters", 3},
+	{"CTN", "Cartons", 0},
+	{"DOZ", "Dozens", 0},
+	{"DRM", "Drums", 0},
+	{"GGK", "Great gross", 0},
+	{"GMS", "Grammes", 3},
+	{"GRS", "Gross", 0},
+	{"GYD", "Gross yards", 3},
+	{"KGS", "Kilograms", 3},
+	{"KLR", "Kilolitre", 3},
+	{"KME", "Kilometre", 3},
+	{"LTR", "Litres", 3},
+	{"MLT", "Millilitre", 3},
+	{"MTR", "Meters", 3},
+	{"MTS", "Metric ton", 3},
+	{"NOS", "Numbers", 0},
+	{"OTH", "Others", 2},
+	{"PAC", "Packs", 0},
+	{"PCS", "Pieces", 0},
+	{"PRS", "Pairs", 0},
+	{"QTL", "Quintal", 3},
+	{"ROL", "Rolls", 0},
+	{"SET", "Sets", 0},
+	{"SQF", "Square feet", 3},
+	{"SQM", "Square meters", 3},
+	{"SQY", "Square yards", 3},
+	{"TBS", "Tablets", 0},
+	{"TGM", "Ten gross", 0},
+	{"THD", "Thousands", 0},
+	{"TON", "Tonnes", 3},
+	{"TUB", "Tubes", 0},
+	{"UGS", "US gallons", 3},
+	{"UNT", "Units", 0},
+	{"YDS", "Yards", 3},
+}
+
+// DefaultGoodsUnit and DefaultServiceUnit are used when a product does not
+// name a UQC.
+const (
+	DefaultGoodsUnit   = "NOS"
+	DefaultServiceUnit = "OTH"
+)
+
+// LookupUnit finds a UQC by code, ignoring case.
+func LookupUnit(code string) (Unit, bool) {
+	code = strings.ToUpper(code)
+	for _, unit := range Units {
+		if unit.Code == code {
+			return unit, true
+		}
+	}
+	return Unit{}, false
+}
+
+func IsValidUnit(code string) bool {
+	_, ok := LookupUnit(code)
+	return ok
+}
+
+// AllowsQuantity reports whether qty has no more decimal places than the
+// unit permits.
+func (u Unit) AllowsQuantity(qty float64) bool {
+	scale := math.Pow10(u.Decimals)
+	return math.Abs(qty*scale-math.Round(qty*scale)) < 1e-6
+}
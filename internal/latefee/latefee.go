@@ -0,0 +1,108 @@
+// Package latefee works out the late payment charges an overdue invoice has
+// accrued under a business's policy.
+package latefee
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	KindFlat     = "flat"
+	KindInterest = "interest"
+)
+
+// Policy is either a flat fee charged once per late invoice, or simple
+// interest at AnnualRate percent a year on the amount outstanding. Nothing
+// is charged until GraceDays have passed after the due date; after that,
+// interest runs from the due date itself. Cap, when set, limits the total
+// charged on one invoice.
+type Policy struct {
+	Kind       string
+	FlatAmount float64
+	AnnualRate float64
+	GraceDays  int
+	Cap        *float64
+}
+
+type Payment struct {
+	Date   time.Time
+	Amount float64
+}
+
+type Invoice struct {
+	Total    float64
+	DueDate  time.Time
+	Payments []Payment
+}
+
+// Accrued is the total charge the invoice has accrued by asOf, rounded to
+// paise. Payments reduce the amount interest runs on from the day they are
+// received.
+func Accrued(policy Policy, invoice Invoice, asOf time.Time) float64 {
+	due := day(invoice.DueDate)
+	asOf = day(asOf)
+	if !asOf.After(due.AddDate(0, 0, policy.GraceDays)) {
+		return 0
+	}
+
+	payments := make([]Payment, len(invoice.Payments))
+	copy(payments, invoice.Payments)
+	sort.Slice(payments, func(i, j int) bool { return payments[i].Date.Before(payments[j].Date) })
+
+	// The balance outstanding on the due date, then each later payment in
+	// turn until asOf.
+	balance := invoice.Total
+	next := 0
+	for next < len(payments) && !day(payments[next].Date).After(due) {
+		balance -= payments[next].Amount
+		next++
+	}
+
+	var charge float64
+	switch policy.Kind {
+	case KindFlat:
+		graceEnd := due.AddDate(0, 0, policy.GraceDays)
+		for i := next; i < len(payments) && !day(payments[i].Date).After(graceEnd); i++ {
+			balance -= payments[i].Amount
+		}
+		if round(balance) > 0 {
+			charge = policy.FlatAmount
+		}
+	case KindInterest:
+		from := due
+		for from.Before(asOf) && round(balance) > 0 {
+			to := asOf
+			if next < len(payments) && day(payments[next].Date).Before(asOf) {
+				to = day(payments[next].Date)
+			}
+
+			days := to.Sub(from).Hours() / 24
+			charge += balance * policy.AnnualRate / 100 * days / 365
+
+			if to.Equal(asOf) {
+				break
+			}
+			for next < len(payments) && day(payments[next].Date).Equal(to) {
+				balance -= payments[next].Amount
+				next++
+			}
+			from = to
+		}
+	}
+
+	if policy.Cap != nil && charge > *policy.Cap {
+		charge = *policy.Cap
+	}
+
+	return round(charge)
+}
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,193 @@
+package latefee
+
+import (
+	"testing"
+	"time"
+)
+
+func date(month time.Month, day int) time.Time {
+	return time.Date(2024, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func amount(v float64) *float64 {
+	return &v
+}
+
+func TestAccrued(t *testing.T) {
+	// 12% a year on 36,500 is 12 a day, and on half of it 6 a day.
+	const total = 36500
+	due := date(time.March, 1)
+	interest := Policy{Kind: KindInterest, AnnualRate: 12, GraceDays: 7}
+	flat := Policy{Kind: KindFlat, FlatAmount: 500, GraceDays: 7}
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		payments []Payment
+		asOf     time.Time
+		want     float64
+	}{
+		{
+			name:   "interest inside grace",
+			policy: interest,
+			asOf:   date(time.March, 5),
+			want:   0,
+		},
+		{
+			name:   "interest exactly at grace",
+			policy: interest,
+			asOf:   date(time.March, 8),
+			want:   0,
+		},
+		{
+			name:   "interest the day after grace runs from the due date",
+			policy: interest,
+			asOf:   date(time.March, 9),
+			want:   96,
+		},
+		{
+			name:   "interest without grace on the due date",
+			policy: Policy{Kind: KindInterest, AnnualRate: 12},
+			asOf:   due,
+			want:   0,
+		},
+		{
+			name:   "interest without grace the day after",
+			policy: Policy{Kind: KindInterest, AnnualRate: 12},
+			asOf:   time.Date(2024, time.March, 2, 18, 30, 0, 0, time.UTC),
+			want:   12,
+		},
+		{
+			name:   "interest below cap",
+			policy: Policy{Kind: KindInterest, AnnualRate: 12, GraceDays: 7, Cap: amount(500)},
+			asOf:   date(time.March, 31),
+			want:   360,
+		},
+		{
+			name:   "interest cap reached",
+			policy: Policy{Kind: KindInterest, AnnualRate: 12, GraceDays: 7, Cap: amount(50)},
+			asOf:   date(time.March, 31),
+			want:   50,
+		},
+		{
+			name:     "partial payment before due reduces the base",
+			policy:   interest,
+			payments: []Payment{{Date: date(time.February, 20), Amount: 18250}},
+			asOf:     date(time.March, 31),
+			want:     180,
+		},
+		{
+			name:     "partial payment when overdue reduces the base from that day",
+			policy:   interest,
+			payments: []Payment{{Date: date(time.March, 11), Amount: 18250}},
+			asOf:     date(time.March, 31),
+			want:     240,
+		},
+		{
+			name:   "payments out of order",
+			policy: interest,
+			payments: []Payment{
+				{Date: date(time.March, 21), Amount: 9125},
+				{Date: date(time.March, 11), Amount: 18250},
+			},
+			asOf: date(time.March, 31),
+			want: 120 + 60 + 30,
+		},
+		{
+			name:     "paid in full when overdue stops interest",
+			policy:   interest,
+			payments: []Payment{{Date: date(time.March, 11), Amount: total}},
+			asOf:     date(time.June, 30),
+			want:     120,
+		},
+		{
+			name:     "paid in full before due",
+			policy:   interest,
+			payments: []Payment{{Date: date(time.February, 28), Amount: total}},
+			asOf:     date(time.March, 31),
+			want:     0,
+		},
+		{
+			name:   "flat inside grace",
+			policy: flat,
+			asOf:   date(time.March, 8),
+			want:   0,
+		},
+		{
+			name:   "flat after grace",
+			policy: flat,
+			asOf:   date(time.March, 9),
+			want:   500,
+		},
+		{
+			name:     "flat after a partial payment within grace",
+			policy:   flat,
+			payments: []Payment{{Date: date(time.March, 5), Amount: 18250}},
+			asOf:     date(time.March, 31),
+			want:     500,
+		},
+		{
+			name:     "flat waived when paid in full within grace",
+			policy:   flat,
+			payments: []Payment{{Date: date(time.March, 8), Amount: total}},
+			asOf:     date(time.March, 31),
+			want:     0,
+		},
+		{
+			name:     "flat still charged when paid after grace",
+			policy:   flat,
+			payments: []Payment{{Date: date(time.March, 9), Amount: total}},
+			asOf:     date(time.March, 31),
+			want:     500,
+		},
+		{
+			name:   "flat cap reached",
+			policy: Policy{Kind: KindFlat, FlatAmount: 500, Cap: amount(200)},
+			asOf:   date(time.March, 31),
+			want:   200,
+		},
+		{
+			name:   "absent policy",
+			policy: Policy{},
+			asOf:   date(time.December, 31),
+			want:   0,
+		},
+		{
+			name:   "zero rate",
+			policy: Policy{Kind: KindInterest},
+			asOf:   date(time.December, 31),
+			want:   0,
+		},
+		{
+			name:   "zero flat fee",
+			policy: Policy{Kind: KindFlat},
+			asOf:   date(time.December, 31),
+			want:   0,
+		},
+		{
+			name:   "zero cap",
+			policy: Policy{Kind: KindInterest, AnnualRate: 12, Cap: amount(0)},
+			asOf:   date(time.December, 31),
+			want:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := Invoice{Total: total, DueDate: due, Payments: tt.payments}
+			if got := Accrued(tt.policy, invoice, tt.asOf); got != tt.want {
+				t.Errorf("Accrued = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccruedRoundsToPaise(t *testing.T) {
+	policy := Policy{Kind: KindInterest, AnnualRate: 18}
+	invoice := Invoice{Total: 1000, DueDate: date(time.March, 1)}
+
+	// 1000 * 18% * 10 / 365 = 4.9315...
+	if got, want := Accrued(policy, invoice, date(time.March, 11)), 4.93; got != want {
+		t.Errorf("Accrued = %v, want %v", got, want)
+	}
+}
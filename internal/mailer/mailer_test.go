@@ -0,0 +1,190 @@
+package mailer
+
+import (
+	"billify-api/internal/mailer/mailertest"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSMTPMailerSend(t *testing.T) {
+	server := mailertest.NewServer()
+	defer server.Close()
+
+	pdf := bytes.Repeat([]byte("%PDF-1.7 invoice "), 20)
+	msg := Message{
+		From:    "Acme Traders <billing@acme.in>",
+		ReplyTo: "accounts@acme.in",
+		To:      []string{"Ravi Kumar <ravi@example.com>", "priya@example.com"},
+		Cc:      []string{"Books <books@example.com>"},
+		Subject: "Invoice #101 – ₹1,180 due",
+		Body:    "Hi Ravi,\n\nPlease find invoice #101 attached.\n.\nThanks",
+		Attachments: []Attachment{
+			{Filename: "invoice-101.pdf", ContentType: "application/pdf", Data: pdf},
+			{Filename: "notes.txt", Data: []byte("no content type")},
+		},
+	}
+
+	mailer := NewSMTPMailer(server.Addr, "apikey", "s3cret")
+	if err := mailer.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("server got %d messages, want 1", len(messages))
+	}
+	got := messages[0]
+
+	if got.From != "billing@acme.in" {
+		t.Errorf("MAIL FROM = %q, want billing@acme.in", got.From)
+	}
+	if want := []string{"ravi@example.com", "priya@example.com", "books@example.com"}; !reflect.DeepEqual(got.To, want) {
+		t.Errorf("RCPT TO = %q, want %q", got.To, want)
+	}
+	if got.Username != "apikey" || got.Password != "s3cret" {
+		t.Errorf("AUTH = %q/%q, want apikey/s3cret", got.Username, got.Password)
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(got.Data))
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+
+	var decoder mime.WordDecoder
+	subject, err := decoder.DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("Subject: %v", err)
+	}
+
+	headers := map[string]string{
+		"From":         msg.From,
+		"To":           "Ravi Kumar <ravi@example.com>, priya@example.com",
+		"Cc":           "Books <books@example.com>",
+		"Reply-To":     "accounts@acme.in",
+		"MIME-Version": "1.0",
+	}
+	for key, want := range headers {
+		if value := parsed.Header.Get(key); value != want {
+			t.Errorf("%s = %q, want %q", key, value, want)
+		}
+	}
+	if subject != msg.Subject {
+		t.Errorf("Subject = %q, want %q", subject, msg.Subject)
+	}
+	if id := parsed.Header.Get("Message-ID"); !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, "@acme.in>") {
+		t.Errorf("Message-ID = %q, want one at acme.in", id)
+	}
+	if _, err := parsed.Header.Date(); err != nil {
+		t.Errorf("Date: %v", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q (%v), want multipart/mixed", parsed.Header.Get("Content-Type"), err)
+	}
+
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+
+	// Raw parts keep their transfer encoding, so it is checked as sent.
+	text, err := reader.NextRawPart()
+	if err != nil {
+		t.Fatalf("text part: %v", err)
+	}
+	if ct := text.Header.Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("text Content-Type = %q", ct)
+	}
+	if cte := text.Header.Get("Content-Transfer-Encoding"); cte != "quoted-printable" {
+		t.Errorf("text Content-Transfer-Encoding = %q, want quoted-printable", cte)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.ReplaceAll(msg.Body, "\n", "\r\n"); string(body) != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+
+	attachments := []struct {
+		filename, contentType string
+		data                  []byte
+	}{
+		{"invoice-101.pdf", "application/pdf", pdf},
+		{"notes.txt", "application/octet-stream", []byte("no content type")},
+	}
+	for _, want := range attachments {
+		part, err := reader.NextRawPart()
+		if err != nil {
+			t.Fatalf("attachment %s: %v", want.filename, err)
+		}
+		if part.FileName() != want.filename {
+			t.Errorf("filename = %q, want %q", part.FileName(), want.filename)
+		}
+		if ct := part.Header.Get("Content-Type"); ct != want.contentType {
+			t.Errorf("%s Content-Type = %q, want %q", want.filename, ct, want.contentType)
+		}
+		if cte := part.Header.Get("Content-Transfer-Encoding"); cte != "base64" {
+			t.Errorf("%s Content-Transfer-Encoding = %q, want base64", want.filename, cte)
+		}
+
+		raw, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(strings.TrimRight(string(raw), "\r\n"), "\r\n") {
+			if len(line) > 76 {
+				t.Errorf("%s has a %d character line", want.filename, len(line))
+			}
+		}
+		data, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, bytes.NewReader(raw)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, want.data) {
+			t.Errorf("%s data = %q, want %q", want.filename, data, want.data)
+		}
+	}
+
+	if _, err := reader.NextRawPart(); err != io.EOF {
+		t.Errorf("extra part after attachments: %v", err)
+	}
+}
+
+func TestSMTPMailerSendInvalid(t *testing.T) {
+	server := mailertest.NewServer()
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{"no recipients", Message{From: "billing@acme.in"}},
+		{"bad from", Message{From: "not an address", To: []string{"ravi@example.com"}}},
+		{"bad recipient", Message{From: "billing@acme.in", To: []string{"ravi@"}}},
+		{"bad cc", Message{From: "billing@acme.in", To: []string{"ravi@example.com"}, Cc: []string{"<>"}}},
+	}
+
+	mailer := NewSMTPMailer(server.Addr, "", "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := mailer.Send(ctx, tt.msg); err == nil {
+				t.Error("Send succeeded")
+			}
+		})
+	}
+
+	if n := len(server.Messages()); n != 0 {
+		t.Errorf("server got %d messages, want none", n)
+	}
+}
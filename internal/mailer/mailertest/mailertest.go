@@ -0,0 +1,166 @@
+// Package mailertest provides an in-process SMTP server for testing code
+// that sends email, in the way net/http/httptest does for HTTP.
+package mailertest
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Message is one mail transaction the server accepted.
+type Message struct {
+	From     string
+	To       []string
+	Username string
+	Password string
+	Data     []byte
+}
+
+// Server accepts mail on a local port and keeps it. It offers AUTH PLAIN
+// but not STARTTLS, and accepts any credentials.
+type Server struct {
+	Addr string
+
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewServer starts a server on a random local port. Call Close when done.
+func NewServer() *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("mailertest: failed to listen: %v", err))
+	}
+
+	s := &Server{Addr: listener.Addr().String(), listener: listener}
+	s.wg.Add(1)
+	go s.serve()
+
+	return s
+}
+
+// Messages returns the mail accepted so far, oldest first.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Message(nil), s.messages...)
+}
+
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...any) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+
+	reply("220 mailertest ESMTP")
+
+	var msg Message
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			reply("250-mailertest")
+			reply("250-8BITMIME")
+			reply("250 AUTH PLAIN")
+		case "HELO":
+			reply("250 mailertest")
+		case "AUTH":
+			mechanism, initial, _ := strings.Cut(arg, " ")
+			decoded, err := base64.StdEncoding.DecodeString(initial)
+			parts := strings.Split(string(decoded), "\x00")
+			if !strings.EqualFold(mechanism, "PLAIN") || err != nil || len(parts) != 3 {
+				reply("504 unsupported authentication")
+				continue
+			}
+			msg.Username, msg.Password = parts[1], parts[2]
+			reply("235 authenticated")
+		case "MAIL":
+			msg.From = envelopeAddress(arg)
+			msg.To = nil
+			reply("250 ok")
+		case "RCPT":
+			msg.To = append(msg.To, envelopeAddress(arg))
+			reply("250 ok")
+		case "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			data, err := readData(r)
+			if err != nil {
+				return
+			}
+			msg.Data = data
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			msg = Message{Username: msg.Username, Password: msg.Password}
+			reply("250 ok")
+		case "RSET":
+			msg = Message{Username: msg.Username, Password: msg.Password}
+			reply("250 ok")
+		case "NOOP":
+			reply("250 ok")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+// envelopeAddress returns the address in "FROM:<a@b.c> BODY=8BITMIME".
+func envelopeAddress(arg string) string {
+	_, address, _ := strings.Cut(arg, ":")
+	address, _, _ = strings.Cut(strings.TrimSpace(address), " ")
+	return strings.Trim(address, "<>")
+}
+
+// readData reads a DATA section up to the lone dot, undoing dot-stuffing.
+func readData(r *bufio.Reader) ([]byte, error) {
+	var data []byte
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if line == ".\r\n" {
+			return data, nil
+		}
+		data = append(data, strings.TrimPrefix(line, ".")...)
+	}
+}
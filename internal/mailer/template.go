@@ -0,0 +1,83 @@
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+const (
+	TemplateInvoice  = "invoice"
+	TemplateReminder = "reminder"
+)
+
+var ErrInvalidTemplate = errors.New("invalid template")
+
+// Template is the subject and body of an email, both Go text templates.
+type Template struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// DefaultTemplates are used by businesses that have not written their own.
+var DefaultTemplates = map[string]Template{
+	TemplateInvoice: {
+		Subject: "Invoice #{{.InvoiceNo}} from {{.BusinessName}}",
+		Body: `Dear {{.CustomerName}},
+
+Please find attached invoice #{{.InvoiceNo}} dated {{.InvoiceDate}} for {{.Amount}}, due on {{.DueDate}}.
+{{if .PaymentLink}}
+You can pay online at {{.PaymentLink}}
+{{end}}
+Thank you for your business.
+
+{{.BusinessName}}
+`,
+	},
+	TemplateReminder: {
+		Subject: "Payment reminder: invoice #{{.InvoiceNo}} from {{.BusinessName}}",
+		Body: `Dear {{.CustomerName}},
+
+{{if gt .DaysOverdue 0}}Invoice #{{.InvoiceNo}} dated {{.InvoiceDate}} was due on {{.DueDate}} and is now {{.DaysOverdue}} days overdue.{{else if eq .DaysOverdue 0}}Invoice #{{.InvoiceNo}} dated {{.InvoiceDate}} is due today.{{else}}Invoice #{{.InvoiceNo}} dated {{.InvoiceDate}} is due on {{.DueDate}}.{{end}} The amount due is {{.AmountDue}}; a copy of the invoice is attached.
+{{if .PaymentLink}}
+You can pay online at {{.PaymentLink}}
+{{end}}
+If you have already paid, please ignore this reminder.
+
+{{.BusinessName}}
+`,
+	},
+}
+
+// Render executes the template with data. Referring to a field data does not
+// have is an error, so Render with sample data also validates a template.
+func (t Template) Render(data any) (string, string, error) {
+	subject, err := render("subject", t.Subject, data)
+	if err != nil {
+		return "", "", err
+	}
+
+	body, err := render("body", t.Body, data)
+	if err != nil {
+		return "", "", err
+	}
+
+	// A subject spanning lines would break the message headers.
+	return strings.Join(strings.Fields(subject), " "), body, nil
+}
+
+func render(name, text string, data any) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrInvalidTemplate, name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrInvalidTemplate, name, err)
+	}
+
+	return buf.String(), nil
+}
@@ -1,11 +1,13 @@
 package pdf
 
 import (
+	"billify-api/internal/gst"
 	"billify-api/internal/store"
 	"bytes"
 	"fmt"
 	"math"
 	"strconv"
+	"strings"
 
 	"github.com/jung-kurt/gofpdf/v2"
 )
@@ -37,15 +39,16 @@ func (p *PDFGenerator) GenerateInvoicePDF(business *store.Business, invoice *sto
 
 	// Invoice Header
 	pdf.SetTextColor(darkBlue[0], darkBlue[1], darkBlue[2])
-	pdf.CellFormat(190, 10, fmt.Sprintf("Invoice #%d", invoice.InvNo), "", 1, "C", false, 0, "")
+	title := "Invoice"
+	if invoice.Kind == store.InvoiceKindDebitNote {
+		title = "Debit Note"
+	}
+	pdf.CellFormat(190, 10, fmt.Sprintf("%s #%d", title, invoice.InvNo), "", 1, "C", false, 0, "")
 	pdf.Ln(10)
 
 	// Company Information
-	pdf.SetFont("Poppins", "B", 16)
 	pdf.SetTextColor(black[0], black[1], black[2])
-	pdf.CellFormat(190, 7, business.Name, "", 1, "", false, 0, "")
-	pdf.SetFont("Poppins", "", 8)
-	pdf.MultiCell(190, 5, fmt.Sprintf("GSTIN: %s\n%s\n%s, %s, %s, %s\nPhone: %s\nEmail: %s", business.GSTNo, business.Address, business.City, business.State, business.ZipCode, business.Country, business.CompanyPhone, business.CompanyEmail), "", "", false)
+	writeLetterhead(pdf, business)
 
 	// Invoice Date / Due Date
 	pdf.Ln(4)
@@ -88,9 +91,10 @@ func (p *PDFGenerator) GenerateInvoicePDF(business *store.Business, invoice *sto
 	pdf.SetFillColor(darkBlue[0], darkBlue[1], darkBlue[2])
 	pdf.SetTextColor(255, 255, 255)
 	pdf.CellFormat(10, 7, "#", "", 0, "C", true, 0, "")
-	pdf.CellFormat(60, 7, "Item", "", 0, "C", true, 0, "")
-	pdf.CellFormat(25, 7, "Rate / Item", "", 0, "C", true, 0, "")
-	pdf.CellFormat(15, 7, "Qty", "", 0, "C", true, 0, "")
+	pdf.CellFormat(40, 7, "Item", "", 0, "C", true, 0, "")
+	pdf.CellFormat(17, 7, "HSN/SAC", "", 0, "C", true, 0, "")
+	pdf.CellFormat(23, 7, "Rate / Item", "", 0, "C", true, 0, "")
+	pdf.CellFormat(20, 7, "Qty", "", 0, "C", true, 0, "")
 	pdf.CellFormat(30, 7, "Taxable Value", "", 0, "C", true, 0, "")
 	pdf.CellFormat(30, 7, "Tax Amount", "", 0, "C", true, 0, "")
 	pdf.CellFormat(30, 7, "Item Total", "", 1, "C", true, 0, "")
@@ -106,14 +110,19 @@ func (p *PDFGenerator) GenerateInvoicePDF(business *store.Business, invoice *sto
 				break
 			}
 		}
-		taxableValue := item.UnitPrice * float64(item.Quantity)
-		taxAmount := (taxableValue * product.TaxRate) / 100
+		name := product.Name
+		if item.VariantName != "" {
+			name = fmt.Sprintf("%s - %s", product.Name, item.VariantName)
+		}
+		taxableValue := item.UnitPrice * item.Quantity
+		taxAmount := (taxableValue * item.TaxRate) / 100
 		totalAmount := taxableValue + taxAmount
 		pdf.SetFillColor(grey[0], grey[1], grey[2])
 		pdf.CellFormat(10, 7, strconv.Itoa(i+1), "", 0, "C", true, 0, "")
-		pdf.CellFormat(60, 7, product.Name, "", 0, "", true, 0, "")
-		pdf.CellFormat(25, 7, fmt.Sprintf("₹ %.2f", item.UnitPrice), "", 0, "R", true, 0, "")
-		pdf.CellFormat(15, 7, strconv.Itoa(item.Quantity), "", 0, "R", true, 0, "")
+		pdf.CellFormat(40, 7, name, "", 0, "", true, 0, "")
+		pdf.CellFormat(17, 7, product.HSNCode, "", 0, "C", true, 0, "")
+		pdf.CellFormat(23, 7, fmt.Sprintf("₹ %.2f", item.UnitPrice), "", 0, "R", true, 0, "")
+		pdf.CellFormat(20, 7, formatQuantity(item.Quantity, product), "", 0, "R", true, 0, "")
 		pdf.CellFormat(30, 7, fmt.Sprintf("₹ %.2f", taxableValue), "", 0, "R", true, 0, "")
 		pdf.CellFormat(30, 7, fmt.Sprintf("₹ %.2f", taxAmount), "", 0, "R", true, 0, "")
 		pdf.CellFormat(30, 7, fmt.Sprintf("₹ %.2f", totalAmount), "", 1, "R", true, 0, "")
@@ -150,3 +159,21 @@ func (p *PDFGenerator) GenerateInvoicePDF(business *store.Business, invoice *sto
 
 	return buf.Bytes(), nil
 }
+
+// writeLetterhead prints the business name, GSTIN and contact details.
+func writeLetterhead(pdf *gofpdf.Fpdf, business *store.Business) {
+	pdf.SetFont("Poppins", "B", 16)
+	pdf.CellFormat(190, 7, business.Name, "", 1, "", false, 0, "")
+	pdf.SetFont("Poppins", "", 8)
+	pdf.MultiCell(190, 5, fmt.Sprintf("GSTIN: %s\n%s\n%s, %s, %s, %s\nPhone: %s\nEmail: %s", business.GSTNo, business.Address, business.City, business.State, business.ZipCode, business.Country, business.CompanyPhone, business.CompanyEmail), "", "", false)
+}
+
+// formatQuantity prints a quantity with as many decimals as its UQC allows,
+// followed by the product's unit label, e.g. "2.50 hrs".
+func formatQuantity(qty float64, product *store.Product) string {
+	decimals := 0
+	if unit, ok := gst.LookupUnit(product.UQC); ok {
+		decimals = unit.Decimals
+	}
+	return strings.TrimSpace(strconv.FormatFloat(qty, 'f', decimals, 64) + " " + product.Unit)
+}
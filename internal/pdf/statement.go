@@ -0,0 +1,113 @@
+package pdf
+
+import (
+	"billify-api/internal/store"
+	"bytes"
+	"fmt"
+
+	"github.com/jung-kurt/gofpdf/v2"
+)
+
+func (p *PDFGenerator) GenerateStatementPDF(business *store.Business, customer *store.Customer, statement *store.Statement) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "./fonts")
+
+	pdf.AddUTF8Font("Poppins", "", "./Poppins-Regular.ttf")
+	pdf.AddUTF8Font("Poppins", "B", "./Poppins-Bold.ttf")
+	pdf.AddUTF8Font("Poppins", "I", "./Poppins-Italic.ttf")
+
+	pdf.AddPage()
+	pdf.SetMargins(5, 5, 5)
+
+	// Colors
+	black := []int{0, 0, 0}
+	darkBlue := []int{78, 79, 235}
+	grey := []int{238, 238, 238}
+
+	// Statement Header
+	pdf.SetFont("Poppins", "B", 18)
+	pdf.SetTextColor(darkBlue[0], darkBlue[1], darkBlue[2])
+	pdf.CellFormat(190, 10, "Statement of Account", "", 1, "C", false, 0, "")
+	pdf.Ln(10)
+
+	// Company Information
+	pdf.SetTextColor(black[0], black[1], black[2])
+	writeLetterhead(pdf, business)
+
+	// Customer Information
+	pdf.Ln(4)
+	pdf.SetFont("Poppins", "B", 8)
+	pdf.CellFormat(95, 6, "Customer Detail:", "", 0, "", false, 0, "")
+	pdf.CellFormat(95, 6, fmt.Sprintf("Period: %s - %s", statement.From.Format("02/01/2006"), statement.To.Format("02/01/2006")), "", 1, "R", false, 0, "")
+	pdf.SetFont("Poppins", "", 8)
+	pdf.CellFormat(95, 5, fmt.Sprintf("Name: %s", customer.Name), "", 1, "", false, 0, "")
+	pdf.CellFormat(95, 5, fmt.Sprintf("GSTNo: %s", customer.GSTNo), "", 1, "", false, 0, "")
+	pdf.MultiCell(95, 5, customer.BAddress, "", "L", false)
+	pdf.Ln(6)
+
+	// Table Header
+	pdf.SetFont("Poppins", "B", 8)
+	pdf.SetFillColor(darkBlue[0], darkBlue[1], darkBlue[2])
+	pdf.SetTextColor(255, 255, 255)
+	pdf.CellFormat(25, 7, "Date", "", 0, "C", true, 0, "")
+	pdf.CellFormat(65, 7, "Particulars", "", 0, "C", true, 0, "")
+	pdf.CellFormat(35, 7, "Debit", "", 0, "C", true, 0, "")
+	pdf.CellFormat(35, 7, "Credit", "", 0, "C", true, 0, "")
+	pdf.CellFormat(40, 7, "Balance", "", 1, "C", true, 0, "")
+
+	// Table Rows
+	pdf.SetTextColor(black[0], black[1], black[2])
+	pdf.SetFillColor(grey[0], grey[1], grey[2])
+
+	pdf.SetFont("Poppins", "B", 8)
+	pdf.CellFormat(25, 7, statement.From.Format("02/01/2006"), "", 0, "C", true, 0, "")
+	pdf.CellFormat(135, 7, "Opening Balance", "", 0, "", true, 0, "")
+	pdf.CellFormat(40, 7, fmt.Sprintf("₹ %.2f", statement.OpeningBalance), "", 1, "R", true, 0, "")
+
+	pdf.SetFont("Poppins", "", 8)
+	for _, entry := range statement.Entries {
+		particulars := fmt.Sprintf("Invoice #%d", entry.InvNo)
+		switch {
+		case entry.Type == store.StatementEntryPayment:
+			particulars = fmt.Sprintf("Payment received - Invoice #%d", entry.InvNo)
+		case entry.Type == store.StatementEntryDebitNote:
+			particulars = fmt.Sprintf("Debit Note #%d", entry.InvNo)
+		case entry.Type == store.StatementEntryCreditNote && entry.CNNo != nil:
+			particulars = fmt.Sprintf("Credit Note #%d - Invoice #%d", *entry.CNNo, entry.InvNo)
+		}
+		if entry.RefInvNo != nil {
+			particulars += fmt.Sprintf(" - Late fee on #%d", *entry.RefInvNo)
+		}
+
+		pdf.CellFormat(25, 7, entry.Date.Format("02/01/2006"), "", 0, "C", true, 0, "")
+		pdf.CellFormat(65, 7, particulars, "", 0, "", true, 0, "")
+		pdf.CellFormat(35, 7, formatStatementAmount(entry.Debit), "", 0, "R", true, 0, "")
+		pdf.CellFormat(35, 7, formatStatementAmount(entry.Credit), "", 0, "R", true, 0, "")
+		pdf.CellFormat(40, 7, fmt.Sprintf("₹ %.2f", entry.Balance), "", 1, "R", true, 0, "")
+	}
+
+	pdf.SetFont("Poppins", "B", 8)
+	pdf.CellFormat(25, 7, statement.To.Format("02/01/2006"), "", 0, "C", true, 0, "")
+	pdf.CellFormat(135, 7, "Closing Balance", "", 0, "", true, 0, "")
+	pdf.CellFormat(40, 7, fmt.Sprintf("₹ %.2f", statement.ClosingBalance), "", 1, "R", true, 0, "")
+	pdf.CellFormat(200, 1, "", "B", 0, "R", false, 1, "")
+
+	if statement.AccruedLateFees > 0 {
+		pdf.Ln(4)
+		pdf.SetFont("Poppins", "", 8)
+		pdf.CellFormat(200, 6, fmt.Sprintf("Late payment charges of ₹ %.2f have accrued on overdue invoices and are not yet included above.", statement.AccruedLateFees), "", 1, "", false, 0, "")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func formatStatementAmount(amount float64) string {
+	if amount == 0 {
+		return ""
+	}
+	return fmt.Sprintf("₹ %.2f", amount)
+}
@@ -0,0 +1,375 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+const (
+	BankTransactionUnmatched = "unmatched"
+	BankTransactionMatched   = "matched"
+	BankTransactionIgnored   = "ignored"
+)
+
+var (
+	ErrTransactionMatched   = errors.New("bank transaction is already matched to a payment")
+	ErrTransactionUnmatched = errors.New("bank transaction is not matched to a payment")
+	ErrNotACredit           = errors.New("only credits can be matched to invoices")
+)
+
+type BankStore struct {
+	db *sql.DB
+}
+
+// Import stores a statement and its transactions. Transactions already
+// imported from an earlier statement, recognised by their FitID, are skipped
+// and counted in statement.Duplicates. With dryRun the counts are worked out
+// and everything is rolled back.
+func (s *BankStore) Import(ctx context.Context, statement *BankStatement, transactions []*BankTransaction, dryRun bool) error {
+	ctx, cancel := context.WithTimeout(ctx, ImportTimeoutDuration)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO bank_statement (buss_id, filename, format)
+        VALUES ($1, $2, $3)
+        RETURNING id, created_at
+    `, statement.BusID, statement.Filename, statement.Format).Scan(&statement.ID, &statement.CreatedAt)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	statement.Imported, statement.Duplicates = 0, 0
+	for _, txn := range transactions {
+		txn.BusID = statement.BusID
+		txn.StatementID = statement.ID
+		txn.Status = BankTransactionUnmatched
+
+		err := tx.QueryRowContext(ctx, `
+            INSERT INTO bank_transaction (buss_id, statement_id, txn_date, amount, description, reference, fit_id)
+            VALUES ($1, $2, $3, $4, $5, $6, $7)
+            ON CONFLICT (buss_id, fit_id) DO NOTHING
+            RETURNING id, created_at
+        `, txn.BusID, txn.StatementID, txn.TxnDate, txn.Amount, txn.Description, txn.Reference, txn.FitID).Scan(
+			&txn.ID,
+			&txn.CreatedAt,
+		)
+		if err == sql.ErrNoRows {
+			statement.Duplicates++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		statement.Imported++
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	return tx.Commit()
+}
+
+const bankTransactionQuery = `
+    SELECT
+        t.id,
+        t.buss_id,
+        t.statement_id,
+        t.txn_date,
+        t.amount,
+        t.description,
+        t.reference,
+        t.fit_id,
+        t.payment_id,
+        p.inv_id,
+        i.inv_no,
+        t.ignored,
+        t.created_at
+    FROM bank_transaction t
+    LEFT JOIN payment p ON p.id = t.payment_id
+    LEFT JOIN invoice i ON i.id = p.inv_id
+`
+
+func scanBankTransaction(row interface{ Scan(...any) error }) (*BankTransaction, error) {
+	txn := &BankTransaction{}
+	err := row.Scan(
+		&txn.ID,
+		&txn.BusID,
+		&txn.StatementID,
+		&txn.TxnDate,
+		&txn.Amount,
+		&txn.Description,
+		&txn.Reference,
+		&txn.FitID,
+		&txn.PaymentID,
+		&txn.InvID,
+		&txn.InvNo,
+		&txn.Ignored,
+		&txn.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case txn.PaymentID != nil:
+		txn.Status = BankTransactionMatched
+	case txn.Ignored:
+		txn.Status = BankTransactionIgnored
+	default:
+		txn.Status = BankTransactionUnmatched
+	}
+
+	return txn, nil
+}
+
+// GetTransactions lists a business's bank transactions, newest first. status
+// narrows them to unmatched, matched or ignored ones; unmatched covers credits
+// and debits alike.
+func (s *BankStore) GetTransactions(ctx context.Context, busID uuid.UUID, status string) ([]*BankTransaction, error) {
+	query := bankTransactionQuery + `WHERE t.buss_id = $1`
+	switch status {
+	case BankTransactionUnmatched:
+		query += ` AND t.payment_id IS NULL AND NOT t.ignored`
+	case BankTransactionMatched:
+		query += ` AND t.payment_id IS NOT NULL`
+	case BankTransactionIgnored:
+		query += ` AND t.payment_id IS NULL AND t.ignored`
+	}
+	query += ` ORDER BY t.txn_date DESC, t.created_at DESC`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, busID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := []*BankTransaction{}
+	for rows.Next() {
+		txn, err := scanBankTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, txn)
+	}
+
+	return transactions, rows.Err()
+}
+
+func (s *BankStore) GetTransaction(ctx context.Context, txnID uuid.UUID) (*BankTransaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	txn, err := scanBankTransaction(s.db.QueryRowContext(ctx, bankTransactionQuery+`WHERE t.id = $1`, txnID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return txn, nil
+}
+
+// GetOpenInvoices returns the invoices of a business with a balance still
+// due, oldest first.
+func (s *BankStore) GetOpenInvoices(ctx context.Context, busID uuid.UUID) ([]*OpenInvoice, error) {
+	query := `
+        SELECT
+            i.id,
+            i.inv_no,
+            i.inv_date,
+            c.id,
+            c.name,
+            i.total_amount,
+            COALESCE(p.amount, 0)
+        FROM invoice i
+        JOIN customer c ON c.id = i.cust_id
+        LEFT JOIN (
+            SELECT inv_id, SUM(amount) AS amount
+            FROM payment
+            WHERE buss_id = $1
+            GROUP BY inv_id
+        ) p ON p.inv_id = i.id
+        WHERE i.buss_id = $1 AND i.total_amount > COALESCE(p.amount, 0)
+        ORDER BY i.inv_date, i.inv_no
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, busID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoices := []*OpenInvoice{}
+	for rows.Next() {
+		invoice := &OpenInvoice{}
+		err := rows.Scan(
+			&invoice.InvID,
+			&invoice.InvNo,
+			&invoice.InvDate,
+			&invoice.CustID,
+			&invoice.CustName,
+			&invoice.Total,
+			&invoice.Paid,
+		)
+		if err != nil {
+			return nil, err
+		}
+		invoice.Balance = invoice.Total - invoice.Paid
+		invoices = append(invoices, invoice)
+	}
+
+	return invoices, rows.Err()
+}
+
+// Match records a credit as a payment against an invoice of the same
+// business and links the two. The whole credit is applied to the invoice.
+func (s *BankStore) Match(ctx context.Context, txnID uuid.UUID, invoiceID uuid.UUID) (*Payment, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	payment := &Payment{InvID: invoiceID, Method: "bank"}
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var (
+			busID       uuid.UUID
+			paymentID   *uuid.UUID
+			description string
+			reference   string
+		)
+		err := tx.QueryRowContext(ctx, `
+            SELECT buss_id, txn_date, amount, description, reference, payment_id
+            FROM bank_transaction
+            WHERE id = $1
+            FOR UPDATE
+        `, txnID).Scan(&busID, &payment.PaidOn, &payment.Amount, &description, &reference, &paymentID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		if paymentID != nil {
+			return ErrTransactionMatched
+		}
+		if payment.Amount <= 0 {
+			return ErrNotACredit
+		}
+
+		var invoiceBusID uuid.UUID
+		err = tx.QueryRowContext(ctx, `SELECT buss_id FROM invoice WHERE id = $1`, invoiceID).Scan(&invoiceBusID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrNotFound
+			}
+			return err
+		}
+		if invoiceBusID != busID {
+			return ErrNotFound
+		}
+
+		if reference == "" {
+			reference = description
+		}
+		if runes := []rune(reference); len(runes) > 100 {
+			reference = string(runes[:100])
+		}
+		payment.Reference = reference
+
+		if err := createPayment(ctx, tx, payment); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+            UPDATE bank_transaction
+            SET payment_id = $2,
+                ignored = FALSE
+            WHERE id = $1
+        `, txnID, payment.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// Unmatch removes the payment recorded from a transaction, leaving the
+// transaction unmatched again.
+func (s *BankStore) Unmatch(ctx context.Context, txnID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var paymentID *uuid.UUID
+		err := tx.QueryRowContext(ctx, `SELECT payment_id FROM bank_transaction WHERE id = $1 FOR UPDATE`, txnID).Scan(&paymentID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrNotFound
+			}
+			return err
+		}
+		if paymentID == nil {
+			return ErrTransactionUnmatched
+		}
+
+		var invoiceID uuid.UUID
+		err = tx.QueryRowContext(ctx, `DELETE FROM payment WHERE id = $1 RETURNING inv_id`, *paymentID).Scan(&invoiceID)
+		if err != nil {
+			return err
+		}
+
+		_, _, err = syncInvoicePaid(ctx, tx, invoiceID)
+		return err
+	})
+}
+
+// SetIgnored marks a transaction that is not an invoice payment, such as a
+// transfer or a refund, so it leaves the review list.
+func (s *BankStore) SetIgnored(ctx context.Context, txnID uuid.UUID, ignored bool) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `
+        UPDATE bank_transaction
+        SET ignored = $2
+        WHERE id = $1 AND payment_id IS NULL
+    `, txnID, ignored)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		if _, err := s.GetTransaction(ctx, txnID); err != nil {
+			return err
+		}
+		return ErrTransactionMatched
+	}
+
+	return nil
+}
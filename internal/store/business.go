@@ -13,6 +13,7 @@ type BusinessStore struct {
 	db *sql.DB
 }
 
+// Create adds a business with its creator as the owner.
 func (s *BusinessStore) Create(ctx context.Context, business *Business) error {
 	query := `
         INSERT INTO business (user_id, name, gstno, company_email, company_phone, address, city, zip_code, state, country, bank_name, account_no, ifsc, bank_branch)
@@ -23,37 +24,48 @@ func (s *BusinessStore) Create(ctx context.Context, business *Business) error {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		business.UserID,
-		business.Name,
-		business.GSTNo,
-		business.CompanyEmail,
-		business.CompanyPhone,
-		business.Address,
-		business.City,
-		business.ZipCode,
-		business.State,
-		business.Country,
-		business.BankName,
-		business.AccountNo,
-		business.IFSC,
-		business.BankBranch,
-	).Scan(
-		&business.ID,
-		&business.CreatedAt,
-	)
-	if err != nil {
-		return err
-	}
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			business.UserID,
+			business.Name,
+			business.GSTNo,
+			business.CompanyEmail,
+			business.CompanyPhone,
+			business.Address,
+			business.City,
+			business.ZipCode,
+			business.State,
+			business.Country,
+			business.BankName,
+			business.AccountNo,
+			business.IFSC,
+			business.BankBranch,
+		).Scan(
+			&business.ID,
+			&business.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		_, err = tx.ExecContext(ctx, `
+            INSERT INTO business_member (buss_id, user_id, role)
+            VALUES ($1, $2, 'owner')
+        `, business.ID, business.UserID)
+		if err != nil {
+			return err
+		}
+		business.Role = RoleOwner
+
+		return nil
+	})
 }
 
 func (s *BusinessStore) GetByID(ctx context.Context, businessID uuid.UUID) (*Business, error) {
 	query := `
-        SELECT buss_id, user_id, name, gstno, company_email, company_phone, address, city, zip_code, state, country, bank_name, account_no, ifsc, bank_branch, created_at, updated_at
+        SELECT buss_id, user_id, name, gstno, company_email, company_phone, address, city, zip_code, state, country, bank_name, account_no, ifsc, bank_branch, require_mfa, created_at, updated_at
         FROM business
         WHERE buss_id = $1
     `
@@ -82,6 +94,7 @@ func (s *BusinessStore) GetByID(ctx context.Context, businessID uuid.UUID) (*Bus
 		&business.AccountNo,
 		&business.IFSC,
 		&business.BankBranch,
+		&business.RequireMFA,
 		&business.CreatedAt,
 		&business.UpdatedAt,
 	)
@@ -95,11 +108,15 @@ func (s *BusinessStore) GetByID(ctx context.Context, businessID uuid.UUID) (*Bus
 	return business, nil
 }
 
+// GetByUserID lists the businesses the user is a member of, with their
+// role in each.
 func (s *BusinessStore) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*Business, error) {
 	query := `
-        SELECT buss_id, user_id, name, gstno
-        FROM business
-        WHERE user_id = $1
+        SELECT b.buss_id, b.user_id, b.name, b.gstno, m.role
+        FROM business b
+        JOIN business_member m ON m.buss_id = b.buss_id
+        WHERE m.user_id = $1
+        ORDER BY b.name
     `
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -119,6 +136,7 @@ func (s *BusinessStore) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*B
 			&business.UserID,
 			&business.Name,
 			&business.GSTNo,
+			&business.Role,
 		)
 		if err != nil {
 			return nil, err
@@ -133,26 +151,26 @@ func (s *BusinessStore) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*B
 	return businesses, nil
 }
 
+// Update changes a business's details. Its owner is not changed.
 func (s *BusinessStore) Update(ctx context.Context, business *Business) error {
 	query := `
         UPDATE business
-        SET user_id = $2,
-            name = $3,
-            gstno = $4,
-            company_email = $5,
-            company_phone = $6,
-            address = $7,
-            city = $8,
-            zip_code = $9,
-            state = $10,
-            country = $11,
-            bank_name = $12,
-            account_no = $13,
-            ifsc = $14,
-            bank_branch = $15,
-            updated_at = $16
+        SET name = $2,
+            gstno = $3,
+            company_email = $4,
+            company_phone = $5,
+            address = $6,
+            city = $7,
+            zip_code = $8,
+            state = $9,
+            country = $10,
+            bank_name = $11,
+            account_no = $12,
+            ifsc = $13,
+            bank_branch = $14,
+            updated_at = $15
         WHERE buss_id = $1
-        RETURNING created_at, updated_at
+        RETURNING user_id, require_mfa, created_at, updated_at
     `
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -162,7 +180,6 @@ func (s *BusinessStore) Update(ctx context.Context, business *Business) error {
 		ctx,
 		query,
 		business.ID,
-		business.UserID,
 		business.Name,
 		business.GSTNo,
 		business.CompanyEmail,
@@ -178,6 +195,8 @@ func (s *BusinessStore) Update(ctx context.Context, business *Business) error {
 		business.BankBranch,
 		time.Now(),
 	).Scan(
+		&business.UserID,
+		&business.RequireMFA,
 		&business.CreatedAt,
 		&business.UpdatedAt,
 	)
@@ -191,6 +210,35 @@ func (s *BusinessStore) Update(ctx context.Context, business *Business) error {
 	return nil
 }
 
+// SetRequireMFA sets whether members of the business must use two-factor
+// authentication.
+func (s *BusinessStore) SetRequireMFA(ctx context.Context, businessID uuid.UUID, require bool) error {
+	query := `
+        UPDATE business
+        SET require_mfa = $2, updated_at = now()
+        WHERE buss_id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, businessID, require)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
 func (s *BusinessStore) Delete(ctx context.Context, businessID uuid.UUID) error {
 	query := `
 		DELETE FROM business
@@ -209,13 +257,19 @@ func (s *BusinessStore) Delete(ctx context.Context, businessID uuid.UUID) error
 }
 
 func (s *BusinessStore) GetDashboard(ctx context.Context, businessID uuid.UUID, from time.Time, to time.Time) (*Dashboard, error) {
-    query := `
+	query := `
         WITH filtered_invoices AS (
             SELECT 
-                is_paid, 
-                total_amount 
-            FROM invoice 
-            WHERE buSs_id = $1 AND inv_date >= $2 AND inv_date <= $3
+                i.is_paid, 
+                i.total_amount,
+                i.total_amount - COALESCE(p.amount, 0) AS amount_due
+            FROM invoice i
+            LEFT JOIN (
+                SELECT inv_id, SUM(amount) AS amount
+                FROM payment
+                GROUP BY inv_id
+            ) p ON p.inv_id = i.id
+            WHERE i.buss_id = $1 AND i.inv_date >= $2 AND i.inv_date <= $3
         ),
         recent_invoices AS (
             SELECT 
@@ -227,12 +281,32 @@ func (s *BusinessStore) GetDashboard(ctx context.Context, businessID uuid.UUID,
             WHERE buss_id = $1
             ORDER BY inv_date DESC
             LIMIT 4
+        ),
+        filtered_bills AS (
+            SELECT
+                b.total_amount,
+                b.total_amount - COALESCE(p.amount, 0) AS amount_due
+            FROM purchase_bill b
+            LEFT JOIN (
+                SELECT bill_id, SUM(amount) AS amount
+                FROM bill_payment
+                GROUP BY bill_id
+            ) p ON p.bill_id = b.id
+            WHERE b.buss_id = $1 AND b.bill_date >= $2 AND b.bill_date <= $3
         )
         SELECT
             COUNT(*) AS total_invoices,
             COUNT(*) FILTER (WHERE is_paid = false) AS pending_invoices,
             SUM(total_amount) AS total_revenue,
-            SUM(total_amount) FILTER (WHERE is_paid = false) AS unpaid_amount,
+            SUM(amount_due) FILTER (WHERE amount_due > 0) AS unpaid_amount,
+            (SELECT COALESCE(SUM(total_amount), 0) FROM filtered_bills) AS total_purchases,
+            (SELECT COUNT(*) FROM filtered_bills WHERE amount_due > 0) AS pending_bills,
+            (SELECT COALESCE(SUM(amount_due), 0) FROM filtered_bills WHERE amount_due > 0) AS payables,
+            (
+                SELECT COALESCE(SUM(amount + tax_amount), 0)
+                FROM expense
+                WHERE buss_id = $1 AND expense_date >= $2 AND expense_date <= $3
+            ) AS total_expenses,
             (
                 SELECT json_agg(recent_invoices)
                 FROM recent_invoices
@@ -240,25 +314,29 @@ func (s *BusinessStore) GetDashboard(ctx context.Context, businessID uuid.UUID,
         FROM filtered_invoices
     `
 
-    dashboard := &Dashboard{}
-	
+	dashboard := &Dashboard{}
+
 	var recentInvoicesJSON []byte
 	var totalRevenue sql.NullFloat64
 	var unpaidAmount sql.NullFloat64
-    err := s.db.QueryRowContext(
-        ctx,
-        query,
-        businessID,
-        from,
-        to,
-    ).Scan(
-        &dashboard.TotalInvoices,
-        &dashboard.PendingInvoices,
-        &totalRevenue,
-        &unpaidAmount,
-        &recentInvoicesJSON,
-    )
-    if err != nil {
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		businessID,
+		from,
+		to,
+	).Scan(
+		&dashboard.TotalInvoices,
+		&dashboard.PendingInvoices,
+		&totalRevenue,
+		&unpaidAmount,
+		&dashboard.TotalPurchases,
+		&dashboard.PendingBills,
+		&dashboard.Payables,
+		&dashboard.TotalExpenses,
+		&recentInvoicesJSON,
+	)
+	if err != nil {
 		switch err {
 		case sql.ErrNoRows:
 			return nil, ErrNotFound
@@ -267,20 +345,19 @@ func (s *BusinessStore) GetDashboard(ctx context.Context, businessID uuid.UUID,
 		}
 	}
 
-	if !totalRevenue.Valid {
-        dashboard.TotalRevenue = 0
-    }
-    if !unpaidAmount.Valid {
-        dashboard.UnpaidAmount = 0
-    }
-
-    var recentInvoices []Invoice
-    if len(recentInvoicesJSON) > 0 {
-        if err := json.Unmarshal(recentInvoicesJSON, &recentInvoices); err != nil {
-            return nil, err
-        }
-    }
-    dashboard.RecentInvoices = recentInvoices
-
-    return dashboard, nil
+	dashboard.TotalRevenue = totalRevenue.Float64
+	dashboard.UnpaidAmount = unpaidAmount.Float64
+	// Net profit is on a gross basis: invoice totals less purchase bills and
+	// expenses, all including GST.
+	dashboard.NetProfit = dashboard.TotalRevenue - dashboard.TotalPurchases - dashboard.TotalExpenses
+
+	var recentInvoices []Invoice
+	if len(recentInvoicesJSON) > 0 {
+		if err := json.Unmarshal(recentInvoicesJSON, &recentInvoices); err != nil {
+			return nil, err
+		}
+	}
+	dashboard.RecentInvoices = recentInvoices
+
+	return dashboard, nil
 }
@@ -0,0 +1,192 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDuplicateCategory = errors.New("category already exists")
+	ErrCategoryCycle     = errors.New("a category cannot be moved under itself")
+	ErrInvalidCategory   = errors.New("category does not exist for this business")
+)
+
+type CategoryStore struct {
+	db *sql.DB
+}
+
+func (s *CategoryStore) Create(ctx context.Context, category *Category) error {
+	query := `
+        INSERT INTO category (buss_id, parent_id, name)
+        VALUES ($1, $2, $3)
+        RETURNING id, created_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		category.BusID,
+		category.ParentID,
+		category.Name,
+	).Scan(
+		&category.ID,
+		&category.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateCategory
+		case isForeignKeyViolation(err):
+			return ErrInvalidCategory
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetByBusID returns the category tree flattened depth first, each category
+// carrying its full path such as "Beverages > Tea".
+func (s *CategoryStore) GetByBusID(ctx context.Context, busID uuid.UUID) ([]*Category, error) {
+	query := `
+        WITH RECURSIVE tree AS (
+            SELECT id, buss_id, parent_id, name, created_at, name::text AS path
+            FROM category
+            WHERE buss_id = $1 AND parent_id IS NULL
+            UNION ALL
+            SELECT c.id, c.buss_id, c.parent_id, c.name, c.created_at, tree.path || ' > ' || c.name
+            FROM category c
+            JOIN tree ON c.parent_id = tree.id
+            WHERE c.buss_id = $1
+        )
+        SELECT id, buss_id, parent_id, name, path, created_at
+        FROM tree
+        ORDER BY path
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, busID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*Category{}
+	for rows.Next() {
+		category := &Category{}
+		err := rows.Scan(
+			&category.ID,
+			&category.BusID,
+			&category.ParentID,
+			&category.Name,
+			&category.Path,
+			&category.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+func (s *CategoryStore) Update(ctx context.Context, category *Category) error {
+	// The new parent must not be the category itself or one of its
+	// descendants, otherwise the tree would become a cycle.
+	cycleQuery := `
+        WITH RECURSIVE subtree AS (
+            SELECT id FROM category WHERE id = $1
+            UNION ALL
+            SELECT c.id FROM category c JOIN subtree ON c.parent_id = subtree.id
+        )
+        SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $2)
+    `
+
+	query := `
+        UPDATE category
+        SET parent_id = $2,
+            name = $3
+        WHERE id = $1
+        RETURNING buss_id, created_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if category.ParentID != nil {
+			var cycle bool
+			if err := tx.QueryRowContext(ctx, cycleQuery, category.ID, *category.ParentID).Scan(&cycle); err != nil {
+				return err
+			}
+			if cycle {
+				return ErrCategoryCycle
+			}
+		}
+
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			category.ID,
+			category.ParentID,
+			category.Name,
+		).Scan(
+			&category.BusID,
+			&category.CreatedAt,
+		)
+		if err != nil {
+			switch {
+			case err == sql.ErrNoRows:
+				return ErrNotFound
+			case isUniqueViolation(err):
+				return ErrDuplicateCategory
+			case isForeignKeyViolation(err):
+				return ErrInvalidCategory
+			default:
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *CategoryStore) Delete(ctx context.Context, categoryID uuid.UUID) error {
+	query := `
+        DELETE FROM category
+        WHERE id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, categoryID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
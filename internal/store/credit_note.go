@@ -0,0 +1,456 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCreditNoteQuantity   = errors.New("credit note quantity exceeds what is left to credit on the invoice")
+	ErrCreditNoteExceedsDue = errors.New("credit note exceeds the amount due on the invoice")
+	ErrCreditNoteEmpty      = errors.New("credit note total must be more than zero")
+	ErrInvoiceCredited      = errors.New("invoice has credit notes against it")
+	ErrInvoiceHasPayments   = errors.New("invoice has payments or credit notes against it")
+)
+
+type CreditNoteStore struct {
+	db *sql.DB
+}
+
+// creditLine is what is left to credit of the lines of an invoice billed at
+// one price and tax rate.
+type creditLine struct {
+	ProdID    uuid.UUID
+	VariantID *uuid.UUID
+	UnitPrice float64
+	TaxRate   float64
+	Remaining float64
+}
+
+// toThousandths compares quantities at the precision they are stored in.
+func toThousandths(quantity float64) int64 {
+	return int64(math.Round(quantity * 1000))
+}
+
+// allocateCredit prices the requested items from the invoice lines they are
+// credited against. An item is taken from the lines of the same product and
+// variant that still have quantity left, highest price first, and is split
+// into one credit note item per line it draws on.
+func allocateCredit(lines []*creditLine, requested []*CreditNoteItem) ([]*CreditNoteItem, error) {
+	var items []*CreditNoteItem
+	for _, want := range requested {
+		left := toThousandths(want.Quantity)
+		for _, line := range lines {
+			if left <= 0 {
+				break
+			}
+			if line.ProdID != want.ProdID || !sameVariant(line.VariantID, want.VariantID) {
+				continue
+			}
+
+			take := min(left, toThousandths(line.Remaining))
+			if take <= 0 {
+				continue
+			}
+			line.Remaining -= float64(take) / 1000
+			left -= take
+
+			items = append(items, &CreditNoteItem{
+				ProdID:    want.ProdID,
+				VariantID: want.VariantID,
+				Quantity:  float64(take) / 1000,
+				UnitPrice: line.UnitPrice,
+				TaxRate:   line.TaxRate,
+			})
+		}
+		if left > 0 {
+			return nil, ErrCreditNoteQuantity
+		}
+	}
+
+	return items, nil
+}
+
+// creditNoteTotal is what a credit note takes off the invoice: the taxable
+// value of its items with their tax, rounded the way invoice totals are.
+func creditNoteTotal(items []*CreditNoteItem) float64 {
+	var total float64
+	for _, item := range items {
+		taxable := item.UnitPrice * item.Quantity
+		total += taxable + taxable*item.TaxRate/100
+	}
+	return math.Round(total*100) / 100
+}
+
+// remainingCredit lists the lines of an invoice by product, variant, price and
+// tax rate with the quantity earlier credit notes have not yet taken.
+func remainingCredit(ctx context.Context, tx *sql.Tx, invoiceID uuid.UUID) ([]*creditLine, error) {
+	rows, err := tx.QueryContext(ctx, `
+        SELECT ii.prod_id, ii.variant_id, ii.unit_price, ii.tax_rate, SUM(ii.quantity) - COALESCE((
+            SELECT SUM(ci.quantity)
+            FROM credit_note_item ci
+            JOIN credit_note cn ON cn.id = ci.cn_id
+            WHERE cn.inv_id = $1
+                AND ci.prod_id = ii.prod_id
+                AND ci.variant_id IS NOT DISTINCT FROM ii.variant_id
+                AND ci.unit_price = ii.unit_price
+                AND ci.tax_rate = ii.tax_rate
+        ), 0)
+        FROM invoice_item ii
+        WHERE ii.inv_id = $1
+        GROUP BY ii.prod_id, ii.variant_id, ii.unit_price, ii.tax_rate
+        ORDER BY ii.unit_price DESC
+    `, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []*creditLine
+	for rows.Next() {
+		line := &creditLine{}
+		if err := rows.Scan(&line.ProdID, &line.VariantID, &line.UnitPrice, &line.TaxRate, &line.Remaining); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, rows.Err()
+}
+
+// lockInvoiceForCredit locks an invoice and its business, so that two
+// credit notes issued at once neither race for a number nor both take the
+// same balance, and returns what is still due on it.
+func lockInvoiceForCredit(ctx context.Context, tx *sql.Tx, note *CreditNote) (float64, error) {
+	var locked uuid.UUID
+	err := tx.QueryRowContext(ctx, `
+        SELECT b.buss_id
+        FROM business b
+        JOIN invoice i ON i.buss_id = b.buss_id
+        WHERE i.id = $1
+        FOR UPDATE OF b
+    `, note.InvID).Scan(&locked)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+
+	var due float64
+	err = tx.QueryRowContext(ctx, `
+        SELECT i.buss_id, i.inv_no, i.total_amount - COALESCE((SELECT SUM(amount) FROM payment WHERE inv_id = i.id), 0)
+        FROM invoice i
+        WHERE i.id = $1
+        FOR UPDATE
+    `, note.InvID).Scan(&note.BusID, &note.InvNo, &due)
+	return due, err
+}
+
+// issueCreditNote writes a credit note with its priced items and applies its
+// total to the invoice's balance. Goods are taken back into stock if
+// note.Restock is set, and the note is posted to the ledger.
+func issueCreditNote(ctx context.Context, tx *sql.Tx, note *CreditNote, items []*CreditNoteItem) error {
+	err := tx.QueryRowContext(ctx, `
+        INSERT INTO credit_note (buss_id, inv_id, cn_no, cn_date, reason, restock, total_amount)
+        SELECT $1, $2, COALESCE(MAX(cn_no), 0) + 1, $3, $4, $5, $6
+        FROM credit_note
+        WHERE buss_id = $1
+        RETURNING id, cn_no, created_at
+    `, note.BusID, note.InvID, note.CNDate, note.Reason, note.Restock, note.TotalAmount).Scan(
+		&note.ID,
+		&note.CNNo,
+		&note.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		item.CNID = note.ID
+		err := tx.QueryRowContext(ctx, `
+            INSERT INTO credit_note_item (cn_id, prod_id, variant_id, quantity, unit_price, tax_rate)
+            VALUES ($1, $2, $3, $4, $5, $6)
+            RETURNING id
+        `, item.CNID, item.ProdID, item.VariantID, item.Quantity, item.UnitPrice, item.TaxRate).Scan(&item.ID)
+		if err != nil {
+			return err
+		}
+
+		if note.Restock {
+			if err := postCreditNoteItemStock(ctx, tx, note, item); err != nil {
+				return err
+			}
+		}
+	}
+	note.Items = items
+
+	if err := repostCreditNote(ctx, tx, note.ID); err != nil {
+		return err
+	}
+
+	return createPayment(ctx, tx, &Payment{
+		InvID:        note.InvID,
+		Amount:       note.TotalAmount,
+		PaidOn:       note.CNDate,
+		Method:       "credit_note",
+		Reference:    fmt.Sprintf("Credit note #%d", note.CNNo),
+		CreditNoteID: &note.ID,
+	})
+}
+
+// Create issues a credit note for the requested items of an invoice. Each
+// item names a product, and optionally a variant, billed on the invoice and
+// the quantity to credit; prices and tax rates come from the invoice.
+func (s *CreditNoteStore) Create(ctx context.Context, note *CreditNote) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		due, err := lockInvoiceForCredit(ctx, tx, note)
+		if err != nil {
+			return err
+		}
+
+		lines, err := remainingCredit(ctx, tx, note.InvID)
+		if err != nil {
+			return err
+		}
+
+		items, err := allocateCredit(lines, note.Items)
+		if err != nil {
+			return err
+		}
+
+		note.TotalAmount = creditNoteTotal(items)
+		if toPaise(note.TotalAmount) <= 0 {
+			return ErrCreditNoteEmpty
+		}
+		if toPaise(note.TotalAmount) > toPaise(due) {
+			return ErrCreditNoteExceedsDue
+		}
+
+		return issueCreditNote(ctx, tx, note, items)
+	})
+}
+
+// Void cancels an invoice that nothing has been paid or credited against by
+// issuing a credit note for all of it on the given date. Every item is taken
+// back into stock and the invoice no longer counts as owed.
+func (s *CreditNoteStore) Void(ctx context.Context, invoiceID uuid.UUID, date time.Time) (*CreditNote, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	note := &CreditNote{
+		InvID:   invoiceID,
+		CNDate:  date,
+		Reason:  "Invoice voided",
+		Restock: true,
+	}
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		due, err := lockInvoiceForCredit(ctx, tx, note)
+		if err != nil {
+			return err
+		}
+
+		var paid bool
+		err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM payment WHERE inv_id = $1)`, invoiceID).Scan(&paid)
+		if err != nil {
+			return err
+		}
+		if paid {
+			return ErrInvoiceHasPayments
+		}
+
+		lines, err := remainingCredit(ctx, tx, invoiceID)
+		if err != nil {
+			return err
+		}
+
+		items := make([]*CreditNoteItem, 0, len(lines))
+		for _, line := range lines {
+			if toThousandths(line.Remaining) <= 0 {
+				continue
+			}
+			items = append(items, &CreditNoteItem{
+				ProdID:    line.ProdID,
+				VariantID: line.VariantID,
+				Quantity:  line.Remaining,
+				UnitPrice: line.UnitPrice,
+				TaxRate:   line.TaxRate,
+			})
+		}
+
+		// The whole invoice is cancelled, so the note is for its total even
+		// where that was not worked out from the items.
+		note.TotalAmount = due
+		if toPaise(note.TotalAmount) <= 0 {
+			return ErrCreditNoteEmpty
+		}
+
+		return issueCreditNote(ctx, tx, note, items)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return note, nil
+}
+
+const creditNoteQuery = `
+    SELECT cn.id, cn.buss_id, cn.inv_id, i.inv_no, cn.cn_no, cn.cn_date, cn.reason, cn.restock, cn.total_amount, cn.created_at
+    FROM credit_note cn
+    JOIN invoice i ON i.id = cn.inv_id
+`
+
+func scanCreditNote(row interface{ Scan(...any) error }) (*CreditNote, error) {
+	note := &CreditNote{}
+	err := row.Scan(
+		&note.ID,
+		&note.BusID,
+		&note.InvID,
+		&note.InvNo,
+		&note.CNNo,
+		&note.CNDate,
+		&note.Reason,
+		&note.Restock,
+		&note.TotalAmount,
+		&note.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return note, nil
+}
+
+func (s *CreditNoteStore) GetByID(ctx context.Context, creditNoteID uuid.UUID) (*CreditNote, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	note, err := scanCreditNote(s.db.QueryRowContext(ctx, creditNoteQuery+`WHERE cn.id = $1`, creditNoteID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	note.Items, err = s.getItems(ctx, note.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return note, nil
+}
+
+func (s *CreditNoteStore) GetByInvoiceID(ctx context.Context, invoiceID uuid.UUID) ([]*CreditNote, error) {
+	query := creditNoteQuery + `
+        WHERE cn.inv_id = $1
+        ORDER BY cn.cn_date, cn.cn_no
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []*CreditNote{}
+	for rows.Next() {
+		note, err := scanCreditNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, note := range notes {
+		note.Items, err = s.getItems(ctx, note.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return notes, nil
+}
+
+func (s *CreditNoteStore) getItems(ctx context.Context, creditNoteID uuid.UUID) ([]*CreditNoteItem, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT ci.id, ci.cn_id, ci.prod_id, ci.variant_id, COALESCE(v.name, ''), ci.quantity, ci.unit_price, ci.tax_rate
+        FROM credit_note_item ci
+        LEFT JOIN product_variant v ON v.id = ci.variant_id
+        WHERE ci.cn_id = $1
+    `, creditNoteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*CreditNoteItem{}
+	for rows.Next() {
+		item := &CreditNoteItem{}
+		err := rows.Scan(
+			&item.ID,
+			&item.CNID,
+			&item.ProdID,
+			&item.VariantID,
+			&item.VariantName,
+			&item.Quantity,
+			&item.UnitPrice,
+			&item.TaxRate,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// Delete withdraws a credit note: the invoice owes its amount again, any
+// stock it took back goes out again and its ledger entry is removed.
+func (s *CreditNoteStore) Delete(ctx context.Context, creditNoteID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := reverseCreditNoteStock(ctx, tx, creditNoteID); err != nil {
+			return err
+		}
+
+		var invoiceID uuid.UUID
+		err := tx.QueryRowContext(ctx, `DELETE FROM credit_note WHERE id = $1 RETURNING inv_id`, creditNoteID).Scan(&invoiceID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		_, _, err = syncInvoicePaid(ctx, tx, invoiceID)
+		return err
+	})
+}
+
+// hasCreditNotes reports whether any credit note has been issued against an
+// invoice, which then can no longer be edited or deleted.
+func hasCreditNotes(ctx context.Context, tx *sql.Tx, invoiceID uuid.UUID) (bool, error) {
+	var exists bool
+	err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM credit_note WHERE inv_id = $1)`, invoiceID).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestAllocateCredit(t *testing.T) {
+	shirt, mug := uuid.New(), uuid.New()
+	large := uuid.New()
+
+	newLines := func() []*creditLine {
+		// Ordered highest price first, as remainingCredit returns them.
+		return []*creditLine{
+			{ProdID: shirt, VariantID: &large, UnitPrice: 550, TaxRate: 12, Remaining: 1},
+			{ProdID: shirt, UnitPrice: 500, TaxRate: 12, Remaining: 3},
+			{ProdID: shirt, UnitPrice: 450, TaxRate: 12, Remaining: 2},
+			{ProdID: mug, UnitPrice: 120.5, TaxRate: 18, Remaining: 1.5},
+		}
+	}
+
+	type line struct {
+		quantity, price float64
+	}
+
+	tests := []struct {
+		name      string
+		requested []*CreditNoteItem
+		want      []line
+		wantErr   error
+	}{
+		{
+			name:      "within one line",
+			requested: []*CreditNoteItem{{ProdID: shirt, Quantity: 2}},
+			want:      []line{{2, 500}},
+		},
+		{
+			name:      "across lines, highest price first",
+			requested: []*CreditNoteItem{{ProdID: shirt, Quantity: 4}},
+			want:      []line{{3, 500}, {1, 450}},
+		},
+		{
+			name:      "variant kept apart",
+			requested: []*CreditNoteItem{{ProdID: shirt, VariantID: &large, Quantity: 1}},
+			want:      []line{{1, 550}},
+		},
+		{
+			name:      "fractional quantity",
+			requested: []*CreditNoteItem{{ProdID: mug, Quantity: 0.75}},
+			want:      []line{{0.75, 120.5}},
+		},
+		{
+			name: "repeated item draws on what is left",
+			requested: []*CreditNoteItem{
+				{ProdID: shirt, Quantity: 3},
+				{ProdID: shirt, Quantity: 2},
+			},
+			want: []line{{3, 500}, {2, 450}},
+		},
+		{
+			name:      "more than billed",
+			requested: []*CreditNoteItem{{ProdID: shirt, Quantity: 6}},
+			wantErr:   ErrCreditNoteQuantity,
+		},
+		{
+			name:      "variant more than billed",
+			requested: []*CreditNoteItem{{ProdID: shirt, VariantID: &large, Quantity: 2}},
+			wantErr:   ErrCreditNoteQuantity,
+		},
+		{
+			name:      "product not on the invoice",
+			requested: []*CreditNoteItem{{ProdID: uuid.New(), Quantity: 1}},
+			wantErr:   ErrCreditNoteQuantity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := allocateCredit(newLines(), tt.requested)
+			if err != tt.wantErr {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if len(items) != len(tt.want) {
+				t.Fatalf("got %d items, want %d", len(items), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if items[i].Quantity != want.quantity || items[i].UnitPrice != want.price {
+					t.Errorf("item %d = %v at %v, want %v at %v", i, items[i].Quantity, items[i].UnitPrice, want.quantity, want.price)
+				}
+			}
+		})
+	}
+}
+
+func TestCreditNoteTotal(t *testing.T) {
+	items := []*CreditNoteItem{
+		{Quantity: 3, UnitPrice: 500, TaxRate: 12},
+		{Quantity: 0.75, UnitPrice: 120.5, TaxRate: 18},
+	}
+
+	// 1680 + 106.6425, rounded to paise.
+	if got, want := creditNoteTotal(items), 1786.64; got != want {
+		t.Errorf("creditNoteTotal = %v, want %v", got, want)
+	}
+}
@@ -3,13 +3,21 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 var (
 	ErrDuplicateCustomer = errors.New("customer already exists")
+	ErrInvalidCursor     = errors.New("invalid cursor")
+	ErrInvalidCustomer   = errors.New("customer does not exist for this business")
 )
 
 type CustomerStore struct {
@@ -56,7 +64,7 @@ func (s *CustomerStore) Create(ctx context.Context, customer *Customer) error {
 
 func (s *CustomerStore) GetByID(ctx context.Context, id uuid.UUID) (*Customer, error) {
 	query := `
-		SELECT id, buss_id, name, gstno, email, phone, baddress, saddress, created_at
+		SELECT id, buss_id, name, gstno, email, phone, baddress, saddress, price_list_id, reminders_opt_out, created_at
 		FROM customer
 		WHERE id = $1
 	`
@@ -74,6 +82,8 @@ func (s *CustomerStore) GetByID(ctx context.Context, id uuid.UUID) (*Customer, e
 		&customer.Phone,
 		&customer.BAddress,
 		&customer.SAddress,
+		&customer.PriceListID,
+		&customer.RemindersOptOut,
 		&customer.CreatedAt,
 	)
 	if err != nil {
@@ -86,36 +96,106 @@ func (s *CustomerStore) GetByID(ctx context.Context, id uuid.UUID) (*Customer, e
 	return customer, nil
 }
 
-func (s *CustomerStore) GetByBusID(ctx context.Context, busID uuid.UUID) ([]*CustomerWithPendingAmount, error) {
-	query := `
-        SELECT 
-            c.id, 
-            c.buss_id, 
-            c.name, 
-            c.gstno, 
-            c.email, 
-            c.phone, 
-            c.baddress, 
-            c.saddress, 
-            c.created_at, 
-            COALESCE(SUM(i.total_amount), 0) AS pending_amount,
-            COUNT(i.id) AS total_invoices
-        FROM customer c
-        LEFT JOIN invoice i ON c.id = i.cust_id
-        WHERE c.buss_id = $1
-        GROUP BY c.id
-    `
+// GetByBusID lists a business's customers a page at a time. A customer's
+// pending amount is what is still due on their invoices after payments,
+// partial ones and credit notes included.
+func (s *CustomerStore) GetByBusID(ctx context.Context, busID uuid.UUID, filter CustomerFilter) (*CustomerPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+
+	sortColumn, ok := customerSortColumns[filter.Sort]
+	if !ok {
+		sortColumn = "name"
+	}
+
+	direction, comparator := "ASC", ">"
+	if filter.Order == "desc" {
+		direction, comparator = "DESC", "<"
+	}
+
+	args := []any{busID}
+	where := []string{"c.buss_id = $1"}
+	if filter.Query != "" {
+		args = append(args, containsPattern(filter.Query))
+		n := len(args)
+		where = append(where, fmt.Sprintf(
+			`(c.name ILIKE $%d ESCAPE '\' OR c.email::text ILIKE $%d ESCAPE '\' OR c.phone ILIKE $%d ESCAPE '\' OR c.gstno ILIKE $%d ESCAPE '\')`,
+			n, n, n, n,
+		))
+	}
+
+	having := ""
+	if filter.HasPending != nil {
+		if *filter.HasPending {
+			having = "HAVING COALESCE(SUM(i.total_amount - COALESCE(p.amount, 0)), 0) > 0"
+		} else {
+			having = "HAVING COALESCE(SUM(i.total_amount - COALESCE(p.amount, 0)), 0) <= 0"
+		}
+	}
+
+	base := fmt.Sprintf(`
+        WITH customers AS (
+            SELECT 
+                c.id, 
+                c.buss_id, 
+                c.name, 
+                c.gstno, 
+                c.email, 
+                c.phone, 
+                c.baddress, 
+                c.saddress, 
+                c.created_at, 
+                COALESCE(SUM(i.total_amount - COALESCE(p.amount, 0)), 0) AS pending_amount,
+                COUNT(i.id) AS total_invoices
+            FROM customer c
+            LEFT JOIN invoice i ON c.id = i.cust_id
+            LEFT JOIN (
+                SELECT inv_id, SUM(amount) AS amount
+                FROM payment
+                GROUP BY inv_id
+            ) p ON p.inv_id = i.id
+            WHERE %s
+            GROUP BY c.id
+            %s
+        )
+    `, strings.Join(where, " AND "), having)
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query, busID)
+	page := &CustomerPage{Customers: []*CustomerWithPendingAmount{}}
+	err := s.db.QueryRowContext(ctx, base+`SELECT COUNT(*) FROM customers`, args...).Scan(&page.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	cursorClause := ""
+	if filter.Cursor != "" {
+		value, id, err := decodeCustomerCursor(filter.Cursor, sortColumn)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value, id)
+		cursorClause = fmt.Sprintf("WHERE (%s, id) %s ($%d, $%d)", sortColumn, comparator, len(args)-1, len(args))
+	}
+
+	// Fetch one extra row to know whether another page follows.
+	args = append(args, filter.Limit+1)
+	query := base + fmt.Sprintf(`
+        SELECT id, buss_id, name, gstno, email, phone, baddress, saddress, created_at, pending_amount, total_invoices
+        FROM customers
+        %s
+        ORDER BY %s %s, id %s
+        LIMIT $%d
+    `, cursorClause, sortColumn, direction, direction, len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var customers []*CustomerWithPendingAmount
 	for rows.Next() {
 		customer := &CustomerWithPendingAmount{}
 		err := rows.Scan(
@@ -134,14 +214,82 @@ func (s *CustomerStore) GetByBusID(ctx context.Context, busID uuid.UUID) ([]*Cus
 		if err != nil {
 			return nil, err
 		}
-		customers = append(customers, customer)
+		page.Customers = append(page.Customers, customer)
 	}
-	
+
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return customers, nil
+	if len(page.Customers) > filter.Limit {
+		page.Customers = page.Customers[:filter.Limit]
+		page.NextCursor = encodeCustomerCursor(page.Customers[filter.Limit-1], sortColumn)
+	}
+
+	return page, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern turns a search term into an ILIKE pattern (with ESCAPE
+// '\') matching it anywhere. Wildcards in the term match only themselves.
+func containsPattern(term string) string {
+	return "%" + likeEscaper.Replace(term) + "%"
+}
+
+var customerSortColumns = map[string]string{
+	"name":           "name",
+	"pending_amount": "pending_amount",
+	"created_at":     "created_at",
+}
+
+type customerCursor struct {
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+func encodeCustomerCursor(customer *CustomerWithPendingAmount, sortColumn string) string {
+	cursor := customerCursor{ID: customer.ID}
+	switch sortColumn {
+	case "pending_amount":
+		cursor.Value = strconv.FormatFloat(customer.PendingAmount, 'f', -1, 64)
+	case "created_at":
+		cursor.Value = customer.CreatedAt.Format(time.RFC3339Nano)
+	default:
+		cursor.Value = customer.Name
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCustomerCursor(encoded string, sortColumn string) (any, uuid.UUID, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, uuid.Nil, ErrInvalidCursor
+	}
+
+	var cursor customerCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, uuid.Nil, ErrInvalidCursor
+	}
+
+	switch sortColumn {
+	case "pending_amount":
+		value, err := strconv.ParseFloat(cursor.Value, 64)
+		if err != nil {
+			return nil, uuid.Nil, ErrInvalidCursor
+		}
+		return value, cursor.ID, nil
+	case "created_at":
+		value, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return nil, uuid.Nil, ErrInvalidCursor
+		}
+		return value, cursor.ID, nil
+	default:
+		return cursor.Value, cursor.ID, nil
+	}
 }
 
 func (s *CustomerStore) Update(ctx context.Context, customer *Customer) error {
@@ -208,3 +356,120 @@ func (s *CustomerStore) Delete(ctx context.Context, customerID uuid.UUID) error
 
 	return nil
 }
+
+// Import upserts customers on (buss_id, email, phone) in a single transaction
+// and reports for each one whether it was newly created. With dryRun set the
+// transaction is rolled back after every row has been written.
+func (s *CustomerStore) Import(ctx context.Context, customers []*Customer, dryRun bool) ([]bool, error) {
+	query := `
+        INSERT INTO customer (buss_id, name, gstno, email, phone, baddress, saddress)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        ON CONFLICT (buss_id, email, phone) DO UPDATE
+        SET name = EXCLUDED.name,
+            gstno = EXCLUDED.gstno,
+            baddress = EXCLUDED.baddress,
+            saddress = EXCLUDED.saddress
+        RETURNING id, created_at, (xmax = 0) AS inserted
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, ImportTimeoutDuration)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	created := make([]bool, len(customers))
+	for i, customer := range customers {
+		err := stmt.QueryRowContext(
+			ctx,
+			customer.BusID,
+			customer.Name,
+			customer.GSTNo,
+			customer.Email,
+			customer.Phone,
+			customer.BAddress,
+			customer.SAddress,
+		).Scan(
+			&customer.ID,
+			&customer.CreatedAt,
+			&created[i],
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if dryRun {
+		return created, nil
+	}
+
+	return created, tx.Commit()
+}
+
+func (s *CustomerStore) SetPriceList(ctx context.Context, customerID uuid.UUID, priceListID *uuid.UUID) error {
+	query := `
+        UPDATE customer
+        SET price_list_id = $2
+        WHERE id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, customerID, priceListID)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// SetRemindersOptOut stops or resumes automated payment reminders to a
+// customer. Invoices can still be emailed to them by hand.
+func (s *CustomerStore) SetRemindersOptOut(ctx context.Context, customerID uuid.UUID, optOut bool) error {
+	query := `
+        UPDATE customer
+        SET reminders_opt_out = $2
+        WHERE id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, customerID, optOut)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
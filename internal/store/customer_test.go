@@ -0,0 +1,94 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestContainsPattern(t *testing.T) {
+	tests := []struct {
+		term string
+		want string
+	}{
+		{"acme", `%acme%`},
+		{"100%", `%100\%%`},
+		{"a_b", `%a\_b%`},
+		{`c:\temp`, `%c:\\temp%`},
+		{`\%_`, `%\\\%\_%`},
+		{"", `%%`},
+	}
+
+	for _, tt := range tests {
+		if got := containsPattern(tt.term); got != tt.want {
+			t.Errorf("containsPattern(%q) = %q, want %q", tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestGetByBusIDHasPending(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	// Acme paid 100 on an invoice later cut to 80, leaving it 20 in credit.
+	overpaid := newTestInvoice(t, s, 100)
+	if err := s.Payments.Create(ctx, &Payment{InvID: overpaid.ID, Amount: 100, PaidOn: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	overpaid.TotalAmount = 80
+	overpaid.IsPaid = true
+	if err := s.Invoices.Update(ctx, overpaid, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	owing := &Customer{BusID: overpaid.BusID, Name: "Beta Stores", GSTNo: "27AAACB1234B1Z2", Email: "accounts@beta.example", Phone: "9822222222"}
+	settled := &Customer{BusID: overpaid.BusID, Name: "Gamma Mart", GSTNo: "27AAACG1234G1Z3", Email: "accounts@gamma.example", Phone: "9833333333"}
+	for _, customer := range []*Customer{owing, settled} {
+		if err := s.Customers.Create(ctx, customer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := s.Invoices.Create(ctx, &Invoice{
+		InvNo:       2,
+		BusID:       owing.BusID,
+		CustID:      owing.ID,
+		TotalAmount: 50,
+		InvDate:     overpaid.InvDate,
+		DueDate:     overpaid.DueDate,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	yes, no := true, false
+	tests := []struct {
+		name       string
+		hasPending *bool
+		want       map[string]float64
+	}{
+		{"all", nil, map[string]float64{"Acme Retail": -20, "Beta Stores": 50, "Gamma Mart": 0}},
+		{"pending", &yes, map[string]float64{"Beta Stores": 50}},
+		{"nothing pending", &no, map[string]float64{"Acme Retail": -20, "Gamma Mart": 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := s.Customers.GetByBusID(ctx, overpaid.BusID, CustomerFilter{HasPending: tt.hasPending})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := map[string]float64{}
+			for _, customer := range page.Customers {
+				got[customer.Name] = customer.PendingAmount
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("customers = %v, want %v", got, tt.want)
+			}
+			if page.Total != len(tt.want) {
+				t.Errorf("Total = %d, want %d", page.Total, len(tt.want))
+			}
+		})
+	}
+}
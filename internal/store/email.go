@@ -0,0 +1,165 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	InvoiceStatusDraft = "draft"
+	InvoiceStatusSent  = "sent"
+
+	EmailDeliverySent   = "sent"
+	EmailDeliveryFailed = "failed"
+)
+
+type EmailStore struct {
+	db *sql.DB
+}
+
+// GetTemplate returns a business's template of the given kind, or
+// ErrNotFound when it still uses the default.
+func (s *EmailStore) GetTemplate(ctx context.Context, busID uuid.UUID, kind string) (*EmailTemplate, error) {
+	query := `
+        SELECT buss_id, kind, subject, body, updated_at
+        FROM email_template
+        WHERE buss_id = $1 AND kind = $2
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tmpl := &EmailTemplate{}
+	err := s.db.QueryRowContext(ctx, query, busID, kind).Scan(
+		&tmpl.BusID,
+		&tmpl.Kind,
+		&tmpl.Subject,
+		&tmpl.Body,
+		&tmpl.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+func (s *EmailStore) SaveTemplate(ctx context.Context, tmpl *EmailTemplate) error {
+	query := `
+        INSERT INTO email_template (buss_id, kind, subject, body)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (buss_id, kind) DO UPDATE
+        SET subject = EXCLUDED.subject,
+            body = EXCLUDED.body,
+            updated_at = now()
+        RETURNING updated_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, tmpl.BusID, tmpl.Kind, tmpl.Subject, tmpl.Body).Scan(&tmpl.UpdatedAt)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// CreateDelivery logs an email sent to a customer. A successful delivery of
+// an invoice also marks the invoice sent.
+func (s *EmailStore) CreateDelivery(ctx context.Context, delivery *EmailDelivery) error {
+	if delivery.CC == nil {
+		delivery.CC = []string{}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+            INSERT INTO email_delivery (buss_id, inv_id, kind, recipient, cc, subject, status, error)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+            RETURNING id, created_at
+        `,
+			delivery.BusID,
+			delivery.InvID,
+			delivery.Kind,
+			delivery.Recipient,
+			pq.Array(delivery.CC),
+			delivery.Subject,
+			delivery.Status,
+			delivery.Error,
+		).Scan(
+			&delivery.ID,
+			&delivery.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		if delivery.InvID == nil || delivery.Status != EmailDeliverySent {
+			return nil
+		}
+
+		_, err = tx.ExecContext(ctx, `
+            UPDATE invoice
+            SET status = $2,
+                sent_at = $3
+            WHERE id = $1
+        `, *delivery.InvID, InvoiceStatusSent, delivery.CreatedAt)
+		return err
+	})
+}
+
+// GetDeliveriesByInvoiceID lists the emails sent for an invoice, newest
+// first.
+func (s *EmailStore) GetDeliveriesByInvoiceID(ctx context.Context, invoiceID uuid.UUID) ([]*EmailDelivery, error) {
+	query := `
+        SELECT id, buss_id, inv_id, kind, recipient, cc, subject, status, error, created_at
+        FROM email_delivery
+        WHERE inv_id = $1
+        ORDER BY created_at DESC
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*EmailDelivery{}
+	for rows.Next() {
+		delivery := &EmailDelivery{}
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.BusID,
+			&delivery.InvID,
+			&delivery.Kind,
+			&delivery.Recipient,
+			pq.Array(&delivery.CC),
+			&delivery.Subject,
+			&delivery.Status,
+			&delivery.Error,
+			&delivery.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
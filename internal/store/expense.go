@@ -0,0 +1,346 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ExpenseStore struct {
+	db *sql.DB
+}
+
+const expenseQuery = `
+    SELECT
+        e.id,
+        e.buss_id,
+        e.vendor_id,
+        COALESCE(v.name, ''),
+        e.category,
+        e.description,
+        e.expense_date,
+        e.amount,
+        e.tax_amount,
+        e.payment_mode,
+        e.receipt_key,
+        e.receipt_name,
+        e.receipt_type,
+        e.created_at
+    FROM expense e
+    LEFT JOIN vendor v ON v.id = e.vendor_id
+`
+
+func scanExpense(row interface{ Scan(...any) error }) (*Expense, error) {
+	expense := &Expense{}
+	err := row.Scan(
+		&expense.ID,
+		&expense.BusID,
+		&expense.VendorID,
+		&expense.VendorName,
+		&expense.Category,
+		&expense.Description,
+		&expense.ExpenseDate,
+		&expense.Amount,
+		&expense.TaxAmount,
+		&expense.PaymentMode,
+		&expense.ReceiptKey,
+		&expense.ReceiptName,
+		&expense.ReceiptType,
+		&expense.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	expense.Total = expense.Amount + expense.TaxAmount
+
+	return expense, nil
+}
+
+func (s *ExpenseStore) Create(ctx context.Context, expense *Expense) error {
+	query := `
+        INSERT INTO expense (buss_id, vendor_id, category, description, expense_date, amount, tax_amount, payment_mode)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING id, created_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			expense.BusID,
+			expense.VendorID,
+			expense.Category,
+			expense.Description,
+			expense.ExpenseDate,
+			expense.Amount,
+			expense.TaxAmount,
+			expense.PaymentMode,
+		).Scan(
+			&expense.ID,
+			&expense.CreatedAt,
+		)
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		return repostExpense(ctx, tx, expense.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	expense.Total = expense.Amount + expense.TaxAmount
+
+	return nil
+}
+
+func (s *ExpenseStore) GetByID(ctx context.Context, expenseID uuid.UUID) (*Expense, error) {
+	query := expenseQuery + `WHERE e.id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	expense, err := scanExpense(s.db.QueryRowContext(ctx, query, expenseID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return expense, nil
+}
+
+// GetByBusID lists the expenses dated within the filter's range, newest
+// first, optionally narrowed to a category, vendor or payment mode.
+func (s *ExpenseStore) GetByBusID(ctx context.Context, busID uuid.UUID, filter ExpenseFilter) ([]*Expense, error) {
+	conditions := []string{"e.buss_id = $1", "e.expense_date BETWEEN $2 AND $3"}
+	args := []any{busID, filter.From.Format("2006-01-02"), filter.To.Format("2006-01-02")}
+
+	if filter.Category != "" {
+		args = append(args, filter.Category)
+		conditions = append(conditions, fmt.Sprintf("lower(e.category) = lower($%d)", len(args)))
+	}
+	if filter.VendorID != nil {
+		args = append(args, *filter.VendorID)
+		conditions = append(conditions, fmt.Sprintf("e.vendor_id = $%d", len(args)))
+	}
+	if filter.PaymentMode != "" {
+		args = append(args, filter.PaymentMode)
+		conditions = append(conditions, fmt.Sprintf("e.payment_mode = $%d", len(args)))
+	}
+
+	query := expenseQuery + `
+        WHERE ` + strings.Join(conditions, " AND ") + `
+        ORDER BY e.expense_date DESC, e.created_at DESC
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	expenses := []*Expense{}
+	for rows.Next() {
+		expense, err := scanExpense(rows)
+		if err != nil {
+			return nil, err
+		}
+		expenses = append(expenses, expense)
+	}
+
+	return expenses, rows.Err()
+}
+
+func (s *ExpenseStore) Update(ctx context.Context, expense *Expense) error {
+	query := `
+        UPDATE expense
+        SET vendor_id = $2,
+            category = $3,
+            description = $4,
+            expense_date = $5,
+            amount = $6,
+            tax_amount = $7,
+            payment_mode = $8
+        WHERE id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(
+			ctx,
+			query,
+			expense.ID,
+			expense.VendorID,
+			expense.Category,
+			expense.Description,
+			expense.ExpenseDate,
+			expense.Amount,
+			expense.TaxAmount,
+			expense.PaymentMode,
+		)
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return repostExpense(ctx, tx, expense.ID)
+	})
+}
+
+// SetReceipt records the stored receipt of an expense; a nil key clears it.
+func (s *ExpenseStore) SetReceipt(ctx context.Context, expenseID uuid.UUID, key, name, contentType *string) error {
+	query := `
+        UPDATE expense
+        SET receipt_key = $2,
+            receipt_name = $3,
+            receipt_type = $4
+        WHERE id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, expenseID, key, name, contentType)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *ExpenseStore) Delete(ctx context.Context, expenseID uuid.UUID) error {
+	query := `DELETE FROM expense WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, expenseID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetMonthlyTotals sums expenses dated within the range by month, with a
+// breakdown of each month's total by category.
+func (s *ExpenseStore) GetMonthlyTotals(ctx context.Context, busID uuid.UUID, from, to time.Time) ([]*ExpenseMonth, error) {
+	query := `
+        SELECT
+            to_char(date_trunc('month', expense_date), 'YYYY-MM') AS month,
+            category,
+            SUM(amount),
+            SUM(tax_amount)
+        FROM expense
+        WHERE buss_id = $1 AND expense_date BETWEEN $2 AND $3
+        GROUP BY 1, 2
+        ORDER BY 1, 2
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, busID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	months := []*ExpenseMonth{}
+	for rows.Next() {
+		var month, category string
+		var amount, tax float64
+		if err := rows.Scan(&month, &category, &amount, &tax); err != nil {
+			return nil, err
+		}
+
+		if len(months) == 0 || months[len(months)-1].Month != month {
+			months = append(months, &ExpenseMonth{Month: month, Categories: map[string]float64{}})
+		}
+		current := months[len(months)-1]
+		current.Amount += amount
+		current.TaxAmount += tax
+		current.Total += amount + tax
+		current.Categories[category] += amount + tax
+	}
+
+	return months, rows.Err()
+}
+
+// GetCategories returns the distinct categories a business has used, for
+// suggesting them when logging a new expense.
+func (s *ExpenseStore) GetCategories(ctx context.Context, busID uuid.UUID) ([]string, error) {
+	query := `
+        SELECT DISTINCT category
+        FROM expense
+        WHERE buss_id = $1
+        ORDER BY category
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, busID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []string{}
+	for rows.Next() {
+		var category string
+		if err := rows.Scan(&category); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
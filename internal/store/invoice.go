@@ -1,166 +1,214 @@
 package store
 
 import (
-    "context"
-    "database/sql"
-    "errors"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
 
-    "github.com/google/uuid"
-    "github.com/lib/pq"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 var (
-    ErrDuplicateInvoice = errors.New("invoice already exists for this customer on this date")
+	ErrDuplicateInvoice = errors.New("invoice already exists for this customer on this date")
 )
 
 type InvoiceStore struct {
-    db *sql.DB
+	db *sql.DB
 }
 
 func isUniqueViolation(err error) bool {
-    if pqErr, ok := err.(*pq.Error); ok {
-        return pqErr.Code == "23505"
-    }
-    return false
+	if pqErr, ok := err.(*pq.Error); ok {
+		return pqErr.Code == "23505"
+	}
+	return false
 }
 
-func (s *InvoiceStore) Create(ctx context.Context, invoice *Invoice) error {
-    query := `
+func isForeignKeyViolation(err error) bool {
+	if pqErr, ok := err.(*pq.Error); ok {
+		return pqErr.Code == "23503"
+	}
+	return false
+}
+
+// Create writes an invoice with its items, posting their sales against
+// stock and the sale to the ledger, all in one transaction.
+func (s *InvoiceStore) Create(ctx context.Context, invoice *Invoice, items []*InvoiceItem) error {
+	query := `
         INSERT INTO invoice (inv_no, buss_id, cust_id, total_amount, inv_date, due_date, is_paid, paid_date)
         VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-        RETURNING id, created_at
+        RETURNING id, status, kind, created_at
     `
 
-    ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-    defer cancel()
-
-    err := s.db.QueryRowContext(
-        ctx,
-        query,
-        invoice.InvNo,
-        invoice.BusID,
-        invoice.CustID,
-        invoice.TotalAmount,
-        invoice.InvDate,
-        invoice.DueDate,
-        invoice.IsPaid,
-        invoice.PaidDate,
-    ).Scan(
-        &invoice.ID,
-        &invoice.CreatedAt,
-    )
-    if err != nil {
-        if isUniqueViolation(err) {
-            return ErrDuplicateInvoice
-        }
-        return err
-    }
-
-    return nil
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			invoice.InvNo,
+			invoice.BusID,
+			invoice.CustID,
+			invoice.TotalAmount,
+			invoice.InvDate,
+			invoice.DueDate,
+			invoice.IsPaid,
+			invoice.PaidDate,
+		).Scan(
+			&invoice.ID,
+			&invoice.Status,
+			&invoice.Kind,
+			&invoice.CreatedAt,
+		)
+		if err != nil {
+			switch {
+			case isUniqueViolation(err):
+				return ErrDuplicateInvoice
+			case isForeignKeyViolation(err):
+				return ErrInvalidCustomer
+			default:
+				return err
+			}
+		}
+
+		if err := insertInvoiceItems(ctx, tx, invoice.ID, items); err != nil {
+			return err
+		}
+
+		if err := repostInvoice(ctx, tx, invoice.ID); err != nil {
+			return err
+		}
+
+		if invoice.IsPaid {
+			paidOn := invoice.InvDate
+			if invoice.PaidDate != nil {
+				paidOn = *invoice.PaidDate
+			}
+			if err := settleInvoice(ctx, tx, invoice.ID, paidOn); err != nil {
+				return err
+			}
+		}
+
+		invoice.IsPaid, invoice.PaidDate, err = syncInvoicePaid(ctx, tx, invoice.ID)
+		return err
+	})
 }
 
 func (s *InvoiceStore) GetNextInvoiceNumber(ctx context.Context, busID uuid.UUID) (int64, error) {
-    query := `
+	query := `
         SELECT COALESCE(MAX(inv_no), 0) + 1
         FROM invoice
         WHERE buss_id = $1
     `
 
-    ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-    defer cancel()
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
 
-    var nextInvNo int64
-    err := s.db.QueryRowContext(ctx, query, busID).Scan(&nextInvNo)
-    if err != nil {
-        return 0, err
-    }
+	var nextInvNo int64
+	err := s.db.QueryRowContext(ctx, query, busID).Scan(&nextInvNo)
+	if err != nil {
+		return 0, err
+	}
 
-    return nextInvNo, nil
+	return nextInvNo, nil
 }
 
 func (s *InvoiceStore) GetByID(ctx context.Context, invoiceID uuid.UUID) (*Invoice, error) {
-    query := `
-        SELECT id, inv_no, buss_id, cust_id, total_amount, inv_date, due_date, is_paid, paid_date, created_at
+	query := `
+        SELECT id, inv_no, buss_id, cust_id, total_amount, inv_date, due_date, is_paid, paid_date, status, sent_at, kind, ref_inv_id, created_at
         FROM invoice
         WHERE id = $1
     `
 
-    ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-    defer cancel()
-
-    invoice := &Invoice{}
-    err := s.db.QueryRowContext(
-        ctx,
-        query,
-        invoiceID,
-    ).Scan(
-        &invoice.ID,
-        &invoice.InvNo,
-        &invoice.BusID,
-        &invoice.CustID,
-        &invoice.TotalAmount,
-        &invoice.InvDate,
-        &invoice.DueDate,
-        &invoice.IsPaid,
-        &invoice.PaidDate,
-        &invoice.CreatedAt,
-    )
-    if err != nil {
-        if err == sql.ErrNoRows {
-            return nil, ErrNotFound
-        }
-        return nil, err
-    }
-
-    return invoice, nil
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	invoice := &Invoice{}
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		invoiceID,
+	).Scan(
+		&invoice.ID,
+		&invoice.InvNo,
+		&invoice.BusID,
+		&invoice.CustID,
+		&invoice.TotalAmount,
+		&invoice.InvDate,
+		&invoice.DueDate,
+		&invoice.IsPaid,
+		&invoice.PaidDate,
+		&invoice.Status,
+		&invoice.SentAt,
+		&invoice.Kind,
+		&invoice.RefInvID,
+		&invoice.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return invoice, nil
 }
 
 func (s *InvoiceStore) GetByBusID(ctx context.Context, busID uuid.UUID) ([]*Invoice, error) {
-    query := `
-        SELECT id, inv_no, buss_id, cust_id, total_amount, inv_date, due_date, is_paid, paid_date, created_at
+	query := `
+        SELECT id, inv_no, buss_id, cust_id, total_amount, inv_date, due_date, is_paid, paid_date, status, sent_at, kind, ref_inv_id, created_at
         FROM invoice
         WHERE buss_id = $1
     `
 
-    ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-    defer cancel()
-
-    rows, err := s.db.QueryContext(ctx, query, busID)
-    if err != nil {
-        return nil, err
-    }
-    defer rows.Close()
-
-    var invoices []*Invoice
-    for rows.Next() {
-        invoice := &Invoice{}
-        err := rows.Scan(
-            &invoice.ID,
-            &invoice.InvNo,
-            &invoice.BusID,
-            &invoice.CustID,
-            &invoice.TotalAmount,
-            &invoice.InvDate,
-            &invoice.DueDate,
-            &invoice.IsPaid,
-            &invoice.PaidDate,
-            &invoice.CreatedAt,
-        )
-        if err != nil {
-            return nil, err
-        }
-        invoices = append(invoices, invoice)
-    }
-
-    if err := rows.Err(); err != nil {
-        return nil, err
-    }
-
-    return invoices, nil
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, busID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invoices []*Invoice
+	for rows.Next() {
+		invoice := &Invoice{}
+		err := rows.Scan(
+			&invoice.ID,
+			&invoice.InvNo,
+			&invoice.BusID,
+			&invoice.CustID,
+			&invoice.TotalAmount,
+			&invoice.InvDate,
+			&invoice.DueDate,
+			&invoice.IsPaid,
+			&invoice.PaidDate,
+			&invoice.Status,
+			&invoice.SentAt,
+			&invoice.Kind,
+			&invoice.RefInvID,
+			&invoice.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, invoice)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return invoices, nil
 }
 
-func (s *InvoiceStore) Update(ctx context.Context, invoice *Invoice) error {
-    query := `
+// Update rewrites an invoice and replaces its items, reposting their stock
+// movements and the sale in the same transaction. An invoice with credit
+// notes against it cannot be changed.
+func (s *InvoiceStore) Update(ctx context.Context, invoice *Invoice, items []*InvoiceItem) error {
+	query := `
         UPDATE invoice
         SET cust_id = $2,
             total_amount = $3,
@@ -172,115 +220,168 @@ func (s *InvoiceStore) Update(ctx context.Context, invoice *Invoice) error {
         RETURNING created_at
     `
 
-    ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-    defer cancel()
-
-    result, err := s.db.ExecContext(
-        ctx,
-        query,
-        invoice.ID,
-        invoice.CustID,
-        invoice.TotalAmount,
-        invoice.InvDate,
-        invoice.DueDate,
-        invoice.IsPaid,
-        invoice.PaidDate,
-    )
-    if err != nil {
-        return err
-    }
-
-    rowsAffected, err := result.RowsAffected()
-    if err != nil {
-        return err
-    }
-
-    if rowsAffected == 0 {
-        return errors.New("failed to update invoice")
-    }
-
-    return nil
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var wasPaid bool
+		err := tx.QueryRowContext(ctx, `SELECT is_paid FROM invoice WHERE id = $1 FOR UPDATE`, invoice.ID).Scan(&wasPaid)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		credited, err := hasCreditNotes(ctx, tx, invoice.ID)
+		if err != nil {
+			return err
+		}
+		if credited {
+			return ErrInvoiceCredited
+		}
+
+		result, err := tx.ExecContext(
+			ctx,
+			query,
+			invoice.ID,
+			invoice.CustID,
+			invoice.TotalAmount,
+			invoice.InvDate,
+			invoice.DueDate,
+			invoice.IsPaid,
+			invoice.PaidDate,
+		)
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrInvalidCustomer
+			}
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		_, err = tx.ExecContext(ctx, `DELETE FROM invoice_item WHERE inv_id = $1`, invoice.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := clearInvoiceStock(ctx, tx, invoice.ID); err != nil {
+			return err
+		}
+
+		if err := insertInvoiceItems(ctx, tx, invoice.ID, items); err != nil {
+			return err
+		}
+
+		if err := repostInvoice(ctx, tx, invoice.ID); err != nil {
+			return err
+		}
+
+		// Payments are the record of what was received. Marking the invoice
+		// paid settles the balance; unmarking a fully paid invoice clears
+		// them, while partial payments are left alone.
+		switch {
+		case invoice.IsPaid:
+			paidOn := time.Now()
+			if invoice.PaidDate != nil {
+				paidOn = *invoice.PaidDate
+			}
+			if err := settleInvoice(ctx, tx, invoice.ID, paidOn); err != nil {
+				return err
+			}
+		case wasPaid:
+			if err := clearPayments(ctx, tx, invoice.ID); err != nil {
+				return err
+			}
+		}
+
+		invoice.IsPaid, invoice.PaidDate, err = syncInvoicePaid(ctx, tx, invoice.ID)
+		return err
+	})
 }
 
+// UpdateStatus toggles an invoice between paid and unpaid. Marking it paid
+// records a payment for the balance today; marking it unpaid removes its
+// payments.
 func (s *InvoiceStore) UpdateStatus(ctx context.Context, invoiceID uuid.UUID) error {
-    query := `
-        UPDATE invoice
-        SET is_paid = NOT is_paid,
-            paid_date = CASE
-                WHEN NOT is_paid THEN NOW()
-                ELSE NULL
-            END
+	query := `
+        SELECT is_paid
+        FROM invoice
         WHERE id = $1
+        FOR UPDATE
     `
 
-    ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-    defer cancel()
-
-    result, err := s.db.ExecContext(ctx, query, invoiceID)
-    if err != nil {
-        return err
-    }
-
-    rowsAffected, err := result.RowsAffected()
-    if err != nil {
-        return err
-    }
-
-    if rowsAffected == 0 {
-        return ErrNotFound
-    }
-
-    return nil
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var isPaid bool
+		if err := tx.QueryRowContext(ctx, query, invoiceID).Scan(&isPaid); err != nil {
+			if err == sql.ErrNoRows {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		if isPaid {
+			if err := clearPayments(ctx, tx, invoiceID); err != nil {
+				return err
+			}
+		} else if err := settleInvoice(ctx, tx, invoiceID, time.Now()); err != nil {
+			return err
+		}
+
+		_, _, err := syncInvoicePaid(ctx, tx, invoiceID)
+		return err
+	})
 }
 
+// Delete removes an invoice and returns the stock it sold. An invoice with
+// credit notes against it cannot be deleted.
 func (s *InvoiceStore) Delete(ctx context.Context, invoiceID uuid.UUID) error {
-    query := `
+	query := `
         DELETE FROM invoice
         WHERE id = $1
     `
 
-    ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-    defer cancel()
-
-    result, err := s.db.ExecContext(ctx, query, invoiceID)
-    if err != nil {
-        return err
-    }
-
-    rowsAffected, err := result.RowsAffected()
-    if err != nil {
-        return err
-    }
-
-    if rowsAffected == 0 {
-        return ErrNotFound
-    }
-
-    return nil
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		credited, err := hasCreditNotes(ctx, tx, invoiceID)
+		if err != nil {
+			return err
+		}
+		if credited {
+			return ErrInvoiceCredited
+		}
+
+		if err := reverseInvoiceStock(ctx, tx, invoiceID); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, query, invoiceID)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return nil
+	})
 }
-
-func (s *InvoiceItemStore) Delete(ctx context.Context, itemID uuid.UUID) error {
-    query := `
-        DELETE FROM invoice_item
-        WHERE id = $1
-    `
-
-    ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-    defer cancel()
-
-    result, err := s.db.ExecContext(ctx, query, itemID)
-    if err != nil {
-        return err
-    }
-
-    rowsAffected, err := result.RowsAffected()
-    if err != nil {
-        return err
-    }
-
-    if rowsAffected == 0 {
-        return ErrNotFound
-    }
-
-    return nil
-}
\ No newline at end of file
@@ -3,45 +3,23 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrInvalidItemReference = errors.New("product or variant of invoice item does not exist")
+)
+
 type InvoiceItemStore struct {
 	db *sql.DB
 }
 
-func (s *InvoiceItemStore) Create(ctx context.Context, item *InvoiceItem) error {
-	query := `
-        INSERT INTO invoice_item (inv_id, prod_id, quantity, unit_price)
-        VALUES ($1, $2, $3, $4)
-        RETURNING id
-    `
-
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
-
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		item.InvID,
-		item.ProdID,
-		item.Quantity,
-		item.UnitPrice,
-	).Scan(
-		&item.ID,
-	)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 func (s *InvoiceItemStore) GetByID(ctx context.Context, itemID uuid.UUID) (*InvoiceItem, error) {
 	query := `
-        SELECT id, inv_id, prod_id, quantity, unit_price, created_at
+        SELECT id, inv_id, prod_id, variant_id, quantity, unit_price, tax_rate
         FROM invoice_item
         WHERE id = $1
     `
@@ -58,8 +36,10 @@ func (s *InvoiceItemStore) GetByID(ctx context.Context, itemID uuid.UUID) (*Invo
 		&item.ID,
 		&item.InvID,
 		&item.ProdID,
+		&item.VariantID,
 		&item.Quantity,
 		&item.UnitPrice,
+		&item.TaxRate,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -73,9 +53,10 @@ func (s *InvoiceItemStore) GetByID(ctx context.Context, itemID uuid.UUID) (*Invo
 
 func (s *InvoiceItemStore) GetByInvoiceID(ctx context.Context, invID uuid.UUID) ([]*InvoiceItem, error) {
 	query := `
-        SELECT id, inv_id, prod_id, quantity, unit_price
-        FROM invoice_item
-        WHERE inv_id = $1
+        SELECT ii.id, ii.inv_id, ii.prod_id, ii.variant_id, COALESCE(v.name, ''), ii.quantity, ii.unit_price, ii.tax_rate
+        FROM invoice_item ii
+        LEFT JOIN product_variant v ON v.id = ii.variant_id
+        WHERE ii.inv_id = $1
     `
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -94,8 +75,11 @@ func (s *InvoiceItemStore) GetByInvoiceID(ctx context.Context, invID uuid.UUID)
 			&item.ID,
 			&item.InvID,
 			&item.ProdID,
+			&item.VariantID,
+			&item.VariantName,
 			&item.Quantity,
 			&item.UnitPrice,
+			&item.TaxRate,
 		)
 		if err != nil {
 			return nil, err
@@ -110,77 +94,30 @@ func (s *InvoiceItemStore) GetByInvoiceID(ctx context.Context, invID uuid.UUID)
 	return items, nil
 }
 
-func (s *InvoiceItemStore) Update(ctx context.Context, item *InvoiceItem) error {
-	query := `
-        UPDATE invoice_item
-        SET inv_id = $2,
-            prod_id = $3,
-            quantity = $4,
-            unit_price = $5
-        WHERE id = $1
-    `
-
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
-
-	_, err := s.db.ExecContext(
-		ctx,
-		query,
-		item.ID,
-		item.InvID,
-		item.ProdID,
-		item.Quantity,
-		item.UnitPrice,
-	)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-func (s *InvoiceItemStore) UpdateAll(ctx context.Context, invoiceID uuid.UUID, items []*InvoiceItem) error {
-	if len(items) == 0 {
-		return nil
-	}
-
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
-
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-
-	// Delete all old items
-	_, err = tx.ExecContext(ctx, `
-        DELETE FROM invoice_item
-        WHERE inv_id = $1
-    `, invoiceID)
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	// Insert new items
+// insertInvoiceItems writes the items of an invoice and posts their sales
+// against stock.
+func insertInvoiceItems(ctx context.Context, tx *sql.Tx, invoiceID uuid.UUID, items []*InvoiceItem) error {
 	for _, item := range items {
 		if item.ProdID == uuid.Nil {
-			tx.Rollback()
 			return fmt.Errorf("prod_id cannot be null")
 		}
 
-		_, err := tx.ExecContext(ctx, `
-            INSERT INTO invoice_item (inv_id, prod_id, quantity, unit_price)
-            VALUES ($1, $2, $3, $4)
-        `, invoiceID, item.ProdID, item.Quantity, item.UnitPrice)
+		item.InvID = invoiceID
+		err := tx.QueryRowContext(ctx, `
+            INSERT INTO invoice_item (inv_id, prod_id, variant_id, quantity, unit_price, tax_rate)
+            VALUES ($1, $2, $3, $4, $5, $6)
+            RETURNING id
+        `, invoiceID, item.ProdID, item.VariantID, item.Quantity, item.UnitPrice, item.TaxRate).Scan(&item.ID)
 		if err != nil {
-			tx.Rollback()
+			if isForeignKeyViolation(err) {
+				return ErrInvalidItemReference
+			}
 			return err
 		}
-	}
 
-	err = tx.Commit()
-	if err != nil {
-		return err
+		if err := postInvoiceItemStock(ctx, tx, invoiceID, item); err != nil {
+			return err
+		}
 	}
 
 	return nil
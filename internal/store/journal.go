@@ -0,0 +1,542 @@
+package store
+
+import (
+	"billify-api/internal/gst"
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AccountTypeAsset     = "asset"
+	AccountTypeLiability = "liability"
+	AccountTypeEquity    = "equity"
+	AccountTypeIncome    = "income"
+	AccountTypeExpense   = "expense"
+)
+
+const (
+	JournalSourceInvoice     = "invoice"
+	JournalSourceReceipt     = "receipt"
+	JournalSourceExpense     = "expense"
+	JournalSourceBill        = "purchase_bill"
+	JournalSourceBillPayment = "bill_payment"
+	JournalSourceCreditNote  = "credit_note"
+)
+
+// journalSourceColumns maps each source type to the journal_entry column
+// that links the entry to its document.
+var journalSourceColumns = map[string]string{
+	JournalSourceInvoice:     "inv_id",
+	JournalSourceReceipt:     "payment_id",
+	JournalSourceExpense:     "expense_id",
+	JournalSourceBill:        "bill_id",
+	JournalSourceBillPayment: "bill_payment_id",
+	JournalSourceCreditNote:  "credit_note_id",
+}
+
+type systemAccount struct {
+	Key  string
+	Code int
+	Name string
+	Type string
+}
+
+// systemAccounts is the default chart of accounts. The accounts are created
+// for a business the first time they are needed, and documents always post
+// to them by key so they can be renamed or renumbered freely.
+var systemAccounts = []systemAccount{
+	{"cash", 1000, "Cash", AccountTypeAsset},
+	{"bank", 1010, "Bank", AccountTypeAsset},
+	{"receivable", 1100, "Accounts Receivable", AccountTypeAsset},
+	{"input_cgst", 1200, "Input CGST", AccountTypeAsset},
+	{"input_sgst", 1210, "Input SGST", AccountTypeAsset},
+	{"input_igst", 1220, "Input IGST", AccountTypeAsset},
+	{"payable", 2000, "Accounts Payable", AccountTypeLiability},
+	{"output_cgst", 2100, "Output CGST", AccountTypeLiability},
+	{"output_sgst", 2110, "Output SGST", AccountTypeLiability},
+	{"output_igst", 2120, "Output IGST", AccountTypeLiability},
+	{"capital", 3000, "Owner's Capital", AccountTypeEquity},
+	{"sales", 4000, "Sales", AccountTypeIncome},
+	{"late_fee_income", 4100, "Late Fees & Interest", AccountTypeIncome},
+	{"purchases", 5000, "Purchases", AccountTypeExpense},
+}
+
+// expenseAccountCode is where accounts created for expense categories are
+// numbered from.
+const expenseAccountCode = 6000
+
+// ensureAccount returns the business's account with the given system key,
+// creating it with the first free code from code onwards if it is missing.
+func ensureAccount(ctx context.Context, tx *sql.Tx, busID uuid.UUID, key string, code int, name, accountType string) (uuid.UUID, error) {
+	lookup := `SELECT id FROM account WHERE buss_id = $1 AND system_key = $2`
+
+	var id uuid.UUID
+	err := tx.QueryRowContext(ctx, lookup, busID, key).Scan(&id)
+	if err != sql.ErrNoRows {
+		return id, err
+	}
+
+	for ; ; code++ {
+		err := tx.QueryRowContext(ctx, `
+            INSERT INTO account (buss_id, code, name, type, system_key)
+            VALUES ($1, $2, $3, $4, $5)
+            ON CONFLICT DO NOTHING
+            RETURNING id
+        `, busID, strconv.Itoa(code), name, accountType, key).Scan(&id)
+		if err != sql.ErrNoRows {
+			return id, err
+		}
+
+		// Either the code is taken or another transaction has just created
+		// the account.
+		err = tx.QueryRowContext(ctx, lookup, busID, key).Scan(&id)
+		if err != sql.ErrNoRows {
+			return id, err
+		}
+	}
+}
+
+func systemAccountID(ctx context.Context, tx *sql.Tx, busID uuid.UUID, key string) (uuid.UUID, error) {
+	for _, account := range systemAccounts {
+		if account.Key == key {
+			return ensureAccount(ctx, tx, busID, account.Key, account.Code, account.Name, account.Type)
+		}
+	}
+	return uuid.Nil, fmt.Errorf("unknown system account %q", key)
+}
+
+func expenseAccountID(ctx context.Context, tx *sql.Tx, busID uuid.UUID, category string) (uuid.UUID, error) {
+	key := "expense:" + strings.ToLower(strings.TrimSpace(category))
+	return ensureAccount(ctx, tx, busID, key, expenseAccountCode, category, AccountTypeExpense)
+}
+
+// settlementAccount is the account a payment made by the given method moves
+// money through.
+func settlementAccount(method string) string {
+	if method == "cash" {
+		return "cash"
+	}
+	return "bank"
+}
+
+func toPaise(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// journalDraft collects the lines of an entry that posts to system accounts
+// by key.
+type journalDraft struct {
+	entry *JournalEntry
+	keys  []string
+}
+
+func (d *journalDraft) add(key string, debit, credit float64) {
+	d.keys = append(d.keys, key)
+	d.entry.Lines = append(d.entry.Lines, JournalLine{Debit: debit, Credit: credit})
+}
+
+// addAccount adds a line for an account that is not a system account.
+func (d *journalDraft) addAccount(accountID uuid.UUID, debit, credit float64) {
+	d.keys = append(d.keys, "")
+	d.entry.Lines = append(d.entry.Lines, JournalLine{AccountID: accountID, Debit: debit, Credit: credit})
+}
+
+func (d *journalDraft) post(ctx context.Context, tx *sql.Tx, sourceID uuid.UUID) error {
+	for i, key := range d.keys {
+		if d.entry.Lines[i].AccountID != uuid.Nil {
+			continue
+		}
+		id, err := systemAccountID(ctx, tx, d.entry.BusID, key)
+		if err != nil {
+			return err
+		}
+		d.entry.Lines[i].AccountID = id
+	}
+
+	return postJournal(ctx, tx, d.entry, sourceID)
+}
+
+// postJournal writes an entry and its lines. Zero lines are dropped and an
+// entry with nothing left is skipped; otherwise debits must equal credits.
+func postJournal(ctx context.Context, tx *sql.Tx, entry *JournalEntry, sourceID uuid.UUID) error {
+	lines := make([]JournalLine, 0, len(entry.Lines))
+	var debit, credit int64
+	for _, line := range entry.Lines {
+		line.Debit = float64(toPaise(line.Debit)) / 100
+		line.Credit = float64(toPaise(line.Credit)) / 100
+		if line.Debit < 0 || line.Credit < 0 || (line.Debit != 0 && line.Credit != 0) {
+			return ErrInvalidJournalLine
+		}
+		if line.Debit == 0 && line.Credit == 0 {
+			continue
+		}
+		debit += toPaise(line.Debit)
+		credit += toPaise(line.Credit)
+		lines = append(lines, line)
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+	if debit != credit {
+		return ErrUnbalancedEntry
+	}
+
+	// Manual entries are not linked to a document, so the link column is
+	// left NULL.
+	var source any
+	column := "inv_id"
+	if entry.SourceType != nil {
+		source = sourceID
+		column = journalSourceColumns[*entry.SourceType]
+	}
+
+	err := tx.QueryRowContext(ctx, `
+        INSERT INTO journal_entry (buss_id, entry_date, narration, source_type, `+column+`)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at
+    `, entry.BusID, entry.EntryDate.Format("2006-01-02"), entry.Narration, entry.SourceType, source).Scan(
+		&entry.ID,
+		&entry.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	for i := range lines {
+		err := tx.QueryRowContext(ctx, `
+            INSERT INTO journal_line (entry_id, account_id, debit, credit)
+            SELECT $1, id, $3, $4
+            FROM account
+            WHERE id = $2 AND buss_id = $5
+            RETURNING id
+        `, entry.ID, lines[i].AccountID, lines[i].Debit, lines[i].Credit, entry.BusID).Scan(&lines[i].ID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrInvalidAccount
+			}
+			return err
+		}
+	}
+	entry.Lines = lines
+
+	return nil
+}
+
+// unpostJournal removes the entries a document posted so they can be posted
+// again from its current state.
+func unpostJournal(ctx context.Context, tx *sql.Tx, sourceType string, sourceID uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `
+        DELETE FROM journal_entry
+        WHERE source_type = $1 AND `+journalSourceColumns[sourceType]+` = $2
+    `, sourceType, sourceID)
+
+	return err
+}
+
+func newDraft(busID uuid.UUID, date time.Time, narration, sourceType string) *journalDraft {
+	return &journalDraft{entry: &JournalEntry{
+		BusID:      busID,
+		EntryDate:  date,
+		Narration:  narration,
+		SourceType: &sourceType,
+	}}
+}
+
+// splitInvoiceTax rounds an invoice's taxable value and tax to paise and
+// splits the tax into CGST and SGST, or IGST for an out-of-state customer.
+func splitInvoiceTax(taxableValue, taxAmount float64, busGSTNo, custGSTNo string) (taxable, cgst, sgst, igst float64) {
+	taxable = math.Round(taxableValue*100) / 100
+	cgst, sgst, igst = gst.SplitTax(math.Round(taxAmount*100)/100, gst.IsIntraState(busGSTNo, custGSTNo))
+	return taxable, cgst, sgst, igst
+}
+
+// repostInvoice rebuilds the sale entry of an invoice from its items. Sales
+// are credited at taxable value with the GST split into CGST and SGST, or
+// IGST for an out-of-state customer.
+func repostInvoice(ctx context.Context, tx *sql.Tx, invoiceID uuid.UUID) error {
+	if err := unpostJournal(ctx, tx, JournalSourceInvoice, invoiceID); err != nil {
+		return err
+	}
+
+	var (
+		busID                   uuid.UUID
+		invNo                   int64
+		invDate                 time.Time
+		busGSTNo, custGSTNo     string
+		custName                string
+		kind                    string
+		lateFeeOn               *int64
+		taxableValue, taxAmount float64
+	)
+	err := tx.QueryRowContext(ctx, `
+        SELECT i.buss_id, i.inv_no, i.inv_date, b.gstno, c.gstno, c.name, i.kind, overdue.inv_no
+        FROM invoice i
+        JOIN business b ON b.buss_id = i.buss_id
+        JOIN customer c ON c.id = i.cust_id
+        LEFT JOIN late_fee_charge f ON f.charge_inv_id = i.id
+        LEFT JOIN invoice overdue ON overdue.id = f.inv_id
+        WHERE i.id = $1
+    `, invoiceID).Scan(&busID, &invNo, &invDate, &busGSTNo, &custGSTNo, &custName, &kind, &lateFeeOn)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+        SELECT
+            COALESCE(SUM(ii.quantity * ii.unit_price), 0),
+            COALESCE(SUM(ii.quantity * ii.unit_price * ii.tax_rate / 100), 0)
+        FROM invoice_item ii
+        WHERE ii.inv_id = $1
+    `, invoiceID).Scan(&taxableValue, &taxAmount)
+	if err != nil {
+		return err
+	}
+
+	taxableValue, cgst, sgst, igst := splitInvoiceTax(taxableValue, taxAmount, busGSTNo, custGSTNo)
+	total := taxableValue + cgst + sgst + igst
+
+	// Late fees are income, but not sales.
+	description := fmt.Sprintf("Invoice #%d to %s", invNo, custName)
+	if kind == InvoiceKindDebitNote {
+		description = fmt.Sprintf("Debit note #%d to %s", invNo, custName)
+	}
+	income := "sales"
+	if lateFeeOn != nil {
+		description += fmt.Sprintf(" for late payment of invoice #%d", *lateFeeOn)
+		income = "late_fee_income"
+	}
+
+	sale := newDraft(busID, invDate, description, JournalSourceInvoice)
+	sale.add("receivable", total, 0)
+	sale.add(income, 0, taxableValue)
+	sale.add("output_cgst", 0, cgst)
+	sale.add("output_sgst", 0, sgst)
+	sale.add("output_igst", 0, igst)
+	return sale.post(ctx, tx, invoiceID)
+}
+
+// repostPayment rebuilds the receipt of a payment received against an
+// invoice. Credit notes applied to an invoice are not receipts and post
+// nothing here.
+func repostPayment(ctx context.Context, tx *sql.Tx, paymentID uuid.UUID) error {
+	if err := unpostJournal(ctx, tx, JournalSourceReceipt, paymentID); err != nil {
+		return err
+	}
+
+	var (
+		busID  uuid.UUID
+		invNo  int64
+		method string
+		paidOn time.Time
+		amount float64
+	)
+	err := tx.QueryRowContext(ctx, `
+        SELECT p.buss_id, i.inv_no, p.method, p.paid_on, p.amount
+        FROM payment p
+        JOIN invoice i ON i.id = p.inv_id
+        WHERE p.id = $1 AND p.credit_note_id IS NULL
+    `, paymentID).Scan(&busID, &invNo, &method, &paidOn, &amount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	draft := newDraft(busID, paidOn, fmt.Sprintf("Payment received for invoice #%d", invNo), JournalSourceReceipt)
+	draft.add(settlementAccount(method), amount, 0)
+	draft.add("receivable", 0, amount)
+
+	return draft.post(ctx, tx, paymentID)
+}
+
+// repostCreditNote rebuilds the entry of a credit note, which reverses the
+// sale it was issued against: the income the invoice credited and its output
+// GST are debited against the receivable.
+func repostCreditNote(ctx context.Context, tx *sql.Tx, creditNoteID uuid.UUID) error {
+	if err := unpostJournal(ctx, tx, JournalSourceCreditNote, creditNoteID); err != nil {
+		return err
+	}
+
+	var (
+		busID                   uuid.UUID
+		cnNo, invNo             int64
+		cnDate                  time.Time
+		busGSTNo, custGSTNo     string
+		custName                string
+		lateFee                 bool
+		taxableValue, taxAmount float64
+	)
+	err := tx.QueryRowContext(ctx, `
+        SELECT cn.buss_id, cn.cn_no, cn.cn_date, i.inv_no, b.gstno, c.gstno, c.name, f.id IS NOT NULL
+        FROM credit_note cn
+        JOIN invoice i ON i.id = cn.inv_id
+        JOIN business b ON b.buss_id = cn.buss_id
+        JOIN customer c ON c.id = i.cust_id
+        LEFT JOIN late_fee_charge f ON f.charge_inv_id = i.id
+        WHERE cn.id = $1
+    `, creditNoteID).Scan(&busID, &cnNo, &cnDate, &invNo, &busGSTNo, &custGSTNo, &custName, &lateFee)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+        SELECT
+            COALESCE(SUM(quantity * unit_price), 0),
+            COALESCE(SUM(quantity * unit_price * tax_rate / 100), 0)
+        FROM credit_note_item
+        WHERE cn_id = $1
+    `, creditNoteID).Scan(&taxableValue, &taxAmount)
+	if err != nil {
+		return err
+	}
+
+	taxableValue, cgst, sgst, igst := splitInvoiceTax(taxableValue, taxAmount, busGSTNo, custGSTNo)
+	total := taxableValue + cgst + sgst + igst
+
+	income := "sales"
+	if lateFee {
+		income = "late_fee_income"
+	}
+
+	draft := newDraft(busID, cnDate, fmt.Sprintf("Credit note #%d to %s against invoice #%d", cnNo, custName, invNo), JournalSourceCreditNote)
+	draft.add(income, taxableValue, 0)
+	draft.add("output_cgst", cgst, 0)
+	draft.add("output_sgst", sgst, 0)
+	draft.add("output_igst", igst, 0)
+	draft.add("receivable", 0, total)
+	return draft.post(ctx, tx, creditNoteID)
+}
+
+// repostExpense rebuilds the entry of an expense: the category's expense
+// account and input GST are debited against cash or bank.
+func repostExpense(ctx context.Context, tx *sql.Tx, expenseID uuid.UUID) error {
+	if err := unpostJournal(ctx, tx, JournalSourceExpense, expenseID); err != nil {
+		return err
+	}
+
+	var (
+		busID                 uuid.UUID
+		category, description string
+		expenseDate           time.Time
+		amount, taxAmount     float64
+		paymentMode           string
+		busGSTNo, vendorGSTNo string
+	)
+	err := tx.QueryRowContext(ctx, `
+        SELECT e.buss_id, e.category, e.description, e.expense_date, e.amount, e.tax_amount, e.payment_mode, b.gstno, COALESCE(v.gstno, '')
+        FROM expense e
+        JOIN business b ON b.buss_id = e.buss_id
+        LEFT JOIN vendor v ON v.id = e.vendor_id
+        WHERE e.id = $1
+    `, expenseID).Scan(&busID, &category, &description, &expenseDate, &amount, &taxAmount, &paymentMode, &busGSTNo, &vendorGSTNo)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	accountID, err := expenseAccountID(ctx, tx, busID, category)
+	if err != nil {
+		return err
+	}
+
+	narration := category
+	if description != "" {
+		narration += ": " + description
+	}
+
+	cgst, sgst, igst := gst.SplitTax(taxAmount, gst.IsIntraState(vendorGSTNo, busGSTNo))
+
+	draft := newDraft(busID, expenseDate, narration, JournalSourceExpense)
+	draft.addAccount(accountID, amount, 0)
+	draft.add("input_cgst", cgst, 0)
+	draft.add("input_sgst", sgst, 0)
+	draft.add("input_igst", igst, 0)
+	draft.add(settlementAccount(paymentMode), 0, amount+taxAmount)
+
+	return draft.post(ctx, tx, expenseID)
+}
+
+// repostBill rebuilds the entry of a purchase bill: purchases and input GST
+// are debited against the vendor's payable.
+func repostBill(ctx context.Context, tx *sql.Tx, billID uuid.UUID) error {
+	if err := unpostJournal(ctx, tx, JournalSourceBill, billID); err != nil {
+		return err
+	}
+
+	var (
+		busID                          uuid.UUID
+		billNo, vendorName             string
+		billDate                       time.Time
+		taxableValue, cgst, sgst, igst float64
+	)
+	err := tx.QueryRowContext(ctx, `
+        SELECT b.buss_id, b.bill_no, v.name, b.bill_date, b.taxable_amount, b.cgst_amount, b.sgst_amount, b.igst_amount
+        FROM purchase_bill b
+        JOIN vendor v ON v.id = b.vendor_id
+        WHERE b.id = $1
+    `, billID).Scan(&busID, &billNo, &vendorName, &billDate, &taxableValue, &cgst, &sgst, &igst)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	draft := newDraft(busID, billDate, fmt.Sprintf("Bill %s from %s", billNo, vendorName), JournalSourceBill)
+	draft.add("purchases", taxableValue, 0)
+	draft.add("input_cgst", cgst, 0)
+	draft.add("input_sgst", sgst, 0)
+	draft.add("input_igst", igst, 0)
+	draft.add("payable", 0, taxableValue+cgst+sgst+igst)
+
+	return draft.post(ctx, tx, billID)
+}
+
+// repostBillPayment rebuilds the entry of a payment made against a bill.
+func repostBillPayment(ctx context.Context, tx *sql.Tx, paymentID uuid.UUID) error {
+	if err := unpostJournal(ctx, tx, JournalSourceBillPayment, paymentID); err != nil {
+		return err
+	}
+
+	var (
+		busID          uuid.UUID
+		billNo, method string
+		paidOn         time.Time
+		amount         float64
+	)
+	err := tx.QueryRowContext(ctx, `
+        SELECT b.buss_id, b.bill_no, p.method, p.paid_on, p.amount
+        FROM bill_payment p
+        JOIN purchase_bill b ON b.id = p.bill_id
+        WHERE p.id = $1
+    `, paymentID).Scan(&busID, &billNo, &method, &paidOn, &amount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	draft := newDraft(busID, paidOn, fmt.Sprintf("Payment for bill %s", billNo), JournalSourceBillPayment)
+	draft.add("payable", amount, 0)
+	draft.add(settlementAccount(method), 0, amount)
+
+	return draft.post(ctx, tx, paymentID)
+}
@@ -0,0 +1,409 @@
+package store
+
+import (
+	"billify-api/internal/latefee"
+	"context"
+	"database/sql"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	InvoiceKindInvoice   = "invoice"
+	InvoiceKindDebitNote = "debit_note"
+)
+
+// lateFeeProductName is the service product late fees are billed as. It is
+// created for a business the first time a fee is issued.
+const lateFeeProductName = "Late payment charges"
+
+type LateFeeStore struct {
+	db *sql.DB
+}
+
+type queryer interface {
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+}
+
+func (s *LateFeeStore) GetPolicies(ctx context.Context, busID uuid.UUID) ([]*LateFeePolicy, error) {
+	query := `
+        SELECT p.id, p.buss_id, p.cust_id, COALESCE(c.name, ''), p.kind, p.flat_amount, p.annual_rate, p.grace_days, p.cap, p.updated_at
+        FROM late_fee_policy p
+        LEFT JOIN customer c ON c.id = p.cust_id
+        WHERE p.buss_id = $1
+        ORDER BY p.cust_id IS NOT NULL, c.name
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, busID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := []*LateFeePolicy{}
+	for rows.Next() {
+		policy := &LateFeePolicy{}
+		err := rows.Scan(
+			&policy.ID,
+			&policy.BusID,
+			&policy.CustID,
+			&policy.CustName,
+			&policy.Kind,
+			&policy.FlatAmount,
+			&policy.AnnualRate,
+			&policy.GraceDays,
+			&policy.Cap,
+			&policy.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+
+	return policies, rows.Err()
+}
+
+// SavePolicy sets the business wide policy, or a customer's own policy when
+// CustID is set, replacing the one already there.
+func (s *LateFeeStore) SavePolicy(ctx context.Context, policy *LateFeePolicy) error {
+	query := `
+        INSERT INTO late_fee_policy (buss_id, cust_id, kind, flat_amount, annual_rate, grace_days, cap)
+        SELECT $1, $2, $3, $4, $5, $6, $7
+        WHERE $2::uuid IS NULL OR EXISTS (SELECT 1 FROM customer WHERE id = $2 AND buss_id = $1)
+        ON CONFLICT (buss_id, cust_id) DO UPDATE
+        SET kind = EXCLUDED.kind,
+            flat_amount = EXCLUDED.flat_amount,
+            annual_rate = EXCLUDED.annual_rate,
+            grace_days = EXCLUDED.grace_days,
+            cap = EXCLUDED.cap,
+            updated_at = now()
+        RETURNING id, updated_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		policy.BusID,
+		policy.CustID,
+		policy.Kind,
+		policy.FlatAmount,
+		policy.AnnualRate,
+		policy.GraceDays,
+		policy.Cap,
+	).Scan(
+		&policy.ID,
+		&policy.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows || isForeignKeyViolation(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *LateFeeStore) DeletePolicy(ctx context.Context, policyID uuid.UUID) error {
+	query := `DELETE FROM late_fee_policy WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, policyID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetAccruals lists the unpaid invoices of a business, or of one customer,
+// that have accrued late fees by asOf under the policy that applies to
+// them, with what has been charged on each so far.
+func (s *LateFeeStore) GetAccruals(ctx context.Context, busID uuid.UUID, custID *uuid.UUID, asOf time.Time) ([]*LateFeeAccrual, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return lateFeeAccruals(ctx, s.db, busID, custID, asOf)
+}
+
+// Issue bills the pending late fees of the business as of asOf, one
+// document per overdue invoice, each either an invoice or a debit note
+// against the overdue one. invoiceIDs limits it to those invoices when not
+// empty. The fee is billed as a single line of the late payment charges
+// product, taxed at that product's rate, and posted to the late fee income
+// account.
+func (s *LateFeeStore) Issue(ctx context.Context, busID uuid.UUID, kind string, asOf time.Time, invoiceIDs []uuid.UUID) ([]*LateFeeCharge, error) {
+	wanted := make(map[uuid.UUID]bool, len(invoiceIDs))
+	for _, id := range invoiceIDs {
+		wanted[id] = true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	charges := []*LateFeeCharge{}
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		// Issuing twice at once would charge the same fee twice and race
+		// for invoice numbers.
+		var locked uuid.UUID
+		err := tx.QueryRowContext(ctx, `SELECT buss_id FROM business WHERE buss_id = $1 FOR UPDATE`, busID).Scan(&locked)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		accruals, err := lateFeeAccruals(ctx, tx, busID, nil, asOf)
+		if err != nil {
+			return err
+		}
+
+		var productID uuid.UUID
+		var taxRate float64
+		for _, accrual := range accruals {
+			if accrual.Pending <= 0 || (len(wanted) > 0 && !wanted[accrual.InvID]) {
+				continue
+			}
+
+			if productID == uuid.Nil {
+				productID, taxRate, err = ensureLateFeeProduct(ctx, tx, busID)
+				if err != nil {
+					return err
+				}
+			}
+
+			charge := &LateFeeCharge{
+				BusID:     busID,
+				InvID:     accrual.InvID,
+				InvNo:     accrual.InvNo,
+				Kind:      kind,
+				Amount:    accrual.Pending,
+				AccruedTo: asOf,
+			}
+			total := math.Round(charge.Amount*(100+taxRate)) / 100
+
+			err := tx.QueryRowContext(ctx, `
+                INSERT INTO invoice (inv_no, buss_id, cust_id, total_amount, inv_date, due_date, kind, ref_inv_id)
+                SELECT COALESCE(MAX(inv_no), 0) + 1, $1, $2, $3, $4, $4, $5, $6
+                FROM invoice
+                WHERE buss_id = $1
+                RETURNING id, inv_no
+            `, busID, accrual.CustID, total, asOf, kind, accrual.InvID).Scan(&charge.ChargeInvID, &charge.ChargeInvNo)
+			if err != nil {
+				if isUniqueViolation(err) {
+					return ErrDuplicateInvoice
+				}
+				return err
+			}
+
+			_, err = tx.ExecContext(ctx, `
+                INSERT INTO invoice_item (inv_id, prod_id, quantity, unit_price, tax_rate)
+                VALUES ($1, $2, 1, $3, $4)
+            `, charge.ChargeInvID, productID, charge.Amount, taxRate)
+			if err != nil {
+				return err
+			}
+
+			err = tx.QueryRowContext(ctx, `
+                INSERT INTO late_fee_charge (buss_id, inv_id, charge_inv_id, amount, accrued_to)
+                VALUES ($1, $2, $3, $4, $5)
+                RETURNING id, created_at
+            `, busID, charge.InvID, charge.ChargeInvID, charge.Amount, asOf).Scan(&charge.ID, &charge.CreatedAt)
+			if err != nil {
+				return err
+			}
+
+			if err := repostInvoice(ctx, tx, charge.ChargeInvID); err != nil {
+				return err
+			}
+
+			charges = append(charges, charge)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return charges, nil
+}
+
+func ensureLateFeeProduct(ctx context.Context, tx *sql.Tx, busID uuid.UUID) (uuid.UUID, float64, error) {
+	var id uuid.UUID
+	var taxRate float64
+	err := tx.QueryRowContext(ctx, `
+        INSERT INTO product (buss_id, name, price, tax_rate, unit, hsn_code, item_type, uqc)
+        VALUES ($1, $2, 0, 0, 'nos', '', 'service', 'OTH')
+        ON CONFLICT (buss_id, name) DO UPDATE SET name = EXCLUDED.name
+        RETURNING id, tax_rate
+    `, busID, lateFeeProductName).Scan(&id, &taxRate)
+	return id, taxRate, err
+}
+
+// lateFeeAccruals works out the fees accrued by asOf on the unpaid invoices
+// of a business. A customer's own policy takes precedence over the business
+// wide one, and late fee documents never accrue fees themselves.
+func lateFeeAccruals(ctx context.Context, db queryer, busID uuid.UUID, custID *uuid.UUID, asOf time.Time) ([]*LateFeeAccrual, error) {
+	query := `
+        SELECT
+            i.id,
+            i.inv_no,
+            i.cust_id,
+            c.name,
+            i.total_amount,
+            i.due_date,
+            p.kind,
+            p.flat_amount,
+            p.annual_rate,
+            p.grace_days,
+            p.cap,
+            COALESCE((SELECT SUM(f.amount) FROM late_fee_charge f WHERE f.inv_id = i.id), 0)
+        FROM invoice i
+        JOIN customer c ON c.id = i.cust_id
+        JOIN LATERAL (
+            SELECT lp.kind, lp.flat_amount, lp.annual_rate, lp.grace_days, lp.cap
+            FROM late_fee_policy lp
+            WHERE lp.buss_id = i.buss_id AND (lp.cust_id = i.cust_id OR lp.cust_id IS NULL)
+            ORDER BY lp.cust_id NULLS LAST
+            LIMIT 1
+        ) p ON TRUE
+        WHERE i.buss_id = $1
+            AND ($2::uuid IS NULL OR i.cust_id = $2)
+            AND NOT i.is_paid
+            AND i.kind = 'invoice'
+            AND NOT EXISTS (SELECT 1 FROM late_fee_charge f WHERE f.charge_inv_id = i.id)
+            AND i.due_date::date + p.grace_days < $3::date
+        ORDER BY c.name, i.inv_no
+    `
+
+	paymentsQuery := `
+        SELECT inv_id, paid_on, amount
+        FROM payment
+        WHERE inv_id = ANY($1::uuid[]) AND paid_on < $2
+    `
+
+	day := asOf.Format("2006-01-02")
+
+	rows, err := db.QueryContext(ctx, query, busID, custID, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type candidate struct {
+		accrual *LateFeeAccrual
+		policy  latefee.Policy
+		invoice latefee.Invoice
+	}
+
+	var candidates []*candidate
+	var ids []uuid.UUID
+	for rows.Next() {
+		c := &candidate{accrual: &LateFeeAccrual{}}
+		err := rows.Scan(
+			&c.accrual.InvID,
+			&c.accrual.InvNo,
+			&c.accrual.CustID,
+			&c.accrual.CustName,
+			&c.invoice.Total,
+			&c.invoice.DueDate,
+			&c.policy.Kind,
+			&c.policy.FlatAmount,
+			&c.policy.AnnualRate,
+			&c.policy.GraceDays,
+			&c.policy.Cap,
+			&c.accrual.Charged,
+		)
+		if err != nil {
+			return nil, err
+		}
+		c.accrual.DueDate = c.invoice.DueDate
+		candidates = append(candidates, c)
+		ids = append(ids, c.accrual.InvID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	accruals := []*LateFeeAccrual{}
+	if len(candidates) == 0 {
+		return accruals, nil
+	}
+
+	// Payments received after asOf are left out, so that accruals as of a
+	// past date come out the same as they did on the day.
+	payments := make(map[uuid.UUID][]latefee.Payment, len(candidates))
+	rows, err = db.QueryContext(ctx, paymentsQuery, pq.Array(ids), asOf.AddDate(0, 0, 1).Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var invID uuid.UUID
+		var payment latefee.Payment
+		if err := rows.Scan(&invID, &payment.Date, &payment.Amount); err != nil {
+			return nil, err
+		}
+		payments[invID] = append(payments[invID], payment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	asOfDay := time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, time.UTC)
+	for _, c := range candidates {
+		c.invoice.Payments = payments[c.accrual.InvID]
+
+		balance := c.invoice.Total
+		for _, payment := range c.invoice.Payments {
+			balance -= payment.Amount
+		}
+
+		due := time.Date(c.invoice.DueDate.Year(), c.invoice.DueDate.Month(), c.invoice.DueDate.Day(), 0, 0, 0, 0, time.UTC)
+
+		c.accrual.Balance = math.Round(balance*100) / 100
+		c.accrual.DaysOverdue = int(asOfDay.Sub(due).Hours() / 24)
+		c.accrual.Accrued = latefee.Accrued(c.policy, c.invoice, asOf)
+		c.accrual.Pending = math.Max(0, math.Round((c.accrual.Accrued-c.accrual.Charged)*100)/100)
+		if c.accrual.Accrued == 0 {
+			continue
+		}
+		accruals = append(accruals, c.accrual)
+	}
+
+	return accruals, nil
+}
+
+// pendingLateFees totals the fees accrued but not yet charged per customer.
+func pendingLateFees(accruals []*LateFeeAccrual) map[uuid.UUID]float64 {
+	pending := make(map[uuid.UUID]float64)
+	for _, accrual := range accruals {
+		pending[accrual.CustID] = math.Round((pending[accrual.CustID]+accrual.Pending)*100) / 100
+	}
+	return pending
+}
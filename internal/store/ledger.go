@@ -0,0 +1,500 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDuplicateAccount   = errors.New("account with this code already exists")
+	ErrAccountInUse       = errors.New("account has journal lines posted to it")
+	ErrSystemAccount      = errors.New("account is used for automatic postings and cannot be deleted")
+	ErrInvalidAccount     = errors.New("account does not exist for this business")
+	ErrInvalidJournalLine = errors.New("each journal line needs either a debit or a credit")
+	ErrUnbalancedEntry    = errors.New("journal entry debits and credits do not balance")
+	ErrPostedEntry        = errors.New("journal entry was posted from a document and can only change with it")
+)
+
+type LedgerStore struct {
+	db *sql.DB
+}
+
+// GetAccounts returns the chart of accounts of a business, creating the
+// default accounts first if they do not exist yet.
+func (s *LedgerStore) GetAccounts(ctx context.Context, busID uuid.UUID) ([]*Account, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	accounts := []*Account{}
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		for _, account := range systemAccounts {
+			if _, err := ensureAccount(ctx, tx, busID, account.Key, account.Code, account.Name, account.Type); err != nil {
+				return err
+			}
+		}
+
+		rows, err := tx.QueryContext(ctx, `
+            SELECT id, buss_id, code, name, type, system_key, created_at
+            FROM account
+            WHERE buss_id = $1
+            ORDER BY code
+        `, busID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			account := &Account{}
+			err := rows.Scan(
+				&account.ID,
+				&account.BusID,
+				&account.Code,
+				&account.Name,
+				&account.Type,
+				&account.SystemKey,
+				&account.CreatedAt,
+			)
+			if err != nil {
+				return err
+			}
+			accounts = append(accounts, account)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+func (s *LedgerStore) CreateAccount(ctx context.Context, account *Account) error {
+	query := `
+        INSERT INTO account (buss_id, code, name, type)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, account.BusID, account.Code, account.Name, account.Type).Scan(
+		&account.ID,
+		&account.CreatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicateAccount
+		}
+		return err
+	}
+
+	return nil
+}
+
+// UpdateAccount renames or renumbers an account. Its type is fixed once
+// created since existing postings depend on it.
+func (s *LedgerStore) UpdateAccount(ctx context.Context, account *Account) error {
+	query := `
+        UPDATE account
+        SET code = $2,
+            name = $3
+        WHERE id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, account.ID, account.Code, account.Name)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicateAccount
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *LedgerStore) DeleteAccount(ctx context.Context, accountID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var systemKey *string
+	err := s.db.QueryRowContext(ctx, `SELECT system_key FROM account WHERE id = $1`, accountID).Scan(&systemKey)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+	if systemKey != nil {
+		return ErrSystemAccount
+	}
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM account WHERE id = $1`, accountID); err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrAccountInUse
+		}
+		return err
+	}
+
+	return nil
+}
+
+// CreateEntry posts a manual journal entry. Every line must use an account of
+// the entry's business and the lines must balance.
+func (s *LedgerStore) CreateEntry(ctx context.Context, entry *JournalEntry) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	entry.SourceType = nil
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := postJournal(ctx, tx, entry, uuid.Nil); err != nil {
+			return err
+		}
+		if entry.ID == uuid.Nil {
+			return ErrInvalidJournalLine
+		}
+		return nil
+	})
+}
+
+// journalEntryQuery selects entries joined to their lines, one row per line.
+const journalEntryQuery = `
+    SELECT
+        e.id,
+        e.buss_id,
+        e.entry_date,
+        e.narration,
+        e.source_type,
+        COALESCE(e.inv_id, e.payment_id, e.expense_id, e.bill_id, e.bill_payment_id),
+        e.created_at,
+        l.id,
+        l.account_id,
+        a.code,
+        a.name,
+        l.debit,
+        l.credit
+    FROM journal_entry e
+    JOIN journal_line l ON l.entry_id = e.id
+    JOIN account a ON a.id = l.account_id
+`
+
+func scanJournalEntries(rows *sql.Rows) ([]*JournalEntry, error) {
+	entries := []*JournalEntry{}
+	for rows.Next() {
+		entry := &JournalEntry{}
+		var line JournalLine
+		err := rows.Scan(
+			&entry.ID,
+			&entry.BusID,
+			&entry.EntryDate,
+			&entry.Narration,
+			&entry.SourceType,
+			&entry.SourceID,
+			&entry.CreatedAt,
+			&line.ID,
+			&line.AccountID,
+			&line.AccountCode,
+			&line.AccountName,
+			&line.Debit,
+			&line.Credit,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(entries) == 0 || entries[len(entries)-1].ID != entry.ID {
+			entries = append(entries, entry)
+		}
+		current := entries[len(entries)-1]
+		current.Lines = append(current.Lines, line)
+	}
+
+	return entries, rows.Err()
+}
+
+func (s *LedgerStore) GetEntryByID(ctx context.Context, entryID uuid.UUID) (*JournalEntry, error) {
+	query := journalEntryQuery + `
+        WHERE e.id = $1
+        ORDER BY l.debit DESC, a.code
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, entryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries, err := scanJournalEntries(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return entries[0], nil
+}
+
+// GetEntries lists the journal entries dated between from and to
+// (inclusive), oldest first.
+func (s *LedgerStore) GetEntries(ctx context.Context, busID uuid.UUID, from, to time.Time) ([]*JournalEntry, error) {
+	query := journalEntryQuery + `
+        WHERE e.buss_id = $1 AND e.entry_date BETWEEN $2 AND $3
+        ORDER BY e.entry_date, e.created_at, e.id, l.debit DESC, a.code
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, busID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanJournalEntries(rows)
+}
+
+// DeleteEntry removes a manual journal entry. Entries posted from documents
+// are removed by deleting or changing the document instead.
+func (s *LedgerStore) DeleteEntry(ctx context.Context, entryID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var sourceType *string
+	err := s.db.QueryRowContext(ctx, `SELECT source_type FROM journal_entry WHERE id = $1`, entryID).Scan(&sourceType)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+	if sourceType != nil {
+		return ErrPostedEntry
+	}
+
+	_, err = s.db.ExecContext(ctx, `DELETE FROM journal_entry WHERE id = $1 AND source_type IS NULL`, entryID)
+
+	return err
+}
+
+// GetTrialBalance nets every account with postings up to asOf (inclusive)
+// into a debit or credit balance.
+func (s *LedgerStore) GetTrialBalance(ctx context.Context, busID uuid.UUID, asOf time.Time) (*TrialBalance, error) {
+	query := `
+        SELECT a.id, a.code, a.name, a.type, SUM(l.debit), SUM(l.credit)
+        FROM account a
+        JOIN journal_line l ON l.account_id = a.id
+        JOIN journal_entry e ON e.id = l.entry_id
+        WHERE a.buss_id = $1 AND e.entry_date <= $2
+        GROUP BY a.id, a.code, a.name, a.type
+        ORDER BY a.code
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, busID, asOf.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &TrialBalance{BusID: busID, AsOf: asOf, Accounts: []TrialBalanceRow{}}
+	for rows.Next() {
+		var row TrialBalanceRow
+		var debit, credit float64
+		if err := rows.Scan(&row.AccountID, &row.Code, &row.Name, &row.Type, &debit, &credit); err != nil {
+			return nil, err
+		}
+
+		balance := toPaise(debit) - toPaise(credit)
+		switch {
+		case balance > 0:
+			row.Debit = float64(balance) / 100
+		case balance < 0:
+			row.Credit = float64(-balance) / 100
+		default:
+			continue
+		}
+
+		report.Accounts = append(report.Accounts, row)
+		report.TotalDebit += row.Debit
+		report.TotalCredit += row.Credit
+	}
+
+	return report, rows.Err()
+}
+
+// GetGeneralLedger lists the postings to each account between from and to
+// (inclusive) with running balances. Balances are debit positive. A nil
+// accountID covers every account that has a balance or postings.
+func (s *LedgerStore) GetGeneralLedger(ctx context.Context, busID uuid.UUID, accountID *uuid.UUID, from, to time.Time) (*GeneralLedger, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	fromDate, toDate := from.Format("2006-01-02"), to.Format("2006-01-02")
+
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT
+            a.id,
+            a.code,
+            a.name,
+            a.type,
+            COALESCE((
+                SELECT SUM(l.debit - l.credit)
+                FROM journal_line l
+                JOIN journal_entry e ON e.id = l.entry_id
+                WHERE l.account_id = a.id AND e.entry_date < $3
+            ), 0)
+        FROM account a
+        WHERE a.buss_id = $1 AND ($2::uuid IS NULL OR a.id = $2)
+        ORDER BY a.code
+    `, busID, accountID, fromDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ledgers := []*AccountLedger{}
+	byID := map[uuid.UUID]*AccountLedger{}
+	for rows.Next() {
+		ledger := &AccountLedger{Lines: []LedgerLine{}}
+		if err := rows.Scan(&ledger.AccountID, &ledger.Code, &ledger.Name, &ledger.Type, &ledger.Opening); err != nil {
+			return nil, err
+		}
+		ledger.Closing = ledger.Opening
+		ledgers = append(ledgers, ledger)
+		byID[ledger.AccountID] = ledger
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if accountID != nil && len(ledgers) == 0 {
+		return nil, ErrNotFound
+	}
+
+	lines, err := s.db.QueryContext(ctx, `
+        SELECT l.account_id, e.id, e.entry_date, e.narration, e.source_type, l.debit, l.credit
+        FROM journal_line l
+        JOIN journal_entry e ON e.id = l.entry_id
+        WHERE e.buss_id = $1 AND ($2::uuid IS NULL OR l.account_id = $2) AND e.entry_date BETWEEN $3 AND $4
+        ORDER BY e.entry_date, e.created_at, e.id
+    `, busID, accountID, fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+	defer lines.Close()
+
+	for lines.Next() {
+		var id uuid.UUID
+		var line LedgerLine
+		err := lines.Scan(&id, &line.EntryID, &line.EntryDate, &line.Narration, &line.SourceType, &line.Debit, &line.Credit)
+		if err != nil {
+			return nil, err
+		}
+
+		ledger, ok := byID[id]
+		if !ok {
+			continue
+		}
+		ledger.Debit += line.Debit
+		ledger.Credit += line.Credit
+		ledger.Closing = float64(toPaise(ledger.Closing)+toPaise(line.Debit)-toPaise(line.Credit)) / 100
+		line.Balance = ledger.Closing
+		ledger.Lines = append(ledger.Lines, line)
+	}
+	if err := lines.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &GeneralLedger{BusID: busID, From: from, To: to, Accounts: []*AccountLedger{}}
+	for _, ledger := range ledgers {
+		if accountID == nil && ledger.Opening == 0 && len(ledger.Lines) == 0 {
+			continue
+		}
+		report.Accounts = append(report.Accounts, ledger)
+	}
+
+	return report, nil
+}
+
+// Rebuild re-posts the journal entries of every document of a business, for
+// books that predate the ledger or after a change to the posting rules.
+// Manual entries are left as they are.
+func (s *LedgerStore) Rebuild(ctx context.Context, busID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, ImportTimeoutDuration)
+	defer cancel()
+
+	sources := []struct {
+		query  string
+		repost func(context.Context, *sql.Tx, uuid.UUID) error
+	}{
+		{`SELECT id FROM invoice WHERE buss_id = $1`, repostInvoice},
+		{`SELECT id FROM payment WHERE buss_id = $1`, repostPayment},
+		{`SELECT id FROM credit_note WHERE buss_id = $1`, repostCreditNote},
+		{`SELECT id FROM expense WHERE buss_id = $1`, repostExpense},
+		{`SELECT id FROM purchase_bill WHERE buss_id = $1`, repostBill},
+		{`SELECT p.id FROM bill_payment p JOIN purchase_bill b ON b.id = p.bill_id WHERE b.buss_id = $1`, repostBillPayment},
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		for _, source := range sources {
+			ids, err := queryIDs(ctx, tx, source.query, busID)
+			if err != nil {
+				return err
+			}
+
+			for _, id := range ids {
+				if err := source.repost(ctx, tx, id); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+func queryIDs(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]uuid.UUID, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -0,0 +1,450 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RoleOwner      = "owner"
+	RoleAdmin      = "admin"
+	RoleAccountant = "accountant"
+	RoleSales      = "sales"
+	RoleViewer     = "viewer"
+
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+	InvitationRevoked  = "revoked"
+)
+
+// Records whose business can be looked up with ResourceBusiness.
+const (
+	ResourceBusiness        = "business"
+	ResourceInvoice         = "invoice"
+	ResourcePayment         = "payment"
+	ResourceCreditNote      = "credit_note"
+	ResourceInvoiceShare    = "invoice_share"
+	ResourceCustomer        = "customer"
+	ResourceProduct         = "product"
+	ResourceProductVariant  = "product_variant"
+	ResourceCategory        = "category"
+	ResourcePriceList       = "price_list"
+	ResourceVendor          = "vendor"
+	ResourcePurchaseBill    = "purchase_bill"
+	ResourceBillPayment     = "bill_payment"
+	ResourceExpense         = "expense"
+	ResourceAccount         = "account"
+	ResourceJournalEntry    = "journal_entry"
+	ResourceBankTransaction = "bank_transaction"
+	ResourceLateFeePolicy   = "late_fee_policy"
+	ResourceReminderRule    = "reminder_rule"
+	ResourceInvitation      = "business_invitation"
+)
+
+var resourceBusinessQueries = map[string]string{
+	ResourceBusiness:        `SELECT buss_id FROM business WHERE buss_id = $1`,
+	ResourceInvoice:         `SELECT buss_id FROM invoice WHERE id = $1`,
+	ResourcePayment:         `SELECT buss_id FROM payment WHERE id = $1`,
+	ResourceCreditNote:      `SELECT buss_id FROM credit_note WHERE id = $1`,
+	ResourceInvoiceShare:    `SELECT i.buss_id FROM invoice_share s JOIN invoice i ON i.id = s.inv_id WHERE s.id = $1`,
+	ResourceCustomer:        `SELECT buss_id FROM customer WHERE id = $1`,
+	ResourceProduct:         `SELECT buss_id FROM product WHERE id = $1`,
+	ResourceProductVariant:  `SELECT buss_id FROM product_variant WHERE id = $1`,
+	ResourceCategory:        `SELECT buss_id FROM category WHERE id = $1`,
+	ResourcePriceList:       `SELECT buss_id FROM price_list WHERE id = $1`,
+	ResourceVendor:          `SELECT buss_id FROM vendor WHERE id = $1`,
+	ResourcePurchaseBill:    `SELECT buss_id FROM purchase_bill WHERE id = $1`,
+	ResourceBillPayment:     `SELECT b.buss_id FROM bill_payment p JOIN purchase_bill b ON b.id = p.bill_id WHERE p.id = $1`,
+	ResourceExpense:         `SELECT buss_id FROM expense WHERE id = $1`,
+	ResourceAccount:         `SELECT buss_id FROM account WHERE id = $1`,
+	ResourceJournalEntry:    `SELECT buss_id FROM journal_entry WHERE id = $1`,
+	ResourceBankTransaction: `SELECT buss_id FROM bank_transaction WHERE id = $1`,
+	ResourceLateFeePolicy:   `SELECT buss_id FROM late_fee_policy WHERE id = $1`,
+	ResourceReminderRule:    `SELECT buss_id FROM reminder_rule WHERE id = $1`,
+	ResourceInvitation:      `SELECT buss_id FROM business_invitation WHERE id = $1`,
+}
+
+var (
+	ErrAlreadyMember       = errors.New("user is already a member of this business")
+	ErrDuplicateInvitation = errors.New("an invitation for this email is already pending")
+)
+
+type MembershipStore struct {
+	db *sql.DB
+}
+
+// ResourceBusiness returns the business a record of the given resource
+// belongs to.
+func (s *MembershipStore) ResourceBusiness(ctx context.Context, resource string, id uuid.UUID) (uuid.UUID, error) {
+	query, ok := resourceBusinessQueries[resource]
+	if !ok {
+		return uuid.Nil, fmt.Errorf("unknown resource %q", resource)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var busID uuid.UUID
+	if err := s.db.QueryRowContext(ctx, query, id).Scan(&busID); err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.Nil, ErrNotFound
+		}
+		return uuid.Nil, err
+	}
+
+	return busID, nil
+}
+
+// GetMember returns the user's membership of the business, or ErrNotFound
+// when they are not a member.
+func (s *MembershipStore) GetMember(ctx context.Context, busID, userID uuid.UUID) (*Member, error) {
+	query := `
+        SELECT m.buss_id, m.user_id, m.role, COALESCE(u.first_name, ''), COALESCE(u.last_name, ''), u.email,
+               f.enabled_at IS NOT NULL, b.require_mfa, m.created_at
+        FROM business_member m
+        JOIN users u ON u.id = m.user_id
+        JOIN business b ON b.buss_id = m.buss_id
+        LEFT JOIN user_mfa f ON f.user_id = m.user_id
+        WHERE m.buss_id = $1 AND m.user_id = $2
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	member := &Member{}
+	err := s.db.QueryRowContext(ctx, query, busID, userID).Scan(
+		&member.BusID,
+		&member.UserID,
+		&member.Role,
+		&member.FirstName,
+		&member.LastName,
+		&member.Email,
+		&member.MFAEnabled,
+		&member.MFARequired,
+		&member.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return member, nil
+}
+
+func (s *MembershipStore) GetMembers(ctx context.Context, busID uuid.UUID) ([]*Member, error) {
+	query := `
+        SELECT m.buss_id, m.user_id, m.role, COALESCE(u.first_name, ''), COALESCE(u.last_name, ''), u.email,
+               f.enabled_at IS NOT NULL, b.require_mfa, m.created_at
+        FROM business_member m
+        JOIN users u ON u.id = m.user_id
+        JOIN business b ON b.buss_id = m.buss_id
+        LEFT JOIN user_mfa f ON f.user_id = m.user_id
+        WHERE m.buss_id = $1
+        ORDER BY m.role = 'owner' DESC, m.created_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, busID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*Member{}
+	for rows.Next() {
+		member := &Member{}
+		err := rows.Scan(
+			&member.BusID,
+			&member.UserID,
+			&member.Role,
+			&member.FirstName,
+			&member.LastName,
+			&member.Email,
+			&member.MFAEnabled,
+			&member.MFARequired,
+			&member.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// UpdateRole changes a member's role. The owner's membership cannot be
+// changed this way.
+func (s *MembershipStore) UpdateRole(ctx context.Context, busID, userID uuid.UUID, role string) error {
+	query := `
+        UPDATE business_member
+        SET role = $3
+        WHERE buss_id = $1 AND user_id = $2 AND role <> 'owner'
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, busID, userID, role)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Remove takes a member out of the business. The owner cannot be removed.
+func (s *MembershipStore) Remove(ctx context.Context, busID, userID uuid.UUID) error {
+	query := `
+        DELETE FROM business_member
+        WHERE buss_id = $1 AND user_id = $2 AND role <> 'owner'
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, busID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// CreateInvitation invites an email address to the business. It fails with
+// ErrAlreadyMember when a user with that email is already a member, and
+// with ErrDuplicateInvitation while an earlier invitation is pending.
+func (s *MembershipStore) CreateInvitation(ctx context.Context, invitation *Invitation) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var member bool
+		err := tx.QueryRowContext(ctx, `
+            SELECT EXISTS (
+                SELECT 1
+                FROM business_member m
+                JOIN users u ON u.id = m.user_id
+                WHERE m.buss_id = $1 AND u.email = $2
+            )
+        `, invitation.BusID, invitation.Email).Scan(&member)
+		if err != nil {
+			return err
+		}
+		if member {
+			return ErrAlreadyMember
+		}
+
+		// An invitation left to expire no longer blocks a new one.
+		_, err = tx.ExecContext(ctx, `
+            UPDATE business_invitation
+            SET status = 'revoked', responded_at = now()
+            WHERE buss_id = $1 AND email = $2 AND status = 'pending' AND expires_at <= now()
+        `, invitation.BusID, invitation.Email)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRowContext(ctx, `
+            INSERT INTO business_invitation (buss_id, email, role, invited_by, expires_at)
+            VALUES ($1, $2, $3, $4, $5)
+            RETURNING id, status, created_at, (SELECT name FROM business WHERE buss_id = $1)
+        `,
+			invitation.BusID,
+			invitation.Email,
+			invitation.Role,
+			invitation.InvitedBy,
+			invitation.ExpiresAt,
+		).Scan(
+			&invitation.ID,
+			&invitation.Status,
+			&invitation.CreatedAt,
+			&invitation.BusinessName,
+		)
+		if err != nil {
+			switch {
+			case isUniqueViolation(err):
+				return ErrDuplicateInvitation
+			case isForeignKeyViolation(err):
+				return ErrNotFound
+			}
+			return err
+		}
+
+		return nil
+	})
+}
+
+const invitationQuery = `
+    SELECT
+        i.id,
+        i.buss_id,
+        b.name,
+        i.email,
+        i.role,
+        i.invited_by,
+        i.status,
+        i.expires_at,
+        i.responded_at,
+        i.created_at
+    FROM business_invitation i
+    JOIN business b ON b.buss_id = i.buss_id
+`
+
+func scanInvitation(row interface{ Scan(...any) error }) (*Invitation, error) {
+	invitation := &Invitation{}
+	err := row.Scan(
+		&invitation.ID,
+		&invitation.BusID,
+		&invitation.BusinessName,
+		&invitation.Email,
+		&invitation.Role,
+		&invitation.InvitedBy,
+		&invitation.Status,
+		&invitation.ExpiresAt,
+		&invitation.RespondedAt,
+		&invitation.CreatedAt,
+	)
+	return invitation, err
+}
+
+func (s *MembershipStore) queryInvitations(ctx context.Context, query string, args ...any) ([]*Invitation, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []*Invitation{}
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	return invitations, rows.Err()
+}
+
+func (s *MembershipStore) GetInvitationsByBusID(ctx context.Context, busID uuid.UUID) ([]*Invitation, error) {
+	return s.queryInvitations(ctx, invitationQuery+`WHERE i.buss_id = $1 ORDER BY i.created_at DESC`, busID)
+}
+
+// GetPendingInvitations lists the invitations an email address can still
+// accept.
+func (s *MembershipStore) GetPendingInvitations(ctx context.Context, email string) ([]*Invitation, error) {
+	return s.queryInvitations(ctx, invitationQuery+`
+        WHERE i.email = $1 AND i.status = 'pending' AND i.expires_at > now()
+        ORDER BY i.created_at DESC
+    `, email)
+}
+
+func (s *MembershipStore) RevokeInvitation(ctx context.Context, invitationID uuid.UUID) error {
+	query := `
+        UPDATE business_invitation
+        SET status = 'revoked', responded_at = now()
+        WHERE id = $1 AND status = 'pending'
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, invitationID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// RespondToInvitation accepts or declines a pending invitation sent to the
+// user's email. Accepting makes the user a member with the invited role. An
+// invitation that is not pending, has expired or was sent to someone else
+// is ErrNotFound.
+func (s *MembershipStore) RespondToInvitation(ctx context.Context, invitationID uuid.UUID, user *User, accept bool) (*Invitation, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	status := InvitationDeclined
+	if accept {
+		status = InvitationAccepted
+	}
+
+	var invitation *Invitation
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var busID uuid.UUID
+		err := tx.QueryRowContext(ctx, `
+            UPDATE business_invitation
+            SET status = $3, responded_at = now()
+            WHERE id = $1 AND email = $2 AND status = 'pending' AND expires_at > now()
+            RETURNING buss_id
+        `, invitationID, user.Email, status).Scan(&busID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		invitation, err = scanInvitation(tx.QueryRowContext(ctx, invitationQuery+`WHERE i.id = $1`, invitationID))
+		if err != nil {
+			return err
+		}
+
+		if !accept {
+			return nil
+		}
+
+		_, err = tx.ExecContext(ctx, `
+            INSERT INTO business_member (buss_id, user_id, role)
+            VALUES ($1, $2, $3)
+            ON CONFLICT (buss_id, user_id) DO NOTHING
+        `, busID, user.ID, invitation.Role)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
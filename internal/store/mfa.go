@@ -0,0 +1,306 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// MaxMFAAttempts is how many codes can be tried against one login
+// challenge before the user has to sign in with their password again.
+const MaxMFAAttempts = 5
+
+var (
+	ErrMFAEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrMFACodeUsed  = errors.New("this code has already been used")
+	ErrMFANotActive = errors.New("two-factor authentication is not enabled")
+)
+
+type MFAStore struct {
+	db *sql.DB
+}
+
+// GetByUserID returns the user's enrolment, pending or enabled, or
+// ErrNotFound when they have not started one.
+func (s *MFAStore) GetByUserID(ctx context.Context, userID uuid.UUID) (*UserMFA, error) {
+	query := `
+        SELECT f.user_id,
+               f.secret,
+               f.enabled_at IS NOT NULL,
+               f.enabled_at,
+               f.last_used_step,
+               (SELECT COUNT(*) FROM mfa_recovery_code c WHERE c.user_id = f.user_id AND c.used_at IS NULL)
+        FROM user_mfa f
+        WHERE f.user_id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	mfa := &UserMFA{}
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(
+		&mfa.UserID,
+		&mfa.Secret,
+		&mfa.Enabled,
+		&mfa.EnabledAt,
+		&mfa.LastUsedStep,
+		&mfa.RecoveryCodesLeft,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return mfa, nil
+}
+
+// Enroll starts a new enrolment with secret, replacing one still pending.
+// It fails with ErrMFAEnabled when the user already has one enabled.
+func (s *MFAStore) Enroll(ctx context.Context, userID uuid.UUID, secret []byte) error {
+	query := `
+        INSERT INTO user_mfa (user_id, secret)
+        VALUES ($1, $2)
+        ON CONFLICT (user_id) DO UPDATE
+        SET secret = EXCLUDED.secret, last_used_step = 0, created_at = now()
+        WHERE user_mfa.enabled_at IS NULL
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrMFAEnabled
+	}
+
+	return nil
+}
+
+// Enable turns on a pending enrolment once the user has entered the code
+// for step, and gives them a fresh set of recovery codes.
+func (s *MFAStore) Enable(ctx context.Context, userID uuid.UUID, step int64, recoveryCodes [][]byte) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+            UPDATE user_mfa
+            SET enabled_at = now(), last_used_step = $2
+            WHERE user_id = $1 AND enabled_at IS NULL
+        `, userID, step)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return ErrMFAEnabled
+		}
+
+		return replaceRecoveryCodes(ctx, tx, userID, recoveryCodes)
+	})
+}
+
+// UseStep records that the code for step was used. A code for the same or
+// an earlier step is ErrMFACodeUsed, so an intercepted code cannot be
+// replayed.
+func (s *MFAStore) UseStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	query := `
+        UPDATE user_mfa
+        SET last_used_step = $2
+        WHERE user_id = $1 AND enabled_at IS NOT NULL AND last_used_step < $2
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrMFACodeUsed
+	}
+
+	return nil
+}
+
+// UseRecoveryCode uses up one of the user's recovery codes. A code that is
+// unknown or already used is ErrNotFound.
+func (s *MFAStore) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash []byte) error {
+	query := `
+        UPDATE mfa_recovery_code
+        SET used_at = now()
+        WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, userID, hash)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// ReplaceRecoveryCodes swaps the user's recovery codes for a new set.
+func (s *MFAStore) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, recoveryCodes [][]byte) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		return replaceRecoveryCodes(ctx, tx, userID, recoveryCodes)
+	})
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID uuid.UUID, recoveryCodes [][]byte) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_code WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range recoveryCodes {
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO mfa_recovery_code (user_id, code_hash)
+            VALUES ($1, $2)
+        `, userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Disable removes the user's enrolment and recovery codes.
+func (s *MFAStore) Disable(ctx context.Context, userID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return ErrMFANotActive
+		}
+
+		_, err = tx.ExecContext(ctx, `DELETE FROM mfa_recovery_code WHERE user_id = $1`, userID)
+		return err
+	})
+}
+
+// CreateChallenge records that the user has entered their password and
+// still has to enter a code.
+func (s *MFAStore) CreateChallenge(ctx context.Context, challenge *MFAChallenge) error {
+	query := `
+        INSERT INTO mfa_challenge (user_id, device, expires_at)
+        VALUES ($1, $2, $3)
+        RETURNING id
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query, challenge.UserID, challenge.Device, challenge.ExpiresAt).Scan(&challenge.ID)
+}
+
+// AttemptChallenge counts an attempt at a challenge and returns it. A
+// challenge that is unknown, completed, expired or out of attempts is
+// ErrNotFound.
+func (s *MFAStore) AttemptChallenge(ctx context.Context, challengeID uuid.UUID) (*MFAChallenge, error) {
+	query := `
+        UPDATE mfa_challenge
+        SET attempts = attempts + 1
+        WHERE id = $1 AND used_at IS NULL AND expires_at > now() AND attempts < $2
+        RETURNING id, user_id, device, expires_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	challenge := &MFAChallenge{}
+	err := s.db.QueryRowContext(ctx, query, challengeID, MaxMFAAttempts).Scan(
+		&challenge.ID,
+		&challenge.UserID,
+		&challenge.Device,
+		&challenge.ExpiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// CompleteChallenge marks a challenge as passed so it cannot be used again.
+func (s *MFAStore) CompleteChallenge(ctx context.Context, challengeID uuid.UUID) error {
+	query := `
+        UPDATE mfa_challenge
+        SET used_at = now()
+        WHERE id = $1 AND used_at IS NULL
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, challengeID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMFAStepsAndRecoveryCodesAreSingleUse(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	user := newTestUser(t, s)
+
+	if err := s.MFA.Enroll(ctx, user.ID, []byte("12345678901234567890")); err != nil {
+		t.Fatal(err)
+	}
+	codes := [][]byte{[]byte("first code hash"), []byte("second code hash")}
+	if err := s.MFA.Enable(ctx, user.ID, 100, codes); err != nil {
+		t.Fatal(err)
+	}
+
+	// The step used to enable counts as used, as do earlier ones.
+	steps := []struct {
+		step int64
+		want error
+	}{
+		{100, ErrMFACodeUsed},
+		{99, ErrMFACodeUsed},
+		{101, nil},
+		{101, ErrMFACodeUsed},
+		{103, nil},
+		{102, ErrMFACodeUsed},
+	}
+	for _, tt := range steps {
+		if err := s.MFA.UseStep(ctx, user.ID, tt.step); err != tt.want {
+			t.Errorf("UseStep(%d) = %v, want %v", tt.step, err, tt.want)
+		}
+	}
+
+	recovery := []struct {
+		hash []byte
+		want error
+	}{
+		{codes[0], nil},
+		{codes[0], ErrNotFound},
+		{[]byte("unknown code hash"), ErrNotFound},
+		{codes[1], nil},
+	}
+	for _, tt := range recovery {
+		if err := s.MFA.UseRecoveryCode(ctx, user.ID, tt.hash); err != tt.want {
+			t.Errorf("UseRecoveryCode(%q) = %v, want %v", tt.hash, err, tt.want)
+		}
+	}
+}
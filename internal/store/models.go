@@ -7,12 +7,13 @@ import (
 )
 
 type User struct {
-	ID        uuid.UUID `json:"id"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Password  password  `json:"-"`
-	Email     string    `json:"email"`
-	CreatedAt string    `json:"created_at"`
+	ID            uuid.UUID `json:"id"`
+	FirstName     string    `json:"first_name"`
+	LastName      string    `json:"last_name"`
+	Password      password  `json:"-"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     string    `json:"created_at"`
 }
 
 type OAuthProvider struct {
@@ -45,6 +46,8 @@ type Business struct {
 	AccountNo    string    `json:"account_no"`
 	IFSC         string    `json:"ifsc"`
 	BankBranch   string    `json:"bank_branch"`
+	RequireMFA   bool      `json:"require_mfa"`
+	Role         string    `json:"role,omitempty"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
@@ -59,72 +62,858 @@ type Invoice struct {
 	DueDate     time.Time  `json:"due_date"`
 	IsPaid      bool       `json:"is_paid"`
 	PaidDate    *time.Time `json:"paid_date,omitempty"`
+	Status      string     `json:"status"`
+	SentAt      *time.Time `json:"sent_at,omitempty"`
+	Kind        string     `json:"kind"`
+	RefInvID    *uuid.UUID `json:"ref_inv_id,omitempty"`
 	CreatedAt   time.Time  `json:"created_at"`
 }
 
 type InvoiceItem struct {
+	ID          uuid.UUID  `json:"id"`
+	InvID       uuid.UUID  `json:"inv_id"`
+	ProdID      uuid.UUID  `json:"prod_id"`
+	VariantID   *uuid.UUID `json:"variant_id,omitempty"`
+	VariantName string     `json:"variant_name,omitempty"`
+	Quantity    float64    `json:"quantity"`
+	UnitPrice   float64    `json:"unit_price"`
+	TaxRate     float64    `json:"tax_rate"`
+}
+
+type CreditNote struct {
+	ID          uuid.UUID         `json:"id"`
+	BusID       uuid.UUID         `json:"bus_id"`
+	InvID       uuid.UUID         `json:"inv_id"`
+	InvNo       int64             `json:"inv_no"`
+	CNNo        int64             `json:"cn_no"`
+	CNDate      time.Time         `json:"cn_date"`
+	Reason      string            `json:"reason"`
+	Restock     bool              `json:"restock"`
+	TotalAmount float64           `json:"total_amount"`
+	Items       []*CreditNoteItem `json:"items"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+type CreditNoteItem struct {
+	ID          uuid.UUID  `json:"id"`
+	CNID        uuid.UUID  `json:"cn_id"`
+	ProdID      uuid.UUID  `json:"prod_id"`
+	VariantID   *uuid.UUID `json:"variant_id,omitempty"`
+	VariantName string     `json:"variant_name,omitempty"`
+	Quantity    float64    `json:"quantity"`
+	UnitPrice   float64    `json:"unit_price"`
+	TaxRate     float64    `json:"tax_rate"`
+}
+
+type Customer struct {
+	ID              uuid.UUID  `json:"id"`
+	BusID           uuid.UUID  `json:"bus_id"`
+	Name            string     `json:"name"`
+	GSTNo           string     `json:"gstno"`
+	Email           string     `json:"email"`
+	Phone           string     `json:"phone"`
+	BAddress        string     `json:"b_address"`
+	SAddress        string     `json:"s_address"`
+	PriceListID     *uuid.UUID `json:"price_list_id,omitempty"`
+	RemindersOptOut bool       `json:"reminders_opt_out"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+type Product struct {
+	ID                uuid.UUID  `json:"id"`
+	BusID             uuid.UUID  `json:"bus_id"`
+	Name              string     `json:"name"`
+	Price             float64    `json:"price"`
+	TaxRate           float64    `json:"tax_rate"`
+	Unit              string     `json:"unit"`
+	HSNCode           string     `json:"hsn_code"`
+	ItemType          string     `json:"item_type"`
+	UQC               string     `json:"uqc"`
+	CostPrice         *float64   `json:"cost_price,omitempty"`
+	TrackStock        bool       `json:"track_stock"`
+	OpeningStock      float64    `json:"opening_stock"`
+	LowStockThreshold *float64   `json:"low_stock_threshold,omitempty"`
+	CategoryID        *uuid.UUID `json:"category_id,omitempty"`
+	SKU               string     `json:"sku,omitempty"`
+	Barcode           string     `json:"barcode,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+type Dashboard struct {
+	TotalInvoices   int       `json:"total_invoices"`
+	PendingInvoices int       `json:"pending_invoices"`
+	TotalRevenue    float64   `json:"total_revenue"`
+	UnpaidAmount    float64   `json:"unpaid_amount"`
+	TotalPurchases  float64   `json:"total_purchases"`
+	PendingBills    int       `json:"pending_bills"`
+	Payables        float64   `json:"payables"`
+	TotalExpenses   float64   `json:"total_expenses"`
+	NetProfit       float64   `json:"net_profit"`
+	RecentInvoices  []Invoice `json:"recent_invoices"`
+}
+
+type CustomerWithPendingAmount struct {
+	ID            uuid.UUID `json:"id"`
+	BusID         uuid.UUID `json:"bus_id"`
+	Name          string    `json:"name"`
+	GSTNo         string    `json:"gstno"`
+	Email         string    `json:"email"`
+	Phone         string    `json:"phone"`
+	BAddress      string    `json:"b_address"`
+	SAddress      string    `json:"s_address"`
+	CreatedAt     time.Time `json:"created_at"`
+	PendingAmount float64   `json:"pending_amount"`
+	TotalInvoices int       `json:"total_invoices"`
+}
+
+type InvoiceResponse struct {
+	ID          uuid.UUID      `json:"id"`
+	InvNo       int64          `json:"inv_no"`
+	BusID       uuid.UUID      `json:"bus_id"`
+	CustID      uuid.UUID      `json:"cust_id"`
+	TotalAmount float64        `json:"total_amount"`
+	InvDate     time.Time      `json:"inv_date"`
+	DueDate     time.Time      `json:"due_date"`
+	IsPaid      bool           `json:"is_paid"`
+	PaidDate    *time.Time     `json:"paid_date,omitempty"`
+	Status      string         `json:"status"`
+	SentAt      *time.Time     `json:"sent_at,omitempty"`
+	Kind        string         `json:"kind"`
+	RefInvID    *uuid.UUID     `json:"ref_inv_id,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	Items       []*InvoiceItem `json:"items"`
+}
+
+type CustomerFilter struct {
+	Query      string
+	HasPending *bool
+	Sort       string
+	Order      string
+	Cursor     string
+	Limit      int
+}
+
+type CustomerPage struct {
+	Customers  []*CustomerWithPendingAmount `json:"customers"`
+	Total      int                          `json:"total"`
+	NextCursor string                       `json:"next_cursor,omitempty"`
+}
+
+type StatementEntry struct {
+	Date    time.Time `json:"date"`
+	Type    string    `json:"type"`
+	InvID   uuid.UUID `json:"inv_id"`
+	InvNo   int64     `json:"inv_no"`
+	Debit   float64   `json:"debit"`
+	Credit  float64   `json:"credit"`
+	Balance float64   `json:"balance"`
+	// RefInvNo is the overdue invoice a late fee entry was charged on.
+	RefInvNo *int64 `json:"ref_inv_no,omitempty"`
+	// CNNo is the number of a credit note entry, which InvNo was issued
+	// against.
+	CNNo *int64 `json:"cn_no,omitempty"`
+}
+
+type Statement struct {
+	CustID         uuid.UUID        `json:"cust_id"`
+	From           time.Time        `json:"from"`
+	To             time.Time        `json:"to"`
+	OpeningBalance float64          `json:"opening_balance"`
+	Entries        []StatementEntry `json:"entries"`
+	ClosingBalance float64          `json:"closing_balance"`
+	// AccruedLateFees have accrued by the end of the statement but are not
+	// yet charged, so they are not part of the closing balance.
+	AccruedLateFees float64 `json:"accrued_late_fees"`
+}
+
+type AgingBuckets struct {
+	Current    float64 `json:"current"`
+	Days1To30  float64 `json:"days_1_30"`
+	Days31To60 float64 `json:"days_31_60"`
+	Days61To90 float64 `json:"days_61_90"`
+	Over90     float64 `json:"days_90_plus"`
+	Total      float64 `json:"total"`
+	// AccruedLateFees are not charged yet and so not part of Total.
+	AccruedLateFees float64 `json:"accrued_late_fees"`
+}
+
+type CustomerAging struct {
+	CustID uuid.UUID `json:"cust_id"`
+	Name   string    `json:"name"`
+	AgingBuckets
+}
+
+type AgingReport struct {
+	BusID     uuid.UUID       `json:"bus_id"`
+	AsOf      time.Time       `json:"as_of"`
+	Customers []CustomerAging `json:"customers"`
+	Totals    AgingBuckets    `json:"totals"`
+}
+
+type StockMovement struct {
+	ID        uuid.UUID  `json:"id"`
+	BusID     uuid.UUID  `json:"bus_id"`
+	ProdID    uuid.UUID  `json:"prod_id"`
+	InvID     *uuid.UUID `json:"inv_id,omitempty"`
+	BillID    *uuid.UUID `json:"bill_id,omitempty"`
+	Kind      string     `json:"kind"`
+	Quantity  float64    `json:"quantity"`
+	Note      string     `json:"note"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type StockLevel struct {
+	ProdID            uuid.UUID `json:"prod_id"`
+	Name              string    `json:"name"`
+	Unit              string    `json:"unit"`
+	OpeningStock      float64   `json:"opening_stock"`
+	OnHand            float64   `json:"on_hand"`
+	LowStockThreshold *float64  `json:"low_stock_threshold,omitempty"`
+	IsLow             bool      `json:"is_low"`
+}
+
+type StockValuationItem struct {
+	ProdID    uuid.UUID `json:"prod_id"`
+	Name      string    `json:"name"`
+	Unit      string    `json:"unit"`
+	OnHand    float64   `json:"on_hand"`
+	UnitCost  float64   `json:"unit_cost"`
+	CostValue float64   `json:"cost_value"`
+	SaleValue float64   `json:"sale_value"`
+}
+
+type StockValuation struct {
+	BusID     uuid.UUID            `json:"bus_id"`
+	Items     []StockValuationItem `json:"items"`
+	CostValue float64              `json:"cost_value"`
+	SaleValue float64              `json:"sale_value"`
+}
+
+type Category struct {
+	ID        uuid.UUID  `json:"id"`
+	BusID     uuid.UUID  `json:"bus_id"`
+	ParentID  *uuid.UUID `json:"parent_id,omitempty"`
+	Name      string     `json:"name"`
+	Path      string     `json:"path"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type ProductVariant struct {
 	ID        uuid.UUID `json:"id"`
-	InvID     uuid.UUID `json:"inv_id"`
 	ProdID    uuid.UUID `json:"prod_id"`
-	Quantity  int       `json:"quantity"`
-	UnitPrice float64   `json:"unit_price"`
+	BusID     uuid.UUID `json:"bus_id"`
+	Name      string    `json:"name"`
+	Price     float64   `json:"price"`
+	SKU       string    `json:"sku,omitempty"`
+	Barcode   string    `json:"barcode,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
-type Customer struct {
+type ProductLookup struct {
+	Product *Product        `json:"product"`
+	Variant *ProductVariant `json:"variant,omitempty"`
+}
+
+type PriceList struct {
+	ID        uuid.UUID        `json:"id"`
+	BusID     uuid.UUID        `json:"bus_id"`
+	Name      string           `json:"name"`
+	ValidFrom *time.Time       `json:"valid_from,omitempty"`
+	ValidTo   *time.Time       `json:"valid_to,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+	Items     []*PriceListItem `json:"items,omitempty"`
+}
+
+type PriceListItem struct {
+	ID        uuid.UUID  `json:"id"`
+	ListID    uuid.UUID  `json:"list_id"`
+	ProdID    uuid.UUID  `json:"prod_id"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+	MinQty    float64    `json:"min_qty"`
+	UnitPrice float64    `json:"unit_price"`
+}
+
+type PriceQuery struct {
+	CustID    uuid.UUID
+	ProdID    uuid.UUID
+	VariantID *uuid.UUID
+	Quantity  float64
+	Date      time.Time
+}
+
+type ResolvedPrice struct {
+	ProdID        uuid.UUID  `json:"prod_id"`
+	VariantID     *uuid.UUID `json:"variant_id,omitempty"`
+	CustID        uuid.UUID  `json:"cust_id"`
+	Quantity      float64    `json:"quantity"`
+	BasePrice     float64    `json:"base_price"`
+	UnitPrice     float64    `json:"unit_price"`
+	PriceListID   *uuid.UUID `json:"price_list_id,omitempty"`
+	PriceListName string     `json:"price_list_name,omitempty"`
+	MinQty        float64    `json:"min_qty,omitempty"`
+}
+
+type Vendor struct {
 	ID        uuid.UUID `json:"id"`
 	BusID     uuid.UUID `json:"bus_id"`
 	Name      string    `json:"name"`
 	GSTNo     string    `json:"gstno"`
 	Email     string    `json:"email"`
 	Phone     string    `json:"phone"`
-	BAddress  string    `json:"b_address"`
-	SAddress  string    `json:"s_address"`
+	Address   string    `json:"address"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
-type Product struct {
+type PurchaseBill struct {
+	ID            uuid.UUID `json:"id"`
+	BusID         uuid.UUID `json:"bus_id"`
+	VendorID      uuid.UUID `json:"vendor_id"`
+	VendorName    string    `json:"vendor_name,omitempty"`
+	BillNo        string    `json:"bill_no"`
+	BillDate      time.Time `json:"bill_date"`
+	DueDate       time.Time `json:"due_date"`
+	TaxableAmount float64   `json:"taxable_amount"`
+	CGSTAmount    float64   `json:"cgst_amount"`
+	SGSTAmount    float64   `json:"sgst_amount"`
+	IGSTAmount    float64   `json:"igst_amount"`
+	TotalAmount   float64   `json:"total_amount"`
+	AmountPaid    float64   `json:"amount_paid"`
+	IsPaid        bool      `json:"is_paid"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type PurchaseBillItem struct {
+	ID          uuid.UUID  `json:"id"`
+	BillID      uuid.UUID  `json:"bill_id"`
+	ProdID      uuid.UUID  `json:"prod_id"`
+	VariantID   *uuid.UUID `json:"variant_id,omitempty"`
+	VariantName string     `json:"variant_name,omitempty"`
+	Quantity    float64    `json:"quantity"`
+	UnitPrice   float64    `json:"unit_price"`
+	TaxRate     float64    `json:"tax_rate"`
+}
+
+type BillPayment struct {
+	ID        uuid.UUID `json:"id"`
+	BillID    uuid.UUID `json:"bill_id"`
+	Amount    float64   `json:"amount"`
+	PaidOn    time.Time `json:"paid_on"`
+	Method    string    `json:"method"`
+	Reference string    `json:"reference"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PurchaseBillResponse struct {
+	*PurchaseBill
+	Items    []*PurchaseBillItem `json:"items"`
+	Payments []*BillPayment      `json:"payments"`
+}
+
+type InputTaxCreditPeriod struct {
+	Month         string  `json:"month"`
+	TaxableAmount float64 `json:"taxable_amount"`
+	CGSTAmount    float64 `json:"cgst_amount"`
+	SGSTAmount    float64 `json:"sgst_amount"`
+	IGSTAmount    float64 `json:"igst_amount"`
+	Total         float64 `json:"total"`
+}
+
+type InputTaxCredit struct {
+	BusID  uuid.UUID              `json:"bus_id"`
+	From   time.Time              `json:"from"`
+	To     time.Time              `json:"to"`
+	Months []InputTaxCreditPeriod `json:"months"`
+	Totals InputTaxCreditPeriod   `json:"totals"`
+}
+
+type Expense struct {
+	ID          uuid.UUID  `json:"id"`
+	BusID       uuid.UUID  `json:"bus_id"`
+	VendorID    *uuid.UUID `json:"vendor_id,omitempty"`
+	VendorName  string     `json:"vendor_name,omitempty"`
+	Category    string     `json:"category"`
+	Description string     `json:"description"`
+	ExpenseDate time.Time  `json:"expense_date"`
+	Amount      float64    `json:"amount"`
+	TaxAmount   float64    `json:"tax_amount"`
+	Total       float64    `json:"total"`
+	PaymentMode string     `json:"payment_mode"`
+	ReceiptKey  *string    `json:"-"`
+	ReceiptName *string    `json:"receipt_name,omitempty"`
+	ReceiptType *string    `json:"receipt_type,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type ExpenseFilter struct {
+	From        time.Time
+	To          time.Time
+	Category    string
+	VendorID    *uuid.UUID
+	PaymentMode string
+}
+
+type ExpenseMonth struct {
+	Month      string             `json:"month"`
+	Amount     float64            `json:"amount"`
+	TaxAmount  float64            `json:"tax_amount"`
+	Total      float64            `json:"total"`
+	Categories map[string]float64 `json:"categories"`
+}
+
+type SalesPoint struct {
+	Period   time.Time `json:"period"`
+	Invoices int       `json:"invoices"`
+	Total    float64   `json:"total"`
+}
+
+type SalesSeries struct {
+	Interval string       `json:"interval"`
+	Points   []SalesPoint `json:"points"`
+	Invoices int          `json:"invoices"`
+	Total    float64      `json:"total"`
+}
+
+type CustomerSales struct {
+	CustID   uuid.UUID `json:"cust_id"`
+	Name     string    `json:"name"`
+	Invoices int       `json:"invoices"`
+	Taxable  float64   `json:"taxable"`
+	Tax      float64   `json:"tax"`
+	Total    float64   `json:"total"`
+}
+
+type ProductSales struct {
+	ProdID   uuid.UUID `json:"prod_id"`
+	Name     string    `json:"name"`
+	HSNCode  string    `json:"hsn_code"`
+	Unit     string    `json:"unit"`
+	Quantity float64   `json:"quantity"`
+	Taxable  float64   `json:"taxable"`
+	Tax      float64   `json:"tax"`
+	Total    float64   `json:"total"`
+}
+
+type TaxRateSales struct {
+	TaxRate float64 `json:"tax_rate"`
+	Taxable float64 `json:"taxable"`
+	Tax     float64 `json:"tax"`
+	Total   float64 `json:"total"`
+}
+
+type CategoryAmount struct {
+	Category string  `json:"category"`
+	Amount   float64 `json:"amount"`
+}
+
+type ProfitAndLoss struct {
+	Sales              float64          `json:"sales"`
+	OutputTax          float64          `json:"output_tax"`
+	Purchases          float64          `json:"purchases"`
+	InputTax           float64          `json:"input_tax"`
+	GrossProfit        float64          `json:"gross_profit"`
+	Expenses           float64          `json:"expenses"`
+	ExpensesByCategory []CategoryAmount `json:"expenses_by_category"`
+	NetProfit          float64          `json:"net_profit"`
+}
+
+type Account struct {
 	ID        uuid.UUID `json:"id"`
 	BusID     uuid.UUID `json:"bus_id"`
+	Code      string    `json:"code"`
 	Name      string    `json:"name"`
-	Price     float64   `json:"price"`
-	TaxRate   float64   `json:"tax_rate"`
-	Unit      string    `json:"unit"`
-	HSNCode   string    `json:"hsn_code"`
+	Type      string    `json:"type"`
+	SystemKey *string   `json:"system_key,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
-type Dashboard struct {
-	TotalInvoices   int       `json:"total_invoices"`
-	PendingInvoices int       `json:"pending_invoices"`
-	TotalRevenue    float64   `json:"total_revenue"`
-	UnpaidAmount    float64   `json:"unpaid_amount"`
-	RecentInvoices  []Invoice `json:"recent_invoices"`
+type JournalEntry struct {
+	ID         uuid.UUID     `json:"id"`
+	BusID      uuid.UUID     `json:"bus_id"`
+	EntryDate  time.Time     `json:"entry_date"`
+	Narration  string        `json:"narration"`
+	SourceType *string       `json:"source_type,omitempty"`
+	SourceID   *uuid.UUID    `json:"source_id,omitempty"`
+	Lines      []JournalLine `json:"lines"`
+	CreatedAt  time.Time     `json:"created_at"`
 }
 
-type CustomerWithPendingAmount struct {
-    ID            uuid.UUID `json:"id"`
-    BusID         uuid.UUID `json:"bus_id"`
-    Name          string    `json:"name"`
-    GSTNo         string    `json:"gstno"`
-    Email         string    `json:"email"`
-    Phone         string    `json:"phone"`
-    BAddress      string    `json:"b_address"`
-    SAddress      string    `json:"s_address"`
-    CreatedAt     time.Time `json:"created_at"`
-    PendingAmount float64   `json:"pending_amount"`
-    TotalInvoices int       `json:"total_invoices"`
+type JournalLine struct {
+	ID          uuid.UUID `json:"id"`
+	AccountID   uuid.UUID `json:"account_id"`
+	AccountCode string    `json:"account_code"`
+	AccountName string    `json:"account_name"`
+	Debit       float64   `json:"debit"`
+	Credit      float64   `json:"credit"`
 }
 
-type InvoiceResponse struct {
-    ID          uuid.UUID      `json:"id"`
-    InvNo       int64          `json:"inv_no"`
-    BusID       uuid.UUID      `json:"bus_id"`
-    CustID      uuid.UUID      `json:"cust_id"`
-    TotalAmount float64        `json:"total_amount"`
-    InvDate     time.Time      `json:"inv_date"`
-    DueDate     time.Time      `json:"due_date"`
-    IsPaid      bool           `json:"is_paid"`
-    PaidDate    *time.Time     `json:"paid_date,omitempty"`
-    CreatedAt   time.Time      `json:"created_at"`
-    Items       []*InvoiceItem  `json:"items"`
-}
\ No newline at end of file
+type TrialBalanceRow struct {
+	AccountID uuid.UUID `json:"account_id"`
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Debit     float64   `json:"debit"`
+	Credit    float64   `json:"credit"`
+}
+
+type TrialBalance struct {
+	BusID       uuid.UUID         `json:"bus_id"`
+	AsOf        time.Time         `json:"as_of"`
+	Accounts    []TrialBalanceRow `json:"accounts"`
+	TotalDebit  float64           `json:"total_debit"`
+	TotalCredit float64           `json:"total_credit"`
+}
+
+type LedgerLine struct {
+	EntryID    uuid.UUID `json:"entry_id"`
+	EntryDate  time.Time `json:"entry_date"`
+	Narration  string    `json:"narration"`
+	SourceType *string   `json:"source_type,omitempty"`
+	Debit      float64   `json:"debit"`
+	Credit     float64   `json:"credit"`
+	Balance    float64   `json:"balance"`
+}
+
+type AccountLedger struct {
+	AccountID uuid.UUID    `json:"account_id"`
+	Code      string       `json:"code"`
+	Name      string       `json:"name"`
+	Type      string       `json:"type"`
+	Opening   float64      `json:"opening"`
+	Lines     []LedgerLine `json:"lines"`
+	Debit     float64      `json:"debit"`
+	Credit    float64      `json:"credit"`
+	Closing   float64      `json:"closing"`
+}
+
+type GeneralLedger struct {
+	BusID    uuid.UUID        `json:"bus_id"`
+	From     time.Time        `json:"from"`
+	To       time.Time        `json:"to"`
+	Accounts []*AccountLedger `json:"accounts"`
+}
+
+type TallyMapping struct {
+	BusID      uuid.UUID `json:"bus_id"`
+	Sales      string    `json:"sales"`
+	CGST       string    `json:"cgst"`
+	SGST       string    `json:"sgst"`
+	IGST       string    `json:"igst"`
+	Receipt    string    `json:"receipt"`
+	PartyGroup string    `json:"party_group"`
+}
+
+type InvoiceVoucher struct {
+	InvID         uuid.UUID
+	InvNo         int64
+	InvDate       time.Time
+	CustID        uuid.UUID
+	CustName      string
+	CustGSTNo     string
+	CustAddress   string
+	TaxableAmount float64
+	CGSTAmount    float64
+	SGSTAmount    float64
+	IGSTAmount    float64
+	TotalAmount   float64
+	Payments      []*Payment
+	CreditNotes   []*CreditNoteVoucher
+}
+
+type CreditNoteVoucher struct {
+	CNID          uuid.UUID
+	CNNo          int64
+	CNDate        time.Time
+	TaxableAmount float64
+	CGSTAmount    float64
+	SGSTAmount    float64
+	IGSTAmount    float64
+}
+
+type Payment struct {
+	ID           uuid.UUID  `json:"id"`
+	BusID        uuid.UUID  `json:"bus_id"`
+	InvID        uuid.UUID  `json:"inv_id"`
+	Amount       float64    `json:"amount"`
+	PaidOn       time.Time  `json:"paid_on"`
+	Method       string     `json:"method"`
+	Reference    string     `json:"reference"`
+	CreditNoteID *uuid.UUID `json:"credit_note_id,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type OpenInvoice struct {
+	InvID    uuid.UUID `json:"inv_id"`
+	InvNo    int64     `json:"inv_no"`
+	InvDate  time.Time `json:"inv_date"`
+	CustID   uuid.UUID `json:"cust_id"`
+	CustName string    `json:"cust_name"`
+	Total    float64   `json:"total"`
+	Paid     float64   `json:"paid"`
+	Balance  float64   `json:"balance"`
+}
+
+type BankStatement struct {
+	ID         uuid.UUID `json:"id"`
+	BusID      uuid.UUID `json:"bus_id"`
+	Filename   string    `json:"filename"`
+	Format     string    `json:"format"`
+	Imported   int       `json:"imported"`
+	Duplicates int       `json:"duplicates"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type BankTransaction struct {
+	ID          uuid.UUID  `json:"id"`
+	BusID       uuid.UUID  `json:"bus_id"`
+	StatementID uuid.UUID  `json:"statement_id"`
+	TxnDate     time.Time  `json:"txn_date"`
+	Amount      float64    `json:"amount"`
+	Description string     `json:"description"`
+	Reference   string     `json:"reference"`
+	FitID       string     `json:"fit_id"`
+	PaymentID   *uuid.UUID `json:"payment_id,omitempty"`
+	InvID       *uuid.UUID `json:"inv_id,omitempty"`
+	InvNo       *int64     `json:"inv_no,omitempty"`
+	Ignored     bool       `json:"ignored"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type PaymentLink struct {
+	ID             uuid.UUID  `json:"id"`
+	BusID          uuid.UUID  `json:"bus_id"`
+	InvID          uuid.UUID  `json:"inv_id"`
+	Provider       string     `json:"provider"`
+	ProviderLinkID string     `json:"provider_link_id"`
+	URL            string     `json:"url"`
+	Amount         float64    `json:"amount"`
+	Status         string     `json:"status"`
+	PaidAt         *time.Time `json:"paid_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type EmailTemplate struct {
+	BusID     uuid.UUID `json:"bus_id"`
+	Kind      string    `json:"kind"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type EmailDelivery struct {
+	ID        uuid.UUID  `json:"id"`
+	BusID     uuid.UUID  `json:"bus_id"`
+	InvID     *uuid.UUID `json:"inv_id,omitempty"`
+	Kind      string     `json:"kind"`
+	Recipient string     `json:"recipient"`
+	CC        []string   `json:"cc"`
+	Subject   string     `json:"subject"`
+	Status    string     `json:"status"`
+	Error     string     `json:"error,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type ReminderRule struct {
+	ID         uuid.UUID `json:"id"`
+	BusID      uuid.UUID `json:"bus_id"`
+	OffsetDays int       `json:"offset_days"`
+	RepeatDays *int      `json:"repeat_days,omitempty"`
+	IsActive   bool      `json:"is_active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DueReminder is one occurrence of a rule falling due for an invoice.
+type DueReminder struct {
+	RuleID     uuid.UUID `json:"rule_id"`
+	InvID      uuid.UUID `json:"inv_id"`
+	Occurrence int       `json:"occurrence"`
+}
+
+type ReminderLog struct {
+	ID         uuid.UUID  `json:"id"`
+	RuleID     uuid.UUID  `json:"rule_id"`
+	InvID      uuid.UUID  `json:"inv_id"`
+	Occurrence int        `json:"occurrence"`
+	DeliveryID *uuid.UUID `json:"delivery_id,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type LateFeePolicy struct {
+	ID         uuid.UUID  `json:"id"`
+	BusID      uuid.UUID  `json:"bus_id"`
+	CustID     *uuid.UUID `json:"cust_id,omitempty"`
+	CustName   string     `json:"cust_name,omitempty"`
+	Kind       string     `json:"kind"`
+	FlatAmount float64    `json:"flat_amount"`
+	AnnualRate float64    `json:"annual_rate"`
+	GraceDays  int        `json:"grace_days"`
+	Cap        *float64   `json:"cap,omitempty"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// LateFeeAccrual is what an overdue invoice has accrued in late fees, how
+// much of it has been charged and what is still to be charged.
+type LateFeeAccrual struct {
+	InvID       uuid.UUID `json:"inv_id"`
+	InvNo       int64     `json:"inv_no"`
+	CustID      uuid.UUID `json:"cust_id"`
+	CustName    string    `json:"cust_name"`
+	DueDate     time.Time `json:"due_date"`
+	DaysOverdue int       `json:"days_overdue"`
+	Balance     float64   `json:"balance"`
+	Accrued     float64   `json:"accrued"`
+	Charged     float64   `json:"charged"`
+	Pending     float64   `json:"pending"`
+}
+
+// LateFeeCharge is a late fee billed on an overdue invoice through an
+// invoice or debit note of its own.
+type LateFeeCharge struct {
+	ID          uuid.UUID `json:"id"`
+	BusID       uuid.UUID `json:"bus_id"`
+	InvID       uuid.UUID `json:"inv_id"`
+	InvNo       int64     `json:"inv_no"`
+	ChargeInvID uuid.UUID `json:"charge_inv_id"`
+	ChargeInvNo int64     `json:"charge_inv_no"`
+	Kind        string    `json:"kind"`
+	Amount      float64   `json:"amount"`
+	AccruedTo   time.Time `json:"accrued_to"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// InvoiceShare is a public, read-only link to an invoice. The token itself
+// is not stored: it is signed from the share's id and expiry.
+type InvoiceShare struct {
+	ID           uuid.UUID  `json:"id"`
+	InvID        uuid.UUID  `json:"inv_id"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	Views        int        `json:"views"`
+	LastViewedAt *time.Time `json:"last_viewed_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type InvoiceShareView struct {
+	ID        uuid.UUID `json:"id"`
+	ShareID   uuid.UUID `json:"share_id"`
+	Format    string    `json:"format"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	ViewedAt  time.Time `json:"viewed_at"`
+}
+
+// PortalAccount is a customer's sign-in to the self-service portal. It is
+// keyed by email, and sees the customer records of every business that
+// bills that address.
+type PortalAccount struct {
+	ID          uuid.UUID  `json:"id"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// PortalLogin is a magic link sent to sign in to the portal. It can be
+// used once.
+type PortalLogin struct {
+	ID        uuid.UUID  `json:"id"`
+	Email     string     `json:"email"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type PortalCustomer struct {
+	CustID       uuid.UUID `json:"cust_id"`
+	BusID        uuid.UUID `json:"bus_id"`
+	BusinessName string    `json:"business_name"`
+	Name         string    `json:"name"`
+}
+
+type PortalInvoice struct {
+	ID           uuid.UUID `json:"id"`
+	BusID        uuid.UUID `json:"bus_id"`
+	BusinessName string    `json:"business_name"`
+	CustID       uuid.UUID `json:"cust_id"`
+	InvNo        int64     `json:"inv_no"`
+	Kind         string    `json:"kind"`
+	InvDate      time.Time `json:"inv_date"`
+	DueDate      time.Time `json:"due_date"`
+	TotalAmount  float64   `json:"total_amount"`
+	Balance      float64   `json:"balance"`
+	IsPaid       bool      `json:"is_paid"`
+}
+
+// Member is a user's membership of a business. MFARequired is set when the
+// business requires its members to use two-factor authentication.
+type Member struct {
+	BusID       uuid.UUID `json:"bus_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Role        string    `json:"role"`
+	FirstName   string    `json:"first_name"`
+	LastName    string    `json:"last_name"`
+	Email       string    `json:"email"`
+	MFAEnabled  bool      `json:"mfa_enabled"`
+	MFARequired bool      `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Invitation struct {
+	ID           uuid.UUID  `json:"id"`
+	BusID        uuid.UUID  `json:"bus_id"`
+	BusinessName string     `json:"business_name"`
+	Email        string     `json:"email"`
+	Role         string     `json:"role"`
+	InvitedBy    *uuid.UUID `json:"invited_by,omitempty"`
+	Status       string     `json:"status"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RespondedAt  *time.Time `json:"responded_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	Current    bool      `json:"current"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// RefreshToken is one link in a session's chain of refresh tokens. Only a
+// hash of the token is stored.
+type RefreshToken struct {
+	ID        uuid.UUID
+	SessionID uuid.UUID
+	Hash      []byte
+	ExpiresAt time.Time
+}
+
+// UserToken is a single-use token emailed to a user to verify their email
+// or reset their password. Only a hash of the token is stored.
+type UserToken struct {
+	UserID    uuid.UUID
+	Purpose   string
+	Hash      []byte
+	ExpiresAt time.Time
+}
+
+// UserMFA is a user's TOTP enrolment. It is pending until the user confirms
+// it with a code from their authenticator app.
+type UserMFA struct {
+	UserID            uuid.UUID  `json:"-"`
+	Secret            []byte     `json:"-"`
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	LastUsedStep      int64      `json:"-"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+type MFAChallenge struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Device    string
+	ExpiresAt time.Time
+}
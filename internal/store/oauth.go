@@ -22,14 +22,14 @@ func (o *OAuthProviderStore) CreateOrUpdate(ctx context.Context, authProvider *O
 	defer cancel()
 
 	_, err := o.db.ExecContext(
-        ctx,
-        query,
-        authProvider.UserID,
-        authProvider.Provider,
-        authProvider.AccessToken,
-        authProvider.RefreshToken,
+		ctx,
+		query,
+		authProvider.UserID,
+		authProvider.Provider,
+		authProvider.AccessToken,
+		authProvider.RefreshToken,
 		authProvider.ExpiresAt,
-    )
+	)
 
-    return err
+	return err
 }
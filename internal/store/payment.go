@@ -0,0 +1,218 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvoiceOverpayment = errors.New("payment exceeds the amount due on the invoice")
+	ErrCreditNotePayment  = errors.New("payment is a credit note; delete the credit note instead")
+)
+
+type PaymentStore struct {
+	db *sql.DB
+}
+
+// createPayment records a payment against an invoice of the payment's
+// business, keeps the invoice's paid flag in step and posts the receipt.
+// A payment with a CreditNoteID applies that credit note to the invoice and
+// posts nothing itself, the credit note having been posted already.
+func createPayment(ctx context.Context, tx *sql.Tx, payment *Payment) error {
+	var total, paid float64
+	err := tx.QueryRowContext(ctx, `
+        SELECT buss_id, total_amount
+        FROM invoice
+        WHERE id = $1
+        FOR UPDATE
+    `, payment.InvID).Scan(&payment.BusID, &total)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+        SELECT COALESCE(SUM(amount), 0)
+        FROM payment
+        WHERE inv_id = $1
+    `, payment.InvID).Scan(&paid)
+	if err != nil {
+		return err
+	}
+
+	// Compare in paise so rounding noise does not reject an exact payment.
+	if toPaise(payment.Amount) > toPaise(total-paid) {
+		return ErrInvoiceOverpayment
+	}
+
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO payment (buss_id, inv_id, amount, paid_on, method, reference, credit_note_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id, created_at
+    `, payment.BusID, payment.InvID, payment.Amount, payment.PaidOn, payment.Method, payment.Reference, payment.CreditNoteID).Scan(
+		&payment.ID,
+		&payment.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if _, _, err := syncInvoicePaid(ctx, tx, payment.InvID); err != nil {
+		return err
+	}
+
+	return repostPayment(ctx, tx, payment.ID)
+}
+
+// syncInvoicePaid marks an invoice paid once its payments cover the total,
+// dated by the last of them.
+func syncInvoicePaid(ctx context.Context, tx *sql.Tx, invoiceID uuid.UUID) (bool, *time.Time, error) {
+	var (
+		isPaid   bool
+		paidDate *time.Time
+	)
+	err := tx.QueryRowContext(ctx, `
+        UPDATE invoice i
+        SET is_paid = p.amount >= i.total_amount,
+            paid_date = CASE WHEN p.amount >= i.total_amount THEN p.paid_on END
+        FROM (
+            SELECT COALESCE(SUM(amount), 0) AS amount, MAX(paid_on)::timestamptz AS paid_on
+            FROM payment
+            WHERE inv_id = $1
+        ) p
+        WHERE i.id = $1
+        RETURNING i.is_paid, i.paid_date
+    `, invoiceID).Scan(&isPaid, &paidDate)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil, ErrNotFound
+		}
+		return false, nil, err
+	}
+
+	return isPaid, paidDate, nil
+}
+
+// settleInvoice records a payment for whatever is still due on an invoice,
+// which is how marking an invoice paid is represented.
+func settleInvoice(ctx context.Context, tx *sql.Tx, invoiceID uuid.UUID, paidOn time.Time) error {
+	var total, paid float64
+	err := tx.QueryRowContext(ctx, `
+        SELECT i.total_amount, COALESCE(SUM(p.amount), 0)
+        FROM invoice i
+        LEFT JOIN payment p ON p.inv_id = i.id
+        WHERE i.id = $1
+        GROUP BY i.id
+    `, invoiceID).Scan(&total, &paid)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	due := toPaise(total - paid)
+	if due <= 0 {
+		return nil
+	}
+
+	return createPayment(ctx, tx, &Payment{
+		InvID:  invoiceID,
+		Amount: float64(due) / 100,
+		PaidOn: paidOn,
+	})
+}
+
+// clearPayments removes every payment of an invoice, along with their
+// receipts, when it is marked unpaid again. Credit notes stay applied.
+func clearPayments(ctx context.Context, tx *sql.Tx, invoiceID uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM payment WHERE inv_id = $1 AND credit_note_id IS NULL`, invoiceID)
+	return err
+}
+
+func (s *PaymentStore) Create(ctx context.Context, payment *Payment) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		return createPayment(ctx, tx, payment)
+	})
+}
+
+func (s *PaymentStore) GetByInvoiceID(ctx context.Context, invoiceID uuid.UUID) ([]*Payment, error) {
+	query := `
+        SELECT id, buss_id, inv_id, amount, paid_on, method, reference, credit_note_id, created_at
+        FROM payment
+        WHERE inv_id = $1
+        ORDER BY paid_on, created_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []*Payment{}
+	for rows.Next() {
+		payment := &Payment{}
+		err := rows.Scan(
+			&payment.ID,
+			&payment.BusID,
+			&payment.InvID,
+			&payment.Amount,
+			&payment.PaidOn,
+			&payment.Method,
+			&payment.Reference,
+			&payment.CreditNoteID,
+			&payment.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+
+	return payments, rows.Err()
+}
+
+// Delete removes a payment and its receipt. A bank transaction matched to it
+// goes back to unmatched. A credit note's application can only be removed by
+// deleting the credit note.
+func (s *PaymentStore) Delete(ctx context.Context, paymentID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var (
+			invoiceID    uuid.UUID
+			creditNoteID *uuid.UUID
+		)
+		err := tx.QueryRowContext(ctx, `SELECT inv_id, credit_note_id FROM payment WHERE id = $1 FOR UPDATE`, paymentID).Scan(&invoiceID, &creditNoteID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrNotFound
+			}
+			return err
+		}
+		if creditNoteID != nil {
+			return ErrCreditNotePayment
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM payment WHERE id = $1`, paymentID); err != nil {
+			return err
+		}
+
+		_, _, err = syncInvoicePaid(ctx, tx, invoiceID)
+		return err
+	})
+}
@@ -0,0 +1,158 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const (
+	PaymentLinkCreated = "created"
+	PaymentLinkPaid    = "paid"
+)
+
+type PaymentLinkStore struct {
+	db *sql.DB
+}
+
+func (s *PaymentLinkStore) Create(ctx context.Context, link *PaymentLink) error {
+	query := `
+        INSERT INTO payment_link (buss_id, inv_id, provider, provider_link_id, url, amount)
+        SELECT buss_id, id, $2, $3, $4, $5
+        FROM invoice
+        WHERE id = $1
+        RETURNING id, buss_id, status, created_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		link.InvID,
+		link.Provider,
+		link.ProviderLinkID,
+		link.URL,
+		link.Amount,
+	).Scan(
+		&link.ID,
+		&link.BusID,
+		&link.Status,
+		&link.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetByInvoiceID lists the links created for an invoice, newest first.
+func (s *PaymentLinkStore) GetByInvoiceID(ctx context.Context, invoiceID uuid.UUID) ([]*PaymentLink, error) {
+	query := `
+        SELECT id, buss_id, inv_id, provider, provider_link_id, url, amount, status, paid_at, created_at
+        FROM payment_link
+        WHERE inv_id = $1
+        ORDER BY created_at DESC
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []*PaymentLink{}
+	for rows.Next() {
+		link := &PaymentLink{}
+		err := rows.Scan(
+			&link.ID,
+			&link.BusID,
+			&link.InvID,
+			&link.Provider,
+			&link.ProviderLinkID,
+			&link.URL,
+			&link.Amount,
+			&link.Status,
+			&link.PaidAt,
+			&link.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+// RecordPayment records a payment a provider reported against one of its
+// links. Providers deliver webhooks at least once, so a payment already
+// recorded under gatewayPaymentID is left alone and false is returned.
+func (s *PaymentLinkStore) RecordPayment(ctx context.Context, provider, providerLinkID, gatewayPaymentID string, payment *Payment) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	recorded := false
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var linkID uuid.UUID
+		err := tx.QueryRowContext(ctx, `
+            SELECT id, inv_id
+            FROM payment_link
+            WHERE provider = $1 AND provider_link_id = $2
+            FOR UPDATE
+        `, provider, providerLinkID).Scan(&linkID, &payment.InvID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		var exists bool
+		err = tx.QueryRowContext(ctx, `
+            SELECT EXISTS (SELECT 1 FROM payment WHERE gateway_payment_id = $1)
+        `, gatewayPaymentID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return nil
+		}
+
+		if err := createPayment(ctx, tx, payment); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE payment SET gateway_payment_id = $2 WHERE id = $1`, payment.ID, gatewayPaymentID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+            UPDATE payment_link
+            SET status = 'paid',
+                paid_at = now()
+            WHERE id = $1
+        `, linkID)
+		if err != nil {
+			return err
+		}
+
+		recorded = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return recorded, nil
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRecordPaymentIsIdempotent(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	invoice := newTestInvoice(t, s, 1180)
+	link := &PaymentLink{
+		InvID:          invoice.ID,
+		Provider:       "razorpay",
+		ProviderLinkID: "plink_fake000001",
+		URL:            "https://rzp.io/i/plink_fake000001",
+		Amount:         1180,
+	}
+	if err := s.PaymentLinks.Create(ctx, link); err != nil {
+		t.Fatal(err)
+	}
+
+	// The provider retries a webhook until it is acknowledged, so the same
+	// payment can arrive more than once.
+	for i, want := range []bool{true, false} {
+		payment := &Payment{Amount: 1180, PaidOn: time.Now(), Method: "upi", Reference: "pay_fake000001"}
+		recorded, err := s.PaymentLinks.RecordPayment(ctx, "razorpay", link.ProviderLinkID, "pay_fake000001", payment)
+		if err != nil {
+			t.Fatalf("delivery %d: %v", i+1, err)
+		}
+		if recorded != want {
+			t.Errorf("delivery %d: recorded = %v, want %v", i+1, recorded, want)
+		}
+	}
+
+	payments, err := s.Payments.GetByInvoiceID(ctx, invoice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payments) != 1 {
+		t.Fatalf("got %d payments, want 1", len(payments))
+	}
+
+	links, err := s.PaymentLinks.GetByInvoiceID(ctx, invoice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 1 || links[0].Status != PaymentLinkPaid {
+		t.Errorf("links = %+v, want one paid link", links)
+	}
+
+	_, err = s.PaymentLinks.RecordPayment(ctx, "razorpay", "plink_unknown", "pay_fake000002", &Payment{Amount: 1, PaidOn: time.Now(), Method: "upi"})
+	if err != ErrNotFound {
+		t.Errorf("unknown link: err = %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+type PortalStore struct {
+	db *sql.DB
+}
+
+func (s *PortalStore) CreateLogin(ctx context.Context, login *PortalLogin) error {
+	query := `
+        INSERT INTO portal_login (email, expires_at)
+        VALUES ($1, $2)
+        RETURNING id, created_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query, login.Email, login.ExpiresAt).Scan(
+		&login.ID,
+		&login.CreatedAt,
+	)
+}
+
+// ConsumeLogin uses up a magic link and signs in the account it was sent
+// to, creating the account on first sign-in. A link that has been used or
+// has expired is ErrNotFound.
+func (s *PortalStore) ConsumeLogin(ctx context.Context, loginID uuid.UUID) (*PortalAccount, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	account := &PortalAccount{}
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+            UPDATE portal_login
+            SET used_at = now()
+            WHERE id = $1 AND used_at IS NULL AND expires_at > now()
+            RETURNING email
+        `, loginID).Scan(&account.Email)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		return tx.QueryRowContext(ctx, `
+            INSERT INTO portal_account (email, last_login_at)
+            VALUES ($1, now())
+            ON CONFLICT (email) DO UPDATE
+            SET last_login_at = EXCLUDED.last_login_at
+            RETURNING id, email, created_at, last_login_at
+        `, account.Email).Scan(
+			&account.ID,
+			&account.Email,
+			&account.CreatedAt,
+			&account.LastLoginAt,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+func (s *PortalStore) GetAccountByID(ctx context.Context, accountID uuid.UUID) (*PortalAccount, error) {
+	query := `
+        SELECT id, email, created_at, last_login_at
+        FROM portal_account
+        WHERE id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	account := &PortalAccount{}
+	err := s.db.QueryRowContext(ctx, query, accountID).Scan(
+		&account.ID,
+		&account.Email,
+		&account.CreatedAt,
+		&account.LastLoginAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// GetCustomers lists the customer records with the given email across all
+// businesses.
+func (s *PortalStore) GetCustomers(ctx context.Context, email string) ([]*PortalCustomer, error) {
+	query := `
+        SELECT c.id, c.buss_id, b.name, c.name
+        FROM customer c
+        JOIN business b ON b.buss_id = c.buss_id
+        WHERE c.email = $1
+        ORDER BY b.name, c.name
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, email)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	customers := []*PortalCustomer{}
+	for rows.Next() {
+		customer := &PortalCustomer{}
+		err := rows.Scan(
+			&customer.CustID,
+			&customer.BusID,
+			&customer.BusinessName,
+			&customer.Name,
+		)
+		if err != nil {
+			return nil, err
+		}
+		customers = append(customers, customer)
+	}
+
+	return customers, rows.Err()
+}
+
+// GetInvoices lists the invoices billed to the given email, newest first,
+// optionally from one business only.
+func (s *PortalStore) GetInvoices(ctx context.Context, email string, busID *uuid.UUID) ([]*PortalInvoice, error) {
+	query := `
+        SELECT i.id,
+               i.buss_id,
+               b.name,
+               i.cust_id,
+               i.inv_no,
+               i.kind,
+               i.inv_date,
+               i.due_date,
+               i.total_amount,
+               i.total_amount - COALESCE(p.amount, 0),
+               i.is_paid
+        FROM invoice i
+        JOIN customer c ON c.id = i.cust_id
+        JOIN business b ON b.buss_id = i.buss_id
+        LEFT JOIN (
+            SELECT inv_id, SUM(amount) AS amount
+            FROM payment
+            GROUP BY inv_id
+        ) p ON p.inv_id = i.id
+        WHERE c.email = $1
+          AND ($2::uuid IS NULL OR i.buss_id = $2)
+        ORDER BY i.inv_date DESC, b.name, i.inv_no DESC
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, email, busID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoices := []*PortalInvoice{}
+	for rows.Next() {
+		invoice := &PortalInvoice{}
+		err := rows.Scan(
+			&invoice.ID,
+			&invoice.BusID,
+			&invoice.BusinessName,
+			&invoice.CustID,
+			&invoice.InvNo,
+			&invoice.Kind,
+			&invoice.InvDate,
+			&invoice.DueDate,
+			&invoice.TotalAmount,
+			&invoice.Balance,
+			&invoice.IsPaid,
+		)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, invoice)
+	}
+
+	return invoices, rows.Err()
+}
@@ -0,0 +1,300 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDuplicatePriceList = errors.New("price list already exists")
+	ErrDuplicatePriceTier = errors.New("price list has more than one price for the same product, variant and quantity")
+)
+
+type PriceListStore struct {
+	db *sql.DB
+}
+
+func (s *PriceListStore) Create(ctx context.Context, list *PriceList) error {
+	query := `
+        INSERT INTO price_list (buss_id, name, valid_from, valid_to)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			list.BusID,
+			list.Name,
+			list.ValidFrom,
+			list.ValidTo,
+		).Scan(
+			&list.ID,
+			&list.CreatedAt,
+		)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrDuplicatePriceList
+			}
+			return err
+		}
+
+		return insertPriceListItems(ctx, tx, list.ID, list.Items)
+	})
+}
+
+func insertPriceListItems(ctx context.Context, tx *sql.Tx, listID uuid.UUID, items []*PriceListItem) error {
+	query := `
+        INSERT INTO price_list_item (list_id, prod_id, variant_id, min_qty, unit_price)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id
+    `
+
+	for _, item := range items {
+		item.ListID = listID
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			listID,
+			item.ProdID,
+			item.VariantID,
+			item.MinQty,
+			item.UnitPrice,
+		).Scan(&item.ID)
+		if err != nil {
+			switch {
+			case isUniqueViolation(err):
+				return ErrDuplicatePriceTier
+			case isForeignKeyViolation(err):
+				return ErrInvalidItemReference
+			default:
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *PriceListStore) GetByID(ctx context.Context, listID uuid.UUID) (*PriceList, error) {
+	query := `
+        SELECT id, buss_id, name, valid_from, valid_to, created_at
+        FROM price_list
+        WHERE id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	list := &PriceList{}
+	err := s.db.QueryRowContext(ctx, query, listID).Scan(
+		&list.ID,
+		&list.BusID,
+		&list.Name,
+		&list.ValidFrom,
+		&list.ValidTo,
+		&list.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT id, list_id, prod_id, variant_id, min_qty, unit_price
+        FROM price_list_item
+        WHERE list_id = $1
+        ORDER BY prod_id, variant_id NULLS FIRST, min_qty
+    `, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list.Items = []*PriceListItem{}
+	for rows.Next() {
+		item := &PriceListItem{}
+		err := rows.Scan(
+			&item.ID,
+			&item.ListID,
+			&item.ProdID,
+			&item.VariantID,
+			&item.MinQty,
+			&item.UnitPrice,
+		)
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, item)
+	}
+
+	return list, rows.Err()
+}
+
+func (s *PriceListStore) GetByBusID(ctx context.Context, busID uuid.UUID) ([]*PriceList, error) {
+	query := `
+        SELECT id, buss_id, name, valid_from, valid_to, created_at
+        FROM price_list
+        WHERE buss_id = $1
+        ORDER BY name
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, busID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []*PriceList{}
+	for rows.Next() {
+		list := &PriceList{}
+		err := rows.Scan(
+			&list.ID,
+			&list.BusID,
+			&list.Name,
+			&list.ValidFrom,
+			&list.ValidTo,
+			&list.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+
+	return lists, rows.Err()
+}
+
+// Update renames the list, changes its validity window and replaces all of
+// its price tiers.
+func (s *PriceListStore) Update(ctx context.Context, list *PriceList) error {
+	query := `
+        UPDATE price_list
+        SET name = $2, valid_from = $3, valid_to = $4
+        WHERE id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, list.ID, list.Name, list.ValidFrom, list.ValidTo)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrDuplicatePriceList
+			}
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		_, err = tx.ExecContext(ctx, `DELETE FROM price_list_item WHERE list_id = $1`, list.ID)
+		if err != nil {
+			return err
+		}
+
+		return insertPriceListItems(ctx, tx, list.ID, list.Items)
+	})
+}
+
+func (s *PriceListStore) Delete(ctx context.Context, listID uuid.UUID) error {
+	query := `DELETE FROM price_list WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, listID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// ResolvePrice returns the unit price a customer pays for a product. The
+// customer's price list applies when it is valid on the given date; within
+// it the tier with the highest min_qty not above the quantity wins, and a
+// tier for the exact variant beats one for the whole product. Without a
+// matching tier the variant or product list price is used.
+func (s *PriceListStore) ResolvePrice(ctx context.Context, q PriceQuery) (*ResolvedPrice, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	resolved := &ResolvedPrice{
+		ProdID:    q.ProdID,
+		VariantID: q.VariantID,
+		CustID:    q.CustID,
+		Quantity:  q.Quantity,
+	}
+
+	err := s.db.QueryRowContext(ctx, `
+        SELECT COALESCE(v.price, p.price)
+        FROM product p
+        LEFT JOIN product_variant v ON v.prod_id = p.id AND v.id = $2
+        WHERE p.id = $1 AND ($2::uuid IS NULL OR v.id IS NOT NULL)
+    `, q.ProdID, q.VariantID).Scan(&resolved.BasePrice)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	resolved.UnitPrice = resolved.BasePrice
+
+	var listID uuid.UUID
+	err = s.db.QueryRowContext(ctx, `
+        SELECT pl.id, pl.name, pli.min_qty, pli.unit_price
+        FROM customer c
+        JOIN price_list pl ON pl.id = c.price_list_id
+        JOIN price_list_item pli ON pli.list_id = pl.id
+        WHERE c.id = $1
+          AND pli.prod_id = $2
+          AND (pli.variant_id = $3 OR pli.variant_id IS NULL)
+          AND pli.min_qty <= $4
+          AND (pl.valid_from IS NULL OR pl.valid_from <= $5::date)
+          AND (pl.valid_to IS NULL OR pl.valid_to >= $5::date)
+        ORDER BY pli.variant_id IS NULL, pli.min_qty DESC
+        LIMIT 1
+    `, q.CustID, q.ProdID, q.VariantID, q.Quantity, q.Date).Scan(
+		&listID,
+		&resolved.PriceListName,
+		&resolved.MinQty,
+		&resolved.UnitPrice,
+	)
+	switch {
+	case err == sql.ErrNoRows:
+		return resolved, nil
+	case err != nil:
+		return nil, err
+	}
+
+	resolved.PriceListID = &listID
+	return resolved, nil
+}
@@ -7,43 +7,102 @@ import (
 	"github.com/google/uuid"
 )
 
+const (
+	ProductTypeGoods   = "goods"
+	ProductTypeService = "service"
+)
+
 type ProductStore struct {
 	db *sql.DB
 }
 
 func (s *ProductStore) Create(ctx context.Context, product *Product) error {
 	query := `
-        INSERT INTO product (buss_id, name, price, tax_rate, unit, hsn_code)
-        VALUES ($1, $2, $3, $4, $5, $6)
+        INSERT INTO product (buss_id, name, price, tax_rate, unit, hsn_code, cost_price, track_stock, opening_stock, low_stock_threshold, category_id, sku, barcode, item_type, uqc)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, ''), NULLIF($13, ''), $14, $15)
         RETURNING id, created_at
     `
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		product.BusID,
-		product.Name,
-		product.Price,
-		product.TaxRate,
-		product.Unit,
-		product.HSNCode,
-	).Scan(
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			product.BusID,
+			product.Name,
+			product.Price,
+			product.TaxRate,
+			product.Unit,
+			product.HSNCode,
+			product.CostPrice,
+			product.TrackStock,
+			product.OpeningStock,
+			product.LowStockThreshold,
+			product.CategoryID,
+			product.SKU,
+			product.Barcode,
+			product.ItemType,
+			product.UQC,
+		).Scan(
+			&product.ID,
+			&product.CreatedAt,
+		)
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrInvalidCategory
+			}
+			return err
+		}
+
+		return syncProductCodes(ctx, tx, product.BusID, product.ID, nil, product.SKU, product.Barcode)
+	})
+}
+
+func (s *ProductStore) GetByID(ctx context.Context, productID uuid.UUID) (*Product, error) {
+	query := `
+        SELECT id, buss_id, name, price, tax_rate, unit, hsn_code, item_type, uqc, cost_price, track_stock, opening_stock, low_stock_threshold, category_id, COALESCE(sku, ''), COALESCE(barcode, ''), created_at
+        FROM product
+        WHERE id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	product := &Product{}
+	err := s.db.QueryRowContext(ctx, query, productID).Scan(
 		&product.ID,
+		&product.BusID,
+		&product.Name,
+		&product.Price,
+		&product.TaxRate,
+		&product.Unit,
+		&product.HSNCode,
+		&product.ItemType,
+		&product.UQC,
+		&product.CostPrice,
+		&product.TrackStock,
+		&product.OpeningStock,
+		&product.LowStockThreshold,
+		&product.CategoryID,
+		&product.SKU,
+		&product.Barcode,
 		&product.CreatedAt,
 	)
 	if err != nil {
-		return err
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
 	}
 
-	return nil
+	return product, nil
 }
 
 func (s *ProductStore) GetByBusID(ctx context.Context, busID uuid.UUID) ([]*Product, error) {
 	query := `
-        SELECT id, buss_id, name, price, tax_rate, unit, hsn_code, created_at
+        SELECT id, buss_id, name, price, tax_rate, unit, hsn_code, item_type, uqc, cost_price, track_stock, opening_stock, low_stock_threshold, category_id, COALESCE(sku, ''), COALESCE(barcode, ''), created_at
         FROM product
         WHERE buss_id = $1
     `
@@ -68,6 +127,15 @@ func (s *ProductStore) GetByBusID(ctx context.Context, busID uuid.UUID) ([]*Prod
 			&product.TaxRate,
 			&product.Unit,
 			&product.HSNCode,
+			&product.ItemType,
+			&product.UQC,
+			&product.CostPrice,
+			&product.TrackStock,
+			&product.OpeningStock,
+			&product.LowStockThreshold,
+			&product.CategoryID,
+			&product.SKU,
+			&product.Barcode,
 			&product.CreatedAt,
 		)
 		if err != nil {
@@ -90,34 +158,98 @@ func (s *ProductStore) Update(ctx context.Context, product *Product) error {
             price = $3,
             tax_rate = $4,
             unit = $5,
-            hsn_code = $6
+            hsn_code = $6,
+            cost_price = $7,
+            track_stock = $8,
+            opening_stock = $9,
+            low_stock_threshold = $10,
+            category_id = $11,
+            sku = NULLIF($12, ''),
+            barcode = NULLIF($13, ''),
+            item_type = $14,
+            uqc = $15
         WHERE id = $1
-        RETURNING created_at
+        RETURNING buss_id, created_at
     `
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		product.ID,
-		product.Name,
-		product.Price,
-		product.TaxRate,
-		product.Unit,
-		product.HSNCode,
-	).Scan(
-		&product.CreatedAt,
-	)
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			product.ID,
+			product.Name,
+			product.Price,
+			product.TaxRate,
+			product.Unit,
+			product.HSNCode,
+			product.CostPrice,
+			product.TrackStock,
+			product.OpeningStock,
+			product.LowStockThreshold,
+			product.CategoryID,
+			product.SKU,
+			product.Barcode,
+			product.ItemType,
+			product.UQC,
+		).Scan(
+			&product.BusID,
+			&product.CreatedAt,
+		)
+		if err != nil {
+			switch {
+			case err == sql.ErrNoRows:
+				return ErrNotFound
+			case isForeignKeyViolation(err):
+				return ErrInvalidCategory
+			default:
+				return err
+			}
+		}
+
+		return syncProductCodes(ctx, tx, product.BusID, product.ID, nil, product.SKU, product.Barcode)
+	})
+}
+
+// GetByCode finds the product, and the variant if the code belongs to one,
+// registered under a SKU or barcode.
+func (s *ProductStore) GetByCode(ctx context.Context, busID uuid.UUID, code string) (*ProductLookup, error) {
+	query := `
+        SELECT prod_id, variant_id
+        FROM product_code
+        WHERE buss_id = $1 AND code = $2
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var productID uuid.UUID
+	var variantID *uuid.UUID
+	err := s.db.QueryRowContext(ctx, query, busID, code).Scan(&productID, &variantID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return ErrNotFound
+			return nil, ErrNotFound
 		}
-		return err
+		return nil, err
 	}
 
-	return nil
+	product, err := s.GetByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := &ProductLookup{Product: product}
+	if variantID != nil {
+		variants := &ProductVariantStore{s.db}
+		lookup.Variant, err = variants.GetByID(ctx, *variantID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return lookup, nil
 }
 
 func (s *ProductStore) Delete(ctx context.Context, productID uuid.UUID) error {
@@ -145,3 +277,64 @@ func (s *ProductStore) Delete(ctx context.Context, productID uuid.UUID) error {
 
 	return nil
 }
+
+// Import upserts products on (buss_id, name) in a single transaction and
+// reports for each one whether it was newly created. With dryRun set the
+// transaction is rolled back after every row has been written.
+func (s *ProductStore) Import(ctx context.Context, products []*Product, dryRun bool) ([]bool, error) {
+	query := `
+        INSERT INTO product (buss_id, name, price, tax_rate, unit, hsn_code, item_type, uqc)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        ON CONFLICT (buss_id, name) DO UPDATE
+        SET price = EXCLUDED.price,
+            tax_rate = EXCLUDED.tax_rate,
+            unit = EXCLUDED.unit,
+            hsn_code = EXCLUDED.hsn_code,
+            item_type = EXCLUDED.item_type,
+            uqc = EXCLUDED.uqc
+        RETURNING id, created_at, (xmax = 0) AS inserted
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, ImportTimeoutDuration)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	created := make([]bool, len(products))
+	for i, product := range products {
+		err := stmt.QueryRowContext(
+			ctx,
+			product.BusID,
+			product.Name,
+			product.Price,
+			product.TaxRate,
+			product.Unit,
+			product.HSNCode,
+			product.ItemType,
+			product.UQC,
+		).Scan(
+			&product.ID,
+			&product.CreatedAt,
+			&created[i],
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if dryRun {
+		return created, nil
+	}
+
+	return created, tx.Commit()
+}
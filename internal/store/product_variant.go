@@ -0,0 +1,254 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDuplicateCode    = errors.New("sku or barcode is already used by another product")
+	ErrDuplicateVariant = errors.New("variant already exists for this product")
+)
+
+type ProductVariantStore struct {
+	db *sql.DB
+}
+
+// syncProductCodes replaces the SKU and barcode registered for a product (or
+// one of its variants) so codes stay unique across a business.
+func syncProductCodes(ctx context.Context, tx *sql.Tx, busID, productID uuid.UUID, variantID *uuid.UUID, codes ...string) error {
+	_, err := tx.ExecContext(ctx, `
+        DELETE FROM product_code
+        WHERE prod_id = $1 AND variant_id IS NOT DISTINCT FROM $2
+    `, productID, variantID)
+	if err != nil {
+		return err
+	}
+
+	for _, code := range codes {
+		if code == "" {
+			continue
+		}
+
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO product_code (buss_id, code, prod_id, variant_id)
+            VALUES ($1, $2, $3, $4)
+            ON CONFLICT DO NOTHING
+        `, busID, code, productID, variantID)
+		if err != nil {
+			return err
+		}
+
+		// A product may use the same value as both SKU and barcode, but no
+		// other product or variant may hold it.
+		var owner uuid.UUID
+		var ownerVariant *uuid.UUID
+		err = tx.QueryRowContext(ctx, `
+            SELECT prod_id, variant_id
+            FROM product_code
+            WHERE buss_id = $1 AND code = $2
+        `, busID, code).Scan(&owner, &ownerVariant)
+		if err != nil {
+			return err
+		}
+		if owner != productID || !sameVariant(ownerVariant, variantID) {
+			return ErrDuplicateCode
+		}
+	}
+
+	return nil
+}
+
+func sameVariant(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func (s *ProductVariantStore) Create(ctx context.Context, variant *ProductVariant) error {
+	query := `
+        INSERT INTO product_variant (prod_id, buss_id, name, price, sku, barcode)
+        SELECT id, buss_id, $2, $3, NULLIF($4, ''), NULLIF($5, '')
+        FROM product
+        WHERE id = $1
+        RETURNING id, buss_id, created_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			variant.ProdID,
+			variant.Name,
+			variant.Price,
+			variant.SKU,
+			variant.Barcode,
+		).Scan(
+			&variant.ID,
+			&variant.BusID,
+			&variant.CreatedAt,
+		)
+		if err != nil {
+			switch {
+			case err == sql.ErrNoRows:
+				return ErrNotFound
+			case isUniqueViolation(err):
+				return ErrDuplicateVariant
+			default:
+				return err
+			}
+		}
+
+		return syncProductCodes(ctx, tx, variant.BusID, variant.ProdID, &variant.ID, variant.SKU, variant.Barcode)
+	})
+}
+
+func (s *ProductVariantStore) GetByID(ctx context.Context, variantID uuid.UUID) (*ProductVariant, error) {
+	query := `
+        SELECT id, prod_id, buss_id, name, price, COALESCE(sku, ''), COALESCE(barcode, ''), created_at
+        FROM product_variant
+        WHERE id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	variant := &ProductVariant{}
+	err := s.db.QueryRowContext(ctx, query, variantID).Scan(
+		&variant.ID,
+		&variant.ProdID,
+		&variant.BusID,
+		&variant.Name,
+		&variant.Price,
+		&variant.SKU,
+		&variant.Barcode,
+		&variant.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return variant, nil
+}
+
+func (s *ProductVariantStore) GetByProductID(ctx context.Context, productID uuid.UUID) ([]*ProductVariant, error) {
+	query := `
+        SELECT id, prod_id, buss_id, name, price, COALESCE(sku, ''), COALESCE(barcode, ''), created_at
+        FROM product_variant
+        WHERE prod_id = $1
+        ORDER BY name
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := []*ProductVariant{}
+	for rows.Next() {
+		variant := &ProductVariant{}
+		err := rows.Scan(
+			&variant.ID,
+			&variant.ProdID,
+			&variant.BusID,
+			&variant.Name,
+			&variant.Price,
+			&variant.SKU,
+			&variant.Barcode,
+			&variant.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, variant)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
+
+func (s *ProductVariantStore) Update(ctx context.Context, variant *ProductVariant) error {
+	query := `
+        UPDATE product_variant
+        SET name = $2,
+            price = $3,
+            sku = NULLIF($4, ''),
+            barcode = NULLIF($5, '')
+        WHERE id = $1
+        RETURNING prod_id, buss_id, created_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			variant.ID,
+			variant.Name,
+			variant.Price,
+			variant.SKU,
+			variant.Barcode,
+		).Scan(
+			&variant.ProdID,
+			&variant.BusID,
+			&variant.CreatedAt,
+		)
+		if err != nil {
+			switch {
+			case err == sql.ErrNoRows:
+				return ErrNotFound
+			case isUniqueViolation(err):
+				return ErrDuplicateVariant
+			default:
+				return err
+			}
+		}
+
+		return syncProductCodes(ctx, tx, variant.BusID, variant.ProdID, &variant.ID, variant.SKU, variant.Barcode)
+	})
+}
+
+func (s *ProductVariantStore) Delete(ctx context.Context, variantID uuid.UUID) error {
+	query := `
+        DELETE FROM product_variant
+        WHERE id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, variantID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
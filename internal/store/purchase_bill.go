@@ -0,0 +1,484 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDuplicateBill = errors.New("bill with this number already exists for the vendor")
+	ErrOverpayment   = errors.New("payment exceeds the amount due on the bill")
+)
+
+type PurchaseBillStore struct {
+	db *sql.DB
+}
+
+type PurchaseBillItemStore struct {
+	db *sql.DB
+}
+
+type BillPaymentStore struct {
+	db *sql.DB
+}
+
+// purchaseBillQuery selects bills with their vendor name and the total paid
+// against them so far.
+const purchaseBillQuery = `
+    SELECT
+        b.id,
+        b.buss_id,
+        b.vendor_id,
+        v.name,
+        b.bill_no,
+        b.bill_date,
+        b.due_date,
+        b.taxable_amount,
+        b.cgst_amount,
+        b.sgst_amount,
+        b.igst_amount,
+        b.total_amount,
+        COALESCE(p.amount, 0) AS amount_paid,
+        b.created_at
+    FROM purchase_bill b
+    JOIN vendor v ON v.id = b.vendor_id
+    LEFT JOIN (
+        SELECT bill_id, SUM(amount) AS amount
+        FROM bill_payment
+        GROUP BY bill_id
+    ) p ON p.bill_id = b.id
+`
+
+func scanPurchaseBill(row interface{ Scan(...any) error }) (*PurchaseBill, error) {
+	bill := &PurchaseBill{}
+	err := row.Scan(
+		&bill.ID,
+		&bill.BusID,
+		&bill.VendorID,
+		&bill.VendorName,
+		&bill.BillNo,
+		&bill.BillDate,
+		&bill.DueDate,
+		&bill.TaxableAmount,
+		&bill.CGSTAmount,
+		&bill.SGSTAmount,
+		&bill.IGSTAmount,
+		&bill.TotalAmount,
+		&bill.AmountPaid,
+		&bill.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	bill.IsPaid = bill.AmountPaid >= bill.TotalAmount
+
+	return bill, nil
+}
+
+// Create inserts the bill header. The vendor must belong to the bill's
+// business, otherwise ErrNotFound is returned.
+func (s *PurchaseBillStore) Create(ctx context.Context, bill *PurchaseBill) error {
+	query := `
+        INSERT INTO purchase_bill (buss_id, vendor_id, bill_no, bill_date, due_date, taxable_amount, cgst_amount, sgst_amount, igst_amount, total_amount)
+        SELECT buss_id, id, $3, $4, $5, $6, $7, $8, $9, $10
+        FROM vendor
+        WHERE id = $2 AND buss_id = $1
+        RETURNING id, created_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			bill.BusID,
+			bill.VendorID,
+			bill.BillNo,
+			bill.BillDate,
+			bill.DueDate,
+			bill.TaxableAmount,
+			bill.CGSTAmount,
+			bill.SGSTAmount,
+			bill.IGSTAmount,
+			bill.TotalAmount,
+		).Scan(
+			&bill.ID,
+			&bill.CreatedAt,
+		)
+		if err != nil {
+			switch {
+			case err == sql.ErrNoRows:
+				return ErrNotFound
+			case isUniqueViolation(err):
+				return ErrDuplicateBill
+			default:
+				return err
+			}
+		}
+
+		return repostBill(ctx, tx, bill.ID)
+	})
+}
+
+func (s *PurchaseBillStore) GetByID(ctx context.Context, billID uuid.UUID) (*PurchaseBill, error) {
+	query := purchaseBillQuery + `WHERE b.id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	bill, err := scanPurchaseBill(s.db.QueryRowContext(ctx, query, billID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return bill, nil
+}
+
+func (s *PurchaseBillStore) GetByBusID(ctx context.Context, busID uuid.UUID) ([]*PurchaseBill, error) {
+	query := purchaseBillQuery + `
+        WHERE b.buss_id = $1
+        ORDER BY b.bill_date DESC, b.created_at DESC
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, busID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bills := []*PurchaseBill{}
+	for rows.Next() {
+		bill, err := scanPurchaseBill(rows)
+		if err != nil {
+			return nil, err
+		}
+		bills = append(bills, bill)
+	}
+
+	return bills, rows.Err()
+}
+
+func (s *PurchaseBillStore) Update(ctx context.Context, bill *PurchaseBill) error {
+	query := `
+        UPDATE purchase_bill b
+        SET vendor_id = v.id,
+            bill_no = $3,
+            bill_date = $4,
+            due_date = $5,
+            taxable_amount = $6,
+            cgst_amount = $7,
+            sgst_amount = $8,
+            igst_amount = $9,
+            total_amount = $10
+        FROM vendor v
+        WHERE b.id = $1 AND v.id = $2 AND v.buss_id = b.buss_id
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(
+			ctx,
+			query,
+			bill.ID,
+			bill.VendorID,
+			bill.BillNo,
+			bill.BillDate,
+			bill.DueDate,
+			bill.TaxableAmount,
+			bill.CGSTAmount,
+			bill.SGSTAmount,
+			bill.IGSTAmount,
+			bill.TotalAmount,
+		)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrDuplicateBill
+			}
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return repostBill(ctx, tx, bill.ID)
+	})
+}
+
+func (s *PurchaseBillStore) Delete(ctx context.Context, billID uuid.UUID) error {
+	query := `
+        DELETE FROM purchase_bill
+        WHERE id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := reverseBillStock(ctx, tx, billID); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, query, billID)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+func (s *PurchaseBillItemStore) Create(ctx context.Context, item *PurchaseBillItem) error {
+	query := `
+        INSERT INTO purchase_bill_item (bill_id, prod_id, variant_id, quantity, unit_price, tax_rate)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			item.BillID,
+			item.ProdID,
+			item.VariantID,
+			item.Quantity,
+			item.UnitPrice,
+			item.TaxRate,
+		).Scan(
+			&item.ID,
+		)
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrInvalidItemReference
+			}
+			return err
+		}
+
+		return postBillItemStock(ctx, tx, item.BillID, item)
+	})
+}
+
+func (s *PurchaseBillItemStore) GetByBillID(ctx context.Context, billID uuid.UUID) ([]*PurchaseBillItem, error) {
+	query := `
+        SELECT bi.id, bi.bill_id, bi.prod_id, bi.variant_id, COALESCE(v.name, ''), bi.quantity, bi.unit_price, bi.tax_rate
+        FROM purchase_bill_item bi
+        LEFT JOIN product_variant v ON v.id = bi.variant_id
+        WHERE bi.bill_id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, billID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*PurchaseBillItem{}
+	for rows.Next() {
+		item := &PurchaseBillItem{}
+		err := rows.Scan(
+			&item.ID,
+			&item.BillID,
+			&item.ProdID,
+			&item.VariantID,
+			&item.VariantName,
+			&item.Quantity,
+			&item.UnitPrice,
+			&item.TaxRate,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// UpdateAll replaces the items of a bill and re-posts their stock.
+func (s *PurchaseBillItemStore) UpdateAll(ctx context.Context, billID uuid.UUID, items []*PurchaseBillItem) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+            DELETE FROM purchase_bill_item
+            WHERE bill_id = $1
+        `, billID)
+		if err != nil {
+			return err
+		}
+
+		if err := clearBillStock(ctx, tx, billID); err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			item.BillID = billID
+			err := tx.QueryRowContext(ctx, `
+                INSERT INTO purchase_bill_item (bill_id, prod_id, variant_id, quantity, unit_price, tax_rate)
+                VALUES ($1, $2, $3, $4, $5, $6)
+                RETURNING id
+            `, billID, item.ProdID, item.VariantID, item.Quantity, item.UnitPrice, item.TaxRate).Scan(&item.ID)
+			if err != nil {
+				if isForeignKeyViolation(err) {
+					return ErrInvalidItemReference
+				}
+				return err
+			}
+
+			if err := postBillItemStock(ctx, tx, billID, item); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Create records a payment made against a bill. The bill row is locked so
+// concurrent payments cannot together exceed the bill total.
+func (s *BillPaymentStore) Create(ctx context.Context, payment *BillPayment) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var total, paid float64
+		err := tx.QueryRowContext(ctx, `
+            SELECT total_amount
+            FROM purchase_bill
+            WHERE id = $1
+            FOR UPDATE
+        `, payment.BillID).Scan(&total)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		err = tx.QueryRowContext(ctx, `
+            SELECT COALESCE(SUM(amount), 0)
+            FROM bill_payment
+            WHERE bill_id = $1
+        `, payment.BillID).Scan(&paid)
+		if err != nil {
+			return err
+		}
+		due := total - paid
+
+		// Compare in paise so rounding noise does not reject an exact payment.
+		if int64(payment.Amount*100+0.5) > int64(due*100+0.5) {
+			return ErrOverpayment
+		}
+
+		err = tx.QueryRowContext(ctx, `
+            INSERT INTO bill_payment (bill_id, amount, paid_on, method, reference)
+            VALUES ($1, $2, $3, $4, $5)
+            RETURNING id, created_at
+        `, payment.BillID, payment.Amount, payment.PaidOn, payment.Method, payment.Reference).Scan(
+			&payment.ID,
+			&payment.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		return repostBillPayment(ctx, tx, payment.ID)
+	})
+}
+
+func (s *BillPaymentStore) GetByBillID(ctx context.Context, billID uuid.UUID) ([]*BillPayment, error) {
+	query := `
+        SELECT id, bill_id, amount, paid_on, method, reference, created_at
+        FROM bill_payment
+        WHERE bill_id = $1
+        ORDER BY paid_on, created_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, billID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []*BillPayment{}
+	for rows.Next() {
+		payment := &BillPayment{}
+		err := rows.Scan(
+			&payment.ID,
+			&payment.BillID,
+			&payment.Amount,
+			&payment.PaidOn,
+			&payment.Method,
+			&payment.Reference,
+			&payment.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+
+	return payments, rows.Err()
+}
+
+func (s *BillPaymentStore) Delete(ctx context.Context, paymentID uuid.UUID) error {
+	query := `DELETE FROM bill_payment WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, paymentID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,310 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrDuplicateReminderRule = errors.New("a reminder rule with this schedule already exists")
+
+type ReminderStore struct {
+	db *sql.DB
+}
+
+func (s *ReminderStore) CreateRule(ctx context.Context, rule *ReminderRule) error {
+	query := `
+        INSERT INTO reminder_rule (buss_id, offset_days, repeat_days, is_active)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		rule.BusID,
+		rule.OffsetDays,
+		rule.RepeatDays,
+		rule.IsActive,
+	).Scan(
+		&rule.ID,
+		&rule.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateReminderRule
+		case isForeignKeyViolation(err):
+			return ErrNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *ReminderStore) GetRulesByBusID(ctx context.Context, busID uuid.UUID) ([]*ReminderRule, error) {
+	query := `
+        SELECT id, buss_id, offset_days, repeat_days, is_active, created_at
+        FROM reminder_rule
+        WHERE buss_id = $1
+        ORDER BY offset_days, repeat_days NULLS FIRST
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, busID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []*ReminderRule{}
+	for rows.Next() {
+		rule := &ReminderRule{}
+		err := rows.Scan(
+			&rule.ID,
+			&rule.BusID,
+			&rule.OffsetDays,
+			&rule.RepeatDays,
+			&rule.IsActive,
+			&rule.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+func (s *ReminderStore) UpdateRule(ctx context.Context, rule *ReminderRule) error {
+	query := `
+        UPDATE reminder_rule
+        SET offset_days = $2,
+            repeat_days = $3,
+            is_active = $4
+        WHERE id = $1
+        RETURNING buss_id, created_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		rule.ID,
+		rule.OffsetDays,
+		rule.RepeatDays,
+		rule.IsActive,
+	).Scan(
+		&rule.BusID,
+		&rule.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			return ErrNotFound
+		case isUniqueViolation(err):
+			return ErrDuplicateReminderRule
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *ReminderStore) DeleteRule(ctx context.Context, ruleID uuid.UUID) error {
+	query := `DELETE FROM reminder_rule WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, ruleID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetDue lists the reminders falling due on asOf across all businesses that
+// have not been sent yet. Unpaid invoices of customers with an email address
+// who have not opted out are considered.
+//
+// A one-off rule is caught up for graceDays after the day it falls on, so a
+// missed run does not lose it, but a rule added today does not remind about
+// every invoice it would have fired for in the past. A repeating rule
+// fires for its latest occurrence only.
+func (s *ReminderStore) GetDue(ctx context.Context, asOf time.Time, graceDays int) ([]*DueReminder, error) {
+	query := `
+        WITH candidate AS (
+            SELECT r.id AS rule_id,
+                   i.id AS inv_id,
+                   r.repeat_days,
+                   ($1::date - i.due_date::date) - r.offset_days AS elapsed
+            FROM reminder_rule r
+            JOIN invoice i ON i.buss_id = r.buss_id
+            JOIN customer c ON c.id = i.cust_id
+            WHERE r.is_active
+              AND NOT i.is_paid
+              AND NOT c.reminders_opt_out
+              AND c.email <> ''
+        ),
+        due AS (
+            SELECT rule_id,
+                   inv_id,
+                   CASE WHEN repeat_days IS NULL THEN 0 ELSE elapsed / repeat_days END AS occurrence
+            FROM candidate
+            WHERE elapsed >= 0
+              AND (repeat_days IS NOT NULL OR elapsed < $2)
+        )
+        SELECT d.rule_id, d.inv_id, d.occurrence
+        FROM due d
+        WHERE NOT EXISTS (
+            SELECT 1
+            FROM reminder_log l
+            WHERE l.rule_id = d.rule_id AND l.inv_id = d.inv_id AND l.occurrence = d.occurrence
+        )
+        ORDER BY d.inv_id, d.rule_id
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, asOf, graceDays)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	due := []*DueReminder{}
+	for rows.Next() {
+		reminder := &DueReminder{}
+		if err := rows.Scan(&reminder.RuleID, &reminder.InvID, &reminder.Occurrence); err != nil {
+			return nil, err
+		}
+		due = append(due, reminder)
+	}
+
+	return due, rows.Err()
+}
+
+// Claim logs a due reminder before it is sent. It reports false when the
+// reminder is already logged, for instance by another instance of the job,
+// in which case it must not be sent.
+func (s *ReminderStore) Claim(ctx context.Context, reminder *DueReminder) (*ReminderLog, bool, error) {
+	query := `
+        INSERT INTO reminder_log (rule_id, inv_id, occurrence)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (rule_id, inv_id, occurrence) DO NOTHING
+        RETURNING id, created_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	entry := &ReminderLog{
+		RuleID:     reminder.RuleID,
+		InvID:      reminder.InvID,
+		Occurrence: reminder.Occurrence,
+	}
+	err := s.db.QueryRowContext(ctx, query, reminder.RuleID, reminder.InvID, reminder.Occurrence).Scan(
+		&entry.ID,
+		&entry.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	return entry, true, nil
+}
+
+// Complete links a claimed reminder to the email that was sent for it.
+func (s *ReminderStore) Complete(ctx context.Context, entry *ReminderLog, deliveryID uuid.UUID) error {
+	query := `
+        UPDATE reminder_log
+        SET delivery_id = $2
+        WHERE id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, query, entry.ID, deliveryID); err != nil {
+		return err
+	}
+	entry.DeliveryID = &deliveryID
+
+	return nil
+}
+
+// Release gives up a claim whose email could not be sent, so that a later
+// run tries again.
+func (s *ReminderStore) Release(ctx context.Context, entry *ReminderLog) error {
+	query := `DELETE FROM reminder_log WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, entry.ID)
+	return err
+}
+
+func (s *ReminderStore) GetLogByInvoiceID(ctx context.Context, invoiceID uuid.UUID) ([]*ReminderLog, error) {
+	query := `
+        SELECT id, rule_id, inv_id, occurrence, delivery_id, created_at
+        FROM reminder_log
+        WHERE inv_id = $1
+        ORDER BY created_at DESC
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*ReminderLog{}
+	for rows.Next() {
+		entry := &ReminderLog{}
+		err := rows.Scan(
+			&entry.ID,
+			&entry.RuleID,
+			&entry.InvID,
+			&entry.Occurrence,
+			&entry.DeliveryID,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,227 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReportStore struct {
+	db *sql.DB
+}
+
+// GetAging buckets the unpaid balance of every invoice that was outstanding
+// at the end of asOf by how many days it was past its due date on that day.
+func (s *ReportStore) GetAging(ctx context.Context, businessID uuid.UUID, asOf time.Time) (*AgingReport, error) {
+	query := `
+        WITH outstanding AS (
+            SELECT
+                i.cust_id,
+                i.total_amount - COALESCE(p.amount, 0) AS total_amount,
+                $2::date - i.due_date::date AS days_overdue
+            FROM invoice i
+            LEFT JOIN (
+                SELECT inv_id, SUM(amount) AS amount
+                FROM payment
+                WHERE buss_id = $1 AND paid_on < $3
+                GROUP BY inv_id
+            ) p ON p.inv_id = i.id
+            WHERE i.buss_id = $1
+                AND i.inv_date < $3
+                AND i.total_amount - COALESCE(p.amount, 0) > 0
+        )
+        SELECT
+            c.id,
+            c.name,
+            COALESCE(SUM(o.total_amount) FILTER (WHERE o.days_overdue <= 0), 0) AS current,
+            COALESCE(SUM(o.total_amount) FILTER (WHERE o.days_overdue BETWEEN 1 AND 30), 0) AS days_1_30,
+            COALESCE(SUM(o.total_amount) FILTER (WHERE o.days_overdue BETWEEN 31 AND 60), 0) AS days_31_60,
+            COALESCE(SUM(o.total_amount) FILTER (WHERE o.days_overdue BETWEEN 61 AND 90), 0) AS days_61_90,
+            COALESCE(SUM(o.total_amount) FILTER (WHERE o.days_overdue > 90), 0) AS days_90_plus,
+            SUM(o.total_amount) AS total
+        FROM outstanding o
+        JOIN customer c ON c.id = o.cust_id
+        GROUP BY c.id, c.name
+        ORDER BY total DESC, c.name
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, businessID, asOf.Format("2006-01-02"), asOf.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &AgingReport{
+		BusID:     businessID,
+		AsOf:      asOf,
+		Customers: []CustomerAging{},
+	}
+	for rows.Next() {
+		var aging CustomerAging
+		err := rows.Scan(
+			&aging.CustID,
+			&aging.Name,
+			&aging.Current,
+			&aging.Days1To30,
+			&aging.Days31To60,
+			&aging.Days61To90,
+			&aging.Over90,
+			&aging.Total,
+		)
+		if err != nil {
+			return nil, err
+		}
+		report.Customers = append(report.Customers, aging)
+
+		report.Totals.Current += aging.Current
+		report.Totals.Days1To30 += aging.Days1To30
+		report.Totals.Days31To60 += aging.Days31To60
+		report.Totals.Days61To90 += aging.Days61To90
+		report.Totals.Over90 += aging.Over90
+		report.Totals.Total += aging.Total
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	accruals, err := lateFeeAccruals(ctx, s.db, businessID, nil, asOf)
+	if err != nil {
+		return nil, err
+	}
+	pending := pendingLateFees(accruals)
+	for i := range report.Customers {
+		aging := &report.Customers[i]
+		aging.AccruedLateFees = pending[aging.CustID]
+		report.Totals.AccruedLateFees += aging.AccruedLateFees
+	}
+
+	return report, nil
+}
+
+// GetStockValuation values the quantity on hand of every stock-tracked product
+// at its cost price, falling back to the selling price when no cost is set.
+func (s *ReportStore) GetStockValuation(ctx context.Context, businessID uuid.UUID) (*StockValuation, error) {
+	query := `
+        SELECT
+            p.id,
+            p.name,
+            p.unit,
+            p.opening_stock + COALESCE(m.quantity, 0) AS on_hand,
+            COALESCE(p.cost_price, p.price) AS unit_cost,
+            p.price
+        FROM product p
+        LEFT JOIN (
+            SELECT prod_id, SUM(quantity) AS quantity
+            FROM stock_movement
+            WHERE buss_id = $1
+            GROUP BY prod_id
+        ) m ON m.prod_id = p.id
+        WHERE p.buss_id = $1 AND p.track_stock
+        ORDER BY p.name
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, businessID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	valuation := &StockValuation{
+		BusID: businessID,
+		Items: []StockValuationItem{},
+	}
+	for rows.Next() {
+		var item StockValuationItem
+		var price float64
+		err := rows.Scan(
+			&item.ProdID,
+			&item.Name,
+			&item.Unit,
+			&item.OnHand,
+			&item.UnitCost,
+			&price,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		item.CostValue = item.OnHand * item.UnitCost
+		item.SaleValue = item.OnHand * price
+		valuation.Items = append(valuation.Items, item)
+
+		valuation.CostValue += item.CostValue
+		valuation.SaleValue += item.SaleValue
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return valuation, nil
+}
+
+// GetInputTaxCredit totals the GST charged on purchase bills dated between
+// from and to (inclusive), month by month.
+func (s *ReportStore) GetInputTaxCredit(ctx context.Context, businessID uuid.UUID, from, to time.Time) (*InputTaxCredit, error) {
+	query := `
+        SELECT
+            to_char(date_trunc('month', bill_date), 'YYYY-MM') AS month,
+            SUM(taxable_amount),
+            SUM(cgst_amount),
+            SUM(sgst_amount),
+            SUM(igst_amount)
+        FROM purchase_bill
+        WHERE buss_id = $1 AND bill_date BETWEEN $2 AND $3
+        GROUP BY 1
+        ORDER BY 1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, businessID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &InputTaxCredit{
+		BusID:  businessID,
+		From:   from,
+		To:     to,
+		Months: []InputTaxCreditPeriod{},
+		Totals: InputTaxCreditPeriod{Month: "Total"},
+	}
+	for rows.Next() {
+		var period InputTaxCreditPeriod
+		err := rows.Scan(
+			&period.Month,
+			&period.TaxableAmount,
+			&period.CGSTAmount,
+			&period.SGSTAmount,
+			&period.IGSTAmount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		period.Total = period.CGSTAmount + period.SGSTAmount + period.IGSTAmount
+		report.Months = append(report.Months, period)
+
+		report.Totals.TaxableAmount += period.TaxableAmount
+		report.Totals.CGSTAmount += period.CGSTAmount
+		report.Totals.SGSTAmount += period.SGSTAmount
+		report.Totals.IGSTAmount += period.IGSTAmount
+		report.Totals.Total += period.Total
+	}
+
+	return report, rows.Err()
+}
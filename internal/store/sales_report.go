@@ -0,0 +1,232 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// salesLines selects every invoice line dated in [$2, $3) for business $1,
+// with its taxable value and the tax charged at the rate it was billed at,
+// the same way the invoice PDF computes them.
+const salesLines = `
+    WITH lines AS (
+        SELECT
+            i.id AS inv_id,
+            i.cust_id,
+            i.inv_date,
+            ii.prod_id,
+            ii.quantity,
+            ii.tax_rate,
+            ii.quantity * ii.unit_price AS taxable,
+            ii.quantity * ii.unit_price * ii.tax_rate / 100 AS tax
+        FROM invoice i
+        JOIN invoice_item ii ON ii.inv_id = i.id
+        WHERE i.buss_id = $1 AND i.inv_date >= $2 AND i.inv_date < $3
+    )
+`
+
+// GetSalesSeries totals invoices per day, ISO week or month between from and
+// to (inclusive). Periods without sales are returned with zero totals so the
+// series can be charted as is.
+func (s *ReportStore) GetSalesSeries(ctx context.Context, businessID uuid.UUID, from, to time.Time, interval string) (*SalesSeries, error) {
+	query := `
+        WITH periods AS (
+            SELECT generate_series(
+                date_trunc($4, $2::timestamp),
+                date_trunc($4, $3::timestamp - interval '1 day'),
+                ('1 ' || $4)::interval
+            ) AS period
+        ),
+        sales AS (
+            SELECT date_trunc($4, inv_date AT TIME ZONE 'UTC') AS period, COUNT(*) AS invoices, SUM(total_amount) AS total
+            FROM invoice
+            WHERE buss_id = $1 AND inv_date >= $2 AND inv_date < $3
+            GROUP BY 1
+        )
+        SELECT p.period, COALESCE(s.invoices, 0), COALESCE(s.total, 0)
+        FROM periods p
+        LEFT JOIN sales s ON s.period = p.period
+        ORDER BY p.period
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, businessID, from, to.AddDate(0, 0, 1), interval)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	series := &SalesSeries{Interval: interval, Points: []SalesPoint{}}
+	for rows.Next() {
+		var point SalesPoint
+		if err := rows.Scan(&point.Period, &point.Invoices, &point.Total); err != nil {
+			return nil, err
+		}
+		series.Points = append(series.Points, point)
+		series.Invoices += point.Invoices
+		series.Total += point.Total
+	}
+
+	return series, rows.Err()
+}
+
+func (s *ReportStore) GetSalesByCustomer(ctx context.Context, businessID uuid.UUID, from, to time.Time) ([]CustomerSales, error) {
+	query := salesLines + `
+        SELECT c.id, c.name, COUNT(DISTINCT l.inv_id), SUM(l.taxable), SUM(l.tax)
+        FROM lines l
+        JOIN customer c ON c.id = l.cust_id
+        GROUP BY c.id, c.name
+        ORDER BY SUM(l.taxable) DESC, c.name
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, businessID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sales := []CustomerSales{}
+	for rows.Next() {
+		var row CustomerSales
+		if err := rows.Scan(&row.CustID, &row.Name, &row.Invoices, &row.Taxable, &row.Tax); err != nil {
+			return nil, err
+		}
+		row.Total = row.Taxable + row.Tax
+		sales = append(sales, row)
+	}
+
+	return sales, rows.Err()
+}
+
+func (s *ReportStore) GetSalesByProduct(ctx context.Context, businessID uuid.UUID, from, to time.Time) ([]ProductSales, error) {
+	query := salesLines + `
+        SELECT p.id, p.name, p.hsn_code, p.unit, SUM(l.quantity), SUM(l.taxable), SUM(l.tax)
+        FROM lines l
+        JOIN product p ON p.id = l.prod_id
+        GROUP BY p.id, p.name, p.hsn_code, p.unit
+        ORDER BY SUM(l.taxable) DESC, p.name
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, businessID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sales := []ProductSales{}
+	for rows.Next() {
+		var row ProductSales
+		err := rows.Scan(
+			&row.ProdID,
+			&row.Name,
+			&row.HSNCode,
+			&row.Unit,
+			&row.Quantity,
+			&row.Taxable,
+			&row.Tax,
+		)
+		if err != nil {
+			return nil, err
+		}
+		row.Total = row.Taxable + row.Tax
+		sales = append(sales, row)
+	}
+
+	return sales, rows.Err()
+}
+
+func (s *ReportStore) GetTaxByRate(ctx context.Context, businessID uuid.UUID, from, to time.Time) ([]TaxRateSales, error) {
+	query := salesLines + `
+        SELECT tax_rate, SUM(taxable), SUM(tax)
+        FROM lines
+        GROUP BY tax_rate
+        ORDER BY tax_rate
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, businessID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []TaxRateSales{}
+	for rows.Next() {
+		var row TaxRateSales
+		if err := rows.Scan(&row.TaxRate, &row.Taxable, &row.Tax); err != nil {
+			return nil, err
+		}
+		row.Total = row.Taxable + row.Tax
+		rates = append(rates, row)
+	}
+
+	return rates, rows.Err()
+}
+
+// GetProfitAndLoss reports profit net of GST: sales and purchases at their
+// taxable value and expenses before tax, with the GST on each side shown
+// separately.
+func (s *ReportStore) GetProfitAndLoss(ctx context.Context, businessID uuid.UUID, from, to time.Time) (*ProfitAndLoss, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	pl := &ProfitAndLoss{ExpensesByCategory: []CategoryAmount{}}
+
+	err := s.db.QueryRowContext(ctx, salesLines+`
+        SELECT COALESCE(SUM(taxable), 0), COALESCE(SUM(tax), 0)
+        FROM lines
+    `, businessID, from, to.AddDate(0, 0, 1)).Scan(&pl.Sales, &pl.OutputTax)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.QueryRowContext(ctx, `
+        SELECT COALESCE(SUM(taxable_amount), 0), COALESCE(SUM(cgst_amount + sgst_amount + igst_amount), 0)
+        FROM purchase_bill
+        WHERE buss_id = $1 AND bill_date BETWEEN $2 AND $3
+    `, businessID, from.Format("2006-01-02"), to.Format("2006-01-02")).Scan(&pl.Purchases, &pl.InputTax)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT category, SUM(amount)
+        FROM expense
+        WHERE buss_id = $1 AND expense_date BETWEEN $2 AND $3
+        GROUP BY category
+        ORDER BY SUM(amount) DESC, category
+    `, businessID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row CategoryAmount
+		if err := rows.Scan(&row.Category, &row.Amount); err != nil {
+			return nil, err
+		}
+		pl.ExpensesByCategory = append(pl.ExpensesByCategory, row)
+		pl.Expenses += row.Amount
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	pl.GrossProfit = pl.Sales - pl.Purchases
+	pl.NetProfit = pl.GrossProfit - pl.Expenses
+
+	return pl, nil
+}
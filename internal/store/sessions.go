@@ -0,0 +1,250 @@
+package store
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reasons a session was ended, kept for auditing.
+const (
+	SessionLoggedOut     = "logout"
+	SessionRevoked       = "revoked"
+	SessionReused        = "reuse"
+	SessionPasswordReset = "password_reset"
+)
+
+// ErrTokenReuse is returned when a refresh token that was already rotated
+// is presented again. The token has most likely been stolen, so the whole
+// session has been revoked.
+var ErrTokenReuse = errors.New("refresh token has already been used")
+
+type SessionStore struct {
+	db *sql.DB
+}
+
+// Create starts a session with its first refresh token. The session keeps
+// the ID it was given, which the tokens issued for it already carry.
+func (s *SessionStore) Create(ctx context.Context, session *Session, token *RefreshToken) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+            INSERT INTO user_session (id, user_id, device, user_agent, ip, expires_at)
+            VALUES ($1, $2, $3, $4, $5, $6)
+            RETURNING last_used_at, created_at
+        `,
+			session.ID,
+			session.UserID,
+			session.Device,
+			session.UserAgent,
+			session.IP,
+			session.ExpiresAt,
+		).Scan(
+			&session.LastUsedAt,
+			&session.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		token.SessionID = session.ID
+		_, err = tx.ExecContext(ctx, `
+            INSERT INTO refresh_token (id, session_id, token_hash, expires_at)
+            VALUES ($1, $2, $3, $4)
+        `, token.ID, token.SessionID, token.Hash, token.ExpiresAt)
+		return err
+	})
+}
+
+// Rotate uses up the current refresh token of a session and replaces it
+// with next. A token that is unknown, expired or belongs to a revoked
+// session is ErrNotFound. A token that was already used is ErrTokenReuse,
+// and the session is revoked.
+func (s *SessionStore) Rotate(ctx context.Context, current, next *RefreshToken, ip, userAgent string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	reused := false
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var (
+			hash      []byte
+			expiresAt time.Time
+			usedAt    *time.Time
+			revokedAt *time.Time
+		)
+		err := tx.QueryRowContext(ctx, `
+            SELECT t.token_hash, t.expires_at, t.used_at, s.revoked_at
+            FROM refresh_token t
+            JOIN user_session s ON s.id = t.session_id
+            WHERE t.id = $1 AND t.session_id = $2
+            FOR UPDATE
+        `, current.ID, current.SessionID).Scan(&hash, &expiresAt, &usedAt, &revokedAt)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		if subtle.ConstantTimeCompare(hash, current.Hash) != 1 || revokedAt != nil {
+			return ErrNotFound
+		}
+
+		if usedAt != nil {
+			reused = true
+			_, err := tx.ExecContext(ctx, `
+                UPDATE user_session
+                SET revoked_at = now(), revoked_reason = $2
+                WHERE id = $1
+            `, current.SessionID, SessionReused)
+			return err
+		}
+
+		if !time.Now().Before(expiresAt) {
+			return ErrNotFound
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE refresh_token SET used_at = now() WHERE id = $1`, current.ID)
+		if err != nil {
+			return err
+		}
+
+		next.SessionID = current.SessionID
+		_, err = tx.ExecContext(ctx, `
+            INSERT INTO refresh_token (id, session_id, token_hash, expires_at)
+            VALUES ($1, $2, $3, $4)
+        `, next.ID, next.SessionID, next.Hash, next.ExpiresAt)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+            UPDATE user_session
+            SET last_used_at = now(), expires_at = $2, ip = $3, user_agent = $4
+            WHERE id = $1
+        `, current.SessionID, next.ExpiresAt, ip, userAgent)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if reused {
+		return ErrTokenReuse
+	}
+
+	return nil
+}
+
+// GetActiveByUserID lists the user's sessions that have not been revoked
+// or expired, most recently used first.
+func (s *SessionStore) GetActiveByUserID(ctx context.Context, userID uuid.UUID) ([]*Session, error) {
+	query := `
+        SELECT id, user_id, device, user_agent, ip, expires_at, last_used_at, created_at
+        FROM user_session
+        WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+        ORDER BY last_used_at DESC
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	for rows.Next() {
+		session := &Session{}
+		err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.Device,
+			&session.UserAgent,
+			&session.IP,
+			&session.ExpiresAt,
+			&session.LastUsedAt,
+			&session.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// IsActive reports whether the user's session has neither been revoked nor
+// expired.
+func (s *SessionStore) IsActive(ctx context.Context, userID, sessionID uuid.UUID) (bool, error) {
+	query := `
+        SELECT EXISTS (
+            SELECT 1
+            FROM user_session
+            WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > now()
+        )
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var active bool
+	if err := s.db.QueryRowContext(ctx, query, sessionID, userID).Scan(&active); err != nil {
+		return false, err
+	}
+
+	return active, nil
+}
+
+// Revoke ends one of the user's sessions. A session that does not exist,
+// belongs to someone else or has already ended is ErrNotFound.
+func (s *SessionStore) Revoke(ctx context.Context, userID, sessionID uuid.UUID, reason string) error {
+	query := `
+        UPDATE user_session
+        SET revoked_at = now(), revoked_reason = $3
+        WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, sessionID, userID, reason)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// RevokeAll ends every session of the user.
+func (s *SessionStore) RevokeAll(ctx context.Context, userID uuid.UUID, reason string) error {
+	query := `
+        UPDATE user_session
+        SET revoked_at = now(), revoked_reason = $2
+        WHERE user_id = $1 AND revoked_at IS NULL
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, reason)
+	return err
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newTestSession(t *testing.T, s Storage, userID uuid.UUID) (*Session, *RefreshToken) {
+	t.Helper()
+
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	session := &Session{ID: uuid.New(), UserID: userID, Device: "test", ExpiresAt: expires}
+	token := &RefreshToken{ID: uuid.New(), Hash: []byte(uuid.NewString()), ExpiresAt: expires}
+	if err := s.Sessions.Create(context.Background(), session, token); err != nil {
+		t.Fatal(err)
+	}
+
+	return session, token
+}
+
+func TestSessionKeepsItsID(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	user := newTestUser(t, s)
+
+	// Tokens are signed with the session's ID before it is stored, so
+	// rotating must find the session under that same ID.
+	id := uuid.New()
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	session := &Session{ID: id, UserID: user.ID, ExpiresAt: expires}
+	first := &RefreshToken{ID: uuid.New(), Hash: []byte("first"), ExpiresAt: expires}
+	if err := s.Sessions.Create(ctx, session, first); err != nil {
+		t.Fatal(err)
+	}
+	if session.ID != id {
+		t.Fatalf("session ID = %v, want %v", session.ID, id)
+	}
+
+	current := &RefreshToken{ID: first.ID, SessionID: id, Hash: []byte("first")}
+	next := &RefreshToken{ID: uuid.New(), Hash: []byte("second"), ExpiresAt: expires}
+	if err := s.Sessions.Rotate(ctx, current, next, "127.0.0.1", "test"); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+}
+
+func TestSessionIsActive(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	user := newTestUser(t, s)
+	other := newTestUser(t, s)
+
+	session, _ := newTestSession(t, s, user.ID)
+	kept, _ := newTestSession(t, s, user.ID)
+
+	check := func(userID, sessionID uuid.UUID, want bool) {
+		t.Helper()
+		active, err := s.Sessions.IsActive(ctx, userID, sessionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if active != want {
+			t.Errorf("IsActive(%v, %v) = %v, want %v", userID, sessionID, active, want)
+		}
+	}
+
+	check(user.ID, session.ID, true)
+	check(other.ID, session.ID, false)
+	check(user.ID, uuid.New(), false)
+
+	if err := s.Sessions.Revoke(ctx, user.ID, session.ID, SessionLoggedOut); err != nil {
+		t.Fatal(err)
+	}
+	check(user.ID, session.ID, false)
+	check(user.ID, kept.ID, true)
+
+	if err := s.Sessions.RevokeAll(ctx, user.ID, SessionRevoked); err != nil {
+		t.Fatal(err)
+	}
+	check(user.ID, kept.ID, false)
+}
@@ -0,0 +1,190 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ShareViewPage = "view"
+	ShareViewPDF  = "pdf"
+)
+
+type InvoiceShareStore struct {
+	db *sql.DB
+}
+
+func (s *InvoiceShareStore) Create(ctx context.Context, share *InvoiceShare) error {
+	query := `
+        INSERT INTO invoice_share (inv_id, expires_at)
+        VALUES ($1, $2)
+        RETURNING id, created_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, share.InvID, share.ExpiresAt).Scan(
+		&share.ID,
+		&share.CreatedAt,
+	)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+const shareQuery = `
+    SELECT
+        s.id,
+        s.inv_id,
+        s.expires_at,
+        s.revoked_at,
+        s.created_at,
+        (SELECT COUNT(*) FROM invoice_share_view v WHERE v.share_id = s.id),
+        (SELECT MAX(v.viewed_at) FROM invoice_share_view v WHERE v.share_id = s.id)
+    FROM invoice_share s
+`
+
+func scanShare(row interface{ Scan(...any) error }) (*InvoiceShare, error) {
+	share := &InvoiceShare{}
+	err := row.Scan(
+		&share.ID,
+		&share.InvID,
+		&share.ExpiresAt,
+		&share.RevokedAt,
+		&share.CreatedAt,
+		&share.Views,
+		&share.LastViewedAt,
+	)
+	return share, err
+}
+
+func (s *InvoiceShareStore) GetByID(ctx context.Context, shareID uuid.UUID) (*InvoiceShare, error) {
+	query := shareQuery + `WHERE s.id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	share, err := scanShare(s.db.QueryRowContext(ctx, query, shareID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return share, nil
+}
+
+func (s *InvoiceShareStore) GetByInvoiceID(ctx context.Context, invoiceID uuid.UUID) ([]*InvoiceShare, error) {
+	query := shareQuery + `WHERE s.inv_id = $1 ORDER BY s.created_at DESC`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []*InvoiceShare{}
+	for rows.Next() {
+		share, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+
+	return shares, rows.Err()
+}
+
+// Revoke disables a share for good. Revoking a share twice keeps the time
+// it was first revoked.
+func (s *InvoiceShareStore) Revoke(ctx context.Context, shareID uuid.UUID) error {
+	query := `
+        UPDATE invoice_share
+        SET revoked_at = COALESCE(revoked_at, now())
+        WHERE id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, shareID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *InvoiceShareStore) RecordView(ctx context.Context, view *InvoiceShareView) error {
+	query := `
+        INSERT INTO invoice_share_view (share_id, format, ip, user_agent)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, viewed_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query, view.ShareID, view.Format, view.IP, view.UserAgent).Scan(
+		&view.ID,
+		&view.ViewedAt,
+	)
+}
+
+func (s *InvoiceShareStore) GetViews(ctx context.Context, shareID uuid.UUID) ([]*InvoiceShareView, error) {
+	query := `
+        SELECT id, share_id, format, ip, user_agent, viewed_at
+        FROM invoice_share_view
+        WHERE share_id = $1
+        ORDER BY viewed_at DESC
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, shareID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	views := []*InvoiceShareView{}
+	for rows.Next() {
+		view := &InvoiceShareView{}
+		err := rows.Scan(
+			&view.ID,
+			&view.ShareID,
+			&view.Format,
+			&view.IP,
+			&view.UserAgent,
+			&view.ViewedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+
+	return views, rows.Err()
+}
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	StatementEntryInvoice    = "invoice"
+	StatementEntryDebitNote  = "debit_note"
+	StatementEntryPayment    = "payment"
+	StatementEntryCreditNote = "credit_note"
+)
+
+// GetStatement lists a customer's invoices, payments and credit notes between
+// from and to (both inclusive dates) in chronological order with a running
+// balance. Every payment recorded against an invoice is credited on the day
+// it was received, and every credit note on its date. Late fees already
+// charged are entries like any other; those accrued by to but not yet
+// charged are reported separately.
+func (s *CustomerStore) GetStatement(ctx context.Context, customerID uuid.UUID, from time.Time, to time.Time) (*Statement, error) {
+	// Credit notes are applied to their invoices as payments, so the
+	// opening balance takes them into account with the rest.
+	openingQuery := `
+        SELECT
+            COALESCE((SELECT SUM(total_amount) FROM invoice WHERE cust_id = $1 AND inv_date < $2), 0)
+            - COALESCE((
+                SELECT SUM(p.amount)
+                FROM payment p
+                JOIN invoice i ON i.id = p.inv_id
+                WHERE i.cust_id = $1 AND p.paid_on < $2
+            ), 0)
+    `
+
+	entriesQuery := `
+        SELECT entry_date, entry_type, id, inv_no, debit, credit, ref_inv_no, cn_no
+        FROM (
+            SELECT i.inv_date AS entry_date, i.kind AS entry_type, 0 AS entry_order, i.id, i.inv_no, i.total_amount AS debit, 0 AS credit, overdue.inv_no AS ref_inv_no, NULL::int AS cn_no
+            FROM invoice i
+            LEFT JOIN late_fee_charge f ON f.charge_inv_id = i.id
+            LEFT JOIN invoice overdue ON overdue.id = f.inv_id
+            WHERE i.cust_id = $1 AND i.inv_date >= $2 AND i.inv_date < $3
+            UNION ALL
+            SELECT p.paid_on::timestamptz, 'payment', 1, i.id, i.inv_no, 0, p.amount, NULL, NULL
+            FROM payment p
+            JOIN invoice i ON i.id = p.inv_id
+            WHERE i.cust_id = $1 AND p.credit_note_id IS NULL AND p.paid_on >= $2 AND p.paid_on < $3
+            UNION ALL
+            SELECT cn.cn_date::timestamptz, 'credit_note', 1, i.id, i.inv_no, 0, p.amount, NULL, cn.cn_no
+            FROM credit_note cn
+            JOIN payment p ON p.credit_note_id = cn.id
+            JOIN invoice i ON i.id = cn.inv_id
+            WHERE i.cust_id = $1 AND cn.cn_date >= $2 AND cn.cn_date < $3
+        ) entries
+        ORDER BY entry_date, entry_order, inv_no
+    `
+
+	// to is an inclusive date, so the upper bound is the start of the next day.
+	until := to.AddDate(0, 0, 1)
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	statement := &Statement{
+		CustID:  customerID,
+		From:    from,
+		To:      to,
+		Entries: []StatementEntry{},
+	}
+
+	err := s.db.QueryRowContext(ctx, openingQuery, customerID, from).Scan(&statement.OpeningBalance)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, entriesQuery, customerID, from, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balance := statement.OpeningBalance
+	for rows.Next() {
+		var entry StatementEntry
+		err := rows.Scan(
+			&entry.Date,
+			&entry.Type,
+			&entry.InvID,
+			&entry.InvNo,
+			&entry.Debit,
+			&entry.Credit,
+			&entry.RefInvNo,
+			&entry.CNNo,
+		)
+		if err != nil {
+			return nil, err
+		}
+		balance += entry.Debit - entry.Credit
+		entry.Balance = balance
+		statement.Entries = append(statement.Entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statement.ClosingBalance = balance
+
+	var busID uuid.UUID
+	if err := s.db.QueryRowContext(ctx, `SELECT buss_id FROM customer WHERE id = $1`, customerID).Scan(&busID); err != nil {
+		return nil, err
+	}
+
+	accruals, err := lateFeeAccruals(ctx, s.db, busID, &customerID, to)
+	if err != nil {
+		return nil, err
+	}
+	statement.AccruedLateFees = pendingLateFees(accruals)[customerID]
+
+	return statement, nil
+}
@@ -0,0 +1,294 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+const (
+	StockMovementSale       = "sale"
+	StockMovementPurchase   = "purchase"
+	StockMovementAdjustment = "adjustment"
+	StockMovementReturn     = "return"
+)
+
+var (
+	ErrStockNotTracked = errors.New("stock is not tracked for this product")
+)
+
+type StockStore struct {
+	db *sql.DB
+}
+
+// stockLevelQuery selects products with their quantity on hand, which is the
+// opening stock plus every movement posted against the product.
+const stockLevelQuery = `
+    SELECT
+        p.id,
+        p.name,
+        p.unit,
+        p.opening_stock,
+        p.opening_stock + COALESCE(m.quantity, 0) AS on_hand,
+        p.low_stock_threshold
+    FROM product p
+    LEFT JOIN (
+        SELECT prod_id, SUM(quantity) AS quantity
+        FROM stock_movement
+        GROUP BY prod_id
+    ) m ON m.prod_id = p.id
+`
+
+func scanStockLevel(row interface{ Scan(...any) error }) (*StockLevel, error) {
+	level := &StockLevel{}
+	err := row.Scan(
+		&level.ProdID,
+		&level.Name,
+		&level.Unit,
+		&level.OpeningStock,
+		&level.OnHand,
+		&level.LowStockThreshold,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	level.IsLow = level.LowStockThreshold != nil && level.OnHand <= *level.LowStockThreshold
+
+	return level, nil
+}
+
+func (s *StockStore) GetLevel(ctx context.Context, productID uuid.UUID) (*StockLevel, error) {
+	query := stockLevelQuery + `WHERE p.id = $1 AND p.track_stock`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	level, err := scanStockLevel(s.db.QueryRowContext(ctx, query, productID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrStockNotTracked
+		}
+		return nil, err
+	}
+
+	return level, nil
+}
+
+func (s *StockStore) GetLowStock(ctx context.Context, businessID uuid.UUID) ([]*StockLevel, error) {
+	query := `
+        SELECT * FROM (` + stockLevelQuery + `WHERE p.buss_id = $1 AND p.track_stock) levels
+        WHERE low_stock_threshold IS NOT NULL AND on_hand <= low_stock_threshold
+        ORDER BY name
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, businessID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	levels := []*StockLevel{}
+	for rows.Next() {
+		level, err := scanStockLevel(rows)
+		if err != nil {
+			return nil, err
+		}
+		levels = append(levels, level)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return levels, nil
+}
+
+func (s *StockStore) GetMovements(ctx context.Context, productID uuid.UUID) ([]*StockMovement, error) {
+	query := `
+        SELECT id, buss_id, prod_id, inv_id, bill_id, kind, quantity, note, created_at
+        FROM stock_movement
+        WHERE prod_id = $1
+        ORDER BY created_at DESC
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movements := []*StockMovement{}
+	for rows.Next() {
+		movement := &StockMovement{}
+		err := rows.Scan(
+			&movement.ID,
+			&movement.BusID,
+			&movement.ProdID,
+			&movement.InvID,
+			&movement.BillID,
+			&movement.Kind,
+			&movement.Quantity,
+			&movement.Note,
+			&movement.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movements = append(movements, movement)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return movements, nil
+}
+
+// CreateMovement records a manual movement such as a purchase or stock
+// adjustment against a product that tracks stock.
+func (s *StockStore) CreateMovement(ctx context.Context, movement *StockMovement) error {
+	query := `
+        INSERT INTO stock_movement (buss_id, prod_id, kind, quantity, note)
+        SELECT buss_id, id, $2, $3, $4
+        FROM product
+        WHERE id = $1 AND track_stock
+        RETURNING id, buss_id, created_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		movement.ProdID,
+		movement.Kind,
+		movement.Quantity,
+		movement.Note,
+	).Scan(
+		&movement.ID,
+		&movement.BusID,
+		&movement.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrStockNotTracked
+		}
+		return err
+	}
+
+	return nil
+}
+
+// postInvoiceItemStock records the sale of an invoice item. Products that do
+// not track stock are skipped.
+func postInvoiceItemStock(ctx context.Context, tx *sql.Tx, invoiceID uuid.UUID, item *InvoiceItem) error {
+	_, err := tx.ExecContext(ctx, `
+        INSERT INTO stock_movement (buss_id, prod_id, inv_id, kind, quantity)
+        SELECT buss_id, id, $1, 'sale', -($3::numeric)
+        FROM product
+        WHERE id = $2 AND track_stock
+    `, invoiceID, item.ProdID, item.Quantity)
+
+	return err
+}
+
+// clearInvoiceStock removes the sale movements of an invoice before its items
+// are posted again.
+func clearInvoiceStock(ctx context.Context, tx *sql.Tx, invoiceID uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `
+        DELETE FROM stock_movement
+        WHERE inv_id = $1 AND kind = 'sale'
+    `, invoiceID)
+
+	return err
+}
+
+// reverseInvoiceStock returns the stock sold on an invoice that is about to be
+// deleted, keeping the original sale movements for the audit trail.
+func reverseInvoiceStock(ctx context.Context, tx *sql.Tx, invoiceID uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `
+        INSERT INTO stock_movement (buss_id, prod_id, kind, quantity, note)
+        SELECT m.buss_id, m.prod_id, 'return', -m.quantity, 'Invoice #' || i.inv_no || ' deleted'
+        FROM stock_movement m
+        JOIN invoice i ON i.id = m.inv_id
+        WHERE m.inv_id = $1 AND m.kind = 'sale'
+    `, invoiceID)
+
+	return err
+}
+
+// postCreditNoteItemStock takes back into stock an item returned on a credit
+// note. Products that do not track stock are skipped.
+func postCreditNoteItemStock(ctx context.Context, tx *sql.Tx, note *CreditNote, item *CreditNoteItem) error {
+	_, err := tx.ExecContext(ctx, `
+        INSERT INTO stock_movement (buss_id, prod_id, inv_id, credit_note_id, kind, quantity, note)
+        SELECT buss_id, id, $1, $2, 'return', $4::numeric, 'Credit note #' || $5::int
+        FROM product
+        WHERE id = $3 AND track_stock
+    `, note.InvID, note.ID, item.ProdID, item.Quantity, note.CNNo)
+
+	return err
+}
+
+// reverseCreditNoteStock sends out again the stock a credit note that is
+// about to be deleted took back, keeping its return movements for the audit
+// trail.
+func reverseCreditNoteStock(ctx context.Context, tx *sql.Tx, creditNoteID uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `
+        INSERT INTO stock_movement (buss_id, prod_id, inv_id, kind, quantity, note)
+        SELECT m.buss_id, m.prod_id, m.inv_id, 'adjustment', -m.quantity, 'Credit note #' || cn.cn_no || ' deleted'
+        FROM stock_movement m
+        JOIN credit_note cn ON cn.id = m.credit_note_id
+        WHERE m.credit_note_id = $1 AND m.kind = 'return'
+    `, creditNoteID)
+
+	return err
+}
+
+// postBillItemStock records the purchase of a bill item. Products that do not
+// track stock are skipped.
+func postBillItemStock(ctx context.Context, tx *sql.Tx, billID uuid.UUID, item *PurchaseBillItem) error {
+	_, err := tx.ExecContext(ctx, `
+        INSERT INTO stock_movement (buss_id, prod_id, bill_id, kind, quantity)
+        SELECT buss_id, id, $1, 'purchase', $3::numeric
+        FROM product
+        WHERE id = $2 AND track_stock
+    `, billID, item.ProdID, item.Quantity)
+
+	return err
+}
+
+// clearBillStock removes the purchase movements of a bill before its items
+// are posted again.
+func clearBillStock(ctx context.Context, tx *sql.Tx, billID uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `
+        DELETE FROM stock_movement
+        WHERE bill_id = $1 AND kind = 'purchase'
+    `, billID)
+
+	return err
+}
+
+// reverseBillStock takes back the stock received on a bill that is about to
+// be deleted, keeping the original purchase movements for the audit trail.
+func reverseBillStock(ctx context.Context, tx *sql.Tx, billID uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `
+        INSERT INTO stock_movement (buss_id, prod_id, kind, quantity, note)
+        SELECT m.buss_id, m.prod_id, 'adjustment', -m.quantity, 'Purchase bill ' || b.bill_no || ' deleted'
+        FROM stock_movement m
+        JOIN purchase_bill b ON b.id = m.bill_id
+        WHERE m.bill_id = $1 AND m.kind = 'purchase'
+    `, billID)
+
+	return err
+}
@@ -13,6 +13,8 @@ var (
 	ErrNotFound          = errors.New("user not found")
 	ErrConflict          = errors.New("resource already exists")
 	QueryTimeoutDuration = time.Second * 5
+	// ImportTimeoutDuration bounds bulk imports that write many rows in one transaction.
+	ImportTimeoutDuration = time.Second * 30
 )
 
 type Storage struct {
@@ -22,6 +24,29 @@ type Storage struct {
 		Create(context.Context, *User) error
 		Update(context.Context, *User) error
 		Delete(context.Context, uuid.UUID) error
+		CreateToken(context.Context, *UserToken) error
+		VerifyEmail(context.Context, []byte) error
+		ResetPassword(context.Context, []byte, *User) error
+	}
+	Sessions interface {
+		Create(context.Context, *Session, *RefreshToken) error
+		Rotate(context.Context, *RefreshToken, *RefreshToken, string, string) error
+		GetActiveByUserID(context.Context, uuid.UUID) ([]*Session, error)
+		IsActive(context.Context, uuid.UUID, uuid.UUID) (bool, error)
+		Revoke(context.Context, uuid.UUID, uuid.UUID, string) error
+		RevokeAll(context.Context, uuid.UUID, string) error
+	}
+	MFA interface {
+		GetByUserID(context.Context, uuid.UUID) (*UserMFA, error)
+		Enroll(context.Context, uuid.UUID, []byte) error
+		Enable(context.Context, uuid.UUID, int64, [][]byte) error
+		UseStep(context.Context, uuid.UUID, int64) error
+		UseRecoveryCode(context.Context, uuid.UUID, []byte) error
+		ReplaceRecoveryCodes(context.Context, uuid.UUID, [][]byte) error
+		Disable(context.Context, uuid.UUID) error
+		CreateChallenge(context.Context, *MFAChallenge) error
+		AttemptChallenge(context.Context, uuid.UUID) (*MFAChallenge, error)
+		CompleteChallenge(context.Context, uuid.UUID) error
 	}
 	OAuthProvider interface {
 		CreateOrUpdate(context.Context, *OAuthProvider) error
@@ -32,12 +57,25 @@ type Storage struct {
 		GetByUserID(context.Context, uuid.UUID) ([]*Business, error)
 		GetDashboard(context.Context, uuid.UUID, time.Time, time.Time) (*Dashboard, error)
 		Update(context.Context, *Business) error
+		SetRequireMFA(context.Context, uuid.UUID, bool) error
 		Delete(context.Context, uuid.UUID) error
 	}
+	Memberships interface {
+		ResourceBusiness(context.Context, string, uuid.UUID) (uuid.UUID, error)
+		GetMember(context.Context, uuid.UUID, uuid.UUID) (*Member, error)
+		GetMembers(context.Context, uuid.UUID) ([]*Member, error)
+		UpdateRole(context.Context, uuid.UUID, uuid.UUID, string) error
+		Remove(context.Context, uuid.UUID, uuid.UUID) error
+		CreateInvitation(context.Context, *Invitation) error
+		GetInvitationsByBusID(context.Context, uuid.UUID) ([]*Invitation, error)
+		GetPendingInvitations(context.Context, string) ([]*Invitation, error)
+		RevokeInvitation(context.Context, uuid.UUID) error
+		RespondToInvitation(context.Context, uuid.UUID, *User, bool) (*Invitation, error)
+	}
 	Invoices interface {
 		GetByID(context.Context, uuid.UUID) (*Invoice, error)
-		Create(context.Context, *Invoice) error
-		Update(context.Context, *Invoice) error
+		Create(context.Context, *Invoice, []*InvoiceItem) error
+		Update(context.Context, *Invoice, []*InvoiceItem) error
 		UpdateStatus(context.Context, uuid.UUID) error
 		Delete(context.Context, uuid.UUID) error
 		GetByBusID(context.Context, uuid.UUID) ([]*Invoice, error)
@@ -46,35 +84,216 @@ type Storage struct {
 	InvoiceItems interface {
 		GetByID(context.Context, uuid.UUID) (*InvoiceItem, error)
 		GetByInvoiceID(context.Context, uuid.UUID) ([]*InvoiceItem, error)
-		Create(context.Context, *InvoiceItem) error
-		Update(context.Context, *InvoiceItem) error
-		UpdateAll(context.Context, uuid.UUID, []*InvoiceItem) error
+	}
+	CreditNotes interface {
+		Create(context.Context, *CreditNote) error
+		GetByID(context.Context, uuid.UUID) (*CreditNote, error)
+		GetByInvoiceID(context.Context, uuid.UUID) ([]*CreditNote, error)
+		Void(context.Context, uuid.UUID, time.Time) (*CreditNote, error)
 		Delete(context.Context, uuid.UUID) error
 	}
 	Customers interface {
 		Create(context.Context, *Customer) error
 		GetByID(context.Context, uuid.UUID) (*Customer, error)
-		GetByBusID(context.Context, uuid.UUID) ([]*CustomerWithPendingAmount, error)
+		GetByBusID(context.Context, uuid.UUID, CustomerFilter) (*CustomerPage, error)
+		GetStatement(context.Context, uuid.UUID, time.Time, time.Time) (*Statement, error)
+		Import(context.Context, []*Customer, bool) ([]bool, error)
+		SetPriceList(context.Context, uuid.UUID, *uuid.UUID) error
+		SetRemindersOptOut(context.Context, uuid.UUID, bool) error
 		Update(context.Context, *Customer) error
 		Delete(context.Context, uuid.UUID) error
 	}
 	Products interface {
+		GetByID(context.Context, uuid.UUID) (*Product, error)
 		GetByBusID(context.Context, uuid.UUID) ([]*Product, error)
 		Create(context.Context, *Product) error
 		Update(context.Context, *Product) error
 		Delete(context.Context, uuid.UUID) error
+		Import(context.Context, []*Product, bool) ([]bool, error)
+		GetByCode(context.Context, uuid.UUID, string) (*ProductLookup, error)
+	}
+	ProductVariants interface {
+		Create(context.Context, *ProductVariant) error
+		GetByID(context.Context, uuid.UUID) (*ProductVariant, error)
+		GetByProductID(context.Context, uuid.UUID) ([]*ProductVariant, error)
+		Update(context.Context, *ProductVariant) error
+		Delete(context.Context, uuid.UUID) error
+	}
+	Categories interface {
+		Create(context.Context, *Category) error
+		GetByBusID(context.Context, uuid.UUID) ([]*Category, error)
+		Update(context.Context, *Category) error
+		Delete(context.Context, uuid.UUID) error
+	}
+	PriceLists interface {
+		Create(context.Context, *PriceList) error
+		GetByID(context.Context, uuid.UUID) (*PriceList, error)
+		GetByBusID(context.Context, uuid.UUID) ([]*PriceList, error)
+		Update(context.Context, *PriceList) error
+		Delete(context.Context, uuid.UUID) error
+		ResolvePrice(context.Context, PriceQuery) (*ResolvedPrice, error)
+	}
+	Vendors interface {
+		Create(context.Context, *Vendor) error
+		GetByID(context.Context, uuid.UUID) (*Vendor, error)
+		GetByBusID(context.Context, uuid.UUID) ([]*Vendor, error)
+		Update(context.Context, *Vendor) error
+		Delete(context.Context, uuid.UUID) error
+	}
+	PurchaseBills interface {
+		Create(context.Context, *PurchaseBill) error
+		GetByID(context.Context, uuid.UUID) (*PurchaseBill, error)
+		GetByBusID(context.Context, uuid.UUID) ([]*PurchaseBill, error)
+		Update(context.Context, *PurchaseBill) error
+		Delete(context.Context, uuid.UUID) error
+	}
+	PurchaseBillItems interface {
+		Create(context.Context, *PurchaseBillItem) error
+		GetByBillID(context.Context, uuid.UUID) ([]*PurchaseBillItem, error)
+		UpdateAll(context.Context, uuid.UUID, []*PurchaseBillItem) error
+	}
+	BillPayments interface {
+		Create(context.Context, *BillPayment) error
+		GetByBillID(context.Context, uuid.UUID) ([]*BillPayment, error)
+		Delete(context.Context, uuid.UUID) error
+	}
+	Expenses interface {
+		Create(context.Context, *Expense) error
+		GetByID(context.Context, uuid.UUID) (*Expense, error)
+		GetByBusID(context.Context, uuid.UUID, ExpenseFilter) ([]*Expense, error)
+		Update(context.Context, *Expense) error
+		SetReceipt(context.Context, uuid.UUID, *string, *string, *string) error
+		Delete(context.Context, uuid.UUID) error
+		GetMonthlyTotals(context.Context, uuid.UUID, time.Time, time.Time) ([]*ExpenseMonth, error)
+		GetCategories(context.Context, uuid.UUID) ([]string, error)
+	}
+	Ledger interface {
+		GetAccounts(context.Context, uuid.UUID) ([]*Account, error)
+		CreateAccount(context.Context, *Account) error
+		UpdateAccount(context.Context, *Account) error
+		DeleteAccount(context.Context, uuid.UUID) error
+		CreateEntry(context.Context, *JournalEntry) error
+		GetEntryByID(context.Context, uuid.UUID) (*JournalEntry, error)
+		GetEntries(context.Context, uuid.UUID, time.Time, time.Time) ([]*JournalEntry, error)
+		DeleteEntry(context.Context, uuid.UUID) error
+		GetTrialBalance(context.Context, uuid.UUID, time.Time) (*TrialBalance, error)
+		GetGeneralLedger(context.Context, uuid.UUID, *uuid.UUID, time.Time, time.Time) (*GeneralLedger, error)
+		Rebuild(context.Context, uuid.UUID) error
+	}
+	Payments interface {
+		Create(context.Context, *Payment) error
+		GetByInvoiceID(context.Context, uuid.UUID) ([]*Payment, error)
+		Delete(context.Context, uuid.UUID) error
+	}
+	PaymentLinks interface {
+		Create(context.Context, *PaymentLink) error
+		GetByInvoiceID(context.Context, uuid.UUID) ([]*PaymentLink, error)
+		RecordPayment(context.Context, string, string, string, *Payment) (bool, error)
+	}
+	Bank interface {
+		Import(context.Context, *BankStatement, []*BankTransaction, bool) error
+		GetTransactions(context.Context, uuid.UUID, string) ([]*BankTransaction, error)
+		GetTransaction(context.Context, uuid.UUID) (*BankTransaction, error)
+		GetOpenInvoices(context.Context, uuid.UUID) ([]*OpenInvoice, error)
+		Match(context.Context, uuid.UUID, uuid.UUID) (*Payment, error)
+		Unmatch(context.Context, uuid.UUID) error
+		SetIgnored(context.Context, uuid.UUID, bool) error
+	}
+	Emails interface {
+		GetTemplate(context.Context, uuid.UUID, string) (*EmailTemplate, error)
+		SaveTemplate(context.Context, *EmailTemplate) error
+		CreateDelivery(context.Context, *EmailDelivery) error
+		GetDeliveriesByInvoiceID(context.Context, uuid.UUID) ([]*EmailDelivery, error)
+	}
+	Reminders interface {
+		CreateRule(context.Context, *ReminderRule) error
+		GetRulesByBusID(context.Context, uuid.UUID) ([]*ReminderRule, error)
+		UpdateRule(context.Context, *ReminderRule) error
+		DeleteRule(context.Context, uuid.UUID) error
+		GetDue(context.Context, time.Time, int) ([]*DueReminder, error)
+		Claim(context.Context, *DueReminder) (*ReminderLog, bool, error)
+		Complete(context.Context, *ReminderLog, uuid.UUID) error
+		Release(context.Context, *ReminderLog) error
+		GetLogByInvoiceID(context.Context, uuid.UUID) ([]*ReminderLog, error)
+	}
+	LateFees interface {
+		GetPolicies(context.Context, uuid.UUID) ([]*LateFeePolicy, error)
+		SavePolicy(context.Context, *LateFeePolicy) error
+		DeletePolicy(context.Context, uuid.UUID) error
+		GetAccruals(context.Context, uuid.UUID, *uuid.UUID, time.Time) ([]*LateFeeAccrual, error)
+		Issue(context.Context, uuid.UUID, string, time.Time, []uuid.UUID) ([]*LateFeeCharge, error)
+	}
+	Shares interface {
+		Create(context.Context, *InvoiceShare) error
+		GetByID(context.Context, uuid.UUID) (*InvoiceShare, error)
+		GetByInvoiceID(context.Context, uuid.UUID) ([]*InvoiceShare, error)
+		Revoke(context.Context, uuid.UUID) error
+		RecordView(context.Context, *InvoiceShareView) error
+		GetViews(context.Context, uuid.UUID) ([]*InvoiceShareView, error)
+	}
+	Portal interface {
+		CreateLogin(context.Context, *PortalLogin) error
+		ConsumeLogin(context.Context, uuid.UUID) (*PortalAccount, error)
+		GetAccountByID(context.Context, uuid.UUID) (*PortalAccount, error)
+		GetCustomers(context.Context, string) ([]*PortalCustomer, error)
+		GetInvoices(context.Context, string, *uuid.UUID) ([]*PortalInvoice, error)
+	}
+	Tally interface {
+		GetMapping(context.Context, uuid.UUID) (*TallyMapping, error)
+		SaveMapping(context.Context, *TallyMapping) error
+		GetInvoiceVouchers(context.Context, uuid.UUID, time.Time, time.Time) ([]*InvoiceVoucher, error)
+	}
+	Stock interface {
+		GetLevel(context.Context, uuid.UUID) (*StockLevel, error)
+		GetLowStock(context.Context, uuid.UUID) ([]*StockLevel, error)
+		GetMovements(context.Context, uuid.UUID) ([]*StockMovement, error)
+		CreateMovement(context.Context, *StockMovement) error
+	}
+	Reports interface {
+		GetAging(context.Context, uuid.UUID, time.Time) (*AgingReport, error)
+		GetStockValuation(context.Context, uuid.UUID) (*StockValuation, error)
+		GetInputTaxCredit(context.Context, uuid.UUID, time.Time, time.Time) (*InputTaxCredit, error)
+		GetSalesSeries(context.Context, uuid.UUID, time.Time, time.Time, string) (*SalesSeries, error)
+		GetSalesByCustomer(context.Context, uuid.UUID, time.Time, time.Time) ([]CustomerSales, error)
+		GetSalesByProduct(context.Context, uuid.UUID, time.Time, time.Time) ([]ProductSales, error)
+		GetTaxByRate(context.Context, uuid.UUID, time.Time, time.Time) ([]TaxRateSales, error)
+		GetProfitAndLoss(context.Context, uuid.UUID, time.Time, time.Time) (*ProfitAndLoss, error)
 	}
 }
 
 func NewStorage(db *sql.DB) Storage {
 	return Storage{
-		Users:         &UserStore{db},
-		OAuthProvider: &OAuthProviderStore{db},
-		Business:      &BusinessStore{db},
-		Invoices:      &InvoiceStore{db},
-		InvoiceItems:  &InvoiceItemStore{db},
-		Customers:     &CustomerStore{db},
-		Products:      &ProductStore{db},
+		Users:             &UserStore{db},
+		Sessions:          &SessionStore{db},
+		MFA:               &MFAStore{db},
+		OAuthProvider:     &OAuthProviderStore{db},
+		Business:          &BusinessStore{db},
+		Memberships:       &MembershipStore{db},
+		Invoices:          &InvoiceStore{db},
+		InvoiceItems:      &InvoiceItemStore{db},
+		CreditNotes:       &CreditNoteStore{db},
+		Customers:         &CustomerStore{db},
+		Products:          &ProductStore{db},
+		ProductVariants:   &ProductVariantStore{db},
+		Categories:        &CategoryStore{db},
+		PriceLists:        &PriceListStore{db},
+		Vendors:           &VendorStore{db},
+		PurchaseBills:     &PurchaseBillStore{db},
+		PurchaseBillItems: &PurchaseBillItemStore{db},
+		BillPayments:      &BillPaymentStore{db},
+		Expenses:          &ExpenseStore{db},
+		Ledger:            &LedgerStore{db},
+		Payments:          &PaymentStore{db},
+		PaymentLinks:      &PaymentLinkStore{db},
+		Bank:              &BankStore{db},
+		Emails:            &EmailStore{db},
+		Reminders:         &ReminderStore{db},
+		LateFees:          &LateFeeStore{db},
+		Shares:            &InvoiceShareStore{db},
+		Portal:            &PortalStore{db},
+		Tally:             &TallyStore{db},
+		Stock:             &StockStore{db},
+		Reports:           &ReportStore{db},
 	}
 }
 
@@ -0,0 +1,246 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type TallyStore struct {
+	db *sql.DB
+}
+
+// GetMapping returns the Tally ledger names configured for a business, or
+// ErrNotFound if it has not configured any.
+func (s *TallyStore) GetMapping(ctx context.Context, busID uuid.UUID) (*TallyMapping, error) {
+	query := `
+        SELECT buss_id, sales_ledger, cgst_ledger, sgst_ledger, igst_ledger, receipt_ledger, party_group
+        FROM tally_mapping
+        WHERE buss_id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	mapping := &TallyMapping{}
+	err := s.db.QueryRowContext(ctx, query, busID).Scan(
+		&mapping.BusID,
+		&mapping.Sales,
+		&mapping.CGST,
+		&mapping.SGST,
+		&mapping.IGST,
+		&mapping.Receipt,
+		&mapping.PartyGroup,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return mapping, nil
+}
+
+func (s *TallyStore) SaveMapping(ctx context.Context, mapping *TallyMapping) error {
+	query := `
+        INSERT INTO tally_mapping (buss_id, sales_ledger, cgst_ledger, sgst_ledger, igst_ledger, receipt_ledger, party_group)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        ON CONFLICT (buss_id) DO UPDATE
+        SET sales_ledger = EXCLUDED.sales_ledger,
+            cgst_ledger = EXCLUDED.cgst_ledger,
+            sgst_ledger = EXCLUDED.sgst_ledger,
+            igst_ledger = EXCLUDED.igst_ledger,
+            receipt_ledger = EXCLUDED.receipt_ledger,
+            party_group = EXCLUDED.party_group,
+            updated_at = now()
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(
+		ctx,
+		query,
+		mapping.BusID,
+		mapping.Sales,
+		mapping.CGST,
+		mapping.SGST,
+		mapping.IGST,
+		mapping.Receipt,
+		mapping.PartyGroup,
+	)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetInvoiceVouchers returns the invoices dated between from and to
+// (inclusive) or with payments received or credit notes issued in that
+// range, with their GST split the same way the ledger posts it and all their
+// payments and credit notes attached.
+func (s *TallyStore) GetInvoiceVouchers(ctx context.Context, busID uuid.UUID, from, to time.Time) ([]*InvoiceVoucher, error) {
+	query := `
+        SELECT
+            i.id,
+            i.inv_no,
+            i.inv_date,
+            c.id,
+            c.name,
+            c.gstno,
+            COALESCE(c.baddress, ''),
+            b.gstno,
+            COALESCE(SUM(ii.quantity * ii.unit_price), 0),
+            COALESCE(SUM(ii.quantity * ii.unit_price * ii.tax_rate / 100), 0)
+        FROM invoice i
+        JOIN customer c ON c.id = i.cust_id
+        JOIN business b ON b.buss_id = i.buss_id
+        LEFT JOIN invoice_item ii ON ii.inv_id = i.id
+        WHERE i.buss_id = $1
+            AND ((i.inv_date >= $2 AND i.inv_date < $3)
+                OR EXISTS (
+                    SELECT 1 FROM payment pm
+                    WHERE pm.inv_id = i.id AND pm.credit_note_id IS NULL
+                        AND pm.paid_on >= $2 AND pm.paid_on < $3
+                )
+                OR EXISTS (
+                    SELECT 1 FROM credit_note cn
+                    WHERE cn.inv_id = i.id AND cn.cn_date >= $2 AND cn.cn_date < $3
+                ))
+        GROUP BY i.id, c.id, b.gstno
+        ORDER BY i.inv_date, i.inv_no
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, busID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	vouchers := []*InvoiceVoucher{}
+	var busGSTNo string
+	for rows.Next() {
+		voucher := &InvoiceVoucher{}
+		var taxableValue, taxAmount float64
+		err := rows.Scan(
+			&voucher.InvID,
+			&voucher.InvNo,
+			&voucher.InvDate,
+			&voucher.CustID,
+			&voucher.CustName,
+			&voucher.CustGSTNo,
+			&voucher.CustAddress,
+			&busGSTNo,
+			&taxableValue,
+			&taxAmount,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		voucher.TaxableAmount, voucher.CGSTAmount, voucher.SGSTAmount, voucher.IGSTAmount = splitInvoiceTax(taxableValue, taxAmount, busGSTNo, voucher.CustGSTNo)
+		voucher.TotalAmount = voucher.TaxableAmount + voucher.CGSTAmount + voucher.SGSTAmount + voucher.IGSTAmount
+		vouchers = append(vouchers, voucher)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	byInvoice := make(map[uuid.UUID]*InvoiceVoucher, len(vouchers))
+	invoiceIDs := make([]string, 0, len(vouchers))
+	for _, voucher := range vouchers {
+		byInvoice[voucher.InvID] = voucher
+		invoiceIDs = append(invoiceIDs, voucher.InvID.String())
+	}
+
+	// All payments of the invoices are loaded, not just those in the range,
+	// so receipts keep the same number from one export to the next.
+	paymentRows, err := s.db.QueryContext(ctx, `
+        SELECT id, buss_id, inv_id, amount, paid_on, method, reference, created_at
+        FROM payment
+        WHERE inv_id = ANY($1::uuid[]) AND credit_note_id IS NULL
+        ORDER BY paid_on, created_at
+    `, pq.Array(invoiceIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer paymentRows.Close()
+
+	for paymentRows.Next() {
+		payment := &Payment{}
+		err := paymentRows.Scan(
+			&payment.ID,
+			&payment.BusID,
+			&payment.InvID,
+			&payment.Amount,
+			&payment.PaidOn,
+			&payment.Method,
+			&payment.Reference,
+			&payment.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if voucher, ok := byInvoice[payment.InvID]; ok {
+			voucher.Payments = append(voucher.Payments, payment)
+		}
+	}
+
+	if err := paymentRows.Err(); err != nil {
+		return nil, err
+	}
+
+	noteRows, err := s.db.QueryContext(ctx, `
+        SELECT
+            cn.id,
+            cn.inv_id,
+            cn.cn_no,
+            cn.cn_date,
+            COALESCE(SUM(ci.quantity * ci.unit_price), 0),
+            COALESCE(SUM(ci.quantity * ci.unit_price * ci.tax_rate / 100), 0)
+        FROM credit_note cn
+        LEFT JOIN credit_note_item ci ON ci.cn_id = cn.id
+        WHERE cn.inv_id = ANY($1::uuid[])
+        GROUP BY cn.id
+        ORDER BY cn.cn_date, cn.cn_no
+    `, pq.Array(invoiceIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer noteRows.Close()
+
+	for noteRows.Next() {
+		note := &CreditNoteVoucher{}
+		var invoiceID uuid.UUID
+		var taxableValue, taxAmount float64
+		err := noteRows.Scan(
+			&note.CNID,
+			&invoiceID,
+			&note.CNNo,
+			&note.CNDate,
+			&taxableValue,
+			&taxAmount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if voucher, ok := byInvoice[invoiceID]; ok {
+			note.TaxableAmount, note.CGSTAmount, note.SGSTAmount, note.IGSTAmount = splitInvoiceTax(taxableValue, taxAmount, busGSTNo, voucher.CustGSTNo)
+			voucher.CreditNotes = append(voucher.CreditNotes, note)
+		}
+	}
+
+	return vouchers, noteRows.Err()
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// newTestStorage connects to the database in TEST_DB_ADDR, which must be
+// migrated up, and skips the test when it is not set. Tests create their own
+// user and remove it afterwards, taking everything they made with it.
+func newTestStorage(t *testing.T) Storage {
+	t.Helper()
+
+	addr := os.Getenv("TEST_DB_ADDR")
+	if addr == "" {
+		t.Skip("TEST_DB_ADDR not set")
+	}
+
+	db, err := sql.Open("postgres", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		t.Fatalf("TEST_DB_ADDR: %v", err)
+	}
+
+	return NewStorage(db)
+}
+
+func newTestUser(t *testing.T, s Storage) *User {
+	t.Helper()
+
+	user := &User{
+		FirstName:     "Test",
+		LastName:      "User",
+		Email:         uuid.NewString() + "@example.com",
+		EmailVerified: true,
+	}
+	if err := user.Password.Set("correct horse battery"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Users.Create(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Users.Delete(context.Background(), user.ID) })
+
+	return user
+}
+
+// newTestInvoice creates a business owned by a new user, a customer and an
+// unpaid invoice for total without items.
+func newTestInvoice(t *testing.T, s Storage, total float64) *Invoice {
+	t.Helper()
+	ctx := context.Background()
+
+	user := newTestUser(t, s)
+	business := &Business{
+		UserID:       user.ID,
+		Name:         "Test Traders",
+		GSTNo:        "27AAAPL1234C1Z5",
+		CompanyEmail: user.Email,
+		CompanyPhone: "9800000000",
+		Address:      "12 MG Road",
+		City:         "Pune",
+		ZipCode:      "411001",
+		State:        "Maharashtra",
+		Country:      "India",
+		BankName:     "Test Bank",
+		AccountNo:    "000123456789",
+		IFSC:         "TEST0000001",
+		BankBranch:   "Pune",
+	}
+	if err := s.Business.Create(ctx, business); err != nil {
+		t.Fatal(err)
+	}
+
+	customer := &Customer{
+		BusID: business.ID,
+		Name:  "Acme Retail",
+		GSTNo: "27AAACA1234A1Z1",
+		Email: "accounts@acme.example",
+		Phone: "9811111111",
+	}
+	if err := s.Customers.Create(ctx, customer); err != nil {
+		t.Fatal(err)
+	}
+
+	today := time.Now().Truncate(24 * time.Hour)
+	invoice := &Invoice{
+		InvNo:       1,
+		BusID:       business.ID,
+		CustID:      customer.ID,
+		TotalAmount: total,
+		InvDate:     today,
+		DueDate:     today.AddDate(0, 0, 15),
+	}
+	if err := s.Invoices.Create(ctx, invoice, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return invoice
+}
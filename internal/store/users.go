@@ -9,6 +9,12 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Purposes of the tokens emailed to users.
+const (
+	TokenVerifyEmail   = "verify_email"
+	TokenResetPassword = "reset_password"
+)
+
 var (
 	ErrDuplicateEmail    = errors.New("a user with that email already exists")
 	ErrDuplicateUsername = errors.New("a user with that username already exists")
@@ -32,22 +38,22 @@ func (p *password) Set(text string) error {
 }
 
 func (p *password) ComparePassword(password string) error {
-    err := bcrypt.CompareHashAndPassword([]byte(p.hash), []byte(password))
-    if err != nil {
-        // Handle bcrypt errors
+	err := bcrypt.CompareHashAndPassword([]byte(p.hash), []byte(password))
+	if err != nil {
+		// Handle bcrypt errors
 		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-            return ErrPasswordMismatch
-        }
-        return err
-    }
+			return ErrPasswordMismatch
+		}
+		return err
+	}
 
-    return nil
+	return nil
 }
 
 func (s *UserStore) Create(ctx context.Context, user *User) error {
 	query := `
-		INSERT INTO users (first_name, last_name, email, password) VALUES
-		($1, $2, $3, $4) RETURNING id, created_at
+		INSERT INTO users (first_name, last_name, email, password, email_verified_at) VALUES
+		($1, $2, $3, $4, CASE WHEN $5::boolean THEN now() END) RETURNING id, created_at
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -60,6 +66,7 @@ func (s *UserStore) Create(ctx context.Context, user *User) error {
 		user.LastName,
 		user.Email,
 		user.Password.hash,
+		user.EmailVerified,
 	).Scan(
 		&user.ID,
 		&user.CreatedAt,
@@ -80,7 +87,7 @@ func (s *UserStore) Create(ctx context.Context, user *User) error {
 
 func (s *UserStore) GetByID(ctx context.Context, userID uuid.UUID) (*User, error) {
 	query := `
-		SELECT id, first_name, last_name, email, password, created_at FROM users WHERE users.id = $1
+		SELECT id, first_name, last_name, email, email_verified_at IS NOT NULL, password, created_at FROM users WHERE users.id = $1
 		`
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
@@ -95,6 +102,7 @@ func (s *UserStore) GetByID(ctx context.Context, userID uuid.UUID) (*User, error
 		&user.FirstName,
 		&user.LastName,
 		&user.Email,
+		&user.EmailVerified,
 		&user.Password.hash,
 		&user.CreatedAt,
 	)
@@ -112,7 +120,7 @@ func (s *UserStore) GetByID(ctx context.Context, userID uuid.UUID) (*User, error
 
 func (s *UserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-		SELECT id, first_name, last_name, email, password, created_at FROM users
+		SELECT id, first_name, last_name, email, email_verified_at IS NOT NULL, password, created_at FROM users
 		WHERE email = $1	
 	`
 
@@ -129,6 +137,7 @@ func (s *UserStore) GetByEmail(ctx context.Context, email string) (*User, error)
 		&user.FirstName,
 		&user.LastName,
 		&user.Email,
+		&user.EmailVerified,
 		&user.Password.hash,
 		&user.CreatedAt,
 	)
@@ -174,3 +183,109 @@ func (s *UserStore) Delete(ctx context.Context, userID uuid.UUID) error {
 
 	return nil
 }
+
+// CreateToken stores a token emailed to the user. Earlier unused tokens for
+// the same purpose stop working, so only the latest email's link is valid.
+func (s *UserStore) CreateToken(ctx context.Context, token *UserToken) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+            UPDATE user_token
+            SET used_at = now()
+            WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+        `, token.UserID, token.Purpose)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+            INSERT INTO user_token (user_id, purpose, token_hash, expires_at)
+            VALUES ($1, $2, $3, $4)
+        `, token.UserID, token.Purpose, token.Hash, token.ExpiresAt)
+		return err
+	})
+}
+
+// consumeToken uses up a token of the given purpose and returns the user it
+// was issued to. A token that is unknown, used or expired is ErrNotFound.
+func consumeToken(ctx context.Context, tx *sql.Tx, purpose string, hash []byte) (uuid.UUID, error) {
+	var userID uuid.UUID
+	err := tx.QueryRowContext(ctx, `
+        UPDATE user_token
+        SET used_at = now()
+        WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > now()
+        RETURNING user_id
+    `, hash, purpose).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.Nil, ErrNotFound
+		}
+		return uuid.Nil, err
+	}
+
+	return userID, nil
+}
+
+// VerifyEmail marks the email of the user the verification token was sent
+// to as verified.
+func (s *UserStore) VerifyEmail(ctx context.Context, hash []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		userID, err := consumeToken(ctx, tx, TokenVerifyEmail, hash)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+            UPDATE users
+            SET email_verified_at = COALESCE(email_verified_at, now())
+            WHERE id = $1
+        `, userID)
+		return err
+	})
+}
+
+// ResetPassword sets a new password for the user the reset token was sent
+// to and signs them out everywhere. Following the emailed link also proves
+// they own the email address.
+func (s *UserStore) ResetPassword(ctx context.Context, hash []byte, user *User) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		userID, err := consumeToken(ctx, tx, TokenResetPassword, hash)
+		if err != nil {
+			return err
+		}
+		user.ID = userID
+
+		_, err = tx.ExecContext(ctx, `
+            UPDATE users
+            SET password = $2, email_verified_at = COALESCE(email_verified_at, now())
+            WHERE id = $1
+        `, userID, user.Password.hash)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+            UPDATE user_token
+            SET used_at = now()
+            WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+        `, userID, TokenResetPassword)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+            UPDATE user_session
+            SET revoked_at = now(), revoked_reason = $2
+            WHERE user_id = $1 AND revoked_at IS NULL
+        `, userID, SessionPasswordReset)
+		return err
+	})
+}
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newTestToken(t *testing.T, s Storage, userID uuid.UUID, purpose string, ttl time.Duration) []byte {
+	t.Helper()
+
+	hash := []byte(uuid.NewString())
+	token := &UserToken{UserID: userID, Purpose: purpose, Hash: hash, ExpiresAt: time.Now().Add(ttl)}
+	if err := s.Users.CreateToken(context.Background(), token); err != nil {
+		t.Fatal(err)
+	}
+
+	return hash
+}
+
+func newPassword(t *testing.T, text string) *User {
+	t.Helper()
+
+	user := &User{}
+	if err := user.Password.Set(text); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestResetTokenIsSingleUse(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	user := newTestUser(t, s)
+
+	hash := newTestToken(t, s, user.ID, TokenResetPassword, time.Hour)
+
+	reset := newPassword(t, "first new password")
+	if err := s.Users.ResetPassword(ctx, hash, reset); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if reset.ID != user.ID {
+		t.Errorf("reset user = %v, want %v", reset.ID, user.ID)
+	}
+
+	if err := s.Users.ResetPassword(ctx, hash, newPassword(t, "second new password")); err != ErrNotFound {
+		t.Errorf("second use: err = %v, want %v", err, ErrNotFound)
+	}
+
+	saved, err := s.Users.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := saved.Password.ComparePassword("first new password"); err != nil {
+		t.Errorf("password after reset: %v", err)
+	}
+}
+
+func TestNewTokenReplacesEarlier(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	user := newTestUser(t, s)
+
+	earlier := newTestToken(t, s, user.ID, TokenResetPassword, time.Hour)
+	verify := newTestToken(t, s, user.ID, TokenVerifyEmail, time.Hour)
+	latest := newTestToken(t, s, user.ID, TokenResetPassword, time.Hour)
+
+	if err := s.Users.ResetPassword(ctx, earlier, newPassword(t, "from the earlier link")); err != ErrNotFound {
+		t.Errorf("earlier link: err = %v, want %v", err, ErrNotFound)
+	}
+	if err := s.Users.ResetPassword(ctx, latest, newPassword(t, "from the latest link")); err != nil {
+		t.Errorf("latest link: %v", err)
+	}
+
+	// A reset token is not a verification token, and issuing one leaves
+	// the verification link alone.
+	if err := s.Users.VerifyEmail(ctx, latest); err != ErrNotFound {
+		t.Errorf("reset token as verification: err = %v, want %v", err, ErrNotFound)
+	}
+	if err := s.Users.VerifyEmail(ctx, verify); err != nil {
+		t.Errorf("verification link: %v", err)
+	}
+}
+
+func TestExpiredTokenRejected(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	user := newTestUser(t, s)
+
+	hash := newTestToken(t, s, user.ID, TokenResetPassword, -time.Minute)
+	if err := s.Users.ResetPassword(ctx, hash, newPassword(t, "too late")); err != ErrNotFound {
+		t.Errorf("err = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestResetPasswordRevokesSessions(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	user := newTestUser(t, s)
+	other := newTestUser(t, s)
+
+	laptop, _ := newTestSession(t, s, user.ID)
+	phone, _ := newTestSession(t, s, user.ID)
+	untouched, _ := newTestSession(t, s, other.ID)
+
+	hash := newTestToken(t, s, user.ID, TokenResetPassword, time.Hour)
+	if err := s.Users.ResetPassword(ctx, hash, newPassword(t, "new password")); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := s.Sessions.GetActiveByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 0 {
+		t.Errorf("%d sessions still active after reset", len(sessions))
+	}
+	for _, session := range []*Session{laptop, phone} {
+		if active, err := s.Sessions.IsActive(ctx, user.ID, session.ID); err != nil || active {
+			t.Errorf("session %v active = %v (%v), want revoked", session.ID, active, err)
+		}
+	}
+
+	if active, err := s.Sessions.IsActive(ctx, other.ID, untouched.ID); err != nil || !active {
+		t.Errorf("another user's session active = %v (%v), want active", active, err)
+	}
+}
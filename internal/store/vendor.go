@@ -0,0 +1,190 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDuplicateVendor = errors.New("vendor already exists")
+	ErrVendorInUse     = errors.New("vendor has purchase bills and cannot be deleted")
+)
+
+type VendorStore struct {
+	db *sql.DB
+}
+
+func (s *VendorStore) Create(ctx context.Context, vendor *Vendor) error {
+	query := `
+        INSERT INTO vendor (buss_id, name, gstno, email, phone, address)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, created_at
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		vendor.BusID,
+		vendor.Name,
+		vendor.GSTNo,
+		vendor.Email,
+		vendor.Phone,
+		vendor.Address,
+	).Scan(
+		&vendor.ID,
+		&vendor.CreatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicateVendor
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *VendorStore) GetByID(ctx context.Context, vendorID uuid.UUID) (*Vendor, error) {
+	query := `
+        SELECT id, buss_id, name, gstno, email, phone, address, created_at
+        FROM vendor
+        WHERE id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	vendor := &Vendor{}
+	err := s.db.QueryRowContext(ctx, query, vendorID).Scan(
+		&vendor.ID,
+		&vendor.BusID,
+		&vendor.Name,
+		&vendor.GSTNo,
+		&vendor.Email,
+		&vendor.Phone,
+		&vendor.Address,
+		&vendor.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return vendor, nil
+}
+
+func (s *VendorStore) GetByBusID(ctx context.Context, busID uuid.UUID) ([]*Vendor, error) {
+	query := `
+        SELECT id, buss_id, name, gstno, email, phone, address, created_at
+        FROM vendor
+        WHERE buss_id = $1
+        ORDER BY name
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, busID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	vendors := []*Vendor{}
+	for rows.Next() {
+		vendor := &Vendor{}
+		err := rows.Scan(
+			&vendor.ID,
+			&vendor.BusID,
+			&vendor.Name,
+			&vendor.GSTNo,
+			&vendor.Email,
+			&vendor.Phone,
+			&vendor.Address,
+			&vendor.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		vendors = append(vendors, vendor)
+	}
+
+	return vendors, rows.Err()
+}
+
+func (s *VendorStore) Update(ctx context.Context, vendor *Vendor) error {
+	query := `
+        UPDATE vendor
+        SET name = $2,
+            gstno = $3,
+            email = $4,
+            phone = $5,
+            address = $6
+        WHERE id = $1
+    `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(
+		ctx,
+		query,
+		vendor.ID,
+		vendor.Name,
+		vendor.GSTNo,
+		vendor.Email,
+		vendor.Phone,
+		vendor.Address,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicateVendor
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *VendorStore) Delete(ctx context.Context, vendorID uuid.UUID) error {
+	query := `DELETE FROM vendor WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, vendorID)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrVendorInUse
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,267 @@
+// Package tally writes sales data as Tally import XML, so that accountants
+// using Tally can import vouchers instead of re-entering them.
+package tally
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Mapping names the Tally ledgers vouchers post to. Party ledgers are named
+// after the customer and created under PartyGroup.
+type Mapping struct {
+	Sales      string `json:"sales"`
+	CGST       string `json:"cgst"`
+	SGST       string `json:"sgst"`
+	IGST       string `json:"igst"`
+	Receipt    string `json:"receipt"`
+	PartyGroup string `json:"party_group"`
+}
+
+// DefaultMapping uses the ledger and group names of a fresh Tally company,
+// with the GST duty ledgers most CAs create.
+var DefaultMapping = Mapping{
+	Sales:      "Sales",
+	CGST:       "Output CGST",
+	SGST:       "Output SGST",
+	IGST:       "Output IGST",
+	Receipt:    "Bank",
+	PartyGroup: "Sundry Debtors",
+}
+
+type Party struct {
+	Name    string
+	GSTIN   string
+	Address string
+}
+
+type SalesVoucher struct {
+	Number    string
+	Date      time.Time
+	Party     string
+	Narration string
+	Taxable   float64
+	CGST      float64
+	SGST      float64
+	IGST      float64
+}
+
+// CreditNoteVoucher reverses part of a sale: the party is credited and
+// sales and output GST are debited.
+type CreditNoteVoucher struct {
+	Number    string
+	Date      time.Time
+	Party     string
+	Narration string
+	Taxable   float64
+	CGST      float64
+	SGST      float64
+	IGST      float64
+}
+
+type ReceiptVoucher struct {
+	Number    string
+	Date      time.Time
+	Party     string
+	Narration string
+	Amount    float64
+}
+
+type Export struct {
+	Company     string
+	Mapping     Mapping
+	Parties     []Party
+	Sales       []SalesVoucher
+	CreditNotes []CreditNoteVoucher
+	Receipts    []ReceiptVoucher
+}
+
+type envelope struct {
+	XMLName xml.Name `xml:"ENVELOPE"`
+	Header  struct {
+		TallyRequest string `xml:"TALLYREQUEST"`
+	} `xml:"HEADER"`
+	Body struct {
+		ImportData struct {
+			RequestDesc struct {
+				ReportName     string `xml:"REPORTNAME"`
+				CurrentCompany string `xml:"STATICVARIABLES>SVCURRENTCOMPANY"`
+			} `xml:"REQUESTDESC"`
+			Messages []message `xml:"REQUESTDATA>TALLYMESSAGE"`
+		} `xml:"IMPORTDATA"`
+	} `xml:"BODY"`
+}
+
+type message struct {
+	Ledger  *ledger  `xml:"LEDGER,omitempty"`
+	Voucher *voucher `xml:"VOUCHER,omitempty"`
+}
+
+type ledger struct {
+	Name       string       `xml:"NAME,attr"`
+	Action     string       `xml:"ACTION,attr"`
+	LedgerName string       `xml:"NAME"`
+	Parent     string       `xml:"PARENT"`
+	Address    *addressList `xml:"ADDRESS.LIST,omitempty"`
+	GSTIN      string       `xml:"PARTYGSTIN,omitempty"`
+	Billwise   string       `xml:"ISBILLWISEON"`
+}
+
+// addressList is a pointer on ledger so that a party without an address
+// has no empty ADDRESS.LIST.
+type addressList struct {
+	Address string `xml:"ADDRESS"`
+}
+
+type voucher struct {
+	VchType     string        `xml:"VCHTYPE,attr"`
+	Action      string        `xml:"ACTION,attr"`
+	Date        string        `xml:"DATE"`
+	TypeName    string        `xml:"VOUCHERTYPENAME"`
+	Number      string        `xml:"VOUCHERNUMBER"`
+	PartyLedger string        `xml:"PARTYLEDGERNAME"`
+	Narration   string        `xml:"NARRATION,omitempty"`
+	Entries     []ledgerEntry `xml:"ALLLEDGERENTRIES.LIST"`
+}
+
+// ledgerEntry is one line of a voucher. Tally signs debits negative and
+// marks them as deemed positive.
+type ledgerEntry struct {
+	LedgerName     string `xml:"LEDGERNAME"`
+	DeemedPositive string `xml:"ISDEEMEDPOSITIVE"`
+	Amount         string `xml:"AMOUNT"`
+}
+
+func debit(name string, amount float64) ledgerEntry {
+	return ledgerEntry{LedgerName: name, DeemedPositive: "Yes", Amount: formatAmount(-amount)}
+}
+
+func credit(name string, amount float64) ledgerEntry {
+	return ledgerEntry{LedgerName: name, DeemedPositive: "No", Amount: formatAmount(amount)}
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func formatDate(date time.Time) string {
+	return date.Format("20060102")
+}
+
+// taxEntries returns the GST lines of a voucher, leaving out zero amounts.
+func taxEntries(mapping Mapping, cgst, sgst, igst float64, entry func(string, float64) ledgerEntry) []ledgerEntry {
+	var entries []ledgerEntry
+	for _, tax := range []struct {
+		ledger string
+		amount float64
+	}{
+		{mapping.CGST, cgst},
+		{mapping.SGST, sgst},
+		{mapping.IGST, igst},
+	} {
+		if tax.amount != 0 {
+			entries = append(entries, entry(tax.ledger, tax.amount))
+		}
+	}
+	return entries
+}
+
+// Write encodes the export as a single Tally import request: party ledgers
+// first so the vouchers that follow can refer to them, then sales, credit
+// note and receipt vouchers. Zero tax lines are left out.
+func Write(w io.Writer, export Export) error {
+	var env envelope
+	env.Header.TallyRequest = "Import Data"
+	env.Body.ImportData.RequestDesc.ReportName = "Vouchers"
+	env.Body.ImportData.RequestDesc.CurrentCompany = export.Company
+
+	messages := []message{}
+	for _, party := range export.Parties {
+		var address *addressList
+		if party.Address != "" {
+			address = &addressList{Address: party.Address}
+		}
+
+		messages = append(messages, message{Ledger: &ledger{
+			Name:       party.Name,
+			Action:     "Create",
+			LedgerName: party.Name,
+			Parent:     export.Mapping.PartyGroup,
+			Address:    address,
+			GSTIN:      party.GSTIN,
+			Billwise:   "Yes",
+		}})
+	}
+
+	for _, sale := range export.Sales {
+		total := sale.Taxable + sale.CGST + sale.SGST + sale.IGST
+		entries := []ledgerEntry{
+			debit(sale.Party, total),
+			credit(export.Mapping.Sales, sale.Taxable),
+		}
+		entries = append(entries, taxEntries(export.Mapping, sale.CGST, sale.SGST, sale.IGST, credit)...)
+
+		messages = append(messages, message{Voucher: &voucher{
+			VchType:     "Sales",
+			Action:      "Create",
+			Date:        formatDate(sale.Date),
+			TypeName:    "Sales",
+			Number:      sale.Number,
+			PartyLedger: sale.Party,
+			Narration:   sale.Narration,
+			Entries:     entries,
+		}})
+	}
+
+	for _, note := range export.CreditNotes {
+		total := note.Taxable + note.CGST + note.SGST + note.IGST
+		entries := []ledgerEntry{
+			credit(note.Party, total),
+			debit(export.Mapping.Sales, note.Taxable),
+		}
+		entries = append(entries, taxEntries(export.Mapping, note.CGST, note.SGST, note.IGST, debit)...)
+
+		messages = append(messages, message{Voucher: &voucher{
+			VchType:     "Credit Note",
+			Action:      "Create",
+			Date:        formatDate(note.Date),
+			TypeName:    "Credit Note",
+			Number:      note.Number,
+			PartyLedger: note.Party,
+			Narration:   note.Narration,
+			Entries:     entries,
+		}})
+	}
+
+	for _, receipt := range export.Receipts {
+		messages = append(messages, message{Voucher: &voucher{
+			VchType:     "Receipt",
+			Action:      "Create",
+			Date:        formatDate(receipt.Date),
+			TypeName:    "Receipt",
+			Number:      receipt.Number,
+			PartyLedger: receipt.Party,
+			Narration:   receipt.Narration,
+			Entries: []ledgerEntry{
+				debit(export.Mapping.Receipt, receipt.Amount),
+				credit(receipt.Party, receipt.Amount),
+			},
+		}})
+	}
+	env.Body.ImportData.Messages = messages
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(env); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
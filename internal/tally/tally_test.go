@@ -0,0 +1,138 @@
+package tally
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func date(day int) time.Time {
+	return time.Date(2024, time.April, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		export Export
+	}{
+		{
+			name: "party_ledgers",
+			export: Export{
+				Company: "Sharma & Sons",
+				Mapping: DefaultMapping,
+				Parties: []Party{
+					{Name: "Acme Traders", GSTIN: "27AAAPL1234C1Z5", Address: "12 MG Road, Pune"},
+					{Name: "Rao & Co <Retail>"},
+				},
+			},
+		},
+		{
+			name: "sales_vouchers",
+			export: Export{
+				Company: "Sharma Traders",
+				Mapping: DefaultMapping,
+				Parties: []Party{{Name: "Acme Traders", GSTIN: "27AAAPL1234C1Z5"}},
+				Sales: []SalesVoucher{
+					{
+						Number:    "101",
+						Date:      date(3),
+						Party:     "Acme Traders",
+						Narration: "Invoice #101",
+						Taxable:   1000,
+						CGST:      90,
+						SGST:      90,
+					},
+					{
+						Number:    "102",
+						Date:      date(5),
+						Party:     "Acme Traders",
+						Narration: "Invoice #102",
+						Taxable:   2500.5,
+						IGST:      450.09,
+					},
+					{
+						Number:  "103",
+						Date:    date(7),
+						Party:   "Acme Traders",
+						Taxable: 300,
+					},
+				},
+			},
+		},
+		{
+			name: "credit_notes",
+			export: Export{
+				Company: "Sharma Traders",
+				Mapping: DefaultMapping,
+				Parties: []Party{{Name: "Acme Traders"}},
+				CreditNotes: []CreditNoteVoucher{
+					{
+						Number:    "CN-1",
+						Date:      date(10),
+						Party:     "Acme Traders",
+						Narration: "Credit note #1 against invoice #101",
+						Taxable:   500,
+						CGST:      45,
+						SGST:      45,
+					},
+					{
+						Number:    "CN-2",
+						Date:      date(12),
+						Party:     "Acme Traders",
+						Narration: "Credit note #2 against invoice #102",
+						Taxable:   250,
+						IGST:      45,
+					},
+				},
+			},
+		},
+		{
+			name: "receipts",
+			export: Export{
+				Company: "Sharma Traders",
+				Mapping: Mapping{
+					Sales:      "Sales Accounts",
+					CGST:       "CGST Payable",
+					SGST:       "SGST Payable",
+					IGST:       "IGST Payable",
+					Receipt:    "HDFC Current A/c",
+					PartyGroup: "Debtors",
+				},
+				Parties: []Party{{Name: "Acme Traders"}},
+				Receipts: []ReceiptVoucher{
+					{Number: "R-101", Date: date(15), Party: "Acme Traders", Narration: "Payment against invoice #101", Amount: 1180},
+					{Number: "R-102-1", Date: date(16), Party: "Acme Traders", Amount: 1000.25},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, tt.export); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+
+			golden := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("Write output differs from %s:\n%s", golden, buf.String())
+			}
+		})
+	}
+}